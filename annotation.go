@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"golang.org/x/exp/slices"
+	"gopkg.in/yaml.v3"
+)
+
+// Annotation is a labeled reference value like an SLO drawn with
+// histograms.
+type Annotation struct {
+	Label string
+	Value float64
+}
+
+// readAnnotationsFile reads annotations from a YAML file like
+//
+//	# Reference lines.
+//	- label: SLO
+//	  value: 250ms
+//	- label: p99 last week
+//	  value: 310ms
+//
+// Values are parsed with parse, so they can be written in the same way as
+// input values. The result is sorted by value.
+func readAnnotationsFile(filename string, parse ValueParser) ([]Annotation, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []struct {
+		Label string `yaml:"label"`
+		Value string `yaml:"value"`
+	}
+	if err := yaml.Unmarshal(data, &items); err != nil {
+		return nil, fmt.Errorf("invalid annotations file %s: %s", filename, err)
+	}
+	annotations := make([]Annotation, len(items))
+	for i, item := range items {
+		if item.Label == "" {
+			return nil, fmt.Errorf("annotation #%d in %s has no label", i+1, filename)
+		}
+		value, err := parse(item.Value)
+		if err != nil {
+			return nil, fmt.Errorf("annotation %q in %s has an invalid value: %s", item.Label, filename, err)
+		}
+		annotations[i] = Annotation{Label: item.Label, Value: value}
+	}
+	slices.SortStableFunc(annotations, func(a, b Annotation) bool { return a.Value < b.Value })
+	return annotations, nil
+}
+
+// annotationRowIndexes returns the indexes of text rows to put the rows
+// of annotations after. Rows are those of visible buckets, and -1 means
+// before the first row. An annotation in a hidden bucket is put after the
+// nearest visible bucket below it.
+func annotationRowIndexes(rangePoints []float64, hidden []bool, annotations []Annotation) []int {
+	indexes := make([]int, len(annotations))
+	for i, a := range annotations {
+		row := -1
+		for bucket := 0; bucket < len(rangePoints)-1 && rangePoints[bucket] <= a.Value; bucket++ {
+			if hidden == nil || !hidden[bucket] {
+				row++
+			}
+		}
+		indexes[i] = row
+	}
+	return indexes
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/exp/slices"
+)
+
+func TestReadAnnotationsFile(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "annotations.yaml")
+	content := "- label: SLO\n  value: 250ms\n- label: p99 last week\n  value: \"1.5s\"\n- label: fast\n  value: 10ms\n"
+	if err := os.WriteFile(filename, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	parse, err := newValueParser(valueTypeDuration, time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := readAnnotationsFile(filename, parse)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Annotation{{Label: "fast", Value: 10}, {Label: "SLO", Value: 250}, {Label: "p99 last week", Value: 1500}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("result mismatch, got=%v, want=%v", got, want)
+	}
+
+	for _, content := range []string{"- value: 1\n", "- label: x\n  value: foo\n", "label: x\n"} {
+		if err := os.WriteFile(filename, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := readAnnotationsFile(filename, parseFiniteFloat); err == nil {
+			t.Errorf("error expected, content=%q", content)
+		}
+	}
+}
+
+func TestAnnotationRowIndexes(t *testing.T) {
+	rangePoints := []float64{0, 1, 2, 3}
+	annotations := []Annotation{{Value: -1}, {Value: 0}, {Value: 1.5}, {Value: 2.5}, {Value: 3}}
+	if got, want := annotationRowIndexes(rangePoints, nil, annotations), []int{-1, 0, 1, 2, 2}; !slices.Equal(got, want) {
+		t.Errorf("result mismatch, got=%v, want=%v", got, want)
+	}
+	hidden := []bool{false, true, false}
+	if got, want := annotationRowIndexes(rangePoints, hidden, annotations), []int{-1, 0, 0, 1, 1}; !slices.Equal(got, want) {
+		t.Errorf("result mismatch with hidden buckets, got=%v, want=%v", got, want)
+	}
+}
+
+func TestMultipleHistogramFormatter_Annotations(t *testing.T) {
+	h := NewHistogram(BuildRangePoints[float64](2, 0, 2))
+	h.AddValues([]float64{0.5, 1.5, 1.7})
+	f := MustNewMultipleHistogramFormatter([]*Histogram[float64]{h}, WithBarChar("*"), WithWidth(40), WithPointFormat("%.1f"))
+	f.SetAnnotations([]Annotation{{Label: "low", Value: -1}, {Label: "SLO", Value: 1.2}})
+	want := strings.Join([]string{
+		"     -- -1.0  low",
+		"   0.0 ~ 1.0  1 |***********",
+		"   1.0 ~ 2.0  2 |***********************",
+		"      -- 1.2  SLO",
+		"out of range  0 |",
+	}, "\n") + "\n"
+	if got := f.String(); got != want {
+		t.Errorf("result mismatch,\n got=%s\nwant=%s", got, want)
+	}
+}
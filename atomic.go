@@ -0,0 +1,142 @@
+package main
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// AtomicHistogram is a histogram which multiple goroutines can add values
+// to without a lock, for recording in hot paths like request handlers.
+// Counts are read with Snapshot.
+//
+// Counters are kept in two sets, one of which is hot and takes new values.
+// Snapshot makes the other set hot, waits for values being added to the
+// previous hot set and copies it, so that a snapshot includes every value
+// whose AddValue returned before Snapshot was called and never a partially
+// added one. The copied counts are then moved to the new hot set.
+type AtomicHistogram[T Number] struct {
+	// h holds range points and settings. Its counts are never used.
+	h *Histogram[T]
+	// countAndHotIdx is the number of values whose adding has started in
+	// the lower 63 bits and the index of the hot counters in the top bit.
+	countAndHotIdx atomic.Uint64
+	counters       [2]*atomicCounters
+	// snapshotMu serializes Snapshot calls.
+	snapshotMu sync.Mutex
+}
+
+type atomicCounters struct {
+	counts    []atomic.Int64
+	underflow atomic.Int64
+	overflow  atomic.Int64
+	nan       atomic.Int64
+	// added is the number of values whose adding has finished.
+	added atomic.Uint64
+}
+
+const atomicHotIdxBit = 1 << 63
+
+// NewAtomicHistogram returns an empty AtomicHistogram with rangePoints.
+func NewAtomicHistogram[T Number](rangePoints []T) *AtomicHistogram[T] {
+	a := &AtomicHistogram[T]{h: NewHistogram(rangePoints)}
+	for i := range a.counters {
+		a.counters[i] = &atomicCounters{counts: make([]atomic.Int64, len(rangePoints)-1)}
+	}
+	return a
+}
+
+// SetBoundaryEpsilon is like Histogram.SetBoundaryEpsilon. It must be
+// called before adding values.
+func (a *AtomicHistogram[T]) SetBoundaryEpsilon(epsilon float64) {
+	a.h.SetBoundaryEpsilon(epsilon)
+}
+
+// SetClosure is like Histogram.SetClosure. It must be called before adding
+// values.
+func (a *AtomicHistogram[T]) SetClosure(closure Closure) {
+	a.h.SetClosure(closure)
+}
+
+// SetInclusiveMax is like Histogram.SetInclusiveMax. It must be called
+// before adding values.
+func (a *AtomicHistogram[T]) SetInclusiveMax(inclusive bool) {
+	a.h.SetInclusiveMax(inclusive)
+}
+
+func (a *AtomicHistogram[T]) AddValue(v T) {
+	n := a.countAndHotIdx.Add(1)
+	c := a.counters[n>>63]
+	switch i := a.h.locate(v); i {
+	case locateUnderflow:
+		c.underflow.Add(1)
+	case locateOverflow:
+		c.overflow.Add(1)
+	case locateNaN:
+		c.nan.Add(1)
+	default:
+		c.counts[i].Add(1)
+	}
+	c.added.Add(1)
+}
+
+func (a *AtomicHistogram[T]) AddValues(values []T) {
+	for _, v := range values {
+		a.AddValue(v)
+	}
+}
+
+func (a *AtomicHistogram[T]) Counts() []int {
+	return a.Snapshot().Counts()
+}
+
+func (a *AtomicHistogram[T]) RangePoints() []T {
+	// rangePoints are never modified after construction.
+	return a.h.RangePoints()
+}
+
+func (a *AtomicHistogram[T]) Quantile(q float64) float64 {
+	return a.Snapshot().Quantile(q)
+}
+
+// Snapshot returns a copy of the current counts as a Histogram, which can
+// be formatted or marshaled. Adding values is not blocked while it runs,
+// though concurrent calls of Snapshot are serialized.
+func (a *AtomicHistogram[T]) Snapshot() *Histogram[T] {
+	a.snapshotMu.Lock()
+	defer a.snapshotMu.Unlock()
+
+	n := a.countAndHotIdx.Add(atomicHotIdxBit)
+	started := n &^ atomicHotIdxBit
+	hot, cold := a.counters[n>>63], a.counters[(n>>63)^1]
+	// Values which took the cold counters before the switch may still be
+	// being added.
+	for cold.added.Load() != started {
+		runtime.Gosched()
+	}
+
+	s := &Histogram[T]{
+		rangePoints:     a.h.rangePoints,
+		counts:          make([]int, len(cold.counts)),
+		underflowCount:  int(cold.underflow.Load()),
+		overflowCount:   int(cold.overflow.Load()),
+		closure:         a.h.closure,
+		inclusiveMax:    a.h.inclusiveMax,
+		boundaryEpsilon: a.h.boundaryEpsilon,
+		uniformWidth:    a.h.uniformWidth,
+	}
+	for i := range cold.counts {
+		s.counts[i] = int(cold.counts[i].Load())
+	}
+	s.outOfRangeCount = s.underflowCount + s.overflowCount + int(cold.nan.Load())
+
+	// Move the counts to the hot counters, so that they are cumulative.
+	for i := range cold.counts {
+		hot.counts[i].Add(cold.counts[i].Swap(0))
+	}
+	hot.underflow.Add(cold.underflow.Swap(0))
+	hot.overflow.Add(cold.overflow.Swap(0))
+	hot.nan.Add(cold.nan.Swap(0))
+	hot.added.Add(cold.added.Swap(0))
+	return s
+}
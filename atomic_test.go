@@ -0,0 +1,103 @@
+package main
+
+import (
+	"math"
+	"sync"
+	"testing"
+
+	"golang.org/x/exp/slices"
+)
+
+func TestAtomicHistogram(t *testing.T) {
+	h := NewAtomicHistogram(BuildRangePoints[float64](4, 0, 4))
+	const goroutines = 8
+	const n = 1000
+	var wg sync.WaitGroup
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < n; j++ {
+				h.AddValue(float64(j % 5))
+			}
+		}()
+	}
+	// Snapshots taken while adding never lose values.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		prev := 0
+		for i := 0; i < 100; i++ {
+			total := h.Snapshot().TotalCount()
+			if total < prev {
+				t.Errorf("total count decreased, got=%d, prev=%d", total, prev)
+			}
+			prev = total
+		}
+	}()
+	wg.Wait()
+	<-done
+
+	snapshot := h.Snapshot()
+	if got, want := snapshot.Counts(), []int{1600, 1600, 1600, 1600}; !slices.Equal(got, want) {
+		t.Errorf("counts mismatch, got=%v, want=%v", got, want)
+	}
+	if got, want := snapshot.OverflowCount(), 1600; got != want {
+		t.Errorf("overflow count mismatch, got=%d, want=%d", got, want)
+	}
+
+	h.AddValue(0.5)
+	if got, want := snapshot.Counts()[0], 1600; got != want {
+		t.Errorf("snapshot must not change, got=%d, want=%d", got, want)
+	}
+	if got, want := h.Counts()[0], 1601; got != want {
+		t.Errorf("count mismatch after snapshot, got=%d, want=%d", got, want)
+	}
+}
+
+func TestAtomicHistogram_Settings(t *testing.T) {
+	h := NewAtomicHistogram([]float64{0, 1, 2})
+	h.SetClosure(ClosureRight)
+	h.AddValues([]float64{-1, 0, 1, 2, 3, math.NaN()})
+
+	// Snapshots are cumulative and match a Histogram with the same
+	// settings.
+	h.Snapshot()
+	want := NewHistogram([]float64{0, 1, 2})
+	want.SetClosure(ClosureRight)
+	want.AddValues([]float64{-1, 0, 1, 2, 3, math.NaN()})
+	got := h.Snapshot()
+	if !slices.Equal(got.Counts(), want.Counts()) {
+		t.Errorf("counts mismatch, got=%v, want=%v", got.Counts(), want.Counts())
+	}
+	if got.UnderflowCount() != want.UnderflowCount() || got.OverflowCount() != want.OverflowCount() || got.OutOfRangeCount() != want.OutOfRangeCount() {
+		t.Errorf("out of range counts mismatch, got=%d/%d/%d, want=%d/%d/%d",
+			got.UnderflowCount(), got.OverflowCount(), got.OutOfRangeCount(),
+			want.UnderflowCount(), want.OverflowCount(), want.OutOfRangeCount())
+	}
+	if got.Closure() != ClosureRight {
+		t.Errorf("closure mismatch, got=%q, want=%q", got.Closure(), ClosureRight)
+	}
+}
+
+func BenchmarkAtomicHistogram_AddValue(b *testing.B) {
+	h := NewAtomicHistogram(BuildRangePoints[float64](100, 0, 100))
+	b.RunParallel(func(pb *testing.PB) {
+		v := 0.0
+		for pb.Next() {
+			h.AddValue(v)
+			v = math.Mod(v+1.5, 100)
+		}
+	})
+}
+
+func BenchmarkConcurrentHistogram_AddValue(b *testing.B) {
+	h := NewConcurrentHistogram(BuildRangePoints[float64](100, 0, 100))
+	b.RunParallel(func(pb *testing.PB) {
+		v := 0.0
+		for pb.Next() {
+			h.AddValue(v)
+			v = math.Mod(v+1.5, 100)
+		}
+	})
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// maxBinWidthBucketCount is the largest bucket count made by a bin width,
+// which guards against a width far too small for the axis range.
+const maxBinWidthBucketCount = 100000
+
+// BuildWidthRangePoints returns points from min or below to max or above
+// which are the multiples of width, so that every bucket has the width.
+// At least one bucket is returned even if min equals max. width must be
+// positive.
+func BuildWidthRangePoints(width, min, max float64) ([]float64, error) {
+	if !(width > 0) {
+		panic("width must be positive")
+	}
+
+	// Points are k*width. Dividing by the inverse of a width like 0.1
+	// instead keeps points like 0.3 exact.
+	point := func(k float64) float64 { return k * width }
+	if inv := 1 / width; inv == math.Round(inv) {
+		point = func(k float64) float64 { return k / inv }
+	}
+	kMin := math.Floor(min / width)
+	kMax := math.Ceil(max / width)
+	// Guard against rounding errors of the divisions above.
+	for point(kMin) > min {
+		kMin--
+	}
+	for point(kMax) < max {
+		kMax++
+	}
+	if kMax == kMin {
+		kMax++
+	}
+
+	count := kMax - kMin
+	if count > maxBinWidthBucketCount {
+		return nil, fmt.Errorf("bin width %g makes %.0f buckets from %g to %g, more than %d", width, count, min, max, maxBinWidthBucketCount)
+	}
+	rangePoints := make([]float64, 0, int(count)+1)
+	for k := kMin; k <= kMax; k++ {
+		rangePoints = append(rangePoints, point(k))
+	}
+	return rangePoints, nil
+}
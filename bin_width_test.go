@@ -0,0 +1,33 @@
+package main
+
+import (
+	"testing"
+
+	"golang.org/x/exp/slices"
+)
+
+func TestBuildWidthRangePoints(t *testing.T) {
+	testCases := []struct {
+		width, min, max float64
+		want            []float64
+	}{
+		{width: 0.5, min: 0.3, max: 1.7, want: []float64{0, 0.5, 1, 1.5, 2}},
+		{width: 0.1, min: 0.25, max: 0.5, want: []float64{0.2, 0.3, 0.4, 0.5}},
+		{width: 10, min: -15, max: 20, want: []float64{-20, -10, 0, 10, 20}},
+		{width: 3, min: 4, max: 4, want: []float64{3, 6}},
+		{width: 2, min: 4, max: 4, want: []float64{4, 6}},
+	}
+	for _, tc := range testCases {
+		got, err := BuildWidthRangePoints(tc.width, tc.min, tc.max)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(got, tc.want) {
+			t.Errorf("result mismatch, width=%g, min=%g, max=%g, got=%v, want=%v", tc.width, tc.min, tc.max, got, tc.want)
+		}
+	}
+
+	if _, err := BuildWidthRangePoints(1e-9, 0, 1); err == nil {
+		t.Error("want error for too many buckets")
+	}
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// BucketCountMethod is a rule to choose the histogram bucket count from data.
+type BucketCountMethod string
+
+const (
+	// BucketCountSturges uses Sturges' rule, ceil(log2(n)) + 1.
+	BucketCountSturges BucketCountMethod = "sturges"
+	// BucketCountRice uses the Rice rule, ceil(2 * n^(1/3)).
+	BucketCountRice BucketCountMethod = "rice"
+	// BucketCountScott uses Scott's normal reference rule for the bucket
+	// width, 3.49 * stddev * n^(-1/3).
+	BucketCountScott BucketCountMethod = "scott"
+	// BucketCountFreedmanDiaconis uses the Freedman–Diaconis rule for the
+	// bucket width, 2 * IQR * n^(-1/3).
+	BucketCountFreedmanDiaconis BucketCountMethod = "fd"
+)
+
+// bucketCountAuto is the --bucket-count value to choose the bucket count
+// with BucketCountFreedmanDiaconis.
+const bucketCountAuto = "auto"
+
+var bucketCountMethods = []BucketCountMethod{
+	BucketCountSturges,
+	BucketCountRice,
+	BucketCountScott,
+	BucketCountFreedmanDiaconis,
+}
+
+func parseBucketCountMethod(s string) (BucketCountMethod, error) {
+	if s == bucketCountAuto {
+		return BucketCountFreedmanDiaconis, nil
+	}
+	for _, m := range bucketCountMethods {
+		if s == string(m) {
+			return m, nil
+		}
+	}
+	return "", fmt.Errorf("invalid bucket count method: %q", s)
+}
+
+// SuggestBucketCount returns the bucket count for values chosen by method.
+// The width based rules, BucketCountScott and BucketCountFreedmanDiaconis,
+// fall back to BucketCountSturges when the spread of values is zero.
+// The result is always at least 1.
+func SuggestBucketCount(values []float64, method BucketCountMethod) int {
+	return suggestBucketCount(weightedValues{values: values}, method)
+}
+
+// suggestBucketCount is like SuggestBucketCount for values each repeated
+// by its count.
+func suggestBucketCount(w weightedValues, method BucketCountMethod) int {
+	n := w.total()
+	if n == 0 {
+		panic("values must not be empty")
+	}
+
+	var count int
+	switch method {
+	case BucketCountSturges:
+		count = sturgesBucketCount(n)
+	case BucketCountRice:
+		count = int(math.Ceil(2 * math.Cbrt(float64(n))))
+	case BucketCountScott:
+		count = bucketCountForWidth(w, 3.49*w.stddev()/math.Cbrt(float64(n)))
+	case BucketCountFreedmanDiaconis:
+		sorted := w.clone()
+		sorted.sort()
+		iqr := sorted.quantileSorted(0.75) - sorted.quantileSorted(0.25)
+		count = bucketCountForWidth(sorted, 2*iqr/math.Cbrt(float64(n)))
+	default:
+		panic(fmt.Sprintf("unknown bucket count method: %q", method))
+	}
+	return MustMax(count, 1)
+}
+
+func sturgesBucketCount(n int) int {
+	return int(math.Ceil(math.Log2(float64(n)))) + 1
+}
+
+func bucketCountForWidth(w weightedValues, width float64) int {
+	if width <= 0 || math.IsNaN(width) {
+		return sturgesBucketCount(w.total())
+	}
+	return int(math.Ceil((MustMax(w.values...) - MustMin(w.values...)) / width))
+}
+
+// minRelativeBucketWidth is the smallest bucket width relative to the
+// magnitude of the axis range. Narrower buckets cannot be told apart
+// because of the float64 precision.
+const minRelativeBucketWidth = 1e-12
+
+// countDistinctValues returns the number of distinct values in lists, or
+// limit+1 if there are more than limit distinct values.
+func countDistinctValues(lists []weightedValues, limit int) int {
+	seen := make(map[float64]struct{})
+	for _, w := range lists {
+		for _, v := range w.values {
+			seen[v] = struct{}{}
+			if len(seen) > limit {
+				return len(seen)
+			}
+		}
+	}
+	return len(seen)
+}
+
+// canCenterBuckets returns whether the axis range can be decided by
+// centeredAxisRange, which is when both ends come from the minimum and the
+// maximum of values and no other rule moves bucket edges.
+func canCenterBuckets(cfg Config) bool {
+	return cfg.BucketCount > 1 &&
+		cfg.AxisMin.Auto && cfg.AxisMin.Percentile == 0 &&
+		cfg.AxisMax.Auto && cfg.AxisMax.Percentile == 0 &&
+		!cfg.IncludeZero && !cfg.NiceEdges
+}
+
+// centeredAxisRange returns the axis range of bucketCount buckets whose
+// centers are evenly spaced from dataMin to dataMax, in logarithms on log
+// scale. It is used after reducing the bucket count to the number of
+// distinct values, so that evenly spaced values like integers get a bucket
+// each with the value in its middle, instead of the largest one falling on
+// the upper end of the axis range.
+func centeredAxisRange(scale string, dataMin, dataMax float64, bucketCount int) (float64, float64) {
+	if scale == scaleLog {
+		lo, hi := centeredAxisRange(scaleLinear, math.Log(dataMin), math.Log(dataMax), bucketCount)
+		return math.Exp(lo), math.Exp(hi)
+	}
+	halfWidth := (dataMax - dataMin) / float64(bucketCount-1) / 2
+	return dataMin - halfWidth, dataMax + halfWidth
+}
+
+// maxBucketCountForWidth returns the largest bucket count for the axis
+// range from min to max whose bucket width is not smaller than
+// minRelativeBucketWidth. It returns an error if even a single bucket is
+// too narrow.
+func maxBucketCountForWidth(min, max float64) (int, error) {
+	minWidth := math.Max(math.Abs(min), math.Abs(max)) * minRelativeBucketWidth
+	if minWidth == 0 {
+		return math.MaxInt, nil
+	}
+	n := math.Floor((max - min) / minWidth)
+	if n < 1 {
+		return 0, fmt.Errorf("axis range from %g to %g is too narrow", min, max)
+	}
+	if n > math.MaxInt32 {
+		return math.MaxInt32, nil
+	}
+	return int(n), nil
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSuggestBucketCount(t *testing.T) {
+	values := make([]float64, 1000)
+	for i := range values {
+		values[i] = float64(i)
+	}
+	testCases := []struct {
+		values []float64
+		method BucketCountMethod
+		want   int
+	}{
+		{values: values, method: BucketCountSturges, want: 11},
+		{values: values, method: BucketCountRice, want: 20},
+		{values: values, method: BucketCountScott, want: 10},
+		{values: values, method: BucketCountFreedmanDiaconis, want: 10},
+		{values: []float64{1}, method: BucketCountSturges, want: 1},
+		{values: []float64{3, 3, 3, 3}, method: BucketCountFreedmanDiaconis, want: 3},
+		{values: []float64{3, 3, 3, 3}, method: BucketCountScott, want: 3},
+	}
+	for _, tc := range testCases {
+		got := SuggestBucketCount(tc.values, tc.method)
+		if got != tc.want {
+			t.Errorf("result mismatch, len(values)=%d, method=%s, got=%d, want=%d", len(tc.values), tc.method, got, tc.want)
+		}
+	}
+}
+
+func TestQuantileSorted(t *testing.T) {
+	sorted := []float64{1, 2, 3, 4, 5}
+	testCases := []struct {
+		input float64
+		want  float64
+	}{
+		{input: 0, want: 1},
+		{input: 0.25, want: 2},
+		{input: 0.5, want: 3},
+		{input: 0.6, want: 3.4},
+		{input: 1, want: 5},
+	}
+	for _, tc := range testCases {
+		got := quantileSorted(sorted, tc.input)
+		if got != tc.want {
+			t.Errorf("result mismatch, input=%g, got=%g, want=%g", tc.input, got, tc.want)
+		}
+	}
+}
+
+func TestCountDistinctValues(t *testing.T) {
+	lists := []weightedValues{{values: []float64{1, 2, 2, 3}}, {values: []float64{3, 4, 1}, counts: []int{2, 1, 5}}}
+	testCases := []struct {
+		limit int
+		want  int
+	}{
+		{limit: 10, want: 4},
+		{limit: 4, want: 4},
+		{limit: 2, want: 3},
+	}
+	for _, tc := range testCases {
+		got := countDistinctValues(lists, tc.limit)
+		if got != tc.want {
+			t.Errorf("result mismatch, limit=%d, got=%d, want=%d", tc.limit, got, tc.want)
+		}
+	}
+}
+
+func TestCenteredAxisRange(t *testing.T) {
+	testCases := []struct {
+		scale            string
+		min, max         float64
+		bucketCount      int
+		wantMin, wantMax float64
+	}{
+		{scale: scaleLinear, min: 1, max: 5, bucketCount: 5, wantMin: 0.5, wantMax: 5.5},
+		{scale: scaleLinear, min: 1, max: 10, bucketCount: 3, wantMin: -1.25, wantMax: 12.25},
+		{scale: scaleLog, min: 1, max: 100, bucketCount: 3, wantMin: math.Sqrt(0.1), wantMax: math.Sqrt(1e5)},
+	}
+	for _, tc := range testCases {
+		gotMin, gotMax := centeredAxisRange(tc.scale, tc.min, tc.max, tc.bucketCount)
+		if math.Abs(gotMin-tc.wantMin) > 1e-9*math.Abs(tc.wantMin) || math.Abs(gotMax-tc.wantMax) > 1e-9*math.Abs(tc.wantMax) {
+			t.Errorf("result mismatch, scale=%s, min=%g, max=%g, bucketCount=%d, got=%g ~ %g, want=%g ~ %g",
+				tc.scale, tc.min, tc.max, tc.bucketCount, gotMin, gotMax, tc.wantMin, tc.wantMax)
+		}
+	}
+}
+
+func TestMaxBucketCountForWidth(t *testing.T) {
+	testCases := []struct {
+		min, max float64
+		want     int
+		wantErr  bool
+	}{
+		{min: 0, max: 1, want: math.MaxInt32},
+		{min: 1000, max: 1000.5, want: 499750124},
+		{min: 1, max: 1 + 1e-10, want: 100},
+		{min: 1, max: 1 + 1e-13, wantErr: true},
+		{min: -1e20, max: 1e20, want: math.MaxInt32},
+	}
+	for _, tc := range testCases {
+		got, err := maxBucketCountForWidth(tc.min, tc.max)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("error expected, min=%g, max=%g", tc.min, tc.max)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("unexpected error, min=%g, max=%g, err=%v", tc.min, tc.max, err)
+		} else if got != tc.want {
+			t.Errorf("result mismatch, min=%g, max=%g, got=%d, want=%d", tc.min, tc.max, got, tc.want)
+		}
+	}
+}
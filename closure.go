@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Closure selects which end of a bucket is included in it.
+type Closure string
+
+const (
+	// ClosureLeft makes buckets [lower, upper), which is the default.
+	ClosureLeft Closure = "left"
+	// ClosureRight makes buckets (lower, upper] like Prometheus histograms,
+	// whose le buckets include their upper bounds.
+	ClosureRight Closure = "right"
+)
+
+func parseClosure(s string) (Closure, error) {
+	switch closure := Closure(s); closure {
+	case ClosureLeft, ClosureRight:
+		return closure, nil
+	default:
+		return "", fmt.Errorf("invalid closure: %q", s)
+	}
+}
+
+// bucketIndexClosed is like bucketIndex but buckets are closed at closure.
+func bucketIndexClosed[T Number](rangePoints []T, v T, closure Closure) (int, bool) {
+	if closure != ClosureRight {
+		return bucketIndex(rangePoints, v)
+	}
+	// Written in this form so that NaN is also out of range.
+	if !(v > rangePoints[0] && v <= rangePoints[len(rangePoints)-1]) {
+		return 0, false
+	}
+	return sort.Search(len(rangePoints), func(i int) bool { return rangePoints[i] >= v }) - 1, true
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+
+	"golang.org/x/exp/slices"
+)
+
+func TestHistogram_SetClosure(t *testing.T) {
+	testCases := []struct {
+		closure        Closure
+		wantCounts     []int
+		wantOutOfRange int
+	}{
+		{closure: ClosureLeft, wantCounts: []int{2, 2}, wantOutOfRange: 3},
+		{closure: ClosureRight, wantCounts: []int{2, 2}, wantOutOfRange: 3},
+	}
+	values := []float64{0, 0.5, 1, 1.5, 2, -1, math.NaN()}
+	for _, c := range testCases {
+		h := NewHistogram([]float64{0, 1, 2})
+		h.SetClosure(c.closure)
+		h.AddValues(values)
+		if got := h.Counts(); !slices.Equal(got, c.wantCounts) || h.outOfRangeCount != c.wantOutOfRange {
+			t.Errorf("result mismatch, closure=%s, counts=%v, outOfRange=%d, want=%v, %d",
+				c.closure, got, h.outOfRangeCount, c.wantCounts, c.wantOutOfRange)
+		}
+	}
+
+	// Values on inner edges go to different buckets.
+	left := NewHistogram([]float64{0, 1, 2})
+	left.AddValues([]float64{1, 1})
+	right := NewHistogram([]float64{0, 1, 2})
+	right.SetClosure(ClosureRight)
+	right.AddValues([]float64{1, 1})
+	if got, want := left.Counts(), []int{0, 2}; !slices.Equal(got, want) {
+		t.Errorf("left result mismatch, got=%v, want=%v", got, want)
+	}
+	if got, want := right.Counts(), []int{2, 0}; !slices.Equal(got, want) {
+		t.Errorf("right result mismatch, got=%v, want=%v", got, want)
+	}
+
+	if err := left.Merge(right); !errors.Is(err, ErrClosureMismatch) {
+		t.Errorf("merge error mismatch, got=%v, want=%v", err, ErrClosureMismatch)
+	}
+}
+
+func TestHistogram_ClosureJSON(t *testing.T) {
+	h := NewHistogram([]float64{0, 1, 2})
+	h.SetClosure(ClosureRight)
+	h.AddValues([]float64{1, 2})
+	data, err := json.Marshal(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(data), `{"rangePoints":[0,1,2],"counts":[1,1],"outOfRangeCount":0,"underflowCount":0,"overflowCount":0,"closure":"right"}`; got != want {
+		t.Errorf("result mismatch, got=%s, want=%s", got, want)
+	}
+	var got Histogram[float64]
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Closure() != ClosureRight {
+		t.Errorf("closure mismatch, got=%s, want=%s", got.Closure(), ClosureRight)
+	}
+
+	if err := json.Unmarshal([]byte(`{"rangePoints":[0,1],"counts":[0],"outOfRangeCount":0,"closure":"up"}`), &got); err == nil {
+		t.Error("want error for invalid closure")
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Values of the --color flag.
+const (
+	colorAuto   = "auto"
+	colorAlways = "always"
+	colorNever  = "never"
+)
+
+// useColor decides whether to color the output for the --color flag
+// value. For "auto", colors are used only if the NO_COLOR environment
+// variable is empty and out is a terminal.
+func useColor(mode string, out *os.File) (bool, error) {
+	switch mode {
+	case colorAlways:
+		return true, nil
+	case colorNever:
+		return false, nil
+	case colorAuto:
+		if os.Getenv("NO_COLOR") != "" {
+			return false, nil
+		}
+		info, err := out.Stat()
+		if err != nil {
+			return false, nil
+		}
+		return info.Mode()&os.ModeCharDevice != 0, nil
+	default:
+		return false, fmt.Errorf("invalid color mode: %q", mode)
+	}
+}
+
+// ANSI escape sequences for colors.
+const (
+	ansiReset      = "\x1b[0m"
+	ansiDim        = "\x1b[2m"
+	ansiOutOfRange = "\x1b[1;35m"
+)
+
+// ansiSeriesColors are 256-color foregrounds for bars of each histogram,
+// taken from the colorblind-safe palette by Okabe and Ito.
+var ansiSeriesColors = []string{
+	"\x1b[38;5;32m",  // blue
+	"\x1b[38;5;214m", // orange
+	"\x1b[38;5;36m",  // bluish green
+	"\x1b[38;5;166m", // vermillion
+	"\x1b[38;5;117m", // sky blue
+	"\x1b[38;5;175m", // reddish purple
+	"\x1b[38;5;227m", // yellow
+}
+
+// Palette is a set of colors of histograms of inputs in colored text
+// output.
+type Palette string
+
+const (
+	// PaletteOkabeIto is the colorblind-safe palette by Okabe and Ito,
+	// which is the default.
+	PaletteOkabeIto Palette = "okabe-ito"
+	// PaletteTol is the bright palette by Paul Tol, which is also
+	// colorblind-safe.
+	PaletteTol Palette = "tol"
+	// PaletteBasic uses the standard ANSI colors for terminals without 256
+	// colors. It is not colorblind-safe.
+	PaletteBasic Palette = "basic"
+)
+
+func parsePalette(s string) (Palette, error) {
+	switch palette := Palette(s); palette {
+	case PaletteOkabeIto, PaletteTol, PaletteBasic:
+		return palette, nil
+	default:
+		return "", fmt.Errorf("invalid palette: %q", s)
+	}
+}
+
+// ansiColors returns the ANSI foregrounds of p. An empty p is
+// PaletteOkabeIto.
+func (p Palette) ansiColors() []string {
+	switch p {
+	case PaletteTol:
+		return []string{
+			"\x1b[38;5;67m",  // blue
+			"\x1b[38;5;81m",  // cyan
+			"\x1b[38;5;28m",  // green
+			"\x1b[38;5;179m", // yellow
+			"\x1b[38;5;204m", // red
+			"\x1b[38;5;132m", // purple
+			"\x1b[38;5;250m", // grey
+		}
+	case PaletteBasic:
+		return []string{
+			"\x1b[34m", // blue
+			"\x1b[33m", // yellow
+			"\x1b[32m", // green
+			"\x1b[31m", // red
+			"\x1b[36m", // cyan
+			"\x1b[35m", // magenta
+		}
+	default:
+		return ansiSeriesColors
+	}
+}
+
+// ansiColorNames are the standard ANSI colors accepted by --series-color.
+var ansiColorNames = map[string]int{
+	"black":   0,
+	"red":     1,
+	"green":   2,
+	"yellow":  3,
+	"blue":    4,
+	"magenta": 5,
+	"cyan":    6,
+	"white":   7,
+}
+
+// parseSeriesColor parses a --series-color value like "2=red" or "1=208"
+// into the index of the input from 0 and its ANSI foreground. Inputs are
+// numbered from 1, and a color is a standard color name or a 256-color
+// number.
+func parseSeriesColor(s string) (int, string, error) {
+	numStr, colorStr, ok := strings.Cut(s, "=")
+	if !ok {
+		return 0, "", fmt.Errorf("series color must be like 2=red, got %q", s)
+	}
+	num, err := strconv.Atoi(numStr)
+	if err != nil || num < 1 {
+		return 0, "", fmt.Errorf("input number must be a positive integer, got %q", numStr)
+	}
+	colorStr = strings.ToLower(colorStr)
+	if code, ok := ansiColorNames[colorStr]; ok {
+		return num - 1, fmt.Sprintf("\x1b[3%dm", code), nil
+	}
+	code, err := strconv.Atoi(colorStr)
+	if err != nil || code < 0 || code > 255 {
+		return 0, "", fmt.Errorf("color must be a name like red or a 256-color number, got %q", colorStr)
+	}
+	return num - 1, fmt.Sprintf("\x1b[38;5;%dm", code), nil
+}
+
+// seriesColor returns the ANSI color of the i-th histogram, which is
+// overridden by colors, or taken from palette in turn.
+func seriesColor(palette Palette, colors map[int]string, i int) string {
+	if color, ok := colors[i]; ok {
+		return color
+	}
+	ansiColors := palette.ansiColors()
+	return ansiColors[i%len(ansiColors)]
+}
+
+// colorize wraps s with color and the reset sequence. An empty s or color
+// is returned as is.
+func colorize(s, color string) string {
+	if s == "" || color == "" {
+		return s
+	}
+	return color + s + ansiReset
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestUseColor(t *testing.T) {
+	file, err := os.Create(filepath.Join(t.TempDir(), "out"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	t.Setenv("NO_COLOR", "")
+	testCases := []struct {
+		mode string
+		want bool
+	}{
+		{mode: colorAlways, want: true},
+		{mode: colorNever, want: false},
+		// A regular file is not a terminal.
+		{mode: colorAuto, want: false},
+	}
+	for _, tc := range testCases {
+		got, err := useColor(tc.mode, file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tc.want {
+			t.Errorf("result mismatch, mode=%s, got=%v, want=%v", tc.mode, got, tc.want)
+		}
+	}
+
+	t.Setenv("NO_COLOR", "1")
+	if got, _ := useColor(colorAlways, file); !got {
+		t.Error("always must override NO_COLOR")
+	}
+	if _, err := useColor("yes", file); err == nil {
+		t.Error("error expected for invalid mode")
+	}
+}
+
+func TestHistogramFormatter_Color(t *testing.T) {
+	h := NewHistogram(BuildRangePoints[float64](2, 0, 2))
+	h.AddValues([]float64{1.5, 1.7, 3})
+	f := MustNewMultipleHistogramFormatter([]*Histogram[float64]{h, h}, WithBarChar("*"), WithWidth(60), WithPointFormat("%.1f"))
+	f.SetColor(true)
+	want := strings.Join([]string{
+		ansiDim + "   0.0 ~ 1.0" + ansiReset + "  " + ansiDim + "0" + ansiReset + " |" + strings.Repeat(" ", 19) + " " + ansiDim + "0" + ansiReset + " |",
+		"   1.0 ~ 2.0" + "  2 |" + ansiSeriesColors[0] + strings.Repeat("*", 19) + ansiReset + " 2 |" + ansiSeriesColors[1] + strings.Repeat("*", 19) + ansiReset,
+		ansiOutOfRange + "out of range" + ansiReset + "  " + ansiOutOfRange + "1" + ansiReset + " |" + strings.Repeat(" ", 19) + " " + ansiOutOfRange + "1" + ansiReset + " |",
+	}, "\n") + "\n"
+	if got := f.String(); got != want {
+		t.Errorf("result mismatch,\n got=%q\nwant=%q", got, want)
+	}
+}
+
+func TestParseSeriesColor(t *testing.T) {
+	testCases := []struct {
+		s         string
+		wantIndex int
+		wantColor string
+	}{
+		{s: "1=red", wantIndex: 0, wantColor: "\x1b[31m"},
+		{s: "3=Cyan", wantIndex: 2, wantColor: "\x1b[36m"},
+		{s: "2=208", wantIndex: 1, wantColor: "\x1b[38;5;208m"},
+	}
+	for _, tc := range testCases {
+		i, color, err := parseSeriesColor(tc.s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i != tc.wantIndex || color != tc.wantColor {
+			t.Errorf("result mismatch, s=%s, got=%d %q, want=%d %q", tc.s, i, color, tc.wantIndex, tc.wantColor)
+		}
+	}
+}
+
+func TestRenderModel_SeriesColor(t *testing.T) {
+	m := &RenderModel{Palette: PaletteBasic, SeriesColors: map[int]string{1: "\x1b[38;5;208m"}}
+	basic := PaletteBasic.ansiColors()
+	testCases := []struct {
+		i    int
+		want string
+	}{
+		{i: 0, want: basic[0]},
+		{i: 1, want: "\x1b[38;5;208m"},
+		{i: 2, want: basic[2]},
+		// Colors of the palette are used in turn.
+		{i: len(basic), want: basic[0]},
+	}
+	for _, tc := range testCases {
+		if got := m.seriesColor(tc.i); got != tc.want {
+			t.Errorf("result mismatch, i=%d, got=%q, want=%q", tc.i, got, tc.want)
+		}
+	}
+	// The zero value is the default palette.
+	if got := (&RenderModel{}).seriesColor(1); got != ansiSeriesColors[1] {
+		t.Errorf("result mismatch, got=%q, want=%q", got, ansiSeriesColors[1])
+	}
+}
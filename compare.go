@@ -0,0 +1,286 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// compareCommandName is the subcommand comparing two inputs.
+const compareCommandName = "compare"
+
+// Bar characters of compareOutputFormat for buckets which shrank and grew.
+const (
+	compareShrankChar = "-"
+	compareGrewChar   = "+"
+)
+
+// compareMaxLabelWidth is the width of labels in the header, which are cut
+// at the start if they are wider than it and the values in their columns.
+const compareMaxLabelWidth = 12
+
+// ANSI colors for buckets which shrank and grew.
+const (
+	ansiShrank = "\x1b[31m"
+	ansiGrew   = "\x1b[32m"
+)
+
+// compareOutputFormat renders two histograms on shared buckets as a table
+// of the change of each bucket from the first one to the second one, like
+//
+//	               before  after  delta  change
+//	 0.00 ~ 25.00      24     20     -4  -16.7%    ----|
+//	25.00 ~ 50.00      25     30     +5  +20.0%        |+++++
+//
+// with a bar of the delta growing to the left for buckets which shrank and
+// to the right for those which grew. With Percent, shares of the totals
+// are compared instead of counts, so that inputs of different sizes can be
+// compared. The shift of the median, which sums up the changes, and the
+// results of tests follow the table.
+//
+// With json, the same numbers are rendered as a JSON object instead.
+type compareOutputFormat struct {
+	tests []StatTest
+	json  bool
+}
+
+func (compareOutputFormat) Name() string { return compareCommandName }
+
+func (f compareOutputFormat) Render(w io.Writer, m *RenderModel) error {
+	if len(m.Histograms) != 2 {
+		return fmt.Errorf("compare needs 2 histograms, got %d", len(m.Histograms))
+	}
+	if f.json {
+		return f.renderJSON(w, m)
+	}
+	if m.Title != "" {
+		if _, err := fmt.Fprintf(w, "%s\n\n", m.Title); err != nil {
+			return err
+		}
+	}
+	before, after := m.Histograms[0], m.Histograms[1]
+	formatter, err := NewHistogramFormatter(before,
+		WithBarChar(m.BarChar),
+		WithWidth(m.GraphWidth),
+		WithPointFormat(m.PointFmt),
+		WithTickStyle(m.TickStyle),
+	)
+	if err != nil {
+		return err
+	}
+	ranges := formatter.RangeStrings()
+
+	beforeValues, afterValues := compareRowValues(before, m.Percent), compareRowValues(after, m.Percent)
+	beforeStrs := make([]string, len(ranges))
+	afterStrs := make([]string, len(ranges))
+	deltaStrs := make([]string, len(ranges))
+	changeStrs := make([]string, len(ranges))
+	deltas := make([]float64, len(ranges))
+	maxAbsDelta := 0.0
+	for i := range ranges {
+		b, a := beforeValues[i], afterValues[i]
+		deltas[i] = a - b
+		maxAbsDelta = math.Max(maxAbsDelta, math.Abs(deltas[i]))
+		if m.Percent {
+			beforeStrs[i] = fmt.Sprintf("%.1f%%", b)
+			afterStrs[i] = fmt.Sprintf("%.1f%%", a)
+			deltaStrs[i] = fmt.Sprintf("%+.1fpp", deltas[i])
+		} else {
+			beforeStrs[i] = strconv.Itoa(int(b))
+			afterStrs[i] = strconv.Itoa(int(a))
+			deltaStrs[i] = fmt.Sprintf("%+d", int(deltas[i]))
+		}
+		changeStrs[i] = formatChange(b, a)
+	}
+
+	columns := [][]string{ranges, beforeStrs, afterStrs, deltaStrs, changeStrs}
+	headers := []string{"", m.Labels[0], m.Labels[1], "delta", "change"}
+	for j := 1; j <= 2; j++ {
+		headers[j] = truncateStartToWidth(headers[j], MustMax(compareMaxLabelWidth, stringSliceMaxWidth(columns[j])))
+	}
+	widths := make([]int, len(columns))
+	textWidth := 0
+	for j, column := range columns {
+		widths[j] = MustMax(stringSliceMaxWidth(column), stringWidth(headers[j]))
+		textWidth += widths[j] + 2
+	}
+	// Each half of the bar excludes the center line.
+	halfWidth := (m.GraphWidth - textWidth - 1) / 2
+	if halfWidth < 1 {
+		return errGraphWidthTooSmall
+	}
+
+	header := make([]string, len(headers))
+	for j, h := range headers {
+		header[j] = padStartSpace(widths[j], h)
+	}
+	if _, err := fmt.Fprintln(w, strings.TrimRight(strings.Join(header, "  "), " ")); err != nil {
+		return err
+	}
+	for i := range ranges {
+		cells := make([]string, len(columns))
+		for j, column := range columns {
+			cells[j] = padStartSpace(widths[j], column[i])
+		}
+		bar := compareBar(deltas[i], maxAbsDelta, halfWidth)
+		if m.Color {
+			color := ""
+			switch {
+			case deltas[i] < 0:
+				color = ansiShrank
+			case deltas[i] > 0:
+				color = ansiGrew
+			}
+			cells[3] = colorize(cells[3], color)
+			bar = colorize(bar, color)
+		}
+		line := strings.Join(cells, "  ") + "  " + bar
+		if _, err := fmt.Fprintln(w, strings.TrimRight(line, " ")); err != nil {
+			return err
+		}
+	}
+
+	if _, err := fmt.Fprintln(w); err != nil {
+		return err
+	}
+	if median := compareMedians(m); !math.IsNaN(median.Delta) {
+		shift := fmt.Sprintf(m.PointFmt, median.Delta)
+		if median.Delta >= 0 {
+			shift = "+" + shift
+		}
+		_, err := fmt.Fprintf(w, "median shift: %s %s, %s -> %s\n", shift, formatRelativeDelta(median.After, median.Before),
+			fmt.Sprintf(m.PointFmt, median.Before), fmt.Sprintf(m.PointFmt, median.After))
+		if err != nil {
+			return err
+		}
+	}
+	if len(f.tests) == 0 {
+		return nil
+	}
+	results, err := formatTestResults(f.tests, before, after)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, results)
+	return err
+}
+
+// compareChange is the change of a number from the first input to the
+// second one.
+type compareChange struct {
+	Before float64 `json:"before"`
+	After  float64 `json:"after"`
+	Delta  float64 `json:"delta"`
+	// Change is Delta relative to Before, which is nil if Before is 0.
+	Change *float64 `json:"change"`
+}
+
+func newCompareChange(before, after float64) compareChange {
+	c := compareChange{Before: before, After: after, Delta: after - before}
+	if before != 0 && !math.IsNaN(c.Delta) {
+		change := c.Delta / math.Abs(before)
+		c.Change = &change
+	}
+	return c
+}
+
+// compareMedians returns the change of the median, whose Delta is NaN if
+// either histogram has no value in range.
+func compareMedians(m *RenderModel) compareChange {
+	return newCompareChange(m.quantile(0, 0.5), m.quantile(1, 0.5))
+}
+
+// renderJSON renders the changes of buckets, the out of range count and
+// the median, and the results of tests as a JSON object.
+func (f compareOutputFormat) renderJSON(w io.Writer, m *RenderModel) error {
+	type bucket struct {
+		Lower float64 `json:"lower"`
+		Upper float64 `json:"upper"`
+		compareChange
+	}
+	type test struct {
+		Name StatTest `json:"name"`
+		TestResult
+	}
+	result := struct {
+		Before     string         `json:"before"`
+		After      string         `json:"after"`
+		Percent    bool           `json:"percent"`
+		Buckets    []bucket       `json:"buckets"`
+		OutOfRange compareChange  `json:"outOfRange"`
+		Median     *compareChange `json:"median"`
+		Tests      []test         `json:"tests,omitempty"`
+	}{Before: m.Labels[0], After: m.Labels[1], Percent: m.Percent}
+
+	before, after := m.Histograms[0], m.Histograms[1]
+	beforeValues, afterValues := compareRowValues(before, m.Percent), compareRowValues(after, m.Percent)
+	for i := 0; i < len(before.counts); i++ {
+		result.Buckets = append(result.Buckets, bucket{
+			Lower:         before.rangePoints[i],
+			Upper:         before.rangePoints[i+1],
+			compareChange: newCompareChange(beforeValues[i], afterValues[i]),
+		})
+	}
+	last := len(beforeValues) - 1
+	result.OutOfRange = newCompareChange(beforeValues[last], afterValues[last])
+	if median := compareMedians(m); !math.IsNaN(median.Delta) {
+		result.Median = &median
+	}
+	for _, name := range f.tests {
+		r, err := runStatTest(name, before, after)
+		if err != nil {
+			return err
+		}
+		result.Tests = append(result.Tests, test{Name: name, TestResult: r})
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(result)
+}
+
+// compareRowValues returns the counts of buckets and the out of range
+// count of h, or their shares of the total in percent with percent.
+func compareRowValues(h *Histogram[float64], percent bool) []float64 {
+	values := make([]float64, 0, len(h.counts)+1)
+	for _, count := range h.counts {
+		values = append(values, float64(count))
+	}
+	values = append(values, float64(h.outOfRangeCount))
+	if total := h.TotalCount(); percent && total > 0 {
+		for i := range values {
+			values[i] = values[i] / float64(total) * 100
+		}
+	}
+	return values
+}
+
+// formatChange formats the relative change from before to after, which is
+// "new" for a bucket which was empty and is empty for one staying empty.
+func formatChange(before, after float64) string {
+	switch {
+	case before == 0 && after == 0:
+		return ""
+	case before == 0:
+		return "new"
+	default:
+		return fmt.Sprintf("%+.1f%%", (after-before)/before*100)
+	}
+}
+
+// compareBar returns a bar of delta scaled so that maxAbsDelta takes
+// halfWidth characters, drawn to the left of the center line for a
+// negative delta and to the right for a positive one.
+func compareBar(delta, maxAbsDelta float64, halfWidth int) string {
+	n := 0
+	if maxAbsDelta > 0 {
+		n = int(math.Round(math.Abs(delta) / maxAbsDelta * float64(halfWidth)))
+	}
+	if delta < 0 {
+		return padStartSpace(halfWidth, strings.Repeat(compareShrankChar, n)) + "|"
+	}
+	return strings.Repeat(" ", halfWidth) + "|" + strings.Repeat(compareGrewChar, n)
+}
@@ -0,0 +1,70 @@
+package main
+
+import "sync"
+
+// ConcurrentHistogram is a Histogram which is safe for concurrent use by
+// multiple goroutines.
+type ConcurrentHistogram[T Number] struct {
+	mu sync.Mutex
+	h  *Histogram[T]
+}
+
+// NewConcurrentHistogram returns an empty ConcurrentHistogram with
+// rangePoints.
+func NewConcurrentHistogram[T Number](rangePoints []T) *ConcurrentHistogram[T] {
+	return &ConcurrentHistogram[T]{h: NewHistogram(rangePoints)}
+}
+
+func (c *ConcurrentHistogram[T]) AddValue(v T) {
+	c.mu.Lock()
+	c.h.AddValue(v)
+	c.mu.Unlock()
+}
+
+func (c *ConcurrentHistogram[T]) AddValues(values []T) {
+	c.mu.Lock()
+	c.h.AddValues(values)
+	c.mu.Unlock()
+}
+
+// Merge adds counts of other to c. See Histogram.Merge.
+func (c *ConcurrentHistogram[T]) Merge(other *Histogram[T]) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.h.Merge(other)
+}
+
+func (c *ConcurrentHistogram[T]) Counts() []int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.h.Counts()
+}
+
+func (c *ConcurrentHistogram[T]) RangePoints() []T {
+	// rangePoints are never modified after construction.
+	return c.h.RangePoints()
+}
+
+func (c *ConcurrentHistogram[T]) Quantile(q float64) float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.h.Quantile(q)
+}
+
+// Snapshot returns a copy of the current state as a Histogram, which can be
+// formatted or marshaled without holding the lock.
+func (c *ConcurrentHistogram[T]) Snapshot() *Histogram[T] {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return &Histogram[T]{
+		rangePoints:     c.h.rangePoints,
+		counts:          c.h.Counts(),
+		outOfRangeCount: c.h.outOfRangeCount,
+		underflowCount:  c.h.underflowCount,
+		overflowCount:   c.h.overflowCount,
+		closure:         c.h.closure,
+		inclusiveMax:    c.h.inclusiveMax,
+		boundaryEpsilon: c.h.boundaryEpsilon,
+		uniformWidth:    c.h.uniformWidth,
+	}
+}
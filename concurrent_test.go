@@ -0,0 +1,38 @@
+package main
+
+import (
+	"sync"
+	"testing"
+
+	"golang.org/x/exp/slices"
+)
+
+func TestConcurrentHistogram(t *testing.T) {
+	h := NewConcurrentHistogram(BuildRangePoints[float64](4, 0, 4))
+	const goroutines = 8
+	const n = 1000
+	var wg sync.WaitGroup
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < n; j++ {
+				h.AddValue(float64(j % 5))
+			}
+		}()
+	}
+	wg.Wait()
+
+	snapshot := h.Snapshot()
+	if got, want := snapshot.Counts(), []int{1600, 1600, 1600, 1600}; !slices.Equal(got, want) {
+		t.Errorf("counts mismatch, got=%v, want=%v", got, want)
+	}
+	if got, want := snapshot.outOfRangeCount, 1600; got != want {
+		t.Errorf("out of range count mismatch, got=%d, want=%d", got, want)
+	}
+
+	h.AddValue(0.5)
+	if got, want := snapshot.Counts()[0], 1600; got != want {
+		t.Errorf("snapshot must not change, got=%d, want=%d", got, want)
+	}
+}
@@ -0,0 +1,1054 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"regexp"
+	"runtime"
+	"time"
+
+	"github.com/urfave/cli/v2"
+)
+
+// Config is the configuration of a run built from command line arguments.
+type Config struct {
+	BucketCount        int
+	BinWidth           float64
+	DropOutliers       *outlierRule
+	BucketCountMethod  BucketCountMethod
+	AxisMin            axisRangeEnd
+	AxisMax            axisRangeEnd
+	Scale              string
+	GraphWidth         int
+	GraphHeight        int
+	FitHeight          int
+	PointFmt           string
+	TickStyle          TickStyle
+	IncludeZero        bool
+	MinCount           int
+	BoundaryEpsilon    float64
+	Closure            Closure
+	InclusiveMax       bool
+	Duration           time.Duration
+	Percent            bool
+	Cumulative         bool
+	CDFBar             bool
+	Backend            string
+	HDRDigits          int
+	TDigestCompression float64
+	InputFormat        InputFormat
+	ValueParser        ValueParser
+	SkipLines          int
+	SkipInvalid        bool
+	MaxErrors          int
+	Warner             Warner
+	Logger             *slog.Logger
+	SummaryStat        *SummaryStat
+	NiceEdges          bool
+	EdgesAtPercentiles []float64
+	Title              string
+	Labels             []string
+	GroupBy            *groupByInputFormat
+	ColumnFormats      []InputFormat
+	SampleEvery        int
+	Parallelism        int
+	SampleProb         float64
+	SampleScale        bool
+	StatTests          []StatTest
+	MetricName         string
+	Annotations        []Annotation
+	Color              bool
+	Palette            Palette
+	SeriesColors       map[int]string
+	BarStyle           BarStyle
+	Orientation        Orientation
+	OutputDir          string
+	Save               string
+	Serve              string
+	Statsd             string
+	StatsdInterval     time.Duration
+	OutputFormat       OutputFormat
+	CPUProfile         string
+	MemProfile         string
+	Trace              string
+	SaveRecipe         string
+	Recipe             Recipe
+	ReportFile         string
+	Filenames          []string
+
+	// ListOutputFormats and ListInputFormats are set instead of other
+	// fields when the names of registered formats are asked for.
+	ListOutputFormats bool
+	ListInputFormats  bool
+}
+
+// ErrHelpShown is returned by ParseConfig when the help or the version is
+// shown instead of running.
+var ErrHelpShown = errors.New("help shown")
+
+// ParseConfig parses command line arguments, excluding the program name,
+// into a Config and validates them.
+func ParseConfig(args []string) (Config, error) {
+	var cfg Config
+	parsed := false
+	app := newApp()
+	app.Action = func(cCtx *cli.Context) error {
+		if cCtx.Bool("version") {
+			cli.ShowVersion(cCtx)
+			return nil
+		}
+		var err error
+		cfg, err = configFromContext(cCtx)
+		parsed = true
+		return err
+	}
+	app.Commands = []*cli.Command{{
+		Name:      "report",
+		Usage:     "render a report of panels over inputs defined in a YAML file, with global options applied to all panels",
+		ArgsUsage: "report.yaml",
+		Action: func(cCtx *cli.Context) error {
+			if cCtx.NArg() != 1 {
+				return errors.New("report needs exactly one report file argument")
+			}
+			// Global options are parsed by the app, whose arguments are
+			// "report" and the report file.
+			var err error
+			cfg, err = configFromContext(cCtx.Lineage()[1])
+			parsed = true
+			if err != nil {
+				return err
+			}
+			switch {
+			case cfg.Serve != "" || cfg.Statsd != "":
+				return errors.New("report cannot be used with --serve or --statsd")
+			case cfg.OutputDir != "":
+				return errors.New("report cannot be used with --output-dir")
+			case cfg.Save != "":
+				return errors.New("report cannot be used with --save")
+			case cfg.GroupBy != nil:
+				return errors.New("report cannot be used with --group-by")
+			}
+			cfg.ReportFile = cCtx.Args().First()
+			cfg.Filenames = nil
+			return nil
+		},
+	}, {
+		Name:      compareCommandName,
+		Usage:     "compare histograms of two inputs on shared buckets with the change of each bucket, with global options applied to both",
+		ArgsUsage: "before after",
+		Action: func(cCtx *cli.Context) error {
+			if cCtx.NArg() != 2 {
+				return errors.New("compare needs exactly two filename arguments")
+			}
+			parent := cCtx.Lineage()[1]
+			var err error
+			cfg, err = configFromContext(parent)
+			parsed = true
+			if err != nil {
+				return err
+			}
+			switch {
+			case parent.IsSet("output") && cfg.OutputFormat.Name() != "text" && cfg.OutputFormat.Name() != "json" || parent.IsSet("template"):
+				return errors.New("compare supports only the text and json outputs")
+			case cfg.Serve != "" || cfg.Statsd != "":
+				return errors.New("compare cannot be used with --serve or --statsd")
+			case cfg.SummaryStat != nil:
+				return errors.New("compare cannot be used with --summary-across-files")
+			}
+			cfg.OutputFormat = compareOutputFormat{tests: cfg.StatTests, json: cfg.OutputFormat.Name() == "json"}
+			return nil
+		},
+	}}
+	if err := app.Run(append([]string{app.Name}, args...)); err != nil {
+		return Config{}, err
+	}
+	if !parsed {
+		return Config{}, ErrHelpShown
+	}
+	return cfg, nil
+}
+
+func newApp() *cli.App {
+	return &cli.App{
+		Name:    "histogram",
+		Version: Version(),
+		// -v is --verbose, so the version is only shown with the --version
+		// flag of the app instead of the global cli.VersionFlag having -v.
+		HideVersion: true,
+		Usage:       "Read numbers from file(s) and show histogram(s) on terminal",
+		UsageText:   fmt.Sprintf("histogram [GLOBAL OPTIONS] filename1 [filename2]\n   histogram [GLOBAL OPTIONS] report report.yaml\n   histogram [GLOBAL OPTIONS] compare before after\n\n   (You can use %q as filename for stdin.)", stdinFilename),
+		// Usage errors are logged by main like other errors, instead of
+		// printing the help to stdout.
+		OnUsageError: func(cCtx *cli.Context, err error, isSubcommand bool) error {
+			return err
+		},
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "axis-min",
+				Aliases: []string{"n"},
+				Value:   axisAuto,
+				Usage:   fmt.Sprintf("axis minimum value, %q, or a percentile of values like p1 to ignore outliers", axisAuto),
+			},
+			&cli.StringFlag{
+				Name:    "axis-max",
+				Aliases: []string{"x"},
+				Value:   axisAuto,
+				Usage:   fmt.Sprintf("axis maximum value, %q, or a percentile of values like p99 to ignore outliers", axisAuto),
+			},
+			&cli.StringFlag{
+				Name:    "bucket-count",
+				Aliases: []string{"c"},
+				Value:   "10",
+				Usage:   fmt.Sprintf(`histogram bucket count, or %q, "sturges", "rice", "scott" or "fd" to choose it from data (%q is same as "fd")`, bucketCountAuto, bucketCountAuto),
+			},
+			&cli.Float64Flag{
+				Name:  "bin-width",
+				Usage: "bucket width instead of --bucket-count, putting edges at multiples of it",
+			},
+			&cli.StringFlag{
+				Name:  "drop-outliers",
+				Usage: fmt.Sprintf(`drop values further than k times the interquartile range from the quartiles with "%s:k", or k standard deviations from the mean with "%s:k", from both the axis range and the counts; k defaults to %g and %g`, outlierMethodIQR, outlierMethodStddev, defaultOutlierFactors[outlierMethodIQR], defaultOutlierFactors[outlierMethodStddev]),
+			},
+			&cli.BoolFlag{
+				Name:    "include-zero",
+				Aliases: []string{"z"},
+				Usage:   "extend auto axis range to include zero and put zero on a bucket boundary",
+			},
+			&cli.StringFlag{
+				Name:    "scale",
+				Aliases: []string{"s"},
+				Value:   scaleLinear,
+				Usage:   fmt.Sprintf("bucket scale, %q or %q", scaleLinear, scaleLog),
+			},
+			&cli.IntFlag{
+				Name:    "graph-width",
+				Aliases: []string{"w"},
+				Value:   defaultGraphWidth,
+				Usage:   "graph column width including labels",
+			},
+			&cli.StringFlag{
+				Name:    "point-format",
+				Aliases: []string{"f"},
+				Value:   defaultPointFmt,
+				Usage:   "format string for axis point value",
+			},
+			&cli.StringFlag{
+				Name:  "backend",
+				Value: backendHistogram,
+				Usage: fmt.Sprintf("%q keeps values in memory when the axis range is auto, %q records them into a high dynamic range histogram while reading, %q records them into a t-digest which is accurate at tail percentiles", backendHistogram, backendHDR, backendTDigest),
+			},
+			&cli.IntFlag{
+				Name:  "hdr-digits",
+				Value: 3,
+				Usage: fmt.Sprintf("significant decimal digits kept by %q backend, 1 to 5", backendHDR),
+			},
+			&cli.Float64Flag{
+				Name:  "tdigest-compression",
+				Value: 100,
+				Usage: fmt.Sprintf("compression of %q backend, a larger value keeps more centroids for accuracy, 10 or more", backendTDigest),
+			},
+			&cli.StringFlag{
+				Name:  "serve",
+				Usage: "run an HTTP server on this address like :8080 accepting values at POST /values?series=name and serving histograms at GET /histogram/{name}, instead of reading files; values of each series are recorded into a t-digest, or an HDR histogram with --backend hdr",
+			},
+			&cli.StringFlag{
+				Name:  "statsd",
+				Usage: "listen on this UDP address like :8125 for statsd timing metrics like \"name:123|ms\" and print the histogram of each metric periodically, instead of reading files; with --serve, they are also served over HTTP",
+			},
+			&cli.DurationFlag{
+				Name:  "statsd-interval",
+				Value: 10 * time.Second,
+				Usage: "interval to print histograms in the statsd mode",
+			},
+			&cli.StringFlag{
+				Name:  "output-dir",
+				Usage: "write the output for each input to a file named after it in this directory, plus a combined file for multiple inputs",
+			},
+			&cli.StringFlag{
+				Name:  "save",
+				Usage: "save the histograms to this JSON state file, which can be given as an input later to render or merge them without reading values again",
+			},
+			&cli.StringFlag{
+				Name:  "config",
+				Usage: fmt.Sprintf("TOML file of flag defaults like \"bucket-count = 20\", which recipes and flags on the command line override (default: histogram/%s in the user config directory if it exists)", configFileName),
+			},
+			&cli.StringFlag{
+				Name:  "recipe",
+				Usage: "YAML file of flags saved with --save-recipe to apply, which flags on the command line override",
+			},
+			&cli.StringFlag{
+				Name:  "save-recipe",
+				Usage: "save the flags of this run to a YAML file to rerun the same analysis on other data with --recipe",
+			},
+			&cli.StringFlag{
+				Name:  "cpuprofile",
+				Usage: "write a CPU profile to this file",
+			},
+			&cli.StringFlag{
+				Name:  "memprofile",
+				Usage: "write a heap profile to this file at the end",
+			},
+			&cli.StringFlag{
+				Name:  "trace",
+				Usage: "write an execution trace to this file",
+			},
+			&cli.StringFlag{
+				Name:  "bar-style",
+				Value: string(BarStyleChar),
+				Usage: fmt.Sprintf("%q draws bars with the %q character, %q with Unicode blocks showing eighths of a character", BarStyleChar, defaultBarChar, BarStyleBlocks),
+			},
+			&cli.Float64Flag{
+				Name:  "boundary-epsilon",
+				Usage: "regard values within this fraction of the bucket width from a bucket edge as on the edge, e.g. 1e-9 to absorb floating point noise; ignored by the hdr and tdigest backends",
+			},
+			&cli.StringFlag{
+				Name:  "closure",
+				Value: string(ClosureLeft),
+				Usage: fmt.Sprintf("%q counts a value on a bucket edge in the upper bucket as [lower, upper), %q in the lower bucket as (lower, upper] like Prometheus; ignored by the hdr and tdigest backends", ClosureLeft, ClosureRight),
+			},
+			&cli.BoolFlag{
+				Name:  "inclusive-max",
+				Usage: "count values equal to the axis maximum in the last bucket instead of as out of range",
+			},
+			&cli.IntFlag{
+				Name:  "fit-height",
+				Usage: "show at most this many bucket rows in text output by aggregating adjacent buckets into a row, 0 for no limit",
+			},
+			&cli.StringFlag{
+				Name:  "orientation",
+				Value: string(OrientationHorizontal),
+				Usage: fmt.Sprintf("%q draws a row per bucket, %q draws a column per bucket with bars growing upward", OrientationHorizontal, OrientationVertical),
+			},
+			&cli.IntFlag{
+				Name:  "graph-height",
+				Value: defaultGraphHeight,
+				Usage: "graph row height excluding labels for the vertical orientation",
+			},
+			&cli.StringFlag{
+				Name:  "color",
+				Value: colorAuto,
+				Usage: fmt.Sprintf("color text output, %q, %q, or %q which colors only for terminals unless NO_COLOR is set", colorAlways, colorNever, colorAuto),
+			},
+			&cli.StringFlag{
+				Name:  "palette",
+				Value: string(PaletteOkabeIto),
+				Usage: fmt.Sprintf("colors of inputs in colored text output, %q or %q which are colorblind-safe, or %q for terminals without 256 colors", PaletteOkabeIto, PaletteTol, PaletteBasic),
+			},
+			&cli.StringSliceFlag{
+				Name:  "series-color",
+				Usage: "color of an input in colored text output overriding --palette, like 2=red or 1=208 for the input number from 1 and a color name or 256-color number (can be repeated)",
+			},
+			&cli.StringFlag{
+				Name:  "annotations",
+				Usage: "YAML file of reference values to show, a list of items with label and value keys",
+			},
+			&cli.StringFlag{
+				Name:  "title",
+				Usage: "title of the chart, shown above text output and in graphical output formats like svg",
+			},
+			&cli.StringSliceFlag{
+				Name:  "label",
+				Usage: "name of an input file shown in legends instead of the filename, given once per file in the same order",
+			},
+			&cli.StringSliceFlag{
+				Name:  "stat-test",
+				Usage: `statistical test of whether the inputs of compare differ significantly, "ks" (Kolmogorov-Smirnov) or "chi2" (chi-square) on the buckets, which can be given multiple times`,
+			},
+			&cli.StringFlag{
+				Name:  "metric-name",
+				Value: defaultMetricName,
+				Usage: "metric name in the prometheus output format",
+			},
+			&cli.BoolFlag{
+				Name:  "nice-edges",
+				Usage: "snap every bucket edge to 1, 2 or 5 times a power of ten, adjusting the bucket count slightly",
+			},
+			&cli.StringFlag{
+				Name:  "edges-at-percentiles",
+				Usage: "put inner bucket edges at these percentiles of values like 50,90,99 instead of equal widths, showing bucket widths",
+			},
+			&cli.StringFlag{
+				Name:  "summary-across-files",
+				Usage: `compute a statistic per file, "min", "max", "mean", "median" or a percentile like "p99", and show the histogram of them`,
+			},
+			&cli.BoolFlag{
+				Name:    "quiet",
+				Aliases: []string{"q"},
+				Usage:   "write nothing but errors to stderr, hiding warnings and notices",
+			},
+			&cli.BoolFlag{
+				Name:    "verbose",
+				Aliases: []string{"v"},
+				Usage:   "write debug messages like the inputs read to stderr, more with -vv or -v -v",
+			},
+			&cli.BoolFlag{
+				Name:   "vv",
+				Usage:  "same as -v -v",
+				Hidden: true,
+			},
+			&cli.BoolFlag{
+				Name:  "version",
+				Usage: "print the version",
+			},
+			&cli.StringFlag{
+				Name:  "warnings",
+				Value: warningsText,
+				Usage: fmt.Sprintf("format of warnings written to stderr, %q or %q for a JSON object per line", warningsText, warningsJSON),
+			},
+			&cli.StringFlag{
+				Name:  "value-type",
+				Value: valueTypeFloat,
+				Usage: fmt.Sprintf("type of input values, %q for numbers, %q for Go durations like 120ms and 2m3s, or %q for byte sizes like 4K, 1.5MiB and 2GB", valueTypeFloat, valueTypeDuration, valueTypeBytes),
+			},
+			&cli.StringFlag{
+				Name:  "duration-unit",
+				Value: "ms",
+				Usage: fmt.Sprintf("unit to convert %q values to, one of ns, us, ms, s, m and h", valueTypeDuration),
+			},
+			&cli.StringFlag{
+				Name:    "input-format",
+				Aliases: []string{"i"},
+				Value:   "plain",
+				Usage:   fmt.Sprintf("input format, %q to show available formats", inputList),
+			},
+			&cli.IntFlag{
+				Name:  "parallelism",
+				Value: runtime.NumCPU(),
+				Usage: "maximum number of input files read and parsed at the same time, 1 to read them one by one",
+			},
+			&cli.IntFlag{
+				Name:  "sample-every",
+				Usage: "read only every Nth value of each input to take a quick look at huge inputs",
+			},
+			&cli.Float64Flag{
+				Name:  "sample-prob",
+				Usage: "read each value with this probability like 0.01 to take a quick look at huge inputs",
+			},
+			&cli.BoolFlag{
+				Name:  "sample-scale",
+				Usage: "scale counts of sampled values back up to estimates of all values",
+			},
+			&cli.IntSliceFlag{
+				Name:  "columns",
+				Usage: fmt.Sprintf("build a histogram per column like 2,4,5 of the only input file over shared buckets, for the %q and %q input formats", "plain", "csv"),
+			},
+			&cli.IntFlag{
+				Name:  "group-by",
+				Usage: fmt.Sprintf("build a histogram per distinct key in this field over shared buckets, with values in the field given by --field, for the %q and %q input formats", "plain", "csv"),
+			},
+			&cli.IntFlag{
+				Name:    "field",
+				Aliases: []string{"value"},
+				Usage:   fmt.Sprintf("read values in this whitespace separated field of lines, numbered from 1 like awk, for the %q input format", "plain"),
+			},
+			&cli.StringFlag{
+				Name:  "path",
+				Usage: fmt.Sprintf(`read values at this path in JSON lines like "response.duration_ms" or "spans[0].ms" for the %q input format, all elements if it is an array`, "jsonl"),
+			},
+			&cli.StringFlag{
+				Name:  "extract",
+				Usage: fmt.Sprintf(`read values in the first capture group of this regular expression like "took (\d+\.?\d*)ms" in lines for the %q input format, skipping lines not matching`, "plain"),
+			},
+			&cli.StringFlag{
+				Name:  "extract-unit",
+				Usage: fmt.Sprintf("duration unit of --extract values like ms, which are converted to --duration-unit as %q values", valueTypeDuration),
+			},
+			&cli.IntFlag{
+				Name:  "skip-lines",
+				Usage: "ignore this number of first lines of each input like headers",
+			},
+			&cli.BoolFlag{
+				Name:  "has-header",
+				Usage: "ignore the first line of each input, the same as --skip-lines 1",
+			},
+			&cli.BoolFlag{
+				Name:  "skip-invalid",
+				Usage: "skip lines which cannot be read instead of stopping, reporting their count at the end",
+			},
+			&cli.IntFlag{
+				Name:  "max-errors",
+				Usage: "stop if more lines than this are skipped by --skip-invalid, 0 for no limit",
+			},
+			&cli.StringFlag{
+				Name:  "missing-field",
+				Value: missingFieldError,
+				Usage: fmt.Sprintf("what to do with lines without the --field field or the --path value, %q or %q", missingFieldError, missingFieldSkip),
+			},
+			&cli.StringFlag{
+				Name:    "output",
+				Aliases: []string{"o", "output-format"},
+				Value:   "text",
+				Usage:   fmt.Sprintf("output format, %q to show available formats", outputList),
+			},
+			&cli.StringFlag{
+				Name:  "template",
+				Usage: "render with this text/template file instead of --output, executed with .Title and .Histograms, each with .Label, .Stats (.Count, .Min, .Max, .Mean, .Stddev), .Total, .OutOfRange, .Underflow, .Overflow and .Buckets, each with .Lower, .Upper, .Count, .Percent of all values, .CumulativePercent of values in range and .Ratio to the largest count; functions repeat and scale like {{repeat \"*\" (scale .Ratio 40)}} draw bars",
+			},
+			&cli.IntFlag{
+				Name:  "min-count",
+				Value: 0,
+				Usage: `hide buckets with fewer values than this and show their total in a "rare" row`,
+			},
+			&cli.DurationFlag{
+				Name:    "duration",
+				Aliases: []string{"d"},
+				Usage:   "total observation window of values like 5m, to show counts per second",
+			},
+			&cli.BoolFlag{
+				Name:  "percent",
+				Usage: "show the percentage of each row in all values",
+			},
+			&cli.BoolFlag{
+				Name:  "cumulative",
+				Usage: "show the cumulative percentage of values in range at or below the upper bound of each bucket",
+			},
+			&cli.BoolFlag{
+				Name:  "cdf-bar",
+				Usage: fmt.Sprintf("draw a bar of %q after each count bar showing the cumulative percentage of values in range, %d characters at 100%%", cdfBarChar, cdfBarWidth),
+			},
+			&cli.StringFlag{
+				Name:    "tick-style",
+				Aliases: []string{"t"},
+				Value:   string(TickStyleFixed),
+				Usage:   `axis point notation, "fixed" (uses --point-format), "sci", "eng" or "auto"`,
+			},
+		},
+	}
+}
+
+// configFromContext builds a Config from the parsed flags and arguments.
+// Errors name the flag at fault and what it accepts.
+func configFromContext(cCtx *cli.Context) (Config, error) {
+	if filename := cCtx.String("recipe"); filename != "" {
+		recipe, err := readRecipeFile(filename)
+		if err != nil {
+			return Config{}, fmt.Errorf("--recipe: %w", err)
+		}
+		if err := applyRecipe(cCtx, recipe); err != nil {
+			return Config{}, fmt.Errorf("--recipe %s: %w", filename, err)
+		}
+	}
+	if err := applyConfigFile(cCtx); err != nil {
+		return Config{}, err
+	}
+
+	// Arguments of the compare command are parsed with global options.
+	filenames := cCtx.Args().Slice()
+	compare := len(filenames) > 0 && filenames[0] == compareCommandName
+	if compare {
+		filenames = filenames[1:]
+	}
+
+	output := cCtx.String("output")
+	if output == outputList {
+		return Config{ListOutputFormats: true}, nil
+	}
+	outputFormat, ok := LookupOutputFormat(output)
+	if !ok {
+		return Config{}, fmt.Errorf("unknown output format %q, use \"--output %s\" to show available formats", output, outputList)
+	}
+	if filename := cCtx.String("template"); filename != "" {
+		if cCtx.IsSet("output") {
+			return Config{}, errors.New("--template cannot be used with --output")
+		}
+		f, err := readTemplateOutputFormat(filename)
+		if err != nil {
+			return Config{}, fmt.Errorf("--template: %w", err)
+		}
+		outputFormat = f
+	}
+
+	inputFormatName := cCtx.String("input-format")
+	if inputFormatName == inputList {
+		return Config{ListInputFormats: true}, nil
+	}
+	inputFormat, ok := LookupInputFormat(inputFormatName)
+	if !ok {
+		return Config{}, fmt.Errorf("unknown input format %q, use \"--input-format %s\" to show available formats", inputFormatName, inputList)
+	}
+
+	missingField := cCtx.String("missing-field")
+	if missingField != missingFieldError && missingField != missingFieldSkip {
+		return Config{}, fmt.Errorf(`--missing-field must be "%s" or "%s", got %q`, missingFieldError, missingFieldSkip, missingField)
+	}
+	var groupBy *groupByInputFormat
+	if group := cCtx.Int("group-by"); group != 0 {
+		field := cCtx.Int("field")
+		switch {
+		case group < 0:
+			return Config{}, fmt.Errorf("--group-by must be positive, got %d", group)
+		case field <= 0:
+			return Config{}, errors.New("--group-by needs --field (or --value) with the positive number of the value field")
+		case field == group:
+			return Config{}, fmt.Errorf("--group-by and --field must be different fields, got %d for both", group)
+		case inputFormat.Name() != "plain" && inputFormat.Name() != "csv":
+			return Config{}, fmt.Errorf("--group-by cannot be used with the %q input format", inputFormat.Name())
+		case cCtx.String("extract") != "":
+			return Config{}, errors.New("--group-by cannot be used with --extract")
+		case cCtx.String("summary-across-files") != "":
+			return Config{}, errors.New("--group-by cannot be used with --summary-across-files")
+		case cCtx.StringSlice("label") != nil:
+			return Config{}, errors.New("--label cannot be used with --group-by, which labels histograms with keys")
+		case compare:
+			return Config{}, errors.New("compare cannot be used with --group-by")
+		}
+		groupBy = &groupByInputFormat{
+			csv:         inputFormat.Name() == "csv",
+			groupField:  group,
+			valueField:  field,
+			skipMissing: missingField == missingFieldSkip,
+		}
+	}
+	var columnFormats []InputFormat
+	columns := cCtx.IntSlice("columns")
+	for _, column := range columns {
+		switch {
+		case column <= 0:
+			return Config{}, fmt.Errorf("--columns must be positive, got %d", column)
+		case inputFormat.Name() == "csv":
+			columnFormats = append(columnFormats, csvInputFormat{column: column, skipMissing: missingField == missingFieldSkip})
+		case inputFormat.Name() == "plain":
+			columnFormats = append(columnFormats, fieldInputFormat{field: column, skipMissing: missingField == missingFieldSkip})
+		default:
+			return Config{}, fmt.Errorf("--columns cannot be used with the %q input format", inputFormat.Name())
+		}
+	}
+	if columns != nil {
+		switch {
+		case len(filenames) != 1 || filenames[0] == stdinFilename:
+			return Config{}, errors.New("--columns needs exactly one input file other than stdin, which is read once per column")
+		case cCtx.Int("field") != 0:
+			return Config{}, errors.New("--columns cannot be used with --field")
+		case groupBy != nil:
+			return Config{}, errors.New("--columns cannot be used with --group-by")
+		case cCtx.String("extract") != "":
+			return Config{}, errors.New("--columns cannot be used with --extract")
+		case cCtx.String("summary-across-files") != "":
+			return Config{}, errors.New("--columns cannot be used with --summary-across-files")
+		}
+	}
+	if field := cCtx.Int("field"); field != 0 && groupBy == nil {
+		switch {
+		case field < 0:
+			return Config{}, fmt.Errorf("--field must be positive, got %d", field)
+		case inputFormat.Name() != "plain":
+			return Config{}, fmt.Errorf("--field cannot be used with the %q input format", inputFormat.Name())
+		}
+		inputFormat = fieldInputFormat{field: field, skipMissing: missingField == missingFieldSkip}
+	}
+	if s := cCtx.String("path"); s != "" {
+		if inputFormat.Name() != "jsonl" {
+			return Config{}, fmt.Errorf("--path can only be used with the %q input format", "jsonl")
+		}
+		path, err := parseJSONPath(s)
+		if err != nil {
+			return Config{}, fmt.Errorf("--path must be keys separated by dots with optional indexes like a.b[0], got %q", s)
+		}
+		inputFormat = jsonlInputFormat{path: path, skipMissing: missingField == missingFieldSkip}
+	}
+
+	skipLineCount := cCtx.Int("skip-lines")
+	switch {
+	case skipLineCount < 0:
+		return Config{}, fmt.Errorf("--skip-lines must not be negative, got %d", skipLineCount)
+	case cCtx.Bool("has-header") && cCtx.IsSet("skip-lines"):
+		return Config{}, errors.New("--has-header cannot be used with --skip-lines")
+	case cCtx.Bool("has-header"):
+		skipLineCount = 1
+	}
+
+	maxErrors := cCtx.Int("max-errors")
+	switch {
+	case maxErrors < 0:
+		return Config{}, fmt.Errorf("--max-errors must not be negative, got %d", maxErrors)
+	case maxErrors > 0 && !cCtx.Bool("skip-invalid"):
+		return Config{}, errors.New("--max-errors needs --skip-invalid")
+	}
+
+	serveAddr := cCtx.String("serve")
+	statsdAddr := cCtx.String("statsd")
+	if serveAddr != "" || statsdAddr != "" {
+		flag := "--serve"
+		if statsdAddr != "" {
+			flag = "--statsd"
+		}
+		switch {
+		case len(filenames) > 0:
+			return Config{}, fmt.Errorf("%s reads values over the network, so filename arguments cannot be given", flag)
+		case cCtx.String("output-dir") != "":
+			return Config{}, fmt.Errorf("--output-dir cannot be used with %s", flag)
+		case cCtx.String("save") != "":
+			return Config{}, fmt.Errorf("--save cannot be used with %s", flag)
+		case cCtx.IsSet("sample-every") || cCtx.IsSet("sample-prob"):
+			return Config{}, fmt.Errorf("--sample-every and --sample-prob cannot be used with %s", flag)
+		case cCtx.String("summary-across-files") != "":
+			return Config{}, fmt.Errorf("--summary-across-files cannot be used with %s", flag)
+		}
+		if interval := cCtx.Duration("statsd-interval"); interval <= 0 {
+			return Config{}, fmt.Errorf("--statsd-interval must be positive, got %s", interval)
+		}
+	} else if len(filenames) == 0 {
+		return Config{}, fmt.Errorf("one or more filename arguments needed, you can use %q as filename for stdin", stdinFilename)
+	}
+
+	var summaryStat *SummaryStat
+	if s := cCtx.String("summary-across-files"); s != "" {
+		stat, err := parseSummaryStat(s)
+		if err != nil {
+			return Config{}, fmt.Errorf(`--summary-across-files must be "min", "max", "mean", "median" or a percentile like "p99", got %q`, s)
+		}
+		summaryStat = &stat
+	}
+
+	quiet := cCtx.Bool("quiet")
+	if quiet && (cCtx.Bool("verbose") || cCtx.Bool("vv")) {
+		return Config{}, errors.New("--quiet cannot be used with --verbose")
+	}
+	verbosity := cCtx.Count("verbose")
+	if cCtx.Bool("vv") {
+		verbosity += 2
+	}
+	logger := newLogger(os.Stderr, logLevel(quiet, verbosity))
+	warner, err := newWarner(cCtx.String("warnings"), os.Stderr, logger)
+	if err != nil {
+		return Config{}, fmt.Errorf(`--warnings must be "%s" or "%s", got %q`, warningsText, warningsJSON, cCtx.String("warnings"))
+	}
+	if quiet {
+		// JSON warnings do not go through the logger.
+		warner = nil
+	}
+
+	durationUnit, err := parseDurationUnit(cCtx.String("duration-unit"))
+	if err != nil {
+		return Config{}, fmt.Errorf("--duration-unit must be one of ns, us, ms, s, m and h, got %q", cCtx.String("duration-unit"))
+	}
+	valueType := cCtx.String("value-type")
+	extractUnit := cCtx.String("extract-unit")
+	if extractUnit != "" {
+		if cCtx.IsSet("value-type") && valueType != valueTypeDuration {
+			return Config{}, fmt.Errorf(`--extract-unit cannot be used with --value-type %s`, valueType)
+		}
+		if _, err := parseDurationUnit(extractUnit); err != nil {
+			return Config{}, fmt.Errorf("--extract-unit must be one of ns, us, ms, s, m and h, got %q", extractUnit)
+		}
+		valueType = valueTypeDuration
+	}
+	valueParser, err := newValueParser(valueType, durationUnit)
+	if err != nil {
+		return Config{}, fmt.Errorf(`--value-type must be "%s", "%s" or "%s", got %q`, valueTypeFloat, valueTypeDuration, valueTypeBytes, cCtx.String("value-type"))
+	}
+	if s := cCtx.String("extract"); s != "" {
+		re, err := regexp.Compile(s)
+		switch {
+		case err != nil:
+			return Config{}, fmt.Errorf("--extract must be a regular expression, got %q: %s", s, err)
+		case re.NumSubexp() == 0:
+			return Config{}, fmt.Errorf("--extract must have a capture group like (\\d+), got %q", s)
+		case cCtx.Int("field") != 0:
+			return Config{}, errors.New("--extract cannot be used with --field")
+		case inputFormat.Name() != "plain":
+			return Config{}, fmt.Errorf("--extract cannot be used with the %q input format", inputFormat.Name())
+		}
+		inputFormat = extractInputFormat{re: re, unit: extractUnit}
+	} else if extractUnit != "" {
+		return Config{}, errors.New("--extract-unit needs --extract")
+	}
+
+	barStyle, err := parseBarStyle(cCtx.String("bar-style"))
+	if err != nil {
+		return Config{}, fmt.Errorf(`--bar-style must be "%s" or "%s", got %q`, BarStyleChar, BarStyleBlocks, cCtx.String("bar-style"))
+	}
+
+	if !validMetricName(cCtx.String("metric-name")) {
+		return Config{}, fmt.Errorf("--metric-name must start with a letter, '_' or ':' followed by letters, digits, '_' or ':', got %q", cCtx.String("metric-name"))
+	}
+
+	orientation, err := parseOrientation(cCtx.String("orientation"))
+	if err != nil {
+		return Config{}, fmt.Errorf(`--orientation must be "%s" or "%s", got %q`, OrientationHorizontal, OrientationVertical, cCtx.String("orientation"))
+	}
+	fitHeight := cCtx.Int("fit-height")
+	if fitHeight < 0 {
+		return Config{}, fmt.Errorf("--fit-height must not be negative, got %d", fitHeight)
+	}
+	if cCtx.Int("graph-width") <= 0 {
+		return Config{}, fmt.Errorf("--graph-width must be positive, got %d", cCtx.Int("graph-width"))
+	}
+	if cCtx.Int("min-count") < 0 {
+		return Config{}, fmt.Errorf("--min-count must not be negative, got %d", cCtx.Int("min-count"))
+	}
+	if cCtx.Int("graph-height") < graphMinHeight {
+		return Config{}, fmt.Errorf("--graph-height must be %d or larger, got %d", graphMinHeight, cCtx.Int("graph-height"))
+	}
+
+	color, err := useColor(cCtx.String("color"), os.Stdout)
+	if err != nil {
+		return Config{}, fmt.Errorf(`--color must be "%s", "%s" or "%s", got %q`, colorAuto, colorAlways, colorNever, cCtx.String("color"))
+	}
+	if cCtx.String("color") == colorAuto && (cCtx.String("output-dir") != "" || serveAddr != "" || statsdAddr != "") {
+		// Files and HTTP responses are not terminals even if stdout is.
+		color = false
+	}
+	palette, err := parsePalette(cCtx.String("palette"))
+	if err != nil {
+		return Config{}, fmt.Errorf(`--palette must be "%s", "%s" or "%s", got %q`, PaletteOkabeIto, PaletteTol, PaletteBasic, cCtx.String("palette"))
+	}
+	var seriesColors map[int]string
+	for _, s := range cCtx.StringSlice("series-color") {
+		i, color, err := parseSeriesColor(s)
+		if err != nil {
+			return Config{}, fmt.Errorf("--series-color: %w", err)
+		}
+		if seriesColors == nil {
+			seriesColors = make(map[int]string)
+		}
+		seriesColors[i] = color
+	}
+
+	var annotations []Annotation
+	if filename := cCtx.String("annotations"); filename != "" {
+		annotations, err = readAnnotationsFile(filename, valueParser)
+		if err != nil {
+			return Config{}, fmt.Errorf("--annotations: %w", err)
+		}
+	}
+
+	axisMin, err := parseAxisRangeEnd(cCtx.String("axis-min"))
+	if err != nil {
+		return Config{}, fmt.Errorf(`--axis-min must be a finite number, "%s" or a percentile like p1, got %q`, axisAuto, cCtx.String("axis-min"))
+	}
+	axisMax, err := parseAxisRangeEnd(cCtx.String("axis-max"))
+	if err != nil {
+		return Config{}, fmt.Errorf(`--axis-max must be a finite number, "%s" or a percentile like p99, got %q`, axisAuto, cCtx.String("axis-max"))
+	}
+	if !axisMin.Auto && !axisMax.Auto && axisMin.Value >= axisMax.Value {
+		return Config{}, fmt.Errorf("--axis-min must be less than --axis-max, got %g and %g", axisMin.Value, axisMax.Value)
+	}
+	if axisMin.Percentile > 0 && axisMax.Percentile > 0 && axisMin.Percentile >= axisMax.Percentile {
+		return Config{}, fmt.Errorf("--axis-min percentile must be less than --axis-max percentile, got p%g and p%g", axisMin.Percentile, axisMax.Percentile)
+	}
+
+	bucketCount, bucketCountMethod, err := parseBucketCount(cCtx.String("bucket-count"))
+	if err != nil {
+		return Config{}, fmt.Errorf(`--bucket-count must be a positive integer, "%s", "sturges", "rice", "scott" or "fd", got %q`, bucketCountAuto, cCtx.String("bucket-count"))
+	}
+
+	scale := cCtx.String("scale")
+	if scale != scaleLinear && scale != scaleLog {
+		return Config{}, fmt.Errorf(`--scale must be "%s" or "%s", got %q`, scaleLinear, scaleLog, scale)
+	}
+	if scale == scaleLog && !axisMin.Auto && axisMin.Value <= 0 {
+		return Config{}, fmt.Errorf("--axis-min must be positive for log scale, got %g", axisMin.Value)
+	}
+
+	includeZero := cCtx.Bool("include-zero")
+	if includeZero && scale == scaleLog {
+		return Config{}, errors.New("--include-zero cannot be used with log scale")
+	}
+
+	backend := cCtx.String("backend")
+	if backend != backendHistogram && backend != backendHDR && backend != backendTDigest {
+		return Config{}, fmt.Errorf(`--backend must be "%s", "%s" or "%s", got %q`, backendHistogram, backendHDR, backendTDigest, backend)
+	}
+	if backend != backendHistogram && bucketCountMethod != "" {
+		return Config{}, fmt.Errorf(`--bucket-count must be an integer for "%s" backend, got %q`, backend, cCtx.String("bucket-count"))
+	}
+	if backend != backendHistogram && (axisMin.Percentile > 0 || axisMax.Percentile > 0) {
+		return Config{}, fmt.Errorf(`percentiles of --axis-min and --axis-max cannot be used with "%s" backend`, backend)
+	}
+	hdrDigits := cCtx.Int("hdr-digits")
+	if hdrDigits < 1 || hdrDigits > 5 {
+		return Config{}, fmt.Errorf("--hdr-digits must be between 1 and 5, got %d", hdrDigits)
+	}
+	tdigestCompression := cCtx.Float64("tdigest-compression")
+	if !(tdigestCompression >= 10) {
+		return Config{}, fmt.Errorf("--tdigest-compression must be 10 or more, got %g", tdigestCompression)
+	}
+
+	boundaryEpsilon := cCtx.Float64("boundary-epsilon")
+	if boundaryEpsilon < 0 {
+		return Config{}, fmt.Errorf("--boundary-epsilon must not be negative, got %g", boundaryEpsilon)
+	}
+	if boundaryEpsilon > 0 && backend != backendHistogram {
+		return Config{}, fmt.Errorf(`--boundary-epsilon cannot be used with "%s" backend`, backend)
+	}
+
+	closure, err := parseClosure(cCtx.String("closure"))
+	if err != nil {
+		return Config{}, fmt.Errorf(`--closure must be "%s" or "%s", got %q`, ClosureLeft, ClosureRight, cCtx.String("closure"))
+	}
+	if closure != ClosureLeft && backend != backendHistogram {
+		return Config{}, fmt.Errorf(`--closure cannot be used with "%s" backend`, backend)
+	}
+
+	if cCtx.Bool("inclusive-max") && backend != backendHistogram {
+		return Config{}, fmt.Errorf(`--inclusive-max cannot be used with "%s" backend`, backend)
+	}
+
+	var edgesAtPercentiles []float64
+	if s := cCtx.String("edges-at-percentiles"); s != "" {
+		edgesAtPercentiles, err = parsePercentiles(s)
+		if err != nil {
+			return Config{}, fmt.Errorf("--edges-at-percentiles must be increasing percentiles between 0 and 100 exclusive like 50,90,99, got %q: %s", s, err)
+		}
+		switch {
+		case backend != backendHistogram:
+			return Config{}, fmt.Errorf(`--edges-at-percentiles cannot be used with "%s" backend`, backend)
+		case cCtx.IsSet("bucket-count"):
+			return Config{}, errors.New("--edges-at-percentiles cannot be used with --bucket-count")
+		case cCtx.Bool("nice-edges"):
+			return Config{}, errors.New("--edges-at-percentiles cannot be used with --nice-edges")
+		}
+	}
+
+	binWidth := cCtx.Float64("bin-width")
+	if cCtx.IsSet("bin-width") {
+		switch {
+		case !(binWidth > 0) || math.IsInf(binWidth, 1):
+			return Config{}, fmt.Errorf("--bin-width must be positive, got %g", binWidth)
+		case backend != backendHistogram:
+			return Config{}, fmt.Errorf(`--bin-width cannot be used with "%s" backend`, backend)
+		case scale == scaleLog:
+			return Config{}, errors.New("--bin-width cannot be used with log scale")
+		case cCtx.IsSet("bucket-count"):
+			return Config{}, errors.New("--bin-width cannot be used with --bucket-count")
+		case cCtx.Bool("nice-edges"):
+			return Config{}, errors.New("--bin-width cannot be used with --nice-edges")
+		case edgesAtPercentiles != nil:
+			return Config{}, errors.New("--bin-width cannot be used with --edges-at-percentiles")
+		}
+	}
+
+	var dropOutliers *outlierRule
+	if s := cCtx.String("drop-outliers"); s != "" {
+		rule, err := parseOutlierRule(s)
+		if err != nil {
+			return Config{}, fmt.Errorf(`--drop-outliers must be "%s" or "%s" optionally followed by ":k" with a positive factor k, got %q`, outlierMethodIQR, outlierMethodStddev, s)
+		}
+		if backend != backendHistogram {
+			return Config{}, fmt.Errorf(`--drop-outliers cannot be used with "%s" backend`, backend)
+		}
+		dropOutliers = &rule
+	}
+
+	parallelism := cCtx.Int("parallelism")
+	if parallelism <= 0 {
+		return Config{}, fmt.Errorf("--parallelism must be positive, got %d", parallelism)
+	}
+
+	sampleEvery := cCtx.Int("sample-every")
+	if sampleEvery < 0 {
+		return Config{}, fmt.Errorf("--sample-every must not be negative, got %d", sampleEvery)
+	}
+	sampleProb := cCtx.Float64("sample-prob")
+	if cCtx.IsSet("sample-prob") && !(sampleProb > 0 && sampleProb <= 1) {
+		return Config{}, fmt.Errorf("--sample-prob must be greater than 0 and at most 1, got %g", sampleProb)
+	}
+	if cCtx.Bool("sample-scale") && sampleEvery <= 1 && !(sampleProb > 0 && sampleProb < 1) {
+		return Config{}, errors.New("--sample-scale needs --sample-every or --sample-prob")
+	}
+
+	var statTests []StatTest
+	for _, s := range cCtx.StringSlice("stat-test") {
+		test, err := parseStatTest(s)
+		if err != nil {
+			return Config{}, fmt.Errorf(`--stat-test must be "%s" or "%s", got %q`, StatTestKS, StatTestChiSquare, s)
+		}
+		statTests = append(statTests, test)
+	}
+	if statTests != nil && !compare {
+		return Config{}, errors.New("--stat-test can only be used with compare")
+	}
+
+	labels := cCtx.StringSlice("label")
+	if labels != nil && columns != nil {
+		if len(labels) != len(columns) {
+			return Config{}, fmt.Errorf("--label must be given once per column of --columns, got %d labels for %d columns", len(labels), len(columns))
+		}
+	} else if labels != nil && len(labels) != len(filenames) {
+		return Config{}, fmt.Errorf("--label must be given once per input file, got %d labels for %d files", len(labels), len(filenames))
+	}
+
+	duration := cCtx.Duration("duration")
+	if duration < 0 {
+		return Config{}, fmt.Errorf("--duration must not be negative, got %s", duration)
+	}
+
+	tickStyle, err := parseTickStyle(cCtx.String("tick-style"))
+	if err != nil {
+		return Config{}, fmt.Errorf(`--tick-style must be "fixed", "sci", "eng" or "auto", got %q`, cCtx.String("tick-style"))
+	}
+
+	pointFmt := cCtx.String("point-format")
+	if scale == scaleLog && !cCtx.IsSet("point-format") {
+		pointFmt = logScalePointFmt
+	}
+
+	return Config{
+		BucketCount:        bucketCount,
+		BinWidth:           binWidth,
+		DropOutliers:       dropOutliers,
+		BucketCountMethod:  bucketCountMethod,
+		AxisMin:            axisMin,
+		AxisMax:            axisMax,
+		Scale:              scale,
+		GraphWidth:         cCtx.Int("graph-width"),
+		GraphHeight:        cCtx.Int("graph-height"),
+		FitHeight:          fitHeight,
+		PointFmt:           pointFmt,
+		TickStyle:          tickStyle,
+		IncludeZero:        includeZero,
+		MinCount:           cCtx.Int("min-count"),
+		BoundaryEpsilon:    boundaryEpsilon,
+		Closure:            closure,
+		InclusiveMax:       cCtx.Bool("inclusive-max"),
+		Duration:           duration,
+		Percent:            cCtx.Bool("percent"),
+		Cumulative:         cCtx.Bool("cumulative"),
+		CDFBar:             cCtx.Bool("cdf-bar"),
+		Backend:            backend,
+		HDRDigits:          hdrDigits,
+		TDigestCompression: tdigestCompression,
+		InputFormat:        inputFormat,
+		ValueParser:        valueParser,
+		SkipLines:          skipLineCount,
+		SkipInvalid:        cCtx.Bool("skip-invalid"),
+		MaxErrors:          maxErrors,
+		Warner:             warner,
+		Logger:             logger,
+		SummaryStat:        summaryStat,
+		NiceEdges:          cCtx.Bool("nice-edges"),
+		EdgesAtPercentiles: edgesAtPercentiles,
+		Title:              cCtx.String("title"),
+		Labels:             labels,
+		GroupBy:            groupBy,
+		ColumnFormats:      columnFormats,
+		SampleEvery:        sampleEvery,
+		Parallelism:        parallelism,
+		SampleProb:         sampleProb,
+		SampleScale:        cCtx.Bool("sample-scale"),
+		StatTests:          statTests,
+		MetricName:         cCtx.String("metric-name"),
+		Annotations:        annotations,
+		Color:              color,
+		Palette:            palette,
+		SeriesColors:       seriesColors,
+		BarStyle:           barStyle,
+		Orientation:        orientation,
+		OutputDir:          cCtx.String("output-dir"),
+		Save:               cCtx.String("save"),
+		Serve:              serveAddr,
+		Statsd:             statsdAddr,
+		StatsdInterval:     cCtx.Duration("statsd-interval"),
+		OutputFormat:       outputFormat,
+		CPUProfile:         cCtx.String("cpuprofile"),
+		MemProfile:         cCtx.String("memprofile"),
+		Trace:              cCtx.String("trace"),
+		SaveRecipe:         cCtx.String("save-recipe"),
+		Recipe:             recipeFromContext(cCtx),
+		Filenames:          filenames,
+	}, nil
+}
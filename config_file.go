@@ -0,0 +1,193 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+)
+
+// configFileName is the name of the default config file in the histogram
+// directory of the user config directory, which is ~/.config on Linux.
+const configFileName = "config.toml"
+
+// defaultConfigFile returns the path of the default config file, or "" if
+// the user config directory is unknown.
+func defaultConfigFile() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "histogram", configFileName)
+}
+
+// readConfigFile reads defaults of flags from a config file like
+//
+//	# Shared chart appearance.
+//	bucket-count = 20
+//	graph-width = 60
+//	point-format = "%.3f"
+//	bar-char = "█"
+//	color = true
+//
+// which is a flat TOML file whose keys are flag names. Values are strings,
+// numbers, booleans or arrays of them. Tables are not supported.
+func readConfigFile(filename string) (Recipe, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	flags, err := parseConfigFile(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %s", filename, err)
+	}
+	return flags, nil
+}
+
+func parseConfigFile(data string) (Recipe, error) {
+	flags := make(Recipe)
+	for i, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(stripConfigComment(line))
+		if line == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected key = value, got %q", i+1, line)
+		}
+		key = strings.TrimSpace(key)
+		if key == "" {
+			return nil, fmt.Errorf("line %d: empty key", i+1)
+		}
+		if _, ok := flags[key]; ok {
+			return nil, fmt.Errorf("line %d: key %q is defined twice", i+1, key)
+		}
+		v, err := parseConfigValue(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", i+1, err)
+		}
+		flags[key] = v
+	}
+	return flags, nil
+}
+
+// stripConfigComment removes a comment starting with "#" outside quotes.
+func stripConfigComment(line string) string {
+	var quote rune
+	escaped := false
+	for i, r := range line {
+		switch {
+		case escaped:
+			escaped = false
+		case quote == '"' && r == '\\':
+			escaped = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '#':
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// parseConfigValue converts a TOML value into a flag value. Elements of
+// an array are joined with commas as slice flags accept.
+func parseConfigValue(s string) (string, error) {
+	switch {
+	case s == "":
+		return "", errors.New("empty value")
+	case strings.HasPrefix(s, "["):
+		if !strings.HasSuffix(s, "]") {
+			return "", fmt.Errorf("unterminated array %q", s)
+		}
+		var elems []string
+		for _, e := range splitConfigArray(s[1 : len(s)-1]) {
+			if e = strings.TrimSpace(e); e == "" {
+				continue
+			}
+			v, err := parseConfigValue(e)
+			if err != nil {
+				return "", err
+			}
+			elems = append(elems, v)
+		}
+		return strings.Join(elems, ","), nil
+	case strings.HasPrefix(s, `"`):
+		v, err := strconv.Unquote(s)
+		if err != nil {
+			return "", fmt.Errorf("invalid string %s", s)
+		}
+		return v, nil
+	case strings.HasPrefix(s, "'"):
+		if len(s) < 2 || !strings.HasSuffix(s, "'") || strings.Contains(s[1:len(s)-1], "'") {
+			return "", fmt.Errorf("invalid string %s", s)
+		}
+		return s[1 : len(s)-1], nil
+	case s == "true" || s == "false":
+		return s, nil
+	default:
+		if _, err := strconv.ParseFloat(strings.ReplaceAll(s, "_", ""), float64BitSize); err != nil {
+			return "", fmt.Errorf("invalid value %s, strings must be quoted", s)
+		}
+		return strings.ReplaceAll(s, "_", ""), nil
+	}
+}
+
+// splitConfigArray splits elements of an array at commas outside quotes.
+func splitConfigArray(s string) []string {
+	var elems []string
+	var quote rune
+	escaped := false
+	start := 0
+	for i, r := range s {
+		switch {
+		case escaped:
+			escaped = false
+		case quote == '"' && r == '\\':
+			escaped = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == ',':
+			elems = append(elems, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(elems, s[start:])
+}
+
+// applyConfigFile applies the config file given with --config, or the
+// default config file if it exists, so that the command line and recipes
+// override it.
+func applyConfigFile(cCtx *cli.Context) error {
+	filename := cCtx.String("config")
+	if filename == "" {
+		filename = defaultConfigFile()
+		if filename == "" {
+			return nil
+		}
+		if _, err := os.Stat(filename); errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+	}
+	flags, err := readConfigFile(filename)
+	if err != nil {
+		return fmt.Errorf("--config: %w", err)
+	}
+	if err := applyRecipe(cCtx, flags); err != nil {
+		return fmt.Errorf("--config %s: %w", filename, err)
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseConfigFile(t *testing.T) {
+	testCases := []struct {
+		input   string
+		want    Recipe
+		wantErr string
+	}{
+		{
+			input: "# comment\nbucket-count = 20\n\npoint-format = \"%.3f\" # trailing\nbar-char = '#'\ncolor = true\nlabel = [\"a\", 'b']\n",
+			want:  Recipe{"bucket-count": "20", "point-format": "%.3f", "bar-char": "#", "color": "true", "label": "a,b"},
+		},
+		{input: "graph-width = 1_000", want: Recipe{"graph-width": "1000"}},
+		{input: "scale = log", wantErr: "line 1: invalid value log, strings must be quoted"},
+		{input: "color", wantErr: `line 1: expected key = value, got "color"`},
+		{input: "[histogram]", wantErr: `line 1: expected key = value, got "[histogram]"`},
+		{input: "a = 1\na = 2", wantErr: `line 2: key "a" is defined twice`},
+		{input: `a = "x`, wantErr: `line 1: invalid string "x`},
+	}
+	for _, tc := range testCases {
+		got, err := parseConfigFile(tc.input)
+		if tc.wantErr != "" {
+			if err == nil || err.Error() != tc.wantErr {
+				t.Errorf("error mismatch, input=%q, got=%v, want=%s", tc.input, err, tc.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("input=%q: %s", tc.input, err)
+		}
+		if len(got) != len(tc.want) {
+			t.Errorf("result mismatch, input=%q, got=%v, want=%v", tc.input, got, tc.want)
+			continue
+		}
+		for k, v := range tc.want {
+			if got[k] != v {
+				t.Errorf("result mismatch, input=%q, key=%s, got=%q, want=%q", tc.input, k, got[k], v)
+			}
+		}
+	}
+}
+
+func TestConfigFile(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	if err := os.Mkdir(filepath.Join(dir, "histogram"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	defaultFile := filepath.Join(dir, "histogram", configFileName)
+	if err := os.WriteFile(defaultFile, []byte("bucket-count = 4\ngraph-width = 30\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := ParseConfig([]string{"-w", "50", "a.txt"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := cfg.BucketCount, 4; got != want {
+		t.Errorf("bucket count mismatch, got=%d, want=%d", got, want)
+	}
+	if got, want := cfg.GraphWidth, 50; got != want {
+		t.Errorf("command line must override config file, got=%d, want=%d", got, want)
+	}
+
+	otherFile := filepath.Join(dir, "other.toml")
+	if err := os.WriteFile(otherFile, []byte("bucket-count = 7\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err = ParseConfig([]string{"--config", otherFile, "a.txt"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := cfg.BucketCount, 7; got != want {
+		t.Errorf("--config must replace the default file, got=%d, want=%d", got, want)
+	}
+	if _, ok := cfg.Recipe["config"]; ok {
+		t.Errorf("recipe must not include --config, got=%v", cfg.Recipe)
+	}
+
+	_, err = ParseConfig([]string{"--config", filepath.Join(dir, "missing.toml"), "a.txt"})
+	if err == nil || !strings.HasPrefix(err.Error(), "--config: ") {
+		t.Errorf("error mismatch, got=%v", err)
+	}
+	if err := os.WriteFile(otherFile, []byte("bucket-size = 7\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	_, err = ParseConfig([]string{"--config", otherFile, "a.txt"})
+	if err == nil || !strings.Contains(err.Error(), `unknown flag "bucket-size"`) {
+		t.Errorf("error mismatch, got=%v", err)
+	}
+}
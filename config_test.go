@@ -0,0 +1,198 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/urfave/cli/v2"
+	"golang.org/x/exp/slices"
+)
+
+func TestParseConfig(t *testing.T) {
+	cfg, err := ParseConfig([]string{"-c", "5", "-n", "0", "-x", "100", "--scale", "linear", "--cumulative", "a.txt", "b.txt"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := cfg.BucketCount, 5; got != want {
+		t.Errorf("bucket count mismatch, got=%d, want=%d", got, want)
+	}
+	if got, want := cfg.AxisMin, (axisRangeEnd{Value: 0}); got != want {
+		t.Errorf("axis min mismatch, got=%+v, want=%+v", got, want)
+	}
+	if got, want := cfg.AxisMax, (axisRangeEnd{Value: 100}); got != want {
+		t.Errorf("axis max mismatch, got=%+v, want=%+v", got, want)
+	}
+	if !cfg.Cumulative {
+		t.Error("cumulative must be set")
+	}
+	if got, want := cfg.OutputFormat.Name(), "text"; got != want {
+		t.Errorf("output format mismatch, got=%s, want=%s", got, want)
+	}
+	if got, want := strings.Join(cfg.Filenames, ","), "a.txt,b.txt"; got != want {
+		t.Errorf("filenames mismatch, got=%s, want=%s", got, want)
+	}
+
+	cfg, err = ParseConfig([]string{"--scale", "log", "a.txt"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := cfg.PointFmt, logScalePointFmt; got != want {
+		t.Errorf("point format for log scale mismatch, got=%s, want=%s", got, want)
+	}
+
+	cfg, err = ParseConfig([]string{"--label", "before,after", "compare", "a.txt", "b.txt"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := cfg.OutputFormat.Name(), compareCommandName; got != want {
+		t.Errorf("output format for compare mismatch, got=%s, want=%s", got, want)
+	}
+	if got, want := strings.Join(cfg.Filenames, ","), "a.txt,b.txt"; got != want {
+		t.Errorf("filenames for compare mismatch, got=%s, want=%s", got, want)
+	}
+	cfg, err = ParseConfig([]string{"--output", "json", "compare", "a.txt", "b.txt"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f, ok := cfg.OutputFormat.(compareOutputFormat); !ok || !f.json {
+		t.Errorf("output format for compare with json mismatch, got=%#v", cfg.OutputFormat)
+	}
+
+	cfg, err = ParseConfig([]string{"--output", outputList})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cfg.ListOutputFormats {
+		t.Error("listing output formats must be asked for")
+	}
+
+	cfg, err = ParseConfig([]string{"-q", "a.txt"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Warner != nil {
+		t.Error("warnings must be hidden with -q")
+	}
+
+	if _, err := ParseConfig([]string{"--version"}); !errors.Is(err, ErrHelpShown) {
+		t.Errorf("error mismatch, got=%v, want=%v", err, ErrHelpShown)
+	}
+	// The app has its own --version flag and leaves the global one alone.
+	if got := cli.VersionFlag.Names(); !slices.Contains(got, "v") {
+		t.Errorf("cli.VersionFlag must not be changed, got names %v", got)
+	}
+}
+
+func TestParseConfigErrors(t *testing.T) {
+	testCases := []struct {
+		args []string
+		want string
+	}{
+		{args: []string{}, want: "one or more filename arguments needed"},
+		{args: []string{"--serve", ":8080", "a.txt"}, want: "--serve reads values over the network, so filename arguments cannot be given"},
+		{args: []string{"--serve", ":8080", "--output-dir", "out"}, want: "--output-dir cannot be used with --serve"},
+		{args: []string{"--statsd", ":8125", "a.txt"}, want: "--statsd reads values over the network, so filename arguments cannot be given"},
+		{args: []string{"--statsd", ":8125", "--statsd-interval", "0s"}, want: "--statsd-interval must be positive, got 0s"},
+		{args: []string{"-n", "abc", "a.txt"}, want: `--axis-min must be a finite number, "auto" or a percentile like p1, got "abc"`},
+		{args: []string{"-x", "NaN", "a.txt"}, want: `--axis-max must be a finite number, "auto" or a percentile like p99, got "NaN"`},
+		{args: []string{"-n", "10", "-x", "10", "a.txt"}, want: "--axis-min must be less than --axis-max, got 10 and 10"},
+		{args: []string{"-c", "0", "a.txt"}, want: `--bucket-count must be a positive integer`},
+		{args: []string{"--scale", "sqrt", "a.txt"}, want: `--scale must be "linear" or "log", got "sqrt"`},
+		{args: []string{"--scale", "log", "-n", "0", "a.txt"}, want: "--axis-min must be positive for log scale, got 0"},
+		{args: []string{"--scale", "log", "-z", "a.txt"}, want: "--include-zero cannot be used with log scale"},
+		{args: []string{"--backend", "foo", "a.txt"}, want: `--backend must be "histogram", "hdr" or "tdigest", got "foo"`},
+		{args: []string{"--backend", "hdr", "-c", "auto", "a.txt"}, want: `--bucket-count must be an integer for "hdr" backend, got "auto"`},
+		{args: []string{"--hdr-digits", "6", "a.txt"}, want: "--hdr-digits must be between 1 and 5, got 6"},
+		{args: []string{"--tdigest-compression", "5", "a.txt"}, want: "--tdigest-compression must be 10 or more, got 5"},
+		{args: []string{"--boundary-epsilon", "-1", "a.txt"}, want: "--boundary-epsilon must not be negative, got -1"},
+		{args: []string{"--boundary-epsilon", "1e-9", "--backend", "tdigest", "a.txt"}, want: `--boundary-epsilon cannot be used with "tdigest" backend`},
+		{args: []string{"--edges-at-percentiles", "90,50", "a.txt"}, want: `--edges-at-percentiles must be increasing percentiles between 0 and 100 exclusive like 50,90,99, got "90,50": percentiles must be increasing, got 50 after 90`},
+		{args: []string{"--edges-at-percentiles", "50,100", "a.txt"}, want: `got "50,100": invalid percentile "100"`},
+		{args: []string{"--edges-at-percentiles", "50", "--backend", "hdr", "a.txt"}, want: `--edges-at-percentiles cannot be used with "hdr" backend`},
+		{args: []string{"--edges-at-percentiles", "50", "-c", "5", "a.txt"}, want: "--edges-at-percentiles cannot be used with --bucket-count"},
+		{args: []string{"-d", "-1s", "a.txt"}, want: "--duration must not be negative, got -1s"},
+		{args: []string{"-t", "roman", "a.txt"}, want: `--tick-style must be "fixed", "sci", "eng" or "auto", got "roman"`},
+		{args: []string{"--bar-style", "dots", "a.txt"}, want: `--bar-style must be "char" or "blocks", got "dots"`},
+		{args: []string{"--orientation", "diagonal", "a.txt"}, want: `--orientation must be "horizontal" or "vertical", got "diagonal"`},
+		{args: []string{"--graph-height", "1", "a.txt"}, want: "--graph-height must be 2 or larger, got 1"},
+		{args: []string{"--color", "sometimes", "a.txt"}, want: `--color must be "auto", "always" or "never", got "sometimes"`},
+		{args: []string{"-w", "0", "a.txt"}, want: "--graph-width must be positive, got 0"},
+		{args: []string{"--min-count", "-3", "a.txt"}, want: "--min-count must not be negative, got -3"},
+		{args: []string{"--palette", "rainbow", "a.txt"}, want: `--palette must be "okabe-ito", "tol" or "basic", got "rainbow"`},
+		{args: []string{"--series-color", "red", "a.txt"}, want: `--series-color: series color must be like 2=red, got "red"`},
+		{args: []string{"--series-color", "0=red", "a.txt"}, want: `--series-color: input number must be a positive integer, got "0"`},
+		{args: []string{"--series-color", "1=pink", "a.txt"}, want: `--series-color: color must be a name like red or a 256-color number, got "pink"`},
+		{args: []string{"--series-color", "1=256", "a.txt"}, want: `--series-color: color must be a name like red or a 256-color number, got "256"`},
+		{args: []string{"--metric-name", "a-b", "a.txt"}, want: `--metric-name must start with a letter`},
+		{args: []string{"--value-type", "date", "a.txt"}, want: `--value-type must be "float", "duration" or "bytes", got "date"`},
+		{args: []string{"--duration-unit", "d", "a.txt"}, want: `--duration-unit must be one of ns, us, ms, s, m and h, got "d"`},
+		{args: []string{"--warnings", "xml", "a.txt"}, want: `--warnings must be "text" or "json", got "xml"`},
+		{args: []string{"--summary-across-files", "p100x", "a.txt"}, want: `--summary-across-files must be`},
+		{args: []string{"--field", "-1", "a.txt"}, want: "--field must be positive, got -1"},
+		{args: []string{"--field", "2", "-i", "csv", "a.txt"}, want: `--field cannot be used with the "csv" input format`},
+		{args: []string{"--field", "2", "--missing-field", "zero", "a.txt"}, want: `--missing-field must be "error" or "skip", got "zero"`},
+		{args: []string{"report"}, want: "report needs exactly one report file argument"},
+		{args: []string{"--output-dir", "out", "report", "r.yaml"}, want: "report cannot be used with --output-dir"},
+		{args: []string{"compare", "a.txt"}, want: "compare needs exactly two filename arguments"},
+		{args: []string{"--template", "t.tmpl", "--output", "json", "a.txt"}, want: "--template cannot be used with --output"},
+		{args: []string{"--template", "testdata/no-such.tmpl", "a.txt"}, want: "--template: open testdata/no-such.tmpl: no such file or directory"},
+		{args: []string{"--sample-prob", "0", "a.txt"}, want: "--sample-prob must be greater than 0 and at most 1, got 0"},
+		{args: []string{"--parallelism", "0", "a.txt"}, want: "--parallelism must be positive, got 0"},
+		{args: []string{"--sample-every", "-2", "a.txt"}, want: "--sample-every must not be negative, got -2"},
+		{args: []string{"--sample-scale", "a.txt"}, want: "--sample-scale needs --sample-every or --sample-prob"},
+		{args: []string{"--columns", "2,0", "a.csv"}, want: "--columns must be positive, got 0"},
+		{args: []string{"--columns", "2,3", "a.csv", "b.csv"}, want: "--columns needs exactly one input file other than stdin, which is read once per column"},
+		{args: []string{"--columns", "2,3", "-i", "jsonl", "a.csv"}, want: `--columns cannot be used with the "jsonl" input format`},
+		{args: []string{"--columns", "2,3", "--label", "x", "a.csv"}, want: "--label must be given once per column of --columns, got 1 labels for 2 columns"},
+		{args: []string{"--group-by", "2", "a.txt"}, want: "--group-by needs --field (or --value) with the positive number of the value field"},
+		{args: []string{"--group-by", "2", "--value", "2", "a.txt"}, want: "--group-by and --field must be different fields, got 2 for both"},
+		{args: []string{"--group-by", "1", "--value", "2", "-i", "jsonl", "a.txt"}, want: `--group-by cannot be used with the "jsonl" input format`},
+		{args: []string{"--stat-test", "ks", "a.txt", "b.txt"}, want: "--stat-test can only be used with compare"},
+		{args: []string{"--stat-test", "t", "compare", "a.txt", "b.txt"}, want: `--stat-test must be "ks" or "chi2", got "t"`},
+		{args: []string{"--output", "svg", "compare", "a.txt", "b.txt"}, want: "compare supports only the text and json outputs"},
+		{args: []string{"--label", "x", "compare", "a.txt", "b.txt"}, want: "--label must be given once per input file, got 1 labels for 2 files"},
+		{args: []string{"--path", "a.b", "a.txt"}, want: `--path can only be used with the "jsonl" input format`},
+		{args: []string{"--path", "a..b", "-i", "jsonl", "a.txt"}, want: `--path must be keys separated by dots with optional indexes like a.b[0], got "a..b"`},
+		{args: []string{"--extract", "took (", "a.txt"}, want: `--extract must be a regular expression, got "took ("`},
+		{args: []string{"--extract", `took \d+`, "a.txt"}, want: `--extract must have a capture group like (\d+), got "took \\d+"`},
+		{args: []string{"--extract", `(\d+)`, "--field", "2", "a.txt"}, want: "--extract cannot be used with --field"},
+		{args: []string{"--extract", `(\d+)`, "-i", "csv", "a.txt"}, want: `--extract cannot be used with the "csv" input format`},
+		{args: []string{"--extract-unit", "ms", "a.txt"}, want: "--extract-unit needs --extract"},
+		{args: []string{"--extract", `(\d+)`, "--extract-unit", "d", "a.txt"}, want: `--extract-unit must be one of ns, us, ms, s, m and h, got "d"`},
+		{args: []string{"--extract", `(\d+)`, "--extract-unit", "ms", "--value-type", "bytes", "a.txt"}, want: "--extract-unit cannot be used with --value-type bytes"},
+		{args: []string{"--max-errors", "-1", "a.txt"}, want: "--max-errors must not be negative, got -1"},
+		{args: []string{"--max-errors", "3", "a.txt"}, want: "--max-errors needs --skip-invalid"},
+		{args: []string{"--skip-lines", "-1", "a.txt"}, want: "--skip-lines must not be negative, got -1"},
+		{args: []string{"--has-header", "--skip-lines", "2", "a.txt"}, want: "--has-header cannot be used with --skip-lines"},
+		{args: []string{"--closure", "both", "a.txt"}, want: `--closure must be "left" or "right", got "both"`},
+		{args: []string{"--closure", "right", "--backend", "hdr", "a.txt"}, want: `--closure cannot be used with "hdr" backend`},
+		{args: []string{"--inclusive-max", "--backend", "tdigest", "a.txt"}, want: `--inclusive-max cannot be used with "tdigest" backend`},
+		{args: []string{"--bin-width", "0", "a.txt"}, want: "--bin-width must be positive, got 0"},
+		{args: []string{"--bin-width", "1", "--bucket-count", "5", "a.txt"}, want: "--bin-width cannot be used with --bucket-count"},
+		{args: []string{"--bin-width", "1", "--scale", "log", "a.txt"}, want: "--bin-width cannot be used with log scale"},
+		{args: []string{"--bin-width", "1", "--backend", "hdr", "a.txt"}, want: `--bin-width cannot be used with "hdr" backend`},
+		{args: []string{"--axis-min", "p0", "a.txt"}, want: `--axis-min must be a finite number, "auto" or a percentile like p1, got "p0"`},
+		{args: []string{"--axis-max", "p100", "a.txt"}, want: `--axis-max must be a finite number, "auto" or a percentile like p99, got "p100"`},
+		{args: []string{"--axis-min", "p50", "--axis-max", "p10", "a.txt"}, want: "--axis-min percentile must be less than --axis-max percentile, got p50 and p10"},
+		{args: []string{"--axis-max", "p99", "--backend", "hdr", "a.txt"}, want: `percentiles of --axis-min and --axis-max cannot be used with "hdr" backend`},
+		{args: []string{"--drop-outliers", "mad", "a.txt"}, want: `--drop-outliers must be "iqr" or "stddev" optionally followed by ":k" with a positive factor k, got "mad"`},
+		{args: []string{"--drop-outliers", "iqr", "--backend", "tdigest", "a.txt"}, want: `--drop-outliers cannot be used with "tdigest" backend`},
+		{args: []string{"--label", "a", "a.txt", "b.txt"}, want: "--label must be given once per input file, got 1 labels for 2 files"},
+		{args: []string{"--output", "pdf", "a.txt"}, want: `unknown output format "pdf"`},
+		{args: []string{"--input-format", "xml", "a.txt"}, want: `unknown input format "xml"`},
+		{args: []string{"--annotations", "testdata/no_such_file.yaml", "a.txt"}, want: "--annotations: "},
+		{args: []string{"-q", "-v", "a.txt"}, want: "--quiet cannot be used with --verbose"},
+		{args: []string{"-q", "-vv", "a.txt"}, want: "--quiet cannot be used with --verbose"},
+	}
+	for _, tc := range testCases {
+		_, err := ParseConfig(tc.args)
+		if err == nil {
+			t.Errorf("want error, args=%q", tc.args)
+			continue
+		}
+		if !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("error mismatch, args=%q, got=%q, want=%q", tc.args, err.Error(), tc.want)
+		}
+	}
+}
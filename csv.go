@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+)
+
+// delimitedOutputFormat renders buckets as rows of
+//
+//	lower,upper,count,percent
+//
+// with a header row, so that scripts and spreadsheets can read them. The
+// last row of each histogram is for values out of range, which has empty
+// bounds. When there are several histograms, a label column is added
+// first. Percentages are of all values including those out of range.
+type delimitedOutputFormat struct {
+	name      string
+	comma     rune
+	extension string
+}
+
+var (
+	csvOutputFormat = delimitedOutputFormat{name: "csv", comma: ',', extension: ".csv"}
+	tsvOutputFormat = delimitedOutputFormat{name: "tsv", comma: '\t', extension: ".tsv"}
+)
+
+func (f delimitedOutputFormat) Name() string { return f.name }
+
+func (f delimitedOutputFormat) FileExtension() string { return f.extension }
+
+func (f delimitedOutputFormat) Render(w io.Writer, m *RenderModel) error {
+	cw := csv.NewWriter(w)
+	cw.Comma = f.comma
+
+	multiple := len(m.Histograms) > 1
+	record := func(label string, fields ...string) []string {
+		if multiple {
+			return append([]string{label}, fields...)
+		}
+		return fields
+	}
+	cw.Write(record("label", "lower", "upper", "count", "percent"))
+	for i, h := range m.Histograms {
+		total := h.TotalCount()
+		percentage := func(count int) string {
+			if total == 0 {
+				return "0"
+			}
+			return formatCSVFloat(float64(count) / float64(total) * 100)
+		}
+		for j, count := range h.counts {
+			cw.Write(record(m.Labels[i],
+				formatCSVFloat(h.rangePoints[j]),
+				formatCSVFloat(h.rangePoints[j+1]),
+				strconv.Itoa(count),
+				percentage(count)))
+		}
+		cw.Write(record(m.Labels[i], "", "", strconv.Itoa(h.outOfRangeCount), percentage(h.outOfRangeCount)))
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// formatCSVFloat formats v in the shortest form which is parsed back to v.
+func formatCSVFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDelimitedOutputFormat(t *testing.T) {
+	rangePoints := BuildRangePoints[float64](2, 0, 1)
+	a := NewHistogram(rangePoints)
+	a.AddValues([]float64{0.25, 0.75, 0.75, 2})
+	b := NewHistogram(rangePoints)
+
+	testCases := []struct {
+		format     delimitedOutputFormat
+		histograms []*Histogram[float64]
+		labels     []string
+		want       string
+	}{
+		{
+			format:     csvOutputFormat,
+			histograms: []*Histogram[float64]{a},
+			labels:     []string{"a.txt"},
+			want: `lower,upper,count,percent
+0,0.5,1,25
+0.5,1,2,50
+,,1,25
+`,
+		},
+		{
+			format:     tsvOutputFormat,
+			histograms: []*Histogram[float64]{a},
+			labels:     []string{"a.txt"},
+			want:       "lower\tupper\tcount\tpercent\n0\t0.5\t1\t25\n0.5\t1\t2\t50\n\t\t1\t25\n",
+		},
+		{
+			format:     csvOutputFormat,
+			histograms: []*Histogram[float64]{a, b},
+			labels:     []string{"a.txt", "b,c.txt"},
+			want: `label,lower,upper,count,percent
+a.txt,0,0.5,1,25
+a.txt,0.5,1,2,50
+a.txt,,,1,25
+"b,c.txt",0,0.5,0,0
+"b,c.txt",0.5,1,0,0
+"b,c.txt",,,0,0
+`,
+		},
+	}
+	for _, tc := range testCases {
+		var buf bytes.Buffer
+		m := &RenderModel{Histograms: tc.histograms, Labels: tc.labels}
+		if err := tc.format.Render(&buf, m); err != nil {
+			t.Fatal(err)
+		}
+		if got := buf.String(); got != tc.want {
+			t.Errorf("result mismatch, format=%s,\n got=%q,\nwant=%q", tc.format.Name(), got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+var (
+	gzipMagic  = []byte{0x1f, 0x8b}
+	bzip2Magic = []byte("BZh")
+	zstdMagic  = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// newDecompressReader returns a reader of r decompressed if r starts with
+// the magic bytes of gzip, bzip2 or zstd, or r as is otherwise.
+// Closing the returned reader also closes r.
+func newDecompressReader(r io.ReadCloser) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+	// Peek returns fewer bytes with an error for a short input, which is
+	// just not compressed.
+	head, _ := br.Peek(len(zstdMagic))
+	switch {
+	case bytes.HasPrefix(head, gzipMagic):
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			r.Close()
+			return nil, err
+		}
+		return &decompressReadCloser{Reader: zr, closers: []io.Closer{zr, r}}, nil
+	case bytes.HasPrefix(head, bzip2Magic):
+		return &decompressReadCloser{Reader: bzip2.NewReader(br), closers: []io.Closer{r}}, nil
+	case bytes.HasPrefix(head, zstdMagic):
+		zr, err := zstd.NewReader(br)
+		if err != nil {
+			r.Close()
+			return nil, err
+		}
+		return &decompressReadCloser{Reader: zr, closers: []io.Closer{zstdCloser{zr}, r}}, nil
+	default:
+		return &decompressReadCloser{Reader: br, closers: []io.Closer{r}}, nil
+	}
+}
+
+type decompressReadCloser struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (d *decompressReadCloser) Close() error {
+	var firstErr error
+	for _, c := range d.closers {
+		if err := c.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// zstdCloser adapts zstd.Decoder, whose Close does not return an error.
+type zstdCloser struct {
+	d *zstd.Decoder
+}
+
+func (c zstdCloser) Close() error {
+	c.d.Close()
+	return nil
+}
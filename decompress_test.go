@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"golang.org/x/exp/slices"
+)
+
+func TestNewReadCloserFile_Decompress(t *testing.T) {
+	const content = "1\n2.5\n-3\n"
+	dir := t.TempDir()
+
+	var gzipBuf bytes.Buffer
+	gw := gzip.NewWriter(&gzipBuf)
+	if _, err := gw.Write([]byte(content)); err != nil {
+		t.Fatal(err)
+	}
+	if err := gw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	zw, err := zstd.NewWriter(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zstdData := zw.EncodeAll([]byte(content), nil)
+	zw.Close()
+
+	bzip2Data, err := os.ReadFile(filepath.Join("testdata", "values.txt.bz2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name string
+		data []byte
+	}{
+		{name: "values.txt", data: []byte(content)},
+		{name: "values.txt.gz", data: gzipBuf.Bytes()},
+		{name: "values.txt.bz2", data: bzip2Data},
+		{name: "values.txt.zst", data: zstdData},
+		// Detection does not depend on the file extension.
+		{name: "values.dat", data: gzipBuf.Bytes()},
+	}
+	for _, tc := range testCases {
+		filename := filepath.Join(dir, tc.name)
+		if err := os.WriteFile(filename, tc.data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		r, err := newReadCloserFile(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := readFloat64Values(r, plainInputFormat{})
+		if err != nil {
+			t.Errorf("unexpected error, name=%s, err=%v", tc.name, err)
+		} else if want := []float64{1, 2.5, -3}; !slices.Equal(got, want) {
+			t.Errorf("result mismatch, name=%s, got=%v, want=%v", tc.name, got, want)
+		}
+		if err := r.Close(); err != nil {
+			t.Errorf("unexpected close error, name=%s, err=%v", tc.name, err)
+		}
+	}
+}
+
+func TestNewDecompressReader_Short(t *testing.T) {
+	r, err := newDecompressReader(io.NopCloser(bytes.NewReader([]byte{0x1f})))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []byte{0x1f}; !bytes.Equal(got, want) {
+		t.Errorf("result mismatch, got=%v, want=%v", got, want)
+	}
+}
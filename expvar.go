@@ -0,0 +1,55 @@
+package main
+
+import (
+	"expvar"
+	"math"
+)
+
+// expvarPercentiles are the percentiles published by PublishExpvar.
+var expvarPercentiles = []struct {
+	name string
+	q    float64
+}{
+	{name: "p50", q: 0.5},
+	{name: "p90", q: 0.9},
+	{name: "p99", q: 0.99},
+}
+
+// expvarHistogram is the published JSON of a histogram. Percentiles are
+// estimated from buckets and null while the histogram is empty.
+type expvarHistogram struct {
+	histogramJSON[float64]
+	Percentiles map[string]*float64 `json:"percentiles"`
+}
+
+// PublishExpvar publishes the live state of h as the expvar variable name,
+// so that it is exposed at /debug/vars with the range points, the counts
+// and percentiles. Like expvar.Publish, it panics if name is already
+// published.
+func PublishExpvar(name string, h *ConcurrentHistogram[float64]) {
+	expvar.Publish(name, expvar.Func(func() any {
+		return newExpvarHistogram(h.Snapshot())
+	}))
+}
+
+func newExpvarHistogram(h *Histogram[float64]) expvarHistogram {
+	v := expvarHistogram{
+		histogramJSON: histogramJSON[float64]{
+			RangePoints:     h.rangePoints,
+			Counts:          h.counts,
+			OutOfRangeCount: h.outOfRangeCount,
+			UnderflowCount:  h.underflowCount,
+			OverflowCount:   h.overflowCount,
+		},
+		Percentiles: make(map[string]*float64, len(expvarPercentiles)),
+	}
+	for _, p := range expvarPercentiles {
+		// NaN cannot be encoded in JSON.
+		if q := h.Quantile(p.q); !math.IsNaN(q) {
+			v.Percentiles[p.name] = &q
+		} else {
+			v.Percentiles[p.name] = nil
+		}
+	}
+	return v
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"encoding/json"
+	"expvar"
+	"testing"
+
+	"golang.org/x/exp/slices"
+)
+
+func TestPublishExpvar(t *testing.T) {
+	h := NewConcurrentHistogram(BuildRangePoints[float64](4, 0, 4))
+	PublishExpvar("test_histogram", h)
+
+	var got struct {
+		RangePoints     []float64           `json:"rangePoints"`
+		Counts          []int               `json:"counts"`
+		OutOfRangeCount int                 `json:"outOfRangeCount"`
+		Percentiles     map[string]*float64 `json:"percentiles"`
+	}
+	if err := json.Unmarshal([]byte(expvar.Get("test_histogram").String()), &got); err != nil {
+		t.Fatal(err)
+	}
+	if p := got.Percentiles["p50"]; p != nil {
+		t.Errorf("percentile of empty histogram must be null, got=%g", *p)
+	}
+
+	h.AddValues([]float64{0.5, 1.5, 1.5, 2.5, 5})
+	if err := json.Unmarshal([]byte(expvar.Get("test_histogram").String()), &got); err != nil {
+		t.Fatal(err)
+	}
+	if want := []float64{0, 1, 2, 3, 4}; !slices.Equal(got.RangePoints, want) {
+		t.Errorf("range points mismatch, got=%v, want=%v", got.RangePoints, want)
+	}
+	if want := []int{1, 2, 1, 0}; !slices.Equal(got.Counts, want) {
+		t.Errorf("counts mismatch, got=%v, want=%v", got.Counts, want)
+	}
+	if got, want := got.OutOfRangeCount, 1; got != want {
+		t.Errorf("out of range count mismatch, got=%d, want=%d", got, want)
+	}
+	p50 := got.Percentiles["p50"]
+	if p50 == nil {
+		t.Fatal("p50 must be published")
+	}
+	if want := h.Quantile(0.5); *p50 != want {
+		t.Errorf("p50 mismatch, got=%g, want=%g", *p50, want)
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import "unsafe"
+
+// fastFloatPow10 are the powers of 10 which are exact in float64.
+var fastFloatPow10 = [...]float64{
+	1e0, 1e1, 1e2, 1e3, 1e4, 1e5, 1e6, 1e7, 1e8, 1e9, 1e10, 1e11,
+	1e12, 1e13, 1e14, 1e15, 1e16, 1e17, 1e18, 1e19, 1e20, 1e21, 1e22,
+}
+
+// parseFloatFast parses s of decimal digits with an optional sign, point
+// and exponent like "-12.5e3" without allocating. It only handles numbers
+// whose digits fit in 53 bits and whose power of 10 is at most 22, which
+// are computed exactly by a multiplication or a division, and returns false
+// for others, which should be parsed with strconv.ParseFloat. Most values
+// in logs and metrics are like that.
+func parseFloatFast[S string | []byte](s S) (float64, bool) {
+	i := 0
+	neg := false
+	if i < len(s) && (s[i] == '+' || s[i] == '-') {
+		neg = s[i] == '-'
+		i++
+	}
+
+	var mantissa uint64
+	digits := 0
+	significant := 0
+	exp := 0
+	for ; i < len(s) && '0' <= s[i] && s[i] <= '9'; i++ {
+		digits++
+		if mantissa == 0 && s[i] == '0' {
+			continue
+		}
+		significant++
+		mantissa = mantissa*10 + uint64(s[i]-'0')
+	}
+	if i < len(s) && s[i] == '.' {
+		i++
+		for ; i < len(s) && '0' <= s[i] && s[i] <= '9'; i++ {
+			digits++
+			exp--
+			if mantissa == 0 && s[i] == '0' {
+				continue
+			}
+			significant++
+			mantissa = mantissa*10 + uint64(s[i]-'0')
+		}
+	}
+	if digits == 0 || significant > 19 {
+		return 0, false
+	}
+	if i < len(s) && (s[i] == 'e' || s[i] == 'E') {
+		i++
+		expNeg := false
+		if i < len(s) && (s[i] == '+' || s[i] == '-') {
+			expNeg = s[i] == '-'
+			i++
+		}
+		expDigits := 0
+		e := 0
+		for ; i < len(s) && '0' <= s[i] && s[i] <= '9'; i++ {
+			expDigits++
+			if e < 10000 {
+				e = e*10 + int(s[i]-'0')
+			}
+		}
+		if expDigits == 0 {
+			return 0, false
+		}
+		if expNeg {
+			e = -e
+		}
+		exp += e
+	}
+	if i != len(s) || mantissa > 1<<53 {
+		return 0, false
+	}
+
+	f := float64(mantissa)
+	switch {
+	case mantissa == 0:
+	case exp >= 0 && exp < len(fastFloatPow10):
+		f *= fastFloatPow10[exp]
+	case exp < 0 && -exp < len(fastFloatPow10):
+		f /= fastFloatPow10[-exp]
+	default:
+		return 0, false
+	}
+	if neg {
+		f = -f
+	}
+	return f, true
+}
+
+// bytesToString returns b as a string without copying it. b must not be
+// modified while the string is used, so the string must not be kept after
+// the buffer of b is reused, like that of bufio.Scanner.Bytes.
+func bytesToString(b []byte) string {
+	return unsafe.String(unsafe.SliceData(b), len(b))
+}
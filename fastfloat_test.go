@@ -0,0 +1,109 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestParseFloatFast(t *testing.T) {
+	testCases := []struct {
+		input  string
+		want   float64
+		wantOK bool
+	}{
+		{input: "0", want: 0, wantOK: true},
+		{input: "-0", want: math.Copysign(0, -1), wantOK: true},
+		{input: "12", want: 12, wantOK: true},
+		{input: "+12.5", want: 12.5, wantOK: true},
+		{input: "-0.001", want: -0.001, wantOK: true},
+		{input: ".5", want: 0.5, wantOK: true},
+		{input: "5.", want: 5, wantOK: true},
+		{input: "1.5e3", want: 1500, wantOK: true},
+		{input: "1.5E-3", want: 0.0015, wantOK: true},
+		{input: "0.1", want: 0.1, wantOK: true},
+		{input: "000123.4500", want: 123.45, wantOK: true},
+		{input: "9007199254740993", wantOK: false},
+		{input: "12345678901234567890", wantOK: false},
+		{input: "1e23", wantOK: false},
+		{input: "1e-400", wantOK: false},
+		{input: "", wantOK: false},
+		{input: "-", wantOK: false},
+		{input: ".", wantOK: false},
+		{input: "1e", wantOK: false},
+		{input: "1e+", wantOK: false},
+		{input: " 1", wantOK: false},
+		{input: "1 ", wantOK: false},
+		{input: "1_000", wantOK: false},
+		{input: "0x10", wantOK: false},
+		{input: "NaN", wantOK: false},
+		{input: "Inf", wantOK: false},
+		{input: "1.2.3", wantOK: false},
+	}
+	for _, tc := range testCases {
+		got, ok := parseFloatFast(tc.input)
+		if ok != tc.wantOK || ok && (got != tc.want || math.Signbit(got) != math.Signbit(tc.want)) {
+			t.Errorf("result mismatch, input=%q, got=(%v, %v), want=(%v, %v)", tc.input, got, ok, tc.want, tc.wantOK)
+		}
+		if gotBytes, okBytes := parseFloatFast([]byte(tc.input)); okBytes != ok || okBytes && gotBytes != got {
+			t.Errorf("bytes result mismatch, input=%q, got=(%v, %v), want=(%v, %v)", tc.input, gotBytes, okBytes, got, ok)
+		}
+	}
+}
+
+func TestParseFloatFast_MatchesParseFloat(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 100000; i++ {
+		var s string
+		switch i % 3 {
+		case 0:
+			s = strconv.FormatFloat(r.NormFloat64()*math.Pow(10, float64(r.Intn(20)-10)), 'f', r.Intn(10), float64BitSize)
+		case 1:
+			s = strconv.FormatFloat(r.ExpFloat64()*1000, 'g', r.Intn(17)+1, float64BitSize)
+		default:
+			s = strconv.Itoa(r.Intn(1 << 30))
+		}
+		want, err := strconv.ParseFloat(s, float64BitSize)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, ok := parseFloatFast(s); ok && got != want {
+			t.Errorf("result mismatch, input=%q, got=%v, want=%v", s, got, want)
+		}
+	}
+}
+
+func TestScanFloat64Values_Allocs(t *testing.T) {
+	input := strings.Repeat("12.5\n3\n-0.25\n", 1000)
+	r := strings.NewReader(input)
+	sum := 0.0
+	allocs := testing.AllocsPerRun(10, func() {
+		r.Reset(input)
+		if err := scanFloat64Values(r, parseFiniteFloat, func(v float64) { sum += v }); err != nil {
+			t.Fatal(err)
+		}
+	})
+	// Only the scanner and its buffer are allocated, not a string per line.
+	if allocs > 5 {
+		t.Errorf("too many allocations, got=%v", allocs)
+	}
+}
+
+func BenchmarkScanFloat64Values(b *testing.B) {
+	r := rand.New(rand.NewSource(1))
+	var sb strings.Builder
+	for i := 0; i < 10000; i++ {
+		sb.WriteString(strconv.FormatFloat(r.ExpFloat64()*100, 'f', 3, float64BitSize))
+		sb.WriteByte('\n')
+	}
+	input := sb.String()
+	b.SetBytes(int64(len(input)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := scanFloat64Values(strings.NewReader(input), parseFiniteFloat, func(float64) {}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import "time"
+
+// Defaults of formatters, which are also those of the flags.
+const (
+	defaultGraphWidth = 80
+	defaultPointFmt   = "%.2f"
+)
+
+// FormatterOption configures a formatter made by NewHistogramFormatter,
+// NewMultipleHistogramFormatter or NewVerticalHistogramFormatter. Options
+// which a formatter does not support are ignored by it.
+type FormatterOption func(o *formatterOptions)
+
+type formatterOptions struct {
+	barChar      string
+	graphWidth   int
+	graphHeight  int
+	pointFmt     string
+	tickStyle    TickStyle
+	minCount     int
+	duration     time.Duration
+	percent      bool
+	cumulative   bool
+	cdfBar       bool
+	bucketWidths bool
+	color        bool
+	barColor     string
+	seriesColors []string
+	barStyle     BarStyle
+	annotations  []Annotation
+	labels       []string
+}
+
+func newFormatterOptions(opts []FormatterOption) formatterOptions {
+	o := formatterOptions{
+		barChar:     defaultBarChar,
+		graphWidth:  defaultGraphWidth,
+		graphHeight: defaultGraphHeight,
+		pointFmt:    defaultPointFmt,
+		tickStyle:   TickStyleFixed,
+		barStyle:    BarStyleChar,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithBarChar sets the character of bars. The default is "*".
+func WithBarChar(barChar string) FormatterOption {
+	return func(o *formatterOptions) { o.barChar = barChar }
+}
+
+// WithWidth sets the width of the graph including labels. The default is
+// 80.
+func WithWidth(width int) FormatterOption {
+	return func(o *formatterOptions) { o.graphWidth = width }
+}
+
+// WithHeight sets the height of the bars of VerticalHistogramFormatter.
+// The default is 20.
+func WithHeight(height int) FormatterOption {
+	return func(o *formatterOptions) { o.graphHeight = height }
+}
+
+// WithPointFormat sets the fmt format of axis points. The default is
+// "%.2f".
+func WithPointFormat(pointFmt string) FormatterOption {
+	return func(o *formatterOptions) { o.pointFmt = pointFmt }
+}
+
+// WithTickStyle sets the style of axis point labels. The default is
+// TickStyleFixed.
+func WithTickStyle(style TickStyle) FormatterOption {
+	return func(o *formatterOptions) { o.tickStyle = style }
+}
+
+// WithMinCount is like SetMinCount of HistogramFormatter.
+func WithMinCount(n int) FormatterOption {
+	return func(o *formatterOptions) { o.minCount = n }
+}
+
+// WithDuration is like SetDuration of HistogramFormatter.
+func WithDuration(d time.Duration) FormatterOption {
+	return func(o *formatterOptions) { o.duration = d }
+}
+
+// WithPercent is like SetPercent of HistogramFormatter.
+func WithPercent(percent bool) FormatterOption {
+	return func(o *formatterOptions) { o.percent = percent }
+}
+
+// WithCumulative is like SetCumulative of HistogramFormatter.
+func WithCumulative(cumulative bool) FormatterOption {
+	return func(o *formatterOptions) { o.cumulative = cumulative }
+}
+
+// WithCDFBar is like SetCDFBar of HistogramFormatter.
+func WithCDFBar(cdfBar bool) FormatterOption {
+	return func(o *formatterOptions) { o.cdfBar = cdfBar }
+}
+
+// WithBucketWidths is like SetWidths of HistogramFormatter.
+func WithBucketWidths(widths bool) FormatterOption {
+	return func(o *formatterOptions) { o.bucketWidths = widths }
+}
+
+// WithColors is like SetColor of HistogramFormatter.
+func WithColors(color bool) FormatterOption {
+	return func(o *formatterOptions) { o.color = color }
+}
+
+// WithBarColor sets the ANSI color of bars of HistogramFormatter with
+// WithColors and of VerticalHistogramFormatter. The default is empty,
+// which is the first color of series for the former and no color for the
+// latter.
+func WithBarColor(color string) FormatterOption {
+	return func(o *formatterOptions) { o.barColor = color }
+}
+
+// WithSeriesColors sets the ANSI colors of bars of each histogram of
+// MultipleHistogramFormatter with WithColors, used in turn. The default is
+// the colorblind-safe palette by Okabe and Ito.
+func WithSeriesColors(colors []string) FormatterOption {
+	return func(o *formatterOptions) { o.seriesColors = colors }
+}
+
+// WithBarStyle sets how bars are drawn. The default is BarStyleChar.
+func WithBarStyle(style BarStyle) FormatterOption {
+	return func(o *formatterOptions) { o.barStyle = style }
+}
+
+// WithAnnotations is like SetAnnotations of MultipleHistogramFormatter.
+func WithAnnotations(annotations []Annotation) FormatterOption {
+	return func(o *formatterOptions) { o.annotations = annotations }
+}
+
+// WithLabels is like SetLabels of MultipleHistogramFormatter.
+func WithLabels(labels []string) FormatterOption {
+	return func(o *formatterOptions) { o.labels = labels }
+}
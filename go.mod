@@ -1,10 +1,12 @@
 module github.com/hnakamur/histogram
 
-go 1.19
+go 1.22
 
 require (
+	github.com/klauspost/compress v1.18.0
 	github.com/urfave/cli/v2 v2.15.0
 	golang.org/x/exp v0.0.0-20220827204233-334a2380cb91
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("update", false, "update golden files in testdata/golden")
+
+// TestRunGolden runs the whole pipeline over fixtures in testdata and
+// compares the output with golden files. Run
+//
+//	go test -run TestRunGolden -update
+//
+// to regenerate golden files after an intended output change.
+func TestRunGolden(t *testing.T) {
+	single := []string{"testdata/latency_a.txt"}
+	pair := []string{"testdata/latency_a.txt", "testdata/latency_b.txt"}
+
+	testCases := []struct {
+		name   string
+		modify func(cfg *Config)
+	}{
+		{name: "text_single", modify: func(cfg *Config) {}},
+		{name: "text_pair", modify: func(cfg *Config) { cfg.Filenames = pair }},
+		{name: "json_single", modify: func(cfg *Config) { cfg.OutputFormat = jsonOutputFormat{} }},
+		{name: "json_pair", modify: func(cfg *Config) {
+			cfg.OutputFormat = jsonOutputFormat{}
+			cfg.Filenames = pair
+		}},
+		{name: "percentile_table_pair", modify: func(cfg *Config) {
+			cfg.OutputFormat = percentileTableOutputFormat{}
+			cfg.PointFmt = "%.2f"
+			cfg.Filenames = pair
+		}},
+		{name: "svg_pair", modify: func(cfg *Config) {
+			cfg.OutputFormat = svgOutputFormat{}
+			cfg.Title = "Latency <ms>"
+			cfg.Filenames = pair
+		}},
+		{name: "text_annotations", modify: func(cfg *Config) {
+			cfg.Annotations = mustReadAnnotationsFile("testdata/annotations.yaml")
+		}},
+		{name: "svg_annotations", modify: func(cfg *Config) {
+			cfg.OutputFormat = svgOutputFormat{}
+			cfg.Annotations = mustReadAnnotationsFile("testdata/annotations.yaml")
+		}},
+		{name: "html_pair", modify: func(cfg *Config) {
+			cfg.OutputFormat = htmlOutputFormat{}
+			cfg.PointFmt = "%.2f"
+			cfg.Title = "Latency <ms>"
+			cfg.Annotations = mustReadAnnotationsFile("testdata/annotations.yaml")
+			cfg.Filenames = pair
+		}},
+		{name: "text_fixed_axis", modify: func(cfg *Config) {
+			cfg.AxisMin = axisRangeEnd{Value: 0}
+			cfg.AxisMax = axisRangeEnd{Value: 50}
+		}},
+		{name: "text_log_scale", modify: func(cfg *Config) {
+			cfg.Scale = scaleLog
+			cfg.PointFmt = logScalePointFmt
+		}},
+		{name: "text_bucket_method", modify: func(cfg *Config) { cfg.BucketCountMethod = BucketCountFreedmanDiaconis }},
+		{name: "text_min_count", modify: func(cfg *Config) { cfg.MinCount = 5 }},
+		{name: "text_duration", modify: func(cfg *Config) { cfg.Duration = time.Minute }},
+		{name: "text_cumulative", modify: func(cfg *Config) {
+			cfg.Cumulative = true
+			cfg.MinCount = 2
+		}},
+		{name: "text_vertical_pair", modify: func(cfg *Config) {
+			cfg.Orientation = OrientationVertical
+			cfg.GraphHeight = 8
+			cfg.Filenames = pair
+		}},
+		{name: "prometheus_pair", modify: func(cfg *Config) {
+			cfg.OutputFormat = prometheusOutputFormat{}
+			cfg.Filenames = pair
+		}},
+		{name: "line_pair", modify: func(cfg *Config) {
+			cfg.OutputFormat = lineOutputFormat{}
+			cfg.GraphHeight = 10
+			cfg.Filenames = pair
+		}},
+		{name: "text_cdf_bar_pair", modify: func(cfg *Config) {
+			cfg.CDFBar = true
+			cfg.GraphWidth = 100
+			cfg.Filenames = pair
+		}},
+		{name: "text_fit_height", modify: func(cfg *Config) {
+			cfg.BucketCount = 40
+			cfg.FitHeight = 8
+		}},
+		{name: "text_edges_at_percentiles", modify: func(cfg *Config) {
+			cfg.EdgesAtPercentiles = []float64{50, 90, 95, 99, 99.9}
+			cfg.PointFmt = "%.2f"
+			cfg.GraphWidth = 80
+		}},
+		{name: "csv_pair", modify: func(cfg *Config) {
+			cfg.OutputFormat = csvOutputFormat
+			cfg.Filenames = pair
+		}},
+		{name: "text_bin_width", modify: func(cfg *Config) { cfg.BinWidth = 20 }},
+		{name: "text_axis_percentiles", modify: func(cfg *Config) {
+			cfg.AxisMin = axisRangeEnd{Auto: true, Percentile: 1}
+			cfg.AxisMax = axisRangeEnd{Auto: true, Percentile: 99}
+			cfg.PointFmt = "%.2f"
+		}},
+		{name: "text_drop_outliers", modify: func(cfg *Config) {
+			cfg.DropOutliers = &outlierRule{Method: outlierMethodIQR, Factor: 1.5}
+		}},
+		{name: "text_labels_pair", modify: func(cfg *Config) {
+			cfg.Title = "Latency"
+			cfg.Labels = []string{"before", "after"}
+			cfg.Filenames = pair
+		}},
+		{name: "compare_pair", modify: func(cfg *Config) {
+			cfg.OutputFormat = compareOutputFormat{tests: []StatTest{StatTestKS, StatTestChiSquare}}
+			cfg.Labels = []string{"before", "after"}
+			cfg.Filenames = pair
+		}},
+		{name: "compare_json_pair", modify: func(cfg *Config) {
+			cfg.OutputFormat = compareOutputFormat{tests: []StatTest{StatTestKS}, json: true}
+			cfg.Labels = []string{"before", "after"}
+			cfg.Filenames = pair
+		}},
+		{name: "text_group_by", modify: func(cfg *Config) {
+			cfg.GroupBy = &groupByInputFormat{groupField: 3, valueField: 4}
+			cfg.GraphWidth = 100
+			cfg.PointFmt = "%.1f"
+			cfg.Filenames = []string{"testdata/grouped.txt"}
+		}},
+		{name: "text_percent_pair", modify: func(cfg *Config) {
+			cfg.Percent = true
+			cfg.Filenames = pair
+		}},
+		{name: "text_tick_sci", modify: func(cfg *Config) { cfg.TickStyle = TickStyleSci }},
+		{name: "text_nice_edges", modify: func(cfg *Config) { cfg.NiceEdges = true }},
+		{name: "text_bar_blocks", modify: func(cfg *Config) { cfg.BarStyle = BarStyleBlocks }},
+		{name: "text_hdr", modify: func(cfg *Config) { cfg.Backend = backendHDR }},
+		{name: "text_tdigest", modify: func(cfg *Config) { cfg.Backend = backendTDigest }},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := Config{
+				BucketCount:        10,
+				AxisMin:            axisRangeEnd{Auto: true},
+				AxisMax:            axisRangeEnd{Auto: true},
+				Scale:              scaleLinear,
+				GraphWidth:         60,
+				PointFmt:           "%g",
+				TickStyle:          TickStyleFixed,
+				Backend:            backendHistogram,
+				HDRDigits:          3,
+				TDigestCompression: 100,
+				InputFormat:        plainInputFormat{},
+				ValueParser:        parseFiniteFloat,
+				OutputFormat:       textOutputFormat{},
+				Filenames:          single,
+			}
+			tc.modify(&cfg)
+
+			var buf bytes.Buffer
+			if err := run(context.Background(), &buf, cfg); err != nil {
+				t.Fatal(err)
+			}
+			goldenFile := filepath.Join("testdata", "golden", tc.name+".golden")
+			if *update {
+				if err := os.WriteFile(goldenFile, buf.Bytes(), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(goldenFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := buf.String(); got != string(want) {
+				t.Errorf("output mismatch with %s,\n got:\n%s\nwant:\n%s", goldenFile, got, want)
+			}
+		})
+	}
+}
+
+func mustReadAnnotationsFile(filename string) []Annotation {
+	annotations, err := readAnnotationsFile(filename, parseFiniteFloat)
+	if err != nil {
+		panic(err)
+	}
+	return annotations
+}
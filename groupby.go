@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/exp/slices"
+)
+
+// groupByInputFormat reads a group key and a value in fields of each line,
+// like the endpoint and the latency of
+//
+//	2023-10-01 GET /api 0.123
+//
+// for groupField 3 and valueField 4. Fields are separated by whitespace, or
+// they are columns of CSV records with csv. Fields are numbered from 1. A
+// line with fewer fields is an error unless skipMissing is set, in which
+// case it is skipped.
+type groupByInputFormat struct {
+	csv         bool
+	groupField  int
+	valueField  int
+	skipMissing bool
+}
+
+// scanGroups reads r to the end and calls fn with the key and the value of
+// each line. With skipInvalid, lines whose value cannot be parsed are
+// skipped and their errors are returned instead of stopping, unless more
+// than maxErrors lines are skipped when it is positive.
+func (f groupByInputFormat) scanGroups(r io.Reader, parse ValueParser, skipInvalid bool, maxErrors int, fn func(key string, v float64)) ([]error, error) {
+	var skipped []error
+	lineNum := 0
+	handle := func(fields []string, line string) error {
+		lineNum++
+		if len(fields) < f.groupField || len(fields) < f.valueField {
+			if f.skipMissing {
+				return nil
+			}
+			return fmt.Errorf("line %d has no field %d: %q", lineNum, MustMax(f.groupField, f.valueField), line)
+		}
+		value, err := parse(strings.TrimSpace(fields[f.valueField-1]))
+		if err != nil {
+			err = fmt.Errorf("line %d: %w", lineNum, err)
+			if !skipInvalid {
+				return err
+			}
+			skipped = append(skipped, err)
+			if maxErrors > 0 && len(skipped) > maxErrors {
+				return fmt.Errorf("more than %d invalid lines, the last one: %w", maxErrors, err)
+			}
+			return nil
+		}
+		fn(strings.TrimSpace(fields[f.groupField-1]), value)
+		return nil
+	}
+
+	if f.csv {
+		cr := csv.NewReader(r)
+		cr.FieldsPerRecord = -1
+		for {
+			record, err := cr.Read()
+			if errors.Is(err, io.EOF) {
+				return skipped, nil
+			}
+			if err != nil {
+				return skipped, err
+			}
+			if err := handle(record, strings.Join(record, ",")); err != nil {
+				return skipped, err
+			}
+		}
+	}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if err := handle(strings.Fields(scanner.Text()), scanner.Text()); err != nil {
+			return skipped, err
+		}
+	}
+	return skipped, scanner.Err()
+}
+
+// groupSources reads values of all files in cfg.Filenames into one source
+// per distinct key of cfg.GroupBy, sorted by the keys, so that they are
+// binned over shared buckets like separate inputs. Values are kept in
+// memory since groups are not known until all lines are read.
+func groupSources(ctx context.Context, cfg Config) ([]Source, error) {
+	groups := make(map[string][]float64)
+	for _, filename := range cfg.Filenames {
+		if err := readGroups(ctx, cfg, filename, groups); err != nil {
+			return nil, err
+		}
+	}
+	if len(groups) == 0 {
+		return nil, fmt.Errorf("no value in %s", strings.Join(displayFilenames(cfg.Filenames), ", "))
+	}
+
+	keys := make([]string, 0, len(groups))
+	for key := range groups {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	sources := make([]Source, len(keys))
+	for i, key := range keys {
+		sources[i] = &valuesSource{name: key, values: groups[key]}
+	}
+	return sources, nil
+}
+
+func readGroups(ctx context.Context, cfg Config, filename string, groups map[string][]float64) error {
+	r, err := newReadCloserFile(filename)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	br := bufio.NewReader(contextReader{ctx: ctx, r: r})
+	if err := skipLines(br, cfg.SkipLines); err != nil {
+		return fmt.Errorf("%s: %w", displayFilename(filename), err)
+	}
+	skipped, err := cfg.GroupBy.scanGroups(br, cfg.ValueParser, cfg.SkipInvalid, cfg.MaxErrors, func(key string, v float64) {
+		groups[key] = append(groups[key], v)
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", displayFilename(filename), err)
+	}
+	if len(skipped) > 0 {
+		cfg.warn(Warning{
+			Kind:    warningInvalidLine,
+			Message: fmt.Sprintf("skipped %d invalid lines, the first one: %s", len(skipped), skipped[0]),
+			Source:  displayFilename(filename),
+			Details: map[string]any{"count": len(skipped)},
+		})
+	}
+	return nil
+}
+
+// displayFilenames returns filenames as shown to users.
+func displayFilenames(filenames []string) []string {
+	names := make([]string, len(filenames))
+	for i, filename := range filenames {
+		names[i] = displayFilename(filename)
+	}
+	return names
+}
+
+// valuesSource is a Source of values in memory.
+type valuesSource struct {
+	name   string
+	values []float64
+}
+
+func (s *valuesSource) Name() string { return s.name }
+
+func (s *valuesSource) Scan(fn func(v float64)) error {
+	for _, v := range s.values {
+		fn(v)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGroupByInputFormat_scanGroups(t *testing.T) {
+	testCases := []struct {
+		format      groupByInputFormat
+		input       string
+		skipInvalid bool
+		want        string
+		wantSkipped int
+		wantErr     string
+	}{
+		{
+			format: groupByInputFormat{groupField: 2, valueField: 3},
+			input:  "GET /a 1.5\nPOST /b  2\n",
+			want:   "/a=1.5 /b=2",
+		},
+		{
+			format: groupByInputFormat{csv: true, groupField: 1, valueField: 2},
+			input:  "200, 0.5\n\"500,x\",3\n",
+			want:   "200=0.5 500,x=3",
+		},
+		{
+			format:  groupByInputFormat{groupField: 2, valueField: 3},
+			input:   "GET /a 1.5\nGET\n",
+			wantErr: `line 2 has no field 3: "GET"`,
+		},
+		{
+			format: groupByInputFormat{groupField: 2, valueField: 3, skipMissing: true},
+			input:  "GET /a 1.5\nGET\n",
+			want:   "/a=1.5",
+		},
+		{
+			format:  groupByInputFormat{groupField: 1, valueField: 2},
+			input:   "a x\nb 2\n",
+			wantErr: `line 1: strconv.ParseFloat: parsing "x": invalid syntax`,
+		},
+		{
+			format:      groupByInputFormat{groupField: 1, valueField: 2},
+			input:       "a x\nb 2\n",
+			skipInvalid: true,
+			want:        "b=2",
+			wantSkipped: 1,
+		},
+	}
+	for _, tc := range testCases {
+		var got []string
+		skipped, err := tc.format.scanGroups(strings.NewReader(tc.input), parseFiniteFloat, tc.skipInvalid, 0, func(key string, v float64) {
+			got = append(got, key+"="+formatCSVFloat(v))
+		})
+		if tc.wantErr != "" {
+			if err == nil || err.Error() != tc.wantErr {
+				t.Errorf("error mismatch, input=%q, got=%v, want=%s", tc.input, err, tc.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("input=%q: %s", tc.input, err)
+		}
+		if got := strings.Join(got, " "); got != tc.want {
+			t.Errorf("result mismatch, input=%q, got=%s, want=%s", tc.input, got, tc.want)
+		}
+		if got := len(skipped); got != tc.wantSkipped {
+			t.Errorf("skipped count mismatch, input=%q, got=%d, want=%d", tc.input, got, tc.wantSkipped)
+		}
+	}
+}
+
+func TestParseConfig_GroupBy(t *testing.T) {
+	cfg, err := ParseConfig([]string{"--group-by", "1", "--value", "3", "-i", "csv", "a.csv"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := *cfg.GroupBy, (groupByInputFormat{csv: true, groupField: 1, valueField: 3}); got != want {
+		t.Errorf("group by mismatch, got=%+v, want=%+v", got, want)
+	}
+}
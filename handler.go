@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// Handler returns an http.Handler serving the current state of h, which
+// can be mounted at a page like /debug/histogram:
+//
+//	http.Handle("/debug/histogram", Handler(h))
+//
+// It serves an HTML page with the chart by default, and the JSON of the
+// histogram for the query "?format=json" or the Accept header
+// "application/json".
+func Handler(h *ConcurrentHistogram[float64]) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		snapshot := h.Snapshot()
+		if r.URL.Query().Get("format") == "json" || strings.Contains(r.Header.Get("Accept"), "application/json") {
+			data, err := json.Marshal(snapshot)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(data)
+			return
+		}
+
+		m := &RenderModel{
+			Histograms: []*Histogram[float64]{snapshot},
+			Labels:     []string{"histogram"},
+			Title:      "Histogram",
+			PointFmt:   "%g",
+		}
+		var buf bytes.Buffer
+		if err := (htmlOutputFormat{}).Render(&buf, m); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(buf.Bytes())
+	})
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"golang.org/x/exp/slices"
+)
+
+func TestHandler(t *testing.T) {
+	h := NewConcurrentHistogram(BuildRangePoints[float64](2, 0, 2))
+	h.AddValues([]float64{0.5, 1.5, 1.5, 3})
+	handler := Handler(h)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/histogram?format=json", nil))
+	if got, want := rec.Header().Get("Content-Type"), "application/json"; got != want {
+		t.Errorf("content type mismatch, got=%s, want=%s", got, want)
+	}
+	var got Histogram[float64]
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{1, 2}; !slices.Equal(got.Counts(), want) {
+		t.Errorf("counts mismatch, got=%v, want=%v", got.Counts(), want)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/debug/histogram", nil)
+	req.Header.Set("Accept", "application/json")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if got, want := rec.Header().Get("Content-Type"), "application/json"; got != want {
+		t.Errorf("content type mismatch for Accept header, got=%s, want=%s", got, want)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/histogram", nil))
+	if got, want := rec.Header().Get("Content-Type"), "text/html; charset=utf-8"; got != want {
+		t.Errorf("content type mismatch, got=%s, want=%s", got, want)
+	}
+	if body := rec.Body.String(); !strings.Contains(body, "<svg") {
+		t.Errorf("page must have the chart, got=%s", body)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/debug/histogram", nil))
+	if got, want := rec.Code, http.StatusMethodNotAllowed; got != want {
+		t.Errorf("status mismatch, got=%d, want=%d", got, want)
+	}
+}
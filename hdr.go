@@ -0,0 +1,197 @@
+package main
+
+import (
+	"math"
+	"sort"
+)
+
+// HDRHistogram is a high dynamic range histogram, which does not need the
+// range of values up front. Like HdrHistogram, each power of two range is
+// divided into linear sub-buckets so that the bucket width relative to its
+// values is at most 10^-significantDigits.
+//
+// Buckets are allocated sparsely, so memory usage depends on the spread of
+// the recorded values, not on their number.
+type HDRHistogram struct {
+	significantDigits int
+	subBucketCount    int
+	positive          map[int]int
+	negative          map[int]int
+	zeroCount         int
+	totalCount        int
+	min               float64
+	max               float64
+}
+
+// NewHDRHistogram returns an empty HDRHistogram keeping significantDigits
+// decimal digits of precision, which must be between 1 and 5.
+func NewHDRHistogram(significantDigits int) *HDRHistogram {
+	if significantDigits < 1 || significantDigits > 5 {
+		panic("significantDigits must be between 1 and 5")
+	}
+	subBucketCount := 1
+	for float64(subBucketCount) < math.Pow10(significantDigits) {
+		subBucketCount *= 2
+	}
+	return &HDRHistogram{
+		significantDigits: significantDigits,
+		subBucketCount:    subBucketCount,
+		positive:          make(map[int]int),
+		negative:          make(map[int]int),
+		min:               math.Inf(1),
+		max:               math.Inf(-1),
+	}
+}
+
+var _ Recorder = (*HDRHistogram)(nil)
+
+// hdrBucket is a bucket from lower (inclusive) to upper (exclusive).
+type hdrBucket struct {
+	lower, upper float64
+	count        int
+}
+
+// AddValue records v. NaN and infinities are ignored.
+func (h *HDRHistogram) AddValue(v float64) {
+	h.addValueCount(v, 1)
+}
+
+// addValueCount records v n times.
+func (h *HDRHistogram) addValueCount(v float64, n int) {
+	if math.IsNaN(v) || math.IsInf(v, 0) || n <= 0 {
+		return
+	}
+	switch {
+	case v > 0:
+		h.positive[h.bucketKey(v)] += n
+	case v < 0:
+		h.negative[h.bucketKey(-v)] += n
+	default:
+		h.zeroCount += n
+	}
+	h.totalCount += n
+	h.min = math.Min(h.min, v)
+	h.max = math.Max(h.max, v)
+}
+
+func (h *HDRHistogram) AddValues(values []float64) {
+	for _, v := range values {
+		h.AddValue(v)
+	}
+}
+
+// bucketKey returns the key of the bucket for a positive value v.
+func (h *HDRHistogram) bucketKey(v float64) int {
+	frac, exp := math.Frexp(v)
+	// frac is in [0.5, 1).
+	sub := int((frac - 0.5) * 2 * float64(h.subBucketCount))
+	return exp*h.subBucketCount + sub
+}
+
+// bucketBounds returns the bounds of the bucket with key for positive
+// values.
+func (h *HDRHistogram) bucketBounds(key int) (lower, upper float64) {
+	exp := key / h.subBucketCount
+	sub := key % h.subBucketCount
+	if sub < 0 {
+		exp--
+		sub += h.subBucketCount
+	}
+	width := 0.5 / float64(h.subBucketCount)
+	lower = math.Ldexp(0.5+float64(sub)*width, exp)
+	upper = math.Ldexp(0.5+float64(sub+1)*width, exp)
+	return lower, upper
+}
+
+// buckets returns non-empty buckets in ascending order of values.
+func (h *HDRHistogram) buckets() []hdrBucket {
+	buckets := make([]hdrBucket, 0, len(h.negative)+len(h.positive)+1)
+	for _, key := range sortedKeys(h.negative, true) {
+		lower, upper := h.bucketBounds(key)
+		buckets = append(buckets, hdrBucket{lower: -upper, upper: -lower, count: h.negative[key]})
+	}
+	if h.zeroCount > 0 {
+		buckets = append(buckets, hdrBucket{lower: 0, upper: 0, count: h.zeroCount})
+	}
+	for _, key := range sortedKeys(h.positive, false) {
+		lower, upper := h.bucketBounds(key)
+		buckets = append(buckets, hdrBucket{lower: lower, upper: upper, count: h.positive[key]})
+	}
+	return buckets
+}
+
+func sortedKeys(m map[int]int, descending bool) []int {
+	keys := make([]int, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	if descending {
+		sort.Sort(sort.Reverse(sort.IntSlice(keys)))
+	} else {
+		sort.Ints(keys)
+	}
+	return keys
+}
+
+// Counts returns counts of non-empty buckets in ascending order of values.
+func (h *HDRHistogram) Counts() []int {
+	buckets := h.buckets()
+	counts := make([]int, len(buckets))
+	for i, b := range buckets {
+		counts[i] = b.count
+	}
+	return counts
+}
+
+// TotalCount returns the number of recorded values.
+func (h *HDRHistogram) TotalCount() int {
+	return h.totalCount
+}
+
+// Min returns the minimum recorded value. It returns +Inf if no value is
+// recorded.
+func (h *HDRHistogram) Min() float64 {
+	return h.min
+}
+
+// Max returns the maximum recorded value. It returns -Inf if no value is
+// recorded.
+func (h *HDRHistogram) Max() float64 {
+	return h.max
+}
+
+// Quantile estimates the q-quantile of recorded values, interpolating
+// linearly within the bucket which contains it. It returns NaN if no value
+// is recorded. q must be between 0 and 1.
+func (h *HDRHistogram) Quantile(q float64) float64 {
+	if q < 0 || q > 1 || math.IsNaN(q) {
+		panic("q must be between 0 and 1")
+	}
+	if h.totalCount == 0 {
+		return math.NaN()
+	}
+
+	target := q * float64(h.totalCount)
+	cum := 0
+	for _, b := range h.buckets() {
+		if target <= float64(cum+b.count) {
+			v := b.lower + (b.upper-b.lower)*(target-float64(cum))/float64(b.count)
+			return math.Max(h.min, math.Min(h.max, v))
+		}
+		cum += b.count
+	}
+	return h.max
+}
+
+// Histogram converts h to a Histogram with rangePoints. The count of each
+// bucket of h is added to the Histogram bucket which contains its midpoint.
+func (h *HDRHistogram) Histogram(rangePoints []float64) *Histogram[float64] {
+	histogram := NewHistogram(rangePoints)
+	for _, b := range h.buckets() {
+		mid := b.lower + (b.upper-b.lower)/2
+		// Keep recorded extremes in range when their bucket sticks out.
+		mid = math.Max(h.min, math.Min(h.max, mid))
+		histogram.addValueCount(mid, b.count)
+	}
+	return histogram
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"golang.org/x/exp/slices"
+)
+
+func TestHDRHistogram_Quantile(t *testing.T) {
+	rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
+	for _, digits := range []int{1, 2, 3} {
+		h := NewHDRHistogram(digits)
+		values := make([]float64, 10000)
+		for i := range values {
+			// Spread over several orders of magnitude with both signs.
+			values[i] = math.Exp(rnd.Float64()*20-10) * float64(1-2*rnd.Intn(2))
+		}
+		values = append(values, 0, 0)
+		h.AddValues(values)
+		slices.Sort(values)
+
+		if got, want := h.TotalCount(), len(values); got != want {
+			t.Errorf("total count mismatch, got=%d, want=%d", got, want)
+		}
+		for _, q := range []float64{0, 0.01, 0.1, 0.5, 0.9, 0.99, 1} {
+			got := h.Quantile(q)
+			// The quantile may fall on either side of a rank.
+			lo := values[int(math.Max(0, math.Floor(q*float64(len(values)))-1))]
+			hi := values[int(math.Min(float64(len(values)-1), math.Ceil(q*float64(len(values)))))]
+			tolerance := math.Pow10(-digits)
+			if got < lo-math.Abs(lo)*tolerance || got > hi+math.Abs(hi)*tolerance {
+				t.Errorf("quantile out of tolerance, digits=%d, q=%g, got=%g, want between %g and %g", digits, q, got, lo, hi)
+			}
+		}
+	}
+}
+
+func TestHDRHistogram_Histogram(t *testing.T) {
+	h := NewHDRHistogram(3)
+	h.AddValues([]float64{0.5, 1.5, 1.7, 2.2, 2.9, 9})
+	if got, want := h.Counts(), []int{1, 1, 1, 1, 1, 1}; !slices.Equal(got, want) {
+		t.Errorf("counts mismatch, got=%v, want=%v", got, want)
+	}
+	if got, want := h.Min(), 0.5; got != want {
+		t.Errorf("min mismatch, got=%g, want=%g", got, want)
+	}
+	if got, want := h.Max(), 9.0; got != want {
+		t.Errorf("max mismatch, got=%g, want=%g", got, want)
+	}
+
+	histogram := h.Histogram(BuildRangePoints[float64](3, 0, 3))
+	if got, want := histogram.Counts(), []int{1, 2, 2}; !slices.Equal(got, want) {
+		t.Errorf("histogram counts mismatch, got=%v, want=%v", got, want)
+	}
+	if got, want := histogram.outOfRangeCount, 1; got != want {
+		t.Errorf("histogram out of range count mismatch, got=%d, want=%d", got, want)
+	}
+}
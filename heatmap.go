@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// heatmapShades are the characters of cells from the lowest count level to
+// the highest. Empty cells are blank.
+var heatmapShades = []string{"░", "▒", "▓", "█"}
+
+// ansiHeatmapBackgrounds are 256-color backgrounds of cells from the lowest
+// count level to the highest, used instead of heatmapShades with colors.
+var ansiHeatmapBackgrounds = []string{
+	"\x1b[48;5;24m",  // dark blue
+	"\x1b[48;5;30m",  // teal
+	"\x1b[48;5;71m",  // green
+	"\x1b[48;5;220m", // yellow
+}
+
+// HeatmapFormatter formats a Histogram2D as a heatmap for terminals. Rows
+// are y buckets with larger values upward and columns are x buckets, and
+// the shade of each cell shows its count relative to the maximum count.
+type HeatmapFormatter struct {
+	histogram *Histogram2D[float64]
+	pointFmt  string
+	tickStyle TickStyle
+	color     bool
+}
+
+func NewHeatmapFormatter(histogram *Histogram2D[float64], pointFmt string) *HeatmapFormatter {
+	return &HeatmapFormatter{
+		histogram: histogram,
+		pointFmt:  pointFmt,
+		tickStyle: TickStyleFixed,
+	}
+}
+
+// SetTickStyle sets the style of axis point labels.
+// The default is TickStyleFixed.
+func (f *HeatmapFormatter) SetTickStyle(style TickStyle) {
+	f.tickStyle = style
+}
+
+// SetColor sets whether to draw cells with ANSI background colors instead
+// of shade characters. The default is false.
+func (f *HeatmapFormatter) SetColor(color bool) {
+	f.color = color
+}
+
+// level returns the index of the shade for count, or -1 for no value.
+func (f *HeatmapFormatter) level(count, maxCount int) int {
+	if count == 0 {
+		return -1
+	}
+	levels := len(heatmapShades)
+	return MustMin(levels-1, (count*levels-1)/maxCount)
+}
+
+// shade returns a cell of width characters at level, which is blank for
+// a negative level.
+func (f *HeatmapFormatter) shade(level, width int) string {
+	switch {
+	case level < 0:
+		return strings.Repeat(" ", width)
+	case f.color:
+		return colorize(strings.Repeat(" ", width), ansiHeatmapBackgrounds[level])
+	default:
+		return strings.Repeat(heatmapShades[level], width)
+	}
+}
+
+func (f *HeatmapFormatter) LineStrings() []string {
+	h := f.histogram
+	xTicks := FormatTicks(h.xRangePoints, f.tickStyle, f.pointFmt)
+	yTicks := FormatTicks(h.yRangePoints, f.tickStyle, f.pointFmt)
+	columnWidth := stringSliceMaxWidth(xTicks) + len(" ")
+	yTickWidth := stringSliceMaxWidth(yTicks)
+	maxCount := h.MaxCount()
+
+	var lines []string
+	for j := h.yBucketCount() - 1; j >= 0; j-- {
+		var b strings.Builder
+		b.WriteString(padStartSpace(yTickWidth, yTicks[j]) + " ~ " + padStartSpace(yTickWidth, yTicks[j+1]) + " |")
+		for i := 0; i < h.xBucketCount(); i++ {
+			b.WriteString(f.shade(f.level(h.Count(i, j), maxCount), columnWidth))
+		}
+		lines = append(lines, strings.TrimRight(b.String(), " "))
+	}
+
+	labelWidth := yTickWidth*2 + len(" ~ ")
+	lines = append(lines, fmt.Sprintf("%*s +%s", labelWidth, "", strings.Repeat("-", columnWidth*h.xBucketCount())))
+	var b strings.Builder
+	for i, tick := range xTicks {
+		if i < len(xTicks)-1 {
+			b.WriteString(padEndSpace(columnWidth, tick))
+		} else {
+			b.WriteString(tick)
+		}
+	}
+	lines = append(lines, fmt.Sprintf("%*s  %s", labelWidth, "", b.String()))
+
+	var legend []string
+	prevUpper := 0
+	for level := range heatmapShades {
+		// The largest count at the level. Levels without any count are
+		// skipped for small maximum counts.
+		upper := (level + 1) * maxCount / len(heatmapShades)
+		if upper == prevUpper {
+			continue
+		}
+		legend = append(legend, fmt.Sprintf("%s <= %d", f.shade(level, 1), upper))
+		prevUpper = upper
+	}
+	lines = append(lines, strings.Join(legend, "  "))
+	lines = append(lines, fmt.Sprintf("out of range: %d", h.outOfRangeCount))
+	return lines
+}
+
+func (f *HeatmapFormatter) String() string {
+	return strings.Join(f.LineStrings(), "\n") + "\n"
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestHeatmapFormatter(t *testing.T) {
+	h := NewHistogram2D(BuildRangePoints[float64](3, 0, 3), BuildRangePoints[float64](2, 0, 20))
+	for i := 0; i < 8; i++ {
+		h.AddValue(1.5, 15)
+	}
+	for i := 0; i < 3; i++ {
+		h.AddValue(0.5, 5)
+	}
+	h.AddValue(2.5, 5)
+	h.AddValue(5, 5)
+
+	formatter := NewHeatmapFormatter(h, "%g")
+	got := formatter.String()
+	want := `10 ~ 20 |  ██
+ 0 ~ 10 |▒▒  ░░
+        +------
+         0 1 2 3
+░ <= 2  ▒ <= 4  ▓ <= 6  █ <= 8
+out of range: 1
+`
+	if got != want {
+		t.Errorf("result mismatch,\n got=%q,\nwant=%q", got, want)
+		fmt.Printf("\n%s", got)
+	}
+
+	formatter.SetColor(true)
+	if got := formatter.String(); !strings.Contains(got, ansiHeatmapBackgrounds[3]) {
+		t.Errorf("result must have the background of the maximum count, got=%q", got)
+	}
+}
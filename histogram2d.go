@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Histogram2D counts pairs of values like request sizes and latencies into
+// a grid of buckets. Each axis has its own range points with the same
+// rules as Histogram: bucket i of an axis holds values v where
+// rangePoints[i] <= v < rangePoints[i+1].
+type Histogram2D[T Number] struct {
+	xRangePoints []T
+	yRangePoints []T
+	// counts holds the count of the bucket (i, j) at i*yBucketCount+j.
+	counts          []int
+	outOfRangeCount int
+}
+
+func NewHistogram2D[T Number](xRangePoints, yRangePoints []T) *Histogram2D[T] {
+	if len(xRangePoints) < 2 || len(yRangePoints) < 2 {
+		panic(fmt.Sprintf("each axis needs at least 2 range points, got %d and %d", len(xRangePoints), len(yRangePoints)))
+	}
+	return &Histogram2D[T]{
+		xRangePoints: xRangePoints,
+		yRangePoints: yRangePoints,
+		counts:       make([]int, (len(xRangePoints)-1)*(len(yRangePoints)-1)),
+	}
+}
+
+// bucketIndex returns the index of the bucket containing v and whether v
+// is in range.
+func bucketIndex[T Number](rangePoints []T, v T) (int, bool) {
+	// Written in this form so that NaN is also out of range.
+	if !(v >= rangePoints[0] && v < rangePoints[len(rangePoints)-1]) {
+		return 0, false
+	}
+	return sort.Search(len(rangePoints), func(i int) bool { return rangePoints[i] > v }) - 1, true
+}
+
+// AddValue counts the pair of x and y. Pairs with either value out of range
+// are counted as out of range.
+func (h *Histogram2D[T]) AddValue(x, y T) {
+	i, xOK := bucketIndex(h.xRangePoints, x)
+	j, yOK := bucketIndex(h.yRangePoints, y)
+	if !xOK || !yOK {
+		h.outOfRangeCount++
+		return
+	}
+	h.counts[i*h.yBucketCount()+j]++
+}
+
+func (h *Histogram2D[T]) xBucketCount() int { return len(h.xRangePoints) - 1 }
+func (h *Histogram2D[T]) yBucketCount() int { return len(h.yRangePoints) - 1 }
+
+func (h *Histogram2D[T]) XRangePoints() []T {
+	return append([]T(nil), h.xRangePoints...)
+}
+
+func (h *Histogram2D[T]) YRangePoints() []T {
+	return append([]T(nil), h.yRangePoints...)
+}
+
+// Count returns the count of the bucket at the i-th x bucket and the j-th
+// y bucket.
+func (h *Histogram2D[T]) Count(i, j int) int {
+	return h.counts[i*h.yBucketCount()+j]
+}
+
+// Counts returns the counts indexed by the x bucket and then the y bucket.
+func (h *Histogram2D[T]) Counts() [][]int {
+	counts := make([][]int, h.xBucketCount())
+	for i := range counts {
+		counts[i] = append([]int(nil), h.counts[i*h.yBucketCount():(i+1)*h.yBucketCount()]...)
+	}
+	return counts
+}
+
+func (h *Histogram2D[T]) MaxCount() int {
+	return MustMax(h.counts...)
+}
+
+func (h *Histogram2D[T]) OutOfRangeCount() int {
+	return h.outOfRangeCount
+}
+
+// MarginalX returns the histogram of x values of pairs. Pairs out of range
+// in either axis are counted as out of range.
+func (h *Histogram2D[T]) MarginalX() *Histogram[T] {
+	marginal := NewHistogram(h.XRangePoints())
+	for i := 0; i < h.xBucketCount(); i++ {
+		for j := 0; j < h.yBucketCount(); j++ {
+			marginal.counts[i] += h.Count(i, j)
+		}
+	}
+	marginal.outOfRangeCount = h.outOfRangeCount
+	return marginal
+}
+
+// MarginalY returns the histogram of y values of pairs. Pairs out of range
+// in either axis are counted as out of range.
+func (h *Histogram2D[T]) MarginalY() *Histogram[T] {
+	marginal := NewHistogram(h.YRangePoints())
+	for i := 0; i < h.xBucketCount(); i++ {
+		for j := 0; j < h.yBucketCount(); j++ {
+			marginal.counts[j] += h.Count(i, j)
+		}
+	}
+	marginal.outOfRangeCount = h.outOfRangeCount
+	return marginal
+}
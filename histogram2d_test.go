@@ -0,0 +1,49 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	"golang.org/x/exp/slices"
+)
+
+func TestHistogram2D(t *testing.T) {
+	h := NewHistogram2D(BuildRangePoints[float64](2, 0, 2), BuildRangePoints[float64](3, 0, 30))
+	pairs := [][2]float64{{0, 0}, {0.5, 15}, {1, 10}, {1.5, 29}, {1.5, 25}, {2, 5}, {1, 30}, {math.NaN(), 1}}
+	for _, p := range pairs {
+		h.AddValue(p[0], p[1])
+	}
+
+	want := [][]int{{1, 1, 0}, {0, 1, 2}}
+	got := h.Counts()
+	for i := range want {
+		if !slices.Equal(got[i], want[i]) {
+			t.Errorf("counts mismatch, got=%v, want=%v", got, want)
+			break
+		}
+	}
+	if got, want := h.Count(1, 2), 2; got != want {
+		t.Errorf("count mismatch, got=%d, want=%d", got, want)
+	}
+	if got, want := h.MaxCount(), 2; got != want {
+		t.Errorf("max count mismatch, got=%d, want=%d", got, want)
+	}
+	if got, want := h.OutOfRangeCount(), 3; got != want {
+		t.Errorf("out of range count mismatch, got=%d, want=%d", got, want)
+	}
+
+	x := h.MarginalX()
+	if got, want := x.Counts(), []int{2, 3}; !slices.Equal(got, want) {
+		t.Errorf("x marginal counts mismatch, got=%v, want=%v", got, want)
+	}
+	if got, want := x.RangePoints(), h.XRangePoints(); !slices.Equal(got, want) {
+		t.Errorf("x marginal range points mismatch, got=%v, want=%v", got, want)
+	}
+	y := h.MarginalY()
+	if got, want := y.Counts(), []int{1, 2, 2}; !slices.Equal(got, want) {
+		t.Errorf("y marginal counts mismatch, got=%v, want=%v", got, want)
+	}
+	if got, want := y.outOfRangeCount, 3; got != want {
+		t.Errorf("y marginal out of range count mismatch, got=%d, want=%d", got, want)
+	}
+}
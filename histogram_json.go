@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// histogramJSON is the JSON representation of Histogram.
+type histogramJSON[T Number] struct {
+	RangePoints     []T   `json:"rangePoints"`
+	Counts          []int `json:"counts"`
+	OutOfRangeCount int   `json:"outOfRangeCount"`
+	UnderflowCount  int   `json:"underflowCount"`
+	OverflowCount   int   `json:"overflowCount"`
+	// Closure is omitted for the default ClosureLeft.
+	Closure         Closure `json:"closure,omitempty"`
+	InclusiveMax    bool    `json:"inclusiveMax,omitempty"`
+	BoundaryEpsilon float64 `json:"boundaryEpsilon,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler.
+func (h *Histogram[T]) MarshalJSON() ([]byte, error) {
+	v := histogramJSON[T]{
+		RangePoints:     h.rangePoints,
+		Counts:          h.counts,
+		OutOfRangeCount: h.outOfRangeCount,
+		UnderflowCount:  h.underflowCount,
+		OverflowCount:   h.overflowCount,
+		InclusiveMax:    h.inclusiveMax,
+		BoundaryEpsilon: h.boundaryEpsilon,
+	}
+	if h.Closure() == ClosureRight {
+		v.Closure = ClosureRight
+	}
+	return json.Marshal(v)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (h *Histogram[T]) UnmarshalJSON(data []byte) error {
+	var v histogramJSON[T]
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	if len(v.RangePoints) < 2 {
+		return fmt.Errorf("histogram needs at least 2 range points, got %d", len(v.RangePoints))
+	}
+	for i := 1; i < len(v.RangePoints); i++ {
+		if v.RangePoints[i] < v.RangePoints[i-1] {
+			return fmt.Errorf("histogram range points must not decrease, got %v", v.RangePoints)
+		}
+	}
+	if len(v.Counts) != len(v.RangePoints)-1 {
+		return fmt.Errorf("histogram counts length must be %d for %d range points, got %d",
+			len(v.RangePoints)-1, len(v.RangePoints), len(v.Counts))
+	}
+	for _, c := range v.Counts {
+		if c < 0 {
+			return fmt.Errorf("histogram counts must not be negative, got %d", c)
+		}
+	}
+	if v.OutOfRangeCount < 0 {
+		return fmt.Errorf("histogram out of range count must not be negative, got %d", v.OutOfRangeCount)
+	}
+	if v.UnderflowCount < 0 || v.OverflowCount < 0 || v.UnderflowCount+v.OverflowCount > v.OutOfRangeCount {
+		return fmt.Errorf("histogram underflow and overflow counts must not be negative and must sum to at most the out of range count %d, got %d and %d",
+			v.OutOfRangeCount, v.UnderflowCount, v.OverflowCount)
+	}
+	if v.BoundaryEpsilon < 0 {
+		return fmt.Errorf("histogram boundary epsilon must not be negative, got %g", v.BoundaryEpsilon)
+	}
+	closure := ClosureLeft
+	if v.Closure != "" {
+		var err error
+		if closure, err = parseClosure(string(v.Closure)); err != nil {
+			return fmt.Errorf("histogram %s", err)
+		}
+	}
+
+	h.rangePoints = v.RangePoints
+	h.counts = v.Counts
+	h.outOfRangeCount = v.OutOfRangeCount
+	h.underflowCount = v.UnderflowCount
+	h.overflowCount = v.OverflowCount
+	h.closure = closure
+	h.inclusiveMax = v.InclusiveMax
+	h.boundaryEpsilon = v.BoundaryEpsilon
+	h.uniformWidth = uniformWidth(v.RangePoints)
+	return nil
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"golang.org/x/exp/slices"
+)
+
+func TestHistogram_MarshalJSON(t *testing.T) {
+	h := NewHistogram(BuildRangePoints[float64](4, 0, 2))
+	h.AddValues([]float64{-1, 0.1, 0.7, 0.8, 1.9, 3, 4})
+
+	data, err := json.Marshal(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(data), `{"rangePoints":[0,0.5,1,1.5,2],"counts":[1,2,0,1],"outOfRangeCount":3,"underflowCount":1,"overflowCount":2}`; got != want {
+		t.Errorf("result mismatch,\n got=%s,\nwant=%s", got, want)
+	}
+
+	var got Histogram[float64]
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !got.Equal(h) || got.outOfRangeCount != h.outOfRangeCount ||
+		got.UnderflowCount() != h.UnderflowCount() || got.OverflowCount() != h.OverflowCount() {
+		t.Errorf("round trip mismatch, got=%+v, want=%+v", got, h)
+	}
+}
+
+func TestHistogram_BoundaryEpsilonJSON(t *testing.T) {
+	h := NewHistogram([]float64{0, 0.1, 0.2, 0.3, 0.4})
+	h.SetBoundaryEpsilon(1e-9)
+	data, err := json.Marshal(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got Histogram[float64]
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	// v is slightly below 0.3, so it is counted in the bucket below 0.3
+	// without the epsilon.
+	a, b := 0.7, 0.4
+	v := a - b
+	got.AddValue(v)
+	if got, want := got.Counts(), []int{0, 0, 0, 1}; !slices.Equal(got, want) {
+		t.Errorf("counts mismatch after round trip, got=%v, want=%v", got, want)
+	}
+	if err := got.Merge(h); err != nil {
+		t.Errorf("merge after round trip failed: %v", err)
+	}
+}
+
+func TestHistogram_UnmarshalJSONError(t *testing.T) {
+	testCases := []string{
+		`{"rangePoints":[0],"counts":[],"outOfRangeCount":0}`,
+		`{"rangePoints":[0,1,2],"counts":[1],"outOfRangeCount":0}`,
+		`{"rangePoints":[0,2,1],"counts":[1,1],"outOfRangeCount":0}`,
+		`{"rangePoints":[0,1],"counts":[-1],"outOfRangeCount":0}`,
+		`{"rangePoints":[0,1],"counts":[1],"outOfRangeCount":-1}`,
+		`{"rangePoints":[0,1],"counts":[1],"outOfRangeCount":1,"underflowCount":1,"overflowCount":1}`,
+		`{"rangePoints":[0,1],"counts":[1],"outOfRangeCount":1,"underflowCount":-1}`,
+		`{"rangePoints":[0,1],"counts":[1],"outOfRangeCount":0,"boundaryEpsilon":-1}`,
+		`[]`,
+	}
+	for _, input := range testCases {
+		var h Histogram[float64]
+		if err := json.Unmarshal([]byte(input), &h); err == nil {
+			t.Errorf("error expected, input=%s", input)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io"
+)
+
+// htmlOutputFormat renders a standalone HTML page with the SVG chart and
+// summary statistics of inputs. Styles are inlined, so the page can be
+// attached to reports as a single file.
+type htmlOutputFormat struct{}
+
+func (htmlOutputFormat) Name() string { return "html" }
+
+func (htmlOutputFormat) FileExtension() string { return ".html" }
+
+var htmlReportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{if .Title}}{{.Title}}{{else}}Histogram{{end}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+h1 { font-size: 1.4em; }
+.chart svg { max-width: 100%; height: auto; border: 1px solid #ddd; }
+table { border-collapse: collapse; margin-top: 1.5em; }
+th, td { border: 1px solid #ccc; padding: 0.3em 0.8em; }
+th { background: #f4f4f4; }
+td.num { text-align: right; font-variant-numeric: tabular-nums; }
+.swatch { display: inline-block; width: 0.8em; height: 0.8em; margin-right: 0.4em; }
+</style>
+</head>
+<body>
+{{if .Title}}<h1>{{.Title}}</h1>
+{{end}}<div class="chart">
+{{.Chart}}</div>
+{{if .Rows}}<table>
+<tr><th>input</th><th>count</th><th>min</th><th>mean</th><th>stddev</th>{{range .Quantiles}}<th>{{.}}</th>{{end}}<th>max</th></tr>
+{{range .Rows}}<tr><td><span class="swatch" style="background: {{.Color}}"></span>{{.Label}}</td><td class="num">{{.Count}}</td><td class="num">{{.Min}}</td><td class="num">{{.Mean}}</td><td class="num">{{.Stddev}}</td>{{range .Quantiles}}<td class="num">{{.}}</td>{{end}}<td class="num">{{.Max}}</td></tr>
+{{end}}</table>
+{{end}}{{if .Annotations}}<table>
+<tr><th>annotation</th><th>value</th></tr>
+{{range .Annotations}}<tr><td>{{.Label}}</td><td class="num">{{.Value}}</td></tr>
+{{end}}</table>
+{{end}}</body>
+</html>
+`))
+
+// htmlReportQuantiles are the quantiles in the statistics table, which are
+// estimated from buckets.
+var htmlReportQuantiles = []struct {
+	header string
+	q      float64
+}{
+	{header: "p50", q: 0.5},
+	{header: "p90", q: 0.9},
+	{header: "p99", q: 0.99},
+}
+
+func (htmlOutputFormat) Render(w io.Writer, m *RenderModel) error {
+	var chart bytes.Buffer
+	if err := (svgOutputFormat{}).Render(&chart, m); err != nil {
+		return err
+	}
+
+	type row struct {
+		Color                  template.CSS
+		Label                  string
+		Count                  int
+		Min, Mean, Stddev, Max string
+		Quantiles              []string
+	}
+	type annotation struct {
+		Label, Value string
+	}
+	data := struct {
+		Title       string
+		Chart       template.HTML
+		Quantiles   []string
+		Rows        []row
+		Annotations []annotation
+	}{
+		Title: m.Title,
+		// The SVG is built by svgOutputFormat, which escapes texts in it.
+		Chart: template.HTML(chart.String()),
+	}
+	for _, q := range htmlReportQuantiles {
+		data.Quantiles = append(data.Quantiles, q.header)
+	}
+	format := func(v float64) string { return fmt.Sprintf(m.PointFmt, v) }
+	for i, s := range m.Stats {
+		r := row{
+			Color:  template.CSS(svgSeriesColors[i%len(svgSeriesColors)]),
+			Label:  m.Labels[i],
+			Count:  s.Count,
+			Min:    format(s.Min),
+			Mean:   format(s.Mean),
+			Stddev: format(s.Stddev),
+			Max:    format(s.Max),
+		}
+		for _, q := range htmlReportQuantiles {
+			r.Quantiles = append(r.Quantiles, format(m.quantile(i, q.q)))
+		}
+		data.Rows = append(data.Rows, r)
+	}
+	for _, a := range m.Annotations {
+		data.Annotations = append(data.Annotations, annotation{Label: a.Label, Value: format(a.Value)})
+	}
+	return htmlReportTemplate.Execute(w, data)
+}
@@ -0,0 +1,488 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/exp/slices"
+)
+
+// InputFormat extracts values from an input in a specific format.
+// Implementations are registered with RegisterInputFormat and selected by
+// Name with the --input-format flag.
+type InputFormat interface {
+	// Name returns the name of the format used in the --input-format flag.
+	Name() string
+	// Scan reads r to the end and calls fn with each value in it.
+	// Values in text are converted with parse.
+	Scan(r io.Reader, parse ValueParser, fn func(v float64)) error
+}
+
+// inputList is the --input-format value to show the registered format
+// names.
+const inputList = "list"
+
+var (
+	inputFormatsMu sync.RWMutex
+	inputFormats   = make(map[string]InputFormat)
+)
+
+// RegisterInputFormat makes f available by its name.
+// It panics if a format with the same name is already registered.
+func RegisterInputFormat(f InputFormat) {
+	inputFormatsMu.Lock()
+	defer inputFormatsMu.Unlock()
+
+	name := f.Name()
+	if name == inputList {
+		panic(fmt.Sprintf("input format name %q is reserved", name))
+	}
+	if _, dup := inputFormats[name]; dup {
+		panic(fmt.Sprintf("input format %q is already registered", name))
+	}
+	inputFormats[name] = f
+}
+
+// LookupInputFormat returns the registered format with name.
+func LookupInputFormat(name string) (InputFormat, bool) {
+	inputFormatsMu.RLock()
+	defer inputFormatsMu.RUnlock()
+
+	f, ok := inputFormats[name]
+	return f, ok
+}
+
+// InputFormatNames returns the sorted names of the registered formats.
+func InputFormatNames() []string {
+	inputFormatsMu.RLock()
+	defer inputFormatsMu.RUnlock()
+
+	names := make([]string, 0, len(inputFormats))
+	for name := range inputFormats {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+func init() {
+	RegisterInputFormat(plainInputFormat{})
+	RegisterInputFormat(csvInputFormat{})
+	RegisterInputFormat(jsonlInputFormat{})
+	RegisterInputFormat(prometheusInputFormat{})
+	RegisterInputFormat(countedInputFormat{})
+}
+
+// skipLines reads n lines from r and discards them. Fewer lines than n
+// are not an error, which leaves no value.
+func skipLines(r *bufio.Reader, n int) error {
+	for skipped := 0; skipped < n; {
+		_, err := r.ReadSlice('\n')
+		switch err {
+		case nil:
+			skipped++
+		case bufio.ErrBufferFull:
+			// The rest of a line longer than the buffer follows.
+		case io.EOF:
+			return nil
+		default:
+			return err
+		}
+	}
+	return nil
+}
+
+// droppingInputFormat is an InputFormat which drops some valid records
+// without values on purpose, like NaN samples of Prometheus, and tells
+// how many were dropped.
+type droppingInputFormat interface {
+	InputFormat
+	scanDropping(r io.Reader, parse ValueParser, fn func(v float64)) (dropped int, err error)
+}
+
+// countingInputFormat is an InputFormat whose records are values with
+// counts, which it can pass at once instead of repeating each value as
+// many times as its count.
+type countingInputFormat interface {
+	InputFormat
+	scanCounts(r io.Reader, parse ValueParser, fn func(v float64, n int)) error
+}
+
+// scanInput is like format.Scan but calls fn with each value and its
+// count, which is 1 unless format is a countingInputFormat. It also returns
+// the number of records dropped if format is a droppingInputFormat.
+func scanInput(r io.Reader, format InputFormat, parse ValueParser, fn func(v float64, n int)) (dropped int, err error) {
+	switch f := format.(type) {
+	case countingInputFormat:
+		return 0, f.scanCounts(r, parse, fn)
+	case droppingInputFormat:
+		return f.scanDropping(r, parse, func(v float64) { fn(v, 1) })
+	}
+	return 0, format.Scan(r, parse, func(v float64) { fn(v, 1) })
+}
+
+// scanSkippingInvalidLines reads r with format line by line, so that a
+// line which cannot be read is skipped instead of stopping. It returns the
+// errors of skipped lines and the number of records dropped by format. It
+// fails if more than maxErrors lines are skipped when maxErrors is
+// positive. Formats whose records span lines cannot be read this way.
+func scanSkippingInvalidLines(r io.Reader, format InputFormat, parse ValueParser, maxErrors int, fn func(v float64, n int)) ([]error, int, error) {
+	var skipped []error
+	dropped := 0
+	var values []valueCount
+	collect := func(v float64, n int) { values = append(values, valueCount{value: v, count: n}) }
+	scanner := bufio.NewScanner(r)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		values = values[:0]
+		n, err := scanInput(strings.NewReader(scanner.Text()), format, parse, collect)
+		if err != nil {
+			skipped = append(skipped, fmt.Errorf("line %d: %w", lineNum, err))
+			if maxErrors > 0 && len(skipped) > maxErrors {
+				return skipped, dropped, fmt.Errorf("more than %d invalid lines, the last one: %w", maxErrors, skipped[len(skipped)-1])
+			}
+			continue
+		}
+		dropped += n
+		for _, v := range values {
+			fn(v.value, v.count)
+		}
+	}
+	return skipped, dropped, scanner.Err()
+}
+
+// parseFiniteFloat parses s as a float64 value and rejects NaN and
+// infinities, which cannot be put in buckets.
+func parseFiniteFloat(s string) (float64, error) {
+	if value, ok := parseFloatFast(s); ok {
+		return value, nil
+	}
+	value, err := strconv.ParseFloat(s, float64BitSize)
+	if err != nil {
+		return 0, err
+	}
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return 0, fmt.Errorf("%w: %q", errNotFinite, s)
+	}
+	return value, nil
+}
+
+// plainInputFormat reads one value per line.
+type plainInputFormat struct{}
+
+func (plainInputFormat) Name() string { return "plain" }
+
+func (plainInputFormat) Scan(r io.Reader, parse ValueParser, fn func(v float64)) error {
+	return scanFloat64Values(r, parse, fn)
+}
+
+// Behaviors of fieldInputFormat for lines without the field.
+const (
+	missingFieldError = "error"
+	missingFieldSkip  = "skip"
+)
+
+// fieldInputFormat reads the value in a whitespace separated field of each
+// line, like the 4th field of
+//
+//	2023-10-01 GET /api 0.123
+//
+// Fields are numbered from 1 like awk. A line with fewer fields is an
+// error unless skipMissing is set, in which case it is skipped. It is not
+// registered since it needs the field number.
+type fieldInputFormat struct {
+	field       int
+	skipMissing bool
+}
+
+func (fieldInputFormat) Name() string { return "plain" }
+
+func (f fieldInputFormat) Scan(r io.Reader, parse ValueParser, fn func(v float64)) error {
+	scanner := bufio.NewScanner(r)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		// Fields are only used until the next line is read.
+		fields := strings.Fields(bytesToString(scanner.Bytes()))
+		if len(fields) < f.field {
+			if f.skipMissing {
+				continue
+			}
+			return fmt.Errorf("line %d has no field %d: %q", lineNum, f.field, scanner.Bytes())
+		}
+		value, err := parse(fields[f.field-1])
+		if err != nil {
+			return fmt.Errorf("line %d: %w", lineNum, err)
+		}
+		fn(value)
+	}
+	return scanner.Err()
+}
+
+// extractInputFormat reads the first capture group of a regular expression
+// matched in each line, like "123.4" in "GET /api took 123.4ms" for
+// `took (\d+\.?\d*)ms`. Lines which do not match are skipped, so that
+// values can be read from raw application logs. unit is appended to the
+// captured text before parsing, so that numbers are converted from it as
+// durations.
+type extractInputFormat struct {
+	re   *regexp.Regexp
+	unit string
+}
+
+func (extractInputFormat) Name() string { return "plain" }
+
+func (f extractInputFormat) Scan(r io.Reader, parse ValueParser, fn func(v float64)) error {
+	scanner := bufio.NewScanner(r)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		match := f.re.FindStringSubmatch(scanner.Text())
+		if match == nil {
+			continue
+		}
+		value, err := parse(match[1] + f.unit)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", lineNum, err)
+		}
+		fn(value)
+	}
+	return scanner.Err()
+}
+
+// countedInputFormat reads pre-aggregated lines of a value and its count
+// separated by whitespace like
+//
+//	0.25 120
+//
+// which stands for 120 values of 0.25, as `sort | uniq -c` prints with the
+// columns swapped. Empty lines are skipped. Scan passes the value to fn
+// as many times as its count, while the pipeline reads it with the count
+// at once, so that statistics like the mean, the standard deviation and
+// quantiles weight it by its count without repeating it.
+type countedInputFormat struct{}
+
+func (countedInputFormat) Name() string { return "counted" }
+
+func (f countedInputFormat) Scan(r io.Reader, parse ValueParser, fn func(v float64)) error {
+	return f.scanCounts(r, parse, func(v float64, n int) {
+		for i := 0; i < n; i++ {
+			fn(v)
+		}
+	})
+}
+
+func (countedInputFormat) scanCounts(r io.Reader, parse ValueParser, fn func(v float64, n int)) error {
+	scanner := bufio.NewScanner(r)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 {
+			return fmt.Errorf("line %d must be a value and a count, got %q", lineNum, scanner.Text())
+		}
+		value, err := parse(fields[0])
+		if err != nil {
+			return fmt.Errorf("line %d: %w", lineNum, err)
+		}
+		count, err := strconv.Atoi(fields[1])
+		if err != nil || count < 0 {
+			return fmt.Errorf("line %d: count must be a non-negative integer, got %q", lineNum, fields[1])
+		}
+		fn(value, count)
+	}
+	return scanner.Err()
+}
+
+// csvInputFormat reads values in the first column of CSV records, or the
+// column numbered from 1 if column is set. A record with fewer columns is
+// an error unless skipMissing is set, in which case it is skipped.
+type csvInputFormat struct {
+	column      int
+	skipMissing bool
+}
+
+func (csvInputFormat) Name() string { return "csv" }
+
+func (f csvInputFormat) Scan(r io.Reader, parse ValueParser, fn func(v float64)) error {
+	column := MustMax(f.column, 1)
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if len(record) < column {
+			if f.skipMissing {
+				continue
+			}
+			line, _ := cr.FieldPos(0)
+			return fmt.Errorf("line %d has no column %d: %q", line, column, strings.Join(record, ","))
+		}
+		value, err := parse(strings.TrimSpace(record[column-1]))
+		if err != nil {
+			return err
+		}
+		fn(value)
+	}
+}
+
+// jsonlInputFormat reads a JSON number, or a JSON string of a value like
+// "1.5s", per line. Empty lines are skipped.
+//
+// With path, each line is a JSON value like a structured log record, and
+// the value at path is read instead. An array at path gives all of its
+// elements. A line without path is an error unless skipMissing is set, in
+// which case it is skipped.
+type jsonlInputFormat struct {
+	path        jsonPath
+	skipMissing bool
+}
+
+func (jsonlInputFormat) Name() string { return "jsonl" }
+
+func (f jsonlInputFormat) Scan(r io.Reader, parse ValueParser, fn func(v float64)) error {
+	if f.path != nil {
+		return f.scanPath(r, parse, fn)
+	}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var value float64
+		if line[0] == '"' {
+			var s string
+			if err := json.Unmarshal(line, &s); err != nil {
+				return fmt.Errorf("invalid JSON string line %q: %s", line, err)
+			}
+			v, err := parse(s)
+			if err != nil {
+				return err
+			}
+			value = v
+		} else if err := json.Unmarshal(line, &value); err != nil {
+			return fmt.Errorf("invalid JSON number line %q: %s", line, err)
+		}
+		fn(value)
+	}
+	return scanner.Err()
+}
+
+func (f jsonlInputFormat) scanPath(r io.Reader, parse ValueParser, fn func(v float64)) error {
+	scanner := bufio.NewScanner(r)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var record any
+		if err := json.Unmarshal(line, &record); err != nil {
+			return fmt.Errorf("line %d: invalid JSON: %s", lineNum, err)
+		}
+		v, ok := f.path.lookup(record)
+		if !ok {
+			if f.skipMissing {
+				continue
+			}
+			return fmt.Errorf("line %d has no value at the JSON path", lineNum)
+		}
+		items, isArray := v.([]any)
+		if !isArray {
+			items = []any{v}
+		}
+		for _, item := range items {
+			value, err := jsonItemValue(item, parse)
+			if err != nil {
+				return fmt.Errorf("line %d: %w", lineNum, err)
+			}
+			fn(value)
+		}
+	}
+	return scanner.Err()
+}
+
+// jsonItemValue converts a decoded JSON number, or a string parsed with
+// parse, to a value.
+func jsonItemValue(item any, parse ValueParser) (float64, error) {
+	switch v := item.(type) {
+	case float64:
+		return v, nil
+	case string:
+		return parse(v)
+	default:
+		return 0, fmt.Errorf("value must be a number or a string, got %v", item)
+	}
+}
+
+// prometheusInputFormat reads sample values in the Prometheus text
+// exposition format like
+//
+//	http_request_duration_seconds{code="200"} 0.25 1700000000000
+//
+// Comment and empty lines are skipped. Sample values are always floating
+// numbers, so parse is not used. NaN samples, like those of a summary
+// without observations, are dropped.
+type prometheusInputFormat struct{}
+
+func (prometheusInputFormat) Name() string { return "prometheus" }
+
+func (f prometheusInputFormat) Scan(r io.Reader, parse ValueParser, fn func(v float64)) error {
+	_, err := f.scanDropping(r, parse, fn)
+	return err
+}
+
+func (prometheusInputFormat) scanDropping(r io.Reader, parse ValueParser, fn func(v float64)) (int, error) {
+	dropped := 0
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		s, err := prometheusSampleValue(line)
+		if err != nil {
+			return dropped, err
+		}
+		if strings.EqualFold(s, "NaN") {
+			dropped++
+			continue
+		}
+		value, err := parseFiniteFloat(s)
+		if err != nil {
+			return dropped, err
+		}
+		fn(value)
+	}
+	return dropped, scanner.Err()
+}
+
+var errInvalidPrometheusSample = errors.New("invalid prometheus sample line")
+
+// prometheusSampleValue returns the value field of a sample line.
+func prometheusSampleValue(line string) (string, error) {
+	rest := line
+	if i := strings.LastIndexByte(rest, '}'); i != -1 {
+		rest = rest[i+1:]
+	} else if i := strings.IndexAny(rest, " \t"); i != -1 {
+		rest = rest[i:]
+	} else {
+		return "", fmt.Errorf("%w: %q", errInvalidPrometheusSample, line)
+	}
+	fields := strings.Fields(rest)
+	if len(fields) == 0 || len(fields) > 2 {
+		return "", fmt.Errorf("%w: %q", errInvalidPrometheusSample, line)
+	}
+	return fields[0], nil
+}
@@ -0,0 +1,362 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/exp/slices"
+)
+
+func TestInputFormats(t *testing.T) {
+	testCases := []struct {
+		format  string
+		input   string
+		want    []float64
+		wantErr bool
+	}{
+		{format: "plain", input: "1\n2.5\n-3\n", want: []float64{1, 2.5, -3}},
+		{format: "plain", input: "1\nfoo\n", wantErr: true},
+		{format: "csv", input: "1,a\n2.5,b,c\n\"3\"\n", want: []float64{1, 2.5, 3}},
+		{format: "csv", input: "value,name\n1,a\n", wantErr: true},
+		{format: "jsonl", input: "1\n\n2.5e1\n", want: []float64{1, 25}},
+		{format: "jsonl", input: "{\"a\":1}\n", wantErr: true},
+		{format: "prometheus", input: "# HELP x help\n# TYPE x gauge\nx 1\ny{a=\"b c\"} 2.5 1700000000000\n\nz{} -3\n", want: []float64{1, 2.5, -3}},
+		{format: "prometheus", input: "x\n", wantErr: true},
+		// NaN samples are dropped.
+		{format: "prometheus", input: "x NaN\ny{a=\"b\"} nan 1700000000000\nz 2\n", want: []float64{2}},
+		{format: "prometheus", input: "x +Inf\n", wantErr: true},
+		{format: "counted", input: "0.25 3\n\n1\t0\n-2  1\n", want: []float64{0.25, 0.25, 0.25, -2}},
+		{format: "counted", input: "1\n", wantErr: true},
+		{format: "counted", input: "1 2 3\n", wantErr: true},
+		{format: "counted", input: "1 -2\n", wantErr: true},
+		{format: "counted", input: "1 1.5\n", wantErr: true},
+	}
+	for _, tc := range testCases {
+		format, ok := LookupInputFormat(tc.format)
+		if !ok {
+			t.Fatalf("format %q must be registered", tc.format)
+		}
+		got, err := readFloat64Values(strings.NewReader(tc.input), format)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("error expected, format=%s, input=%q", tc.format, tc.input)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("unexpected error, format=%s, input=%q, err=%v", tc.format, tc.input, err)
+		} else if !slices.Equal(got, tc.want) {
+			t.Errorf("result mismatch, format=%s, input=%q, got=%v, want=%v", tc.format, tc.input, got, tc.want)
+		}
+	}
+}
+
+func TestInputFormatNames(t *testing.T) {
+	got := InputFormatNames()
+	if !slices.IsSorted(got) {
+		t.Errorf("names must be sorted, got=%v", got)
+	}
+	for _, name := range []string{"counted", "csv", "jsonl", "plain", "prometheus"} {
+		if !slices.Contains(got, name) {
+			t.Errorf("names must contain %q, got=%v", name, got)
+		}
+	}
+}
+
+func TestFieldInputFormat(t *testing.T) {
+	testCases := []struct {
+		format  fieldInputFormat
+		input   string
+		want    []float64
+		wantErr bool
+	}{
+		{format: fieldInputFormat{field: 4}, input: "2023-10-01 GET /api 0.123\n2023-10-01  POST\t/api  1.5\n", want: []float64{0.123, 1.5}},
+		{format: fieldInputFormat{field: 1}, input: "1 a\n2\n", want: []float64{1, 2}},
+		{format: fieldInputFormat{field: 2}, input: "a 1\nb\n", wantErr: true},
+		{format: fieldInputFormat{field: 2, skipMissing: true}, input: "a 1\nb\n\nc 2 x\n", want: []float64{1, 2}},
+		{format: fieldInputFormat{field: 2, skipMissing: true}, input: "a b\n", wantErr: true},
+	}
+	for _, tc := range testCases {
+		got, err := readFloat64Values(strings.NewReader(tc.input), tc.format)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("error expected, format=%+v, input=%q", tc.format, tc.input)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("unexpected error, format=%+v, input=%q, err=%v", tc.format, tc.input, err)
+		} else if !slices.Equal(got, tc.want) {
+			t.Errorf("result mismatch, format=%+v, input=%q, got=%v, want=%v", tc.format, tc.input, got, tc.want)
+		}
+	}
+}
+
+func TestCSVInputFormatColumn(t *testing.T) {
+	testCases := []struct {
+		format  csvInputFormat
+		input   string
+		want    []float64
+		wantErr bool
+	}{
+		{format: csvInputFormat{column: 2}, input: "a,1\nb, 2.5 ,x\n", want: []float64{1, 2.5}},
+		{format: csvInputFormat{column: 2}, input: "a,1\nb\n", wantErr: true},
+		{format: csvInputFormat{column: 2, skipMissing: true}, input: "a,1\nb\nc,2\n", want: []float64{1, 2}},
+	}
+	for _, tc := range testCases {
+		got, err := readFloat64Values(strings.NewReader(tc.input), tc.format)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("error expected, format=%+v, input=%q", tc.format, tc.input)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("unexpected error, format=%+v, input=%q, err=%v", tc.format, tc.input, err)
+		} else if !slices.Equal(got, tc.want) {
+			t.Errorf("result mismatch, format=%+v, input=%q, got=%v, want=%v", tc.format, tc.input, got, tc.want)
+		}
+	}
+}
+
+func TestColumnSources(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "metrics.csv")
+	if err := os.WriteFile(filename, []byte("cpu,latency,mem\n1,10,100\n2,20,200\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := ParseConfig([]string{"-i", "csv", "--has-header", "--columns", "2,3", "--label", "latency,mem", filename})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sources := columnSources(cfg)
+	if got, want := strings.Join(sourceNames(sources), ","), "latency,mem"; got != want {
+		t.Errorf("names mismatch, got=%s, want=%s", got, want)
+	}
+	for i, want := range [][]float64{{10, 20}, {100, 200}} {
+		got, err := readSourceValues(sources[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("values of %s mismatch, got=%v, want=%v", sources[i].Name(), got, want)
+		}
+	}
+
+	cfg.Labels = nil
+	if got, want := strings.Join(sourceNames(columnSources(cfg)), ","), "column 2,column 3"; got != want {
+		t.Errorf("default names mismatch, got=%s, want=%s", got, want)
+	}
+}
+
+func TestJSONLInputFormatPath(t *testing.T) {
+	input := `{"response": {"duration_ms": 12.5}}
+{"response": {"duration_ms": "3"}}
+
+{"response": {}}
+{"response": {"duration_ms": [1, 2]}}
+`
+	testCases := []struct {
+		format  jsonlInputFormat
+		input   string
+		want    []float64
+		wantErr bool
+	}{
+		{format: jsonlInputFormat{path: mustParseJSONPath("response.duration_ms"), skipMissing: true}, input: input, want: []float64{12.5, 3, 1, 2}},
+		{format: jsonlInputFormat{path: mustParseJSONPath("response.duration_ms")}, input: input, wantErr: true},
+		{format: jsonlInputFormat{path: mustParseJSONPath("a")}, input: `{"a": true}`, wantErr: true},
+		{format: jsonlInputFormat{path: mustParseJSONPath("a")}, input: `{"a": 1`, wantErr: true},
+		{format: jsonlInputFormat{path: mustParseJSONPath("[1]")}, input: "[1, 2]\n[3, 4]\n", want: []float64{2, 4}},
+	}
+	for _, tc := range testCases {
+		got, err := readFloat64Values(strings.NewReader(tc.input), tc.format)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("error expected, input=%q", tc.input)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("unexpected error, input=%q, err=%v", tc.input, err)
+		} else if !slices.Equal(got, tc.want) {
+			t.Errorf("result mismatch, input=%q, got=%v, want=%v", tc.input, got, tc.want)
+		}
+	}
+}
+
+func TestExtractInputFormat(t *testing.T) {
+	testCases := []struct {
+		format  extractInputFormat
+		parse   ValueParser
+		input   string
+		want    []float64
+		wantErr bool
+	}{
+		{
+			format: extractInputFormat{re: regexp.MustCompile(`took (\d+\.?\d*)ms`)},
+			parse:  parseFiniteFloat,
+			input:  "GET /a took 12.5ms\nstarting\nGET /b took 300ms, ok\n",
+			want:   []float64{12.5, 300},
+		},
+		{
+			format: extractInputFormat{re: regexp.MustCompile(`took (\d+)`), unit: "us"},
+			parse:  func(s string) (float64, error) { return parseDurationValue(s, time.Millisecond) },
+			input:  "took 1500 microseconds\n",
+			want:   []float64{1.5},
+		},
+		{
+			format:  extractInputFormat{re: regexp.MustCompile(`took (\S+)`)},
+			parse:   parseFiniteFloat,
+			input:   "took 1\ntook fast\n",
+			wantErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		var got []float64
+		err := tc.format.Scan(strings.NewReader(tc.input), tc.parse, func(v float64) {
+			got = append(got, v)
+		})
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("error expected, input=%q", tc.input)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("unexpected error, input=%q, err=%v", tc.input, err)
+		} else if !slices.Equal(got, tc.want) {
+			t.Errorf("result mismatch, input=%q, got=%v, want=%v", tc.input, got, tc.want)
+		}
+	}
+}
+
+func TestScanSkippingInvalidLines(t *testing.T) {
+	testCases := []struct {
+		format      InputFormat
+		input       string
+		maxErrors   int
+		want        []float64
+		wantSkipped int
+		wantErr     bool
+	}{
+		{format: plainInputFormat{}, input: "1\nx\n2\ny\n3\n", want: []float64{1, 2, 3}, wantSkipped: 2},
+		{format: plainInputFormat{}, input: "1\nx\n2\ny\n3\n", maxErrors: 2, want: []float64{1, 2, 3}, wantSkipped: 2},
+		{format: plainInputFormat{}, input: "1\nx\n2\ny\n3\n", maxErrors: 1, wantErr: true},
+		// Values of a line are added only if the whole line is valid.
+		{format: countedInputFormat{}, input: "1 2\n3 x\n", want: []float64{1, 1}, wantSkipped: 1},
+	}
+	for _, tc := range testCases {
+		var got []float64
+		skipped, _, err := scanSkippingInvalidLines(strings.NewReader(tc.input), tc.format, parseFiniteFloat, tc.maxErrors, repeatCounts(func(v float64) {
+			got = append(got, v)
+		}))
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("error expected, input=%q, maxErrors=%d", tc.input, tc.maxErrors)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("unexpected error, input=%q, err=%v", tc.input, err)
+			continue
+		}
+		if !slices.Equal(got, tc.want) {
+			t.Errorf("result mismatch, input=%q, got=%v, want=%v", tc.input, got, tc.want)
+		}
+		if len(skipped) != tc.wantSkipped {
+			t.Errorf("skipped count mismatch, input=%q, got=%v, want=%d", tc.input, skipped, tc.wantSkipped)
+		}
+	}
+}
+
+func TestFileSourceSkipInvalid(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "values.txt")
+	if err := os.WriteFile(filename, []byte("1\nx\n2\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var warnings bytes.Buffer
+	cfg := Config{
+		InputFormat: plainInputFormat{},
+		ValueParser: parseFiniteFloat,
+		SkipInvalid: true,
+		Warner:      &textWarner{logger: newLogger(&warnings, slog.LevelInfo)},
+	}
+	values, err := readSourceValues(newFileSource(cfg, filename))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []float64{1, 2}; !slices.Equal(values, want) {
+		t.Errorf("values mismatch, got=%v, want=%v", values, want)
+	}
+	want := fmt.Sprintf("level=WARN msg=\"skipped 1 invalid lines, the first one: line 2: strconv.ParseFloat: parsing \\\"x\\\": invalid syntax\" source=%s\n", filename)
+	if got := warnings.String(); got != want {
+		t.Errorf("warnings mismatch,\n got=%q,\nwant=%q", got, want)
+	}
+}
+
+func TestFileSourcePrometheusNaN(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "metrics.txt")
+	if err := os.WriteFile(filename, []byte("# TYPE x summary\nx{quantile=\"0.5\"} NaN\nx{quantile=\"0.9\"} NaN\nx_sum 3\nx_count 0\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, skipInvalid := range []bool{false, true} {
+		var warnings bytes.Buffer
+		warner, err := newWarner(warningsJSON, &warnings, discardLogger)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg := Config{
+			InputFormat: prometheusInputFormat{},
+			ValueParser: parseFiniteFloat,
+			SkipInvalid: skipInvalid,
+			Warner:      warner,
+		}
+		values, err := readSourceValues(newFileSource(cfg, filename))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := []float64{3, 0}; !slices.Equal(values, want) {
+			t.Errorf("values mismatch, skipInvalid=%v, got=%v, want=%v", skipInvalid, values, want)
+		}
+		want := fmt.Sprintf(`{"kind":"samples_dropped","message":"dropped 2 samples without a value like NaN","source":%q,"details":{"count":2}}`+"\n", filename)
+		if got := warnings.String(); got != want {
+			t.Errorf("warnings mismatch, skipInvalid=%v,\n got=%q,\nwant=%q", skipInvalid, got, want)
+		}
+	}
+}
+
+func TestSkipLines(t *testing.T) {
+	longLine := strings.Repeat("x", 10000)
+	testCases := []struct {
+		input string
+		n     int
+		want  string
+	}{
+		{input: "a\nb\nc\n", n: 0, want: "a\nb\nc\n"},
+		{input: "a\nb\nc\n", n: 2, want: "c\n"},
+		{input: "a\nb", n: 5, want: ""},
+		{input: longLine + "\n1\n", n: 1, want: "1\n"},
+	}
+	for _, tc := range testCases {
+		r := bufio.NewReaderSize(strings.NewReader(tc.input), 16)
+		if err := skipLines(r, tc.n); err != nil {
+			t.Errorf("unexpected error, n=%d, err=%v", tc.n, err)
+			continue
+		}
+		rest, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := string(rest); got != tc.want {
+			t.Errorf("result mismatch, n=%d, got=%q, want=%q", tc.n, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// jsonPathElem is an object key or an array index in a jsonPath.
+type jsonPathElem struct {
+	key     string
+	index   int
+	isIndex bool
+}
+
+// jsonPath selects a value in a JSON value decoded into any, like
+// "response.duration_ms" or "spans[0].timings".
+type jsonPath []jsonPathElem
+
+// parseJSONPath parses keys separated by dots, each of which may be
+// followed by array indexes like "[0]". A path may also start with an
+// index like "[1].ms".
+func parseJSONPath(s string) (jsonPath, error) {
+	if s == "" {
+		return nil, fmt.Errorf("empty JSON path")
+	}
+	var path jsonPath
+	for i, segment := range strings.Split(s, ".") {
+		key, rest, hasIndex := strings.Cut(segment, "[")
+		if key == "" && (i > 0 || !hasIndex) {
+			return nil, fmt.Errorf("empty key in JSON path %q", s)
+		}
+		if key != "" {
+			path = append(path, jsonPathElem{key: key})
+		}
+		if !hasIndex {
+			continue
+		}
+		// Indexes like "0]" or "0][1]" after the first "[".
+		for _, index := range strings.Split(rest, "[") {
+			n, err := strconv.Atoi(strings.TrimSuffix(index, "]"))
+			if !strings.HasSuffix(index, "]") || err != nil || n < 0 {
+				return nil, fmt.Errorf("invalid index in JSON path %q", s)
+			}
+			path = append(path, jsonPathElem{index: n, isIndex: true})
+		}
+	}
+	return path, nil
+}
+
+// lookup returns the value at p in v and whether it exists.
+func (p jsonPath) lookup(v any) (any, bool) {
+	for _, elem := range p {
+		if elem.isIndex {
+			a, ok := v.([]any)
+			if !ok || elem.index >= len(a) {
+				return nil, false
+			}
+			v = a[elem.index]
+			continue
+		}
+		o, ok := v.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if v, ok = o[elem.key]; !ok {
+			return nil, false
+		}
+	}
+	return v, true
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestParseJSONPath(t *testing.T) {
+	testCases := []struct {
+		input   string
+		want    jsonPath
+		wantErr bool
+	}{
+		{input: "a", want: jsonPath{{key: "a"}}},
+		{input: "response.duration_ms", want: jsonPath{{key: "response"}, {key: "duration_ms"}}},
+		{input: "a[0][1].b", want: jsonPath{{key: "a"}, {index: 0, isIndex: true}, {index: 1, isIndex: true}, {key: "b"}}},
+		{input: "[1].ms", want: jsonPath{{index: 1, isIndex: true}, {key: "ms"}}},
+		{input: "", wantErr: true},
+		{input: "a..b", wantErr: true},
+		{input: ".a", wantErr: true},
+		{input: "a.", wantErr: true},
+		{input: "a.[0]", wantErr: true},
+		{input: "a[", wantErr: true},
+		{input: "a[x]", wantErr: true},
+		{input: "a[-1]", wantErr: true},
+		{input: "a[0]b", wantErr: true},
+	}
+	for _, tc := range testCases {
+		got, err := parseJSONPath(tc.input)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("must return an error, input=%q, got=%v", tc.input, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("unexpected error, input=%q, err=%v", tc.input, err)
+		} else if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("result mismatch, input=%q, got=%v, want=%v", tc.input, got, tc.want)
+		}
+	}
+}
+
+func TestJSONPathLookup(t *testing.T) {
+	var record any
+	if err := json.Unmarshal([]byte(`{"a": {"b": [1, {"c": 2}]}, "d": null}`), &record); err != nil {
+		t.Fatal(err)
+	}
+	testCases := []struct {
+		path   string
+		want   any
+		wantOK bool
+	}{
+		{path: "a.b[0]", want: 1.0, wantOK: true},
+		{path: "a.b[1].c", want: 2.0, wantOK: true},
+		{path: "d", want: nil, wantOK: true},
+		{path: "a.b[2]", wantOK: false},
+		{path: "a.x", wantOK: false},
+		{path: "a[0]", wantOK: false},
+		{path: "a.b.c", wantOK: false},
+	}
+	for _, tc := range testCases {
+		got, ok := mustParseJSONPath(tc.path).lookup(record)
+		if ok != tc.wantOK || !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("result mismatch, path=%s, got=%v, %v, want=%v, %v", tc.path, got, ok, tc.want, tc.wantOK)
+		}
+	}
+}
+
+func mustParseJSONPath(s string) jsonPath {
+	path, err := parseJSONPath(s)
+	if err != nil {
+		panic(err)
+	}
+	return path
+}
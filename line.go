@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// lineSeriesMarkers are the characters of points of each histogram in
+// lineOutputFormat.
+var lineSeriesMarkers = []string{"*", "o", "+", "x", "#", "@", "%"}
+
+// lineConnector is the character of segments connecting points.
+const lineConnector = "."
+
+// lineOutputFormat renders histograms as frequency polygons, which connect
+// the counts at the middles of buckets with lines. The histograms share the
+// axes, so several smooth distributions are easier to compare than with
+// bars.
+type lineOutputFormat struct{}
+
+func (lineOutputFormat) Name() string { return "line" }
+
+func (lineOutputFormat) Render(w io.Writer, m *RenderModel) error {
+	_, err := io.WriteString(w, strings.Join(lineChartStrings(m), "\n")+"\n")
+	return err
+}
+
+// lineChartCell is a character in the plot area of a line chart.
+type lineChartCell struct {
+	s      string
+	series int
+}
+
+func lineChartStrings(m *RenderModel) []string {
+	rangePoints := m.Histograms[0].rangePoints
+	ticks := FormatTicks(rangePoints, m.TickStyle, m.PointFmt)
+	columnWidth := stringSliceMaxWidth(ticks) + len(" ")
+	bucketCount := len(rangePoints) - 1
+	height := m.GraphHeight
+	if height < graphMinHeight {
+		height = defaultGraphHeight
+	}
+
+	maxCount := 0
+	for _, h := range m.Histograms {
+		maxCount = MustMax(maxCount, h.MaxCount())
+	}
+	maxCountStr := strconv.Itoa(maxCount)
+	axisWidth := len(maxCountStr)
+
+	grid := make([][]lineChartCell, height)
+	for row := range grid {
+		grid[row] = make([]lineChartCell, columnWidth*bucketCount)
+	}
+	put := func(x, y int, s string, series int) {
+		grid[height-1-y][x] = lineChartCell{s: s, series: series}
+	}
+	rowOf := func(count int) int {
+		if maxCount == 0 {
+			return 0
+		}
+		return int(math.Round(float64(count) / float64(maxCount) * float64(height-1)))
+	}
+
+	for i, h := range m.Histograms {
+		// Draw segments first so that points are drawn over them.
+		for j := 0; j+1 < len(h.counts); j++ {
+			x0, y0 := j*columnWidth+columnWidth/2, rowOf(h.counts[j])
+			x1, y1 := x0+columnWidth, rowOf(h.counts[j+1])
+			prevY := y0
+			for x := x0 + 1; x <= x1; x++ {
+				y := int(math.Round(float64(y0) + float64(y1-y0)*float64(x-x0)/float64(x1-x0)))
+				// Fill vertical gaps so that steep segments stay connected.
+				for yy := MustMin(prevY, y); yy <= MustMax(prevY, y); yy++ {
+					put(x, yy, lineConnector, i)
+				}
+				prevY = y
+			}
+		}
+		marker := lineSeriesMarkers[i%len(lineSeriesMarkers)]
+		for j, count := range h.counts {
+			put(j*columnWidth+columnWidth/2, rowOf(count), marker, i)
+		}
+	}
+
+	var lines []string
+	for row, cells := range grid {
+		label := ""
+		if row == 0 {
+			label = maxCountStr
+		}
+		var b strings.Builder
+		for _, cell := range cells {
+			if cell.s == "" {
+				b.WriteString(" ")
+				continue
+			}
+			color := ""
+			if m.Color {
+				color = m.seriesColor(cell.series)
+			}
+			b.WriteString(colorize(cell.s, color))
+		}
+		lines = append(lines, strings.TrimRight(fmt.Sprintf("%*s |%s", axisWidth, label, b.String()), " "))
+	}
+	lines = append(lines, fmt.Sprintf("%*s +%s", axisWidth, "0", strings.Repeat("-", columnWidth*bucketCount)))
+
+	var b strings.Builder
+	for i, tick := range ticks {
+		if i < len(ticks)-1 {
+			b.WriteString(padEndSpace(columnWidth, tick))
+		} else {
+			b.WriteString(tick)
+		}
+	}
+	lines = append(lines, fmt.Sprintf("%*s  %s", axisWidth, "", b.String()))
+
+	for i, h := range m.Histograms {
+		marker := lineSeriesMarkers[i%len(lineSeriesMarkers)]
+		if m.Color {
+			marker = colorize(marker, m.seriesColor(i))
+		}
+		lines = append(lines, fmt.Sprintf("%s %s (out of range: %d)", marker, m.Labels[i], h.outOfRangeCount))
+	}
+	return lines
+}
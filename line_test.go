@@ -0,0 +1,39 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestLineOutputFormat(t *testing.T) {
+	rangePoints := BuildRangePoints[float64](3, 0, 3)
+	a := NewHistogram(rangePoints)
+	a.AddValues([]float64{0.5, 1.5, 1.5, 1.7, 2.5, 2.5, 4})
+	b := NewHistogram(rangePoints)
+	b.AddValues([]float64{0.5, 0.5, 0.5, 2.5})
+	m := &RenderModel{
+		Histograms:  []*Histogram[float64]{a, b},
+		Labels:      []string{"a.txt", "b.txt"},
+		PointFmt:    "%.1f",
+		GraphHeight: 4,
+	}
+	var buf bytes.Buffer
+	if err := (lineOutputFormat{}).Render(&buf, m); err != nil {
+		t.Fatal(err)
+	}
+	got := buf.String()
+	want := `3 |  o. .*...
+  |   ...   .*
+  |  *. .. ..o
+  |      o..
+0 +------------
+   0.0 1.0 2.0 3.0
+* a.txt (out of range: 1)
+o b.txt (out of range: 0)
+`
+	if got != want {
+		t.Errorf("result mismatch,\n got=%q,\nwant=%q", got, want)
+		fmt.Printf("\n%s", got)
+	}
+}
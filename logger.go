@@ -0,0 +1,61 @@
+package main
+
+import (
+	"io"
+	"log/slog"
+)
+
+// levelTrace is the level of the most detailed messages, shown with -vv.
+const levelTrace = slog.LevelDebug - 4
+
+// logLevel returns the level of messages shown for the -q and -v flags.
+// Errors are always shown, and -q hides everything else including
+// warnings. By default informational messages like the address of a
+// server are shown, -v adds debug messages like the inputs read and -vv
+// adds traces like the counts of buckets.
+func logLevel(quiet bool, verbosity int) slog.Level {
+	switch {
+	case quiet:
+		return slog.LevelError
+	case verbosity >= 2:
+		return levelTrace
+	case verbosity == 1:
+		return slog.LevelDebug
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// newLogger returns a logger writing messages at level or above to w, one
+// per line like
+//
+//	level=INFO msg="listening on" addr=:8080
+//
+// Times are left out since the messages are for people watching a
+// command.
+func newLogger(w io.Writer, level slog.Level) *slog.Logger {
+	return slog.New(slog.NewTextHandler(w, &slog.HandlerOptions{
+		Level: level,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if len(groups) == 0 && a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			if len(groups) == 0 && a.Key == slog.LevelKey && a.Value.Any() == levelTrace {
+				return slog.String(slog.LevelKey, "TRACE")
+			}
+			return a
+		},
+	}))
+}
+
+// discardLogger is used when Config.Logger is nil, like in tests.
+var discardLogger = slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{Level: slog.LevelError + 1}))
+
+// logger returns cfg.Logger, or a logger discarding messages if it is
+// nil.
+func (cfg Config) logger() *slog.Logger {
+	if cfg.Logger != nil {
+		return cfg.Logger
+	}
+	return discardLogger
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestLogLevel(t *testing.T) {
+	testCases := []struct {
+		quiet     bool
+		verbosity int
+		want      slog.Level
+	}{
+		{quiet: false, verbosity: 0, want: slog.LevelInfo},
+		{quiet: false, verbosity: 1, want: slog.LevelDebug},
+		{quiet: false, verbosity: 2, want: levelTrace},
+		{quiet: false, verbosity: 3, want: levelTrace},
+		{quiet: true, verbosity: 0, want: slog.LevelError},
+	}
+	for _, c := range testCases {
+		if got := logLevel(c.quiet, c.verbosity); got != c.want {
+			t.Errorf("result mismatch, quiet=%v, verbosity=%d, got=%v, want=%v", c.quiet, c.verbosity, got, c.want)
+		}
+	}
+}
+
+func TestNewLogger(t *testing.T) {
+	var sb strings.Builder
+	logger := newLogger(&sb, slog.LevelDebug)
+	logger.Info("listening", "addr", ":8080")
+	logger.Debug("read input", "values", 3)
+	logger.Log(context.Background(), levelTrace, "counted buckets")
+	want := "level=INFO msg=listening addr=:8080\n" +
+		"level=DEBUG msg=\"read input\" values=3\n"
+	if got := sb.String(); got != want {
+		t.Errorf("result mismatch, got=%q, want=%q", got, want)
+	}
+
+	sb.Reset()
+	newLogger(&sb, levelTrace).Log(context.Background(), levelTrace, "counted buckets")
+	if got, want := sb.String(), "level=TRACE msg=\"counted buckets\"\n"; got != want {
+		t.Errorf("result mismatch, got=%q, want=%q", got, want)
+	}
+}
@@ -2,16 +2,21 @@ package main
 
 import (
 	"bufio"
+	"context"
+	"errors"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
+	"math"
 	"os"
+	"os/signal"
 	"runtime/debug"
 	"sort"
 	"strconv"
 	"strings"
+	"syscall"
+	"time"
 
-	"github.com/urfave/cli/v2"
 	"golang.org/x/exp/constraints"
 	"golang.org/x/exp/slices"
 )
@@ -19,71 +24,91 @@ import (
 const axisAuto = "auto"
 const stdinFilename = "-"
 
+const backendHistogram = "histogram"
+const backendHDR = "hdr"
+const backendTDigest = "tdigest"
+
+const scaleLinear = "linear"
+const scaleLog = "log"
+
+// logScalePointFmt is used for axis points on log scale unless the user
+// gives --point-format explicitly, since fixed decimals do not fit values
+// spanning several orders of magnitude.
+const logScalePointFmt = "%.3g"
+
 func main() {
-	app := &cli.App{
-		Name:      "histogram",
-		Version:   Version(),
-		Usage:     "Read numbers from file(s) and show histogram(s) on terminal",
-		UsageText: fmt.Sprintf("histogram [GLOBAL OPTIONS] filename1 [filename2]\n\n   (You can use %q as filename for stdin.)", stdinFilename),
-		Flags: []cli.Flag{
-			&cli.StringFlag{
-				Name:    "axis-min",
-				Aliases: []string{"n"},
-				Value:   axisAuto,
-				Usage:   "axis minimum value",
-			},
-			&cli.StringFlag{
-				Name:    "axis-max",
-				Aliases: []string{"x"},
-				Value:   axisAuto,
-				Usage:   "axis maximum value",
-			},
-			&cli.IntFlag{
-				Name:    "bucket-count",
-				Aliases: []string{"c"},
-				Value:   10,
-				Usage:   "histogram bucket count",
-			},
-			&cli.IntFlag{
-				Name:    "graph-width",
-				Aliases: []string{"w"},
-				Value:   80,
-				Usage:   "graph column width including labels",
-			},
-			&cli.StringFlag{
-				Name:    "point-format",
-				Aliases: []string{"f"},
-				Value:   "%.2f",
-				Usage:   "format string for axis point value",
-			},
-		},
-	}
-	app.Action = func(cCtx *cli.Context) error {
-		if cCtx.NArg() != 1 && cCtx.NArg() != 2 {
-			fmt.Fprintf(app.ErrWriter, "One or two filename arguments needed.\nYou can use %q as filename for stdin.\n\n", stdinFilename)
-			cli.ShowAppHelpAndExit(cCtx, 2)
-		}
-
-		axisMin, err := parseAxisRangeEnd(cCtx.String("axis-min"))
-		if err != nil {
-			return fmt.Errorf(`axis min value must be a floating number or "%s"`, axisAuto)
+	cfg, err := ParseConfig(os.Args[1:])
+	if errors.Is(err, ErrHelpShown) {
+		return
+	}
+	if err != nil {
+		fatal(newLogger(os.Stderr, slog.LevelInfo), err)
+	}
+	switch {
+	case cfg.ListOutputFormats:
+		for _, name := range OutputFormatNames() {
+			fmt.Println(name)
 		}
-		axisMax, err := parseAxisRangeEnd(cCtx.String("axis-max"))
-		if err != nil {
-			return fmt.Errorf(`axis max value must be a floating number or "%s"`, axisAuto)
+		return
+	case cfg.ListInputFormats:
+		for _, name := range InputFormatNames() {
+			fmt.Println(name)
+		}
+		return
+	}
+
+	if cfg.SaveRecipe != "" {
+		if err := writeRecipeFile(cfg.SaveRecipe, cfg.Recipe); err != nil {
+			fatal(cfg.logger(), err)
 		}
+	}
 
-		bucketCount := cCtx.Int("bucket-count")
-		graphWidth := cCtx.Int("graph-width")
-		pointFmt := cCtx.String("point-format")
-		args := cCtx.Args().Slice()
-		return run(bucketCount, axisMin, axisMax, graphWidth, pointFmt, args)
+	stopProfiling, err := startProfiling(cfg.CPUProfile, cfg.MemProfile, cfg.Trace)
+	if err != nil {
+		fatal(cfg.logger(), err)
+	}
+	// The first signal stops reading inputs and servers cleanly, and
+	// another one kills the process as usual.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	context.AfterFunc(ctx, stop)
+
+	switch {
+	case cfg.Statsd != "":
+		err = runStatsd(ctx, cfg)
+	case cfg.Serve != "":
+		err = serve(ctx, cfg.Serve, cfg, newSeriesStore(cfg))
+	case cfg.ReportFile != "":
+		err = writeStdout(func(w io.Writer) error { return runReport(ctx, w, cfg) })
+	default:
+		err = writeStdout(func(w io.Writer) error { return run(ctx, w, cfg) })
 	}
-	if err := app.Run(os.Args); err != nil {
-		log.Fatal(err)
+	if stopErr := stopProfiling(); err == nil {
+		err = stopErr
+	}
+	if err != nil {
+		fatal(cfg.logger(), err)
 	}
 }
 
+// fatal logs err and exits with status 1. Errors are logged even with -q,
+// and nothing is written to stdout.
+func fatal(logger *slog.Logger, err error) {
+	logger.Error(err.Error())
+	os.Exit(1)
+}
+
+// writeStdout calls write with a buffered writer of stdout, so that output
+// written in many small pieces like lines takes few system calls.
+func writeStdout(write func(w io.Writer) error) error {
+	bw := bufio.NewWriter(os.Stdout)
+	err := write(bw)
+	if flushErr := bw.Flush(); err == nil {
+		err = flushErr
+	}
+	return err
+}
+
 func Version() string {
 	info, ok := debug.ReadBuildInfo()
 	if !ok {
@@ -92,171 +117,606 @@ func Version() string {
 	return info.Main.Version
 }
 
+// axisRangeEnd is an end of the axis range, which is a fixed Value or
+// decided from data if Auto is true. With Percentile, an auto end is
+// decided from the percentile of values instead of the minimum or the
+// maximum, so that a few outliers do not stretch the axis range.
 type axisRangeEnd struct {
-	Auto  bool
-	Value float64
+	Auto       bool
+	Value      float64
+	Percentile float64
 }
 
+// parseAxisRangeEnd parses s, which is "auto", a number or a percentile
+// like "p99".
 func parseAxisRangeEnd(s string) (axisRangeEnd, error) {
 	if s == axisAuto {
 		return axisRangeEnd{Auto: true}, nil
 	}
+	if p, ok := strings.CutPrefix(s, "p"); ok {
+		v, err := strconv.ParseFloat(p, 64)
+		if err != nil || !(v > 0 && v < 100) {
+			return axisRangeEnd{}, fmt.Errorf("invalid percentile %q", s)
+		}
+		return axisRangeEnd{Auto: true, Percentile: v}, nil
+	}
 	v, err := strconv.ParseFloat(s, 64)
 	if err != nil {
 		return axisRangeEnd{}, err
 	}
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return axisRangeEnd{}, errNotFinite
+	}
 	return axisRangeEnd{Value: v}, nil
 }
 
-func run(bucketCount int, axisMin, axisMax axisRangeEnd, graphWidth int, pointFmt string, filenames []string) error {
-	fileCount := len(filenames)
-	valuesList := make([][]float64, fileCount)
-	for i, filename := range filenames {
-		values, err := readFloat64ValuesFile(filenames[i])
-		if err != nil {
+// parseBucketCount parses s as a fixed bucket count or a method to choose
+// it from data. The returned method is empty for a fixed bucket count.
+func parseBucketCount(s string) (int, BucketCountMethod, error) {
+	if count, err := strconv.Atoi(s); err == nil {
+		if count <= 0 {
+			return 0, "", errors.New("bucket count must be positive")
+		}
+		return count, "", nil
+	}
+	method, err := parseBucketCountMethod(s)
+	if err != nil {
+		return 0, "", err
+	}
+	return 0, method, nil
+}
+
+// run builds histograms for cfg and writes them to w, or to files in
+// cfg.OutputDir if it is set. If the inputs are state files saved with
+// --save, their histograms are rendered instead of reading values.
+func run(ctx context.Context, w io.Writer, cfg Config) error {
+	p := newPipeline(cfg)
+	states, err := stateInputFilenames(cfg.Filenames)
+	if err != nil {
+		return err
+	}
+	if cfg.GroupBy != nil && !states {
+		if p.Sources, err = groupSources(ctx, cfg); err != nil {
+			return err
+		}
+	}
+	var m *RenderModel
+	if states {
+		m, err = binStates(p, cfg.Filenames)
+	} else {
+		m, err = p.BinContext(ctx)
+	}
+	if err != nil {
+		return err
+	}
+	if _, factor := samplers(cfg); cfg.SampleScale && !states {
+		for _, h := range m.Histograms {
+			scaleCounts(h, factor)
+		}
+	}
+
+	if cfg.Save != "" {
+		if err := writeStateFile(cfg.Save, m); err != nil {
 			return err
 		}
-		if len(values) == 0 {
-			return fmt.Errorf("no value in %s", filenameForErrorMessage(filename))
+	}
+	if cfg.OutputDir == "" {
+		return p.Renderer.Render(w, m)
+	}
+	return writeOutputDir(cfg.OutputDir, p.Renderer, m)
+}
+
+// buildRangePoints builds range points for the axis range with the scale
+// and the bucket count in cfg. The bucket count is reduced with a warning
+// if buckets would be too narrow to be told apart. With cfg.NiceEdges,
+// edges are snapped to nice numbers, which may extend the axis range and
+// change the bucket count slightly.
+func buildRangePoints(cfg Config, axisMin, axisMax float64) ([]float64, error) {
+	if cfg.Scale == scaleLog {
+		if axisMin <= 0 {
+			return nil, errors.New("axis min value must be positive for log scale")
+		}
+		// The data or a fixed end can be on the wrong side of the other
+		// end, like --axis-max 0.5 with values above it.
+		if axisMax <= axisMin {
+			return nil, fmt.Errorf("axis max value must be greater than axis min value for log scale, got %g and %g", axisMin, axisMax)
+		}
+		if cfg.NiceEdges {
+			return BuildNiceLogRangePoints(cfg.BucketCount, axisMin, axisMax)
+		}
+		return BuildLogRangePoints(cfg.BucketCount, axisMin, axisMax)
+	}
+
+	if cfg.BinWidth > 0 {
+		return BuildWidthRangePoints(cfg.BinWidth, axisMin, axisMax)
+	}
+
+	bucketCount := cfg.BucketCount
+	if axisMin < axisMax {
+		maxCount, err := maxBucketCountForWidth(axisMin, axisMax)
+		if err != nil {
+			return nil, err
+		}
+		if maxCount < bucketCount {
+			cfg.warn(Warning{
+				Kind:    warningBucketCountReduced,
+				Message: fmt.Sprintf("reduced bucket count from %d to %d, since narrower buckets cannot be told apart", bucketCount, maxCount),
+				Details: map[string]any{"from": bucketCount, "to": maxCount, "reason": "width"},
+			})
+			bucketCount = maxCount
 		}
+		if cfg.NiceEdges {
+			return BuildNiceRangePoints(bucketCount, axisMin, axisMax), nil
+		}
+	}
+	return BuildRangePoints(bucketCount, axisMin, axisMax), nil
+}
 
-		valuesList[i] = values
+// decideAxisRange returns the axis range for the data range from dataMin to
+// dataMax. Auto ends are rounded outward to two significant digits whose
+// second digit is a multiple of two or five.
+//
+// If includeZero is true, auto ends are extended to include zero. When both
+// ends are auto and the range straddles zero, the range is also widened so
+// that zero falls on a bucket boundary.
+func decideAxisRange(axisMin, axisMax axisRangeEnd, dataMin, dataMax float64, bucketCount int, includeZero bool) (float64, float64, error) {
+	if includeZero {
+		dataMin = math.Min(dataMin, 0)
+		dataMax = math.Max(dataMax, 0)
 	}
 
+	min := axisMin.Value
 	if axisMin.Auto {
-		minList := make([]float64, fileCount)
-		for i, values := range valuesList {
-			minList[i] = Min(values...)
+		v, err := floorSecondSignificantDigitToMultiplesOfTwoOrFive(dataMin)
+		if err != nil {
+			return 0, 0, fmt.Errorf("cannot decide axis min value: %s", err)
 		}
-		min := Min(minList...)
-		axisMin.Value = floorSecondSignificantDigitToMultiplesOfTwoOrFive(min)
+		min = v
 	}
+	max := axisMax.Value
 	if axisMax.Auto {
-		maxList := make([]float64, fileCount)
-		for i, values := range valuesList {
-			maxList[i] = Max(values...)
+		v, err := ceilSecondSignificantDigitToMultiplesOfTwoOrFive(dataMax)
+		if err != nil {
+			return 0, 0, fmt.Errorf("cannot decide axis max value: %s", err)
 		}
-		max := Max(maxList...)
-		axisMax.Value = ceilSecondSignificantDigitToMultiplesOfTwoOrFive(max)
+		max = v
 	}
 
-	rangePoints := BuildRangePoints(bucketCount, axisMin.Value, axisMax.Value)
-	histograms := make([]*Histogram[float64], fileCount)
-	for i, values := range valuesList {
-		histogram := NewHistogram(rangePoints)
-		histogram.AddValues(values)
-		histograms[i] = histogram
+	if min == max {
+		// Buckets of the zero width cannot hold the values.
+		lo, hi := ConstantDataRange(min)
+		if axisMin.Auto {
+			min = lo
+		}
+		if axisMax.Auto {
+			max = hi
+		}
 	}
 
-	formatter := NewMultipleHistogramFormatter(histograms, defaultBarChar, graphWidth, pointFmt)
-	fmt.Print(formatter)
+	// A fixed end can be on the wrong side of the values, like --axis-max
+	// 0.5 with values above it, which ParseConfig cannot check.
+	switch {
+	case min < max:
+	case axisMin.Auto:
+		return 0, 0, fmt.Errorf("--axis-max %g must be greater than the axis min value %g decided from values", max, min)
+	default:
+		return 0, 0, fmt.Errorf("--axis-min %g must be less than the axis max value %g decided from values", min, max)
+	}
 
-	return nil
+	if includeZero && axisMin.Auto && axisMax.Auto && min < 0 && 0 < max {
+		return alignZeroToBucketBoundary(min, max, bucketCount)
+	}
+	return min, max, nil
 }
 
-func filenameForErrorMessage(filename string) string {
-	if filename == stdinFilename {
-		return "stdin"
+// dataRange returns the minimum and the maximum of values of all lists, or
+// their percentiles for ends of the axis range with Percentile. Some lists
+// may be empty, but not all of them.
+func dataRange(axisMin, axisMax axisRangeEnd, lists []weightedValues) (float64, float64) {
+	if axisMin.Percentile == 0 && axisMax.Percentile == 0 {
+		var minList, maxList []float64
+		for _, w := range lists {
+			if len(w.values) > 0 {
+				minList = append(minList, MustMin(w.values...))
+				maxList = append(maxList, MustMax(w.values...))
+			}
+		}
+		return MustMin(minList...), MustMax(maxList...)
 	}
-	return filename
+
+	all := concatWeightedValues(lists)
+	all.sort()
+	min, max := all.values[0], all.values[len(all.values)-1]
+	if axisMin.Percentile > 0 {
+		min = all.quantileSorted(axisMin.Percentile / 100)
+	}
+	if axisMax.Percentile > 0 {
+		max = all.quantileSorted(axisMax.Percentile / 100)
+	}
+	return min, max
+}
+
+// ConstantDataRange returns an axis range for data whose values are all v,
+// since the range from the minimum to the maximum has no width. The range
+// is centered on v and as wide as the magnitude of v, like 4.5 to 5.5 for
+// 5 and 150 to 250 for 200, or -0.5 to 0.5 for 0. The minimum stays
+// positive for positive v, so the range can be used with log scale.
+func ConstantDataRange(v float64) (min, max float64) {
+	magnitude := 1.0
+	if v != 0 {
+		magnitude = math.Pow(10, math.Floor(math.Log10(math.Abs(v))))
+	}
+	return v - magnitude/2, v + magnitude/2
+}
+
+// alignZeroToBucketBoundary widens the range from min to max, where
+// min < 0 < max, so that the bucket width is a two significant digits value
+// whose second digit is a multiple of two or five and zero falls on a
+// bucket boundary.
+func alignZeroToBucketBoundary(min, max float64, bucketCount int) (float64, float64, error) {
+	width, err := ceilSecondSignificantDigitToMultiplesOfTwoOrFive((max - min) / float64(bucketCount))
+	for err == nil {
+		k := math.Floor(min / width)
+		lo := roundToDecimalDigits(k * width)
+		hi := roundToDecimalDigits((k + float64(bucketCount)) * width)
+		if hi >= max {
+			return lo, hi, nil
+		}
+		width, err = ceilSecondSignificantDigitToMultiplesOfTwoOrFive(math.Nextafter(width, math.Inf(1)))
+	}
+	return 0, 0, fmt.Errorf("cannot align zero to bucket boundary: %s", err)
+}
+
+// roundToDecimalDigits removes noise like -3.5999999999999996 from v so that
+// range points built from the result hit multiples of the width exactly.
+func roundToDecimalDigits(v float64) float64 {
+	return mustParseFloat(strconv.FormatFloat(v, 'g', 15, float64BitSize), float64BitSize)
 }
 
-func readFloat64ValuesFile(filename string) ([]float64, error) {
-	r, err := newReadCloserFile(filename)
+func mustParseFloat(s string, bitSize int) float64 {
+	f, err := strconv.ParseFloat(s, bitSize)
 	if err != nil {
-		return nil, err
+		panic("failed to parse float value")
+	}
+	return f
+}
+
+// suggestBucketCountForValuesList suggests the bucket count for values of
+// all files together. On log scale the rule is applied to the logarithms of
+// values since buckets are equally spaced there.
+func suggestBucketCountForValuesList(lists []weightedValues, method BucketCountMethod, scale string) int {
+	all := concatWeightedValues(lists)
+	if scale == scaleLog {
+		for i, v := range all.values {
+			all.values[i] = math.Log(v)
+		}
 	}
-	defer r.Close()
+	return suggestBucketCount(all, method)
+}
 
-	return readFloat64Values(r)
+func displayFilename(filename string) string {
+	if filename == stdinFilename {
+		return "stdin"
+	}
+	return filename
 }
 
+// newReadCloserFile opens filename, or stdin for stdinFilename. Input
+// compressed with gzip, bzip2 or zstd is decompressed on the fly.
 func newReadCloserFile(filename string) (io.ReadCloser, error) {
 	if filename == stdinFilename {
-		return io.NopCloser(os.Stdin), nil
+		return newDecompressReader(io.NopCloser(os.Stdin))
 	}
 
-	return os.Open(filename)
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	return newDecompressReader(file)
 }
 
 const float64BitSize = 64
 
-func readFloat64Values(r io.Reader) ([]float64, error) {
+func readFloat64Values(r io.Reader, format InputFormat) ([]float64, error) {
 	var values []float64
+	err := format.Scan(r, parseFiniteFloat, func(v float64) {
+		values = append(values, v)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// scanFloat64Values parses each line of r as a value with parse and calls fn
+// with it. Lines are passed to parse without copying them to strings.
+func scanFloat64Values(r io.Reader, parse ValueParser, fn func(v float64)) error {
 	scanner := bufio.NewScanner(r)
 	for scanner.Scan() {
-		value, err := strconv.ParseFloat(scanner.Text(), float64BitSize)
+		value, err := parse(bytesToString(scanner.Bytes()))
 		if err != nil {
-			return nil, err
+			return err
 		}
-		values = append(values, value)
+		fn(value)
 	}
-	if err := scanner.Err(); err != nil {
-		return nil, err
-	}
-	return values, nil
+	return scanner.Err()
 }
 
 const defaultBarChar = "*"
 const barMinWidth = 10
 
+// errGraphWidthTooSmall is returned by formatters when bars do not fit in
+// the graph width.
+var errGraphWidthTooSmall = errors.New("graph width too small")
+
+// cdfBarWidth is the width of the cumulative percentage bar at 100%, and
+// cdfBarChar is its character.
+const cdfBarWidth = 20
+const cdfBarChar = "="
+
+// BarStyle selects how bars are drawn.
+type BarStyle string
+
+const (
+	// BarStyleChar repeats the bar character, so a bar length is a whole
+	// number of characters.
+	BarStyleChar BarStyle = "char"
+	// BarStyleBlocks draws bars with Unicode block elements, whose
+	// eighth-width blocks show fractional lengths.
+	BarStyleBlocks BarStyle = "blocks"
+)
+
+func parseBarStyle(s string) (BarStyle, error) {
+	switch style := BarStyle(s); style {
+	case BarStyleChar, BarStyleBlocks:
+		return style, nil
+	default:
+		return "", fmt.Errorf("invalid bar style: %q", s)
+	}
+}
+
+// eighthBlocks are block elements from empty to full width in eighths.
+var eighthBlocks = []string{"", "▏", "▎", "▍", "▌", "▋", "▊", "▉", "█"}
+
+// blockBar returns a bar of width cells drawn with eighthBlocks and the
+// number of cells it occupies.
+func blockBar(width float64) (string, int) {
+	eighths := int(width * 8)
+	bar := strings.Repeat(eighthBlocks[8], eighths/8) + eighthBlocks[eighths%8]
+	cells := eighths / 8
+	if eighths%8 != 0 {
+		cells++
+	}
+	return bar, cells
+}
+
 type MultipleHistogramFormatter struct {
+	formatterOptions
 	histograms []*Histogram[float64]
-	pointFmt   string
-	barChar    string
-	graphWidth int
 }
 
-func NewMultipleHistogramFormatter(histograms []*Histogram[float64], barChar string, graphWidth int, pointFmt string) *MultipleHistogramFormatter {
+// NewMultipleHistogramFormatter returns a formatter of histograms side by
+// side configured with opts. It returns an error if histograms is empty,
+// their range points differ, the bar character is empty, the width is not
+// positive or the number of labels differs from that of histograms.
+func NewMultipleHistogramFormatter(histograms []*Histogram[float64], opts ...FormatterOption) (*MultipleHistogramFormatter, error) {
 	if len(histograms) == 0 {
-		panic("histograms must not be empty")
+		return nil, errors.New("histograms must not be empty")
 	}
-	if len(barChar) == 0 {
-		panic("barChar must not be empty")
-	}
-	if graphWidth == 0 {
-		panic("graphWidth too small")
+	o := newFormatterOptions(opts)
+	if err := validateBarCharAndGraphWidth(o.barChar, o.graphWidth); err != nil {
+		return nil, err
 	}
-
 	for i := 1; i < len(histograms); i++ {
 		if !slices.Equal(histograms[i].rangePoints, histograms[0].rangePoints) {
-			panic("all histograms rangePoints must be same")
+			return nil, errors.New("all histograms rangePoints must be same")
 		}
 	}
+	if o.labels != nil && len(o.labels) != len(histograms) {
+		return nil, fmt.Errorf("labels length must be %d, got %d", len(histograms), len(o.labels))
+	}
+
+	f := &MultipleHistogramFormatter{
+		formatterOptions: o,
+		histograms:       histograms,
+	}
+	if len(f.seriesColors) == 0 {
+		f.seriesColors = ansiSeriesColors
+	}
+	return f, nil
+}
+
+// MustNewMultipleHistogramFormatter is like NewMultipleHistogramFormatter
+// but panics if the arguments are invalid.
+func MustNewMultipleHistogramFormatter(histograms []*Histogram[float64], opts ...FormatterOption) *MultipleHistogramFormatter {
+	f, err := NewMultipleHistogramFormatter(histograms, opts...)
+	if err != nil {
+		panic(err)
+	}
+	return f
+}
+
+// SetTickStyle sets the style of axis point labels.
+// The default is TickStyleFixed.
+func (f *MultipleHistogramFormatter) SetTickStyle(style TickStyle) {
+	WithTickStyle(style)(&f.formatterOptions)
+}
+
+// SetMinCount hides buckets whose counts are less than n in all
+// histograms and shows the sum of their counts in a "rare" row instead.
+// The default is 0, which hides no bucket.
+func (f *MultipleHistogramFormatter) SetMinCount(n int) {
+	WithMinCount(n)(&f.formatterOptions)
+}
+
+// SetDuration sets the observation window of values to show the rate per
+// second of each row next to the count. The default is 0, which shows no
+// rate.
+func (f *MultipleHistogramFormatter) SetDuration(d time.Duration) {
+	WithDuration(d)(&f.formatterOptions)
+}
+
+// SetPercent sets whether to show the percentage of each row in all
+// values of each histogram. The default is false.
+func (f *MultipleHistogramFormatter) SetPercent(percent bool) {
+	WithPercent(percent)(&f.formatterOptions)
+}
+
+// SetCumulative sets whether to show the cumulative percentage of values
+// in range at or below the upper bound of each bucket row. The default is
+// false.
+func (f *MultipleHistogramFormatter) SetCumulative(cumulative bool) {
+	WithCumulative(cumulative)(&f.formatterOptions)
+}
+
+// SetCDFBar sets whether to draw a bar of the cumulative percentage of
+// values in range after the count bar of each bucket row. The default is
+// false.
+func (f *MultipleHistogramFormatter) SetCDFBar(cdfBar bool) {
+	WithCDFBar(cdfBar)(&f.formatterOptions)
+}
+
+// SetWidths sets whether to show the width of each bucket after its range.
+// The default is false.
+func (f *MultipleHistogramFormatter) SetWidths(widths bool) {
+	WithBucketWidths(widths)(&f.formatterOptions)
+}
+
+// SetAnnotations sets reference values shown in rows after the buckets
+// containing them. annotations must be sorted by value.
+func (f *MultipleHistogramFormatter) SetAnnotations(annotations []Annotation) {
+	WithAnnotations(annotations)(&f.formatterOptions)
+}
 
-	return &MultipleHistogramFormatter{
-		histograms: histograms,
-		barChar:    barChar,
-		graphWidth: graphWidth,
-		pointFmt:   pointFmt,
+// SetLabels sets the names of histograms like input filenames, which are
+// shown in a legend row above the columns of histograms. Labels too long
+// for their columns are truncated. No legend is shown for a single
+// histogram. The default is nil, which shows no legend. It returns an error
+// if the number of labels differs from that of histograms.
+func (f *MultipleHistogramFormatter) SetLabels(labels []string) error {
+	if labels != nil && len(labels) != len(f.histograms) {
+		return fmt.Errorf("labels length must be %d, got %d", len(f.histograms), len(labels))
 	}
+	WithLabels(labels)(&f.formatterOptions)
+	return nil
+}
+
+// SetColor sets whether to color rows with ANSI escape sequences. Bars of
+// each histogram get a distinct color. The default is false.
+func (f *MultipleHistogramFormatter) SetColor(color bool) {
+	WithColors(color)(&f.formatterOptions)
 }
 
+// SetBarStyle sets how bars are drawn. The default is BarStyleChar.
+func (f *MultipleHistogramFormatter) SetBarStyle(style BarStyle) {
+	WithBarStyle(style)(&f.formatterOptions)
+}
+
+// newHistogramFormatter returns the formatter for the i-th histogram with
+// the settings of f.
+func (f *MultipleHistogramFormatter) newHistogramFormatter(i int) *HistogramFormatter {
+	formatter := MustNewHistogramFormatter(f.histograms[i],
+		WithBarChar(f.barChar),
+		WithWidth(f.graphWidth),
+		WithPointFormat(f.pointFmt),
+		WithTickStyle(f.tickStyle),
+		WithDuration(f.duration),
+		WithPercent(f.percent),
+		WithCumulative(f.cumulative),
+		WithCDFBar(f.cdfBar),
+		WithBucketWidths(f.bucketWidths),
+		WithColors(f.color),
+		WithBarColor(f.seriesColors[i%len(f.seriesColors)]),
+		WithBarStyle(f.barStyle),
+	)
+	formatter.minCount = f.minCount
+	formatter.hidden = hiddenBuckets(f.histograms, f.minCount)
+	return formatter
+}
+
+// Format returns the formatted histograms. It returns an error if bars do
+// not fit in the graph width.
+func (f *MultipleHistogramFormatter) Format() (string, error) {
+	var sb strings.Builder
+	if _, err := f.WriteTo(&sb); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+// WriteTo writes the formatted histograms to w line by line without
+// building the whole output as a string. Nothing is written if bars do
+// not fit in the graph width.
+func (f *MultipleHistogramFormatter) WriteTo(w io.Writer) (int64, error) {
+	lines, err := f.LineStrings(f.graphWidth, f.barChar, false)
+	if err != nil {
+		return 0, err
+	}
+	return writeLines(w, lines)
+}
+
+// String is like Format but returns the error message if it fails.
 func (f *MultipleHistogramFormatter) String() string {
-	lines := f.LineStrings(f.graphWidth, f.barChar, false)
-	return strings.Join(lines, "\n") + "\n"
+	s, err := f.Format()
+	if err != nil {
+		return err.Error() + "\n"
+	}
+	return s
+}
+
+func (f *MultipleHistogramFormatter) LineStrings(graphWidth int, barChar string, padEnd bool) ([]string, error) {
+	lines, legend, err := f.histogramLineStrings(graphWidth, barChar, padEnd)
+	if err != nil {
+		return nil, err
+	}
+	var result []string
+	if legend != "" {
+		result = append(result, legend)
+	}
+	if len(f.annotations) == 0 {
+		return append(result, lines...), nil
+	}
+
+	rangeWidth := stringWidth(f.newHistogramFormatter(0).RangeStrings()[0])
+	hidden := hiddenBuckets(f.histograms, f.minCount)
+	rows := annotationRowIndexes(f.histograms[0].rangePoints, hidden, f.annotations)
+	next := 0
+	for i := -1; i < len(lines); i++ {
+		if i >= 0 {
+			result = append(result, lines[i])
+		}
+		for ; next < len(f.annotations) && rows[next] == i; next++ {
+			a := f.annotations[next]
+			marker := "-- " + fmt.Sprintf(f.pointFmt, a.Value)
+			result = append(result, padStartSpace(rangeWidth, marker)+"  "+a.Label)
+		}
+	}
+	return result, nil
 }
 
-func (f *MultipleHistogramFormatter) LineStrings(graphWidth int, barChar string, padEnd bool) []string {
+// histogramLineStrings returns the rows of histograms and the legend row,
+// which is empty without labels or for a single histogram.
+func (f *MultipleHistogramFormatter) histogramLineStrings(graphWidth int, barChar string, padEnd bool) ([]string, string, error) {
 	n := len(f.histograms)
 	if n == 1 {
-		formatter := NewHistogramFormatter(f.histograms[0], f.barChar, f.graphWidth, f.pointFmt)
-		return formatter.LineStrings(graphWidth, barChar, padEnd)
+		formatter := f.newHistogramFormatter(0)
+		lines, err := formatter.LineStrings(graphWidth, barChar, padEnd)
+		return lines, "", err
 	}
 
 	maxCounts := make([]int, n)
 	for i, h := range f.histograms {
 		maxCounts[i] = h.MaxCount()
 	}
-	maxCountMax := Max(maxCounts...)
+	maxCountMax := MustMax(maxCounts...)
 
 	formatters := make([]*HistogramFormatter, n)
-	for i, h := range f.histograms {
-		formatters[i] = NewHistogramFormatter(h, f.barChar, f.graphWidth, f.pointFmt)
+	for i := range f.histograms {
+		formatters[i] = f.newHistogramFormatter(i)
 	}
 
 	ranges := formatters[0].RangeStrings()
-	rangeWidth := len(ranges[0])
+	rangeWidth := stringWidth(ranges[0])
 
 	countStrsList := make([][]string, n)
 	for i, f2 := range formatters {
@@ -266,27 +726,43 @@ func (f *MultipleHistogramFormatter) LineStrings(graphWidth int, barChar string,
 	countWidthsTotal := 0
 	countWidths := make([]int, n)
 	for i, countStrs := range countStrsList {
-		countWidths[i] = len(countStrs[0])
+		countWidths[i] = stringWidth(countStrs[0])
 		countWidthsTotal += countWidths[i]
 	}
 
 	jointWidthsTotal := n - 1
-	barWidthsTotal := f.graphWidth - (rangeWidth + len(" ") + countWidthsTotal + (len(" ")+len(" |"))*n + jointWidthsTotal)
+	cdfBarWidth := formatters[0].cdfBarTotalWidth()
+	barWidthsTotal := f.graphWidth - (rangeWidth + len(" ") + countWidthsTotal + (len(" ")+len(" |")+cdfBarWidth)*n + jointWidthsTotal)
 	barMaxWidth := barWidthsTotal / n
 
 	barWidthRatio := float64(0)
 	if maxCountMax != 0 {
-		barWidthRatio = float64(barMaxWidth) / (float64(maxCountMax) * float64(len(barChar)))
+		barWidthRatio = float64(barMaxWidth) / (float64(maxCountMax) * float64(stringWidth(barChar)))
 	}
 
 	countAndBarsList := make([][]string, n)
 	for i, f2 := range formatters {
-		countAndBarMaxWidth := len(" ") + countWidths[i] + len(" |") + barMaxWidth
+		countAndBarMaxWidth := len(" ") + countWidths[i] + len(" |") + barMaxWidth + cdfBarWidth
 		padEnd2 := true
 		if i == len(f.histograms)-1 {
 			padEnd2 = padEnd
 		}
-		countAndBarsList[i] = f2.CountAndBarStrings(countAndBarMaxWidth, barWidthRatio, f.barChar, padEnd2)
+		countAndBars, err := f2.CountAndBarStrings(countAndBarMaxWidth, barWidthRatio, f.barChar, padEnd2)
+		if err != nil {
+			return nil, "", err
+		}
+		countAndBarsList[i] = countAndBars
+	}
+
+	var legend string
+	if f.labels != nil {
+		// Each label is above the count and the bar of its histogram.
+		fields := make([]string, n)
+		for i, label := range f.labels {
+			fieldWidth := countWidths[i] + len(" |") + barMaxWidth + cdfBarWidth
+			fields[i] = padEndSpace(fieldWidth, truncateToWidth(label, fieldWidth))
+		}
+		legend = strings.TrimRight(strings.Repeat(" ", rangeWidth)+"  "+strings.Join(fields, " "), " ")
 	}
 
 	lines := make([]string, len(ranges))
@@ -295,66 +771,315 @@ func (f *MultipleHistogramFormatter) LineStrings(graphWidth int, barChar string,
 		for j := range f.histograms {
 			fields[j] = countAndBarsList[j][i]
 		}
-		lines[i] = ranges[i] + "  " + strings.Join(fields, " ")
+		lines[i] = colorize(ranges[i], f.rangeColor(formatters, i)) + "  " + strings.Join(fields, " ")
 	}
-	return lines
+	return lines, legend, nil
+}
+
+// rangeColor returns the color of the range label of a row, which is
+// dimmed only if the row is dimmed for all histograms.
+func (f *MultipleHistogramFormatter) rangeColor(formatters []*HistogramFormatter, row int) string {
+	color := formatters[0].rowColor(row)
+	for _, f2 := range formatters[1:] {
+		if f2.rowColor(row) != color {
+			return ""
+		}
+	}
+	return color
 }
 
 type HistogramFormatter struct {
-	histogram  *Histogram[float64]
-	pointFmt   string
-	barChar    string
-	graphWidth int
+	// formatterOptions holds the settings, which both the options of the
+	// constructor and the setters change so that they cannot diverge.
+	formatterOptions
+	histogram *Histogram[float64]
+	hidden    []bool
 }
 
-func NewHistogramFormatter(histogram *Histogram[float64], barChar string, graphWidth int, pointFmt string) *HistogramFormatter {
-	if len(barChar) == 0 {
-		panic("barChar must not be empty")
+// NewHistogramFormatter returns a formatter of histogram configured with
+// opts. It returns an error if the bar character is empty or the width is
+// not positive.
+func NewHistogramFormatter(histogram *Histogram[float64], opts ...FormatterOption) (*HistogramFormatter, error) {
+	o := newFormatterOptions(opts)
+	if err := validateBarCharAndGraphWidth(o.barChar, o.graphWidth); err != nil {
+		return nil, err
 	}
-	if graphWidth == 0 {
-		panic("graphWidth too small")
+	f := &HistogramFormatter{
+		formatterOptions: o,
+		histogram:        histogram,
 	}
-	return &HistogramFormatter{
-		histogram:  histogram,
-		barChar:    barChar,
-		graphWidth: graphWidth,
-		pointFmt:   pointFmt,
+	if f.barColor == "" {
+		f.barColor = ansiSeriesColors[0]
 	}
+	f.SetMinCount(f.minCount)
+	return f, nil
+}
+
+// MustNewHistogramFormatter is like NewHistogramFormatter but panics if the
+// arguments are invalid.
+func MustNewHistogramFormatter(histogram *Histogram[float64], opts ...FormatterOption) *HistogramFormatter {
+	f, err := NewHistogramFormatter(histogram, opts...)
+	if err != nil {
+		panic(err)
+	}
+	return f
+}
+
+func validateBarCharAndGraphWidth(barChar string, graphWidth int) error {
+	if stringWidth(barChar) == 0 {
+		return errors.New("barChar must not be empty")
+	}
+	if graphWidth <= 0 {
+		return fmt.Errorf("graphWidth must be positive, got %d", graphWidth)
+	}
+	return nil
+}
+
+// SetTickStyle sets the style of axis point labels.
+// The default is TickStyleFixed.
+func (f *HistogramFormatter) SetTickStyle(style TickStyle) {
+	WithTickStyle(style)(&f.formatterOptions)
+}
+
+// SetMinCount hides buckets whose counts are less than n and shows the sum
+// of their counts in a "rare" row instead.
+// The default is 0, which hides no bucket.
+func (f *HistogramFormatter) SetMinCount(n int) {
+	WithMinCount(n)(&f.formatterOptions)
+	f.hidden = hiddenBuckets([]*Histogram[float64]{f.histogram}, n)
+}
+
+// SetDuration sets the observation window of values to show the rate per
+// second of each row next to the count. The default is 0, which shows no
+// rate.
+func (f *HistogramFormatter) SetDuration(d time.Duration) {
+	WithDuration(d)(&f.formatterOptions)
+}
+
+// SetPercent sets whether to show the percentage of each row in all
+// values including those out of range. The default is false.
+func (f *HistogramFormatter) SetPercent(percent bool) {
+	WithPercent(percent)(&f.formatterOptions)
+}
+
+// SetCumulative sets whether to show the cumulative percentage of values
+// in range at or below the upper bound of each bucket row. Hidden buckets
+// are included in the rows after them. The default is false.
+func (f *HistogramFormatter) SetCumulative(cumulative bool) {
+	WithCumulative(cumulative)(&f.formatterOptions)
+}
+
+// SetCDFBar sets whether to draw a bar of the cumulative percentage of
+// values in range after the count bar of each bucket row, so that a chart
+// shows both the distribution and its cumulative one. The default is false.
+func (f *HistogramFormatter) SetCDFBar(cdfBar bool) {
+	WithCDFBar(cdfBar)(&f.formatterOptions)
+}
+
+// SetWidths sets whether to show the width of each bucket after its range,
+// which is useful when buckets have different widths. The default is false.
+func (f *HistogramFormatter) SetWidths(widths bool) {
+	WithBucketWidths(widths)(&f.formatterOptions)
+}
+
+// cdfBarTotalWidth returns the width of the cumulative percentage bar
+// including its separator, or 0 if it is not drawn.
+func (f *HistogramFormatter) cdfBarTotalWidth() int {
+	if !f.cdfBar {
+		return 0
+	}
+	return len(" |") + cdfBarWidth
+}
+
+// SetColor sets whether to color rows with ANSI escape sequences: bars
+// are colored, rows of empty buckets are dimmed and the out of range row
+// gets a distinct color. The default is false.
+func (f *HistogramFormatter) SetColor(color bool) {
+	WithColors(color)(&f.formatterOptions)
+}
+
+// SetBarStyle sets how bars are drawn. The default is BarStyleChar.
+func (f *HistogramFormatter) SetBarStyle(style BarStyle) {
+	WithBarStyle(style)(&f.formatterOptions)
+}
+
+// rowColor returns the color of the labels and the count of a row.
+func (f *HistogramFormatter) rowColor(row int) string {
+	if !f.color {
+		return ""
+	}
+	counts, barRowCount := f.rowCounts()
+	switch {
+	case row == len(counts)-1:
+		return ansiOutOfRange
+	case row < barRowCount && counts[row] == 0:
+		return ansiDim
+	default:
+		return ""
+	}
+}
+
+// hiddenBuckets returns which buckets have counts less than minCount in all
+// histograms. It returns nil if no bucket is hidden.
+func hiddenBuckets(histograms []*Histogram[float64], minCount int) []bool {
+	hidden := make([]bool, len(histograms[0].counts))
+	hiddenCount := 0
+	for i := range hidden {
+		hidden[i] = true
+		for _, h := range histograms {
+			if h.counts[i] >= minCount {
+				hidden[i] = false
+				break
+			}
+		}
+		if hidden[i] {
+			hiddenCount++
+		}
+	}
+	if hiddenCount == 0 {
+		return nil
+	}
+	return hidden
+}
+
+func (f *HistogramFormatter) isHidden(bucket int) bool {
+	return f.hidden != nil && f.hidden[bucket]
+}
+
+// rowCounts returns counts of rows, which are visible buckets, the "rare"
+// row if some buckets are hidden, and the out of range row.
+// barRowCount is the number of rows for buckets.
+func (f *HistogramFormatter) rowCounts() (counts []int, barRowCount int) {
+	rareCount := 0
+	for i, count := range f.histogram.counts {
+		if f.isHidden(i) {
+			rareCount += count
+		} else {
+			counts = append(counts, count)
+		}
+	}
+	barRowCount = len(counts)
+	if f.hidden != nil {
+		counts = append(counts, rareCount)
+	}
+	counts = append(counts, f.histogram.outOfRangeCount)
+	return counts, barRowCount
 }
 
 func (f *HistogramFormatter) RangeStrings() []string {
-	tickWidth := 0
-	ticks := make([]string, len(f.histogram.rangePoints))
-	for i, tick := range f.histogram.rangePoints {
-		s := fmt.Sprintf(f.pointFmt, tick)
-		ticks[i] = s
-		tickWidth = Max(tickWidth, len(s))
+	rangePoints := f.histogram.rangePoints
+	ticks := FormatTicks(rangePoints, f.tickStyle, f.pointFmt)
+	tickWidth := stringSliceMaxWidth(ticks)
+
+	var widths []string
+	if f.bucketWidths {
+		bucketWidths := make([]float64, len(rangePoints)-1)
+		for i := range bucketWidths {
+			bucketWidths[i] = rangePoints[i+1] - rangePoints[i]
+		}
+		widths = FormatTicks(bucketWidths, f.tickStyle, f.pointFmt)
+		alignRightStringSlice(widths)
 	}
 
-	ranges := make([]string, len(ticks))
+	var ranges []string
 	for i := 0; i < len(ticks)-1; i++ {
-		ranges[i] = fmt.Sprintf("%*s ~ %*s",
-			tickWidth, ticks[i],
-			tickWidth, ticks[i+1])
+		if f.isHidden(i) {
+			continue
+		}
+		r := padStartSpace(tickWidth, ticks[i]) + " ~ " + padStartSpace(tickWidth, ticks[i+1])
+		if widths != nil {
+			r += " (width " + widths[i] + ")"
+		}
+		ranges = append(ranges, r)
+	}
+	if f.hidden != nil {
+		ranges = append(ranges, fmt.Sprintf("rare (< %d)", f.minCount))
 	}
-	ranges[len(ticks)-1] = "out of range"
+	ranges = append(ranges, "out of range")
 
 	alignRightStringSlice(ranges)
 	return ranges
 }
 
 func (f *HistogramFormatter) CountStrings() []string {
-	countStrs := make([]string, len(f.histogram.counts)+1)
-	for i, count := range f.histogram.counts {
-		s := strconv.Itoa(count)
-		countStrs[i] = s
+	counts, _ := f.rowCounts()
+	countStrs := make([]string, len(counts))
+	for i, count := range counts {
+		countStrs[i] = strconv.Itoa(count)
 	}
-	countStrs[len(f.histogram.counts)] = strconv.Itoa(f.histogram.outOfRangeCount)
-
 	alignRightStringSlice(countStrs)
+
+	if f.duration > 0 {
+		rateStrs := make([]string, len(counts))
+		for i, count := range counts {
+			rateStrs[i] = fmt.Sprintf("%.3g/s", float64(count)/f.duration.Seconds())
+		}
+		alignRightStringSlice(rateStrs)
+		for i := range countStrs {
+			countStrs[i] += " " + rateStrs[i]
+		}
+	}
+
+	if f.percent {
+		total := f.histogram.TotalCount()
+		percentStrs := make([]string, len(counts))
+		for i, count := range counts {
+			percent := 0.0
+			if total > 0 {
+				percent = float64(count) / float64(total) * 100
+			}
+			percentStrs[i] = fmt.Sprintf("%.1f%%", percent)
+		}
+		alignRightStringSlice(percentStrs)
+		for i := range countStrs {
+			countStrs[i] += " " + percentStrs[i]
+		}
+	}
+
+	if f.cumulative {
+		percentStrs := f.cumulativePercentStrings(len(counts))
+		alignRightStringSlice(percentStrs)
+		for i := range countStrs {
+			countStrs[i] += " " + percentStrs[i]
+		}
+	}
 	return countStrs
 }
 
+// cumulativePercentStrings returns the cumulative percentages for rows of
+// visible buckets and empty strings for the other rows up to rowCount.
+func (f *HistogramFormatter) cumulativePercentStrings(rowCount int) []string {
+	percentStrs := make([]string, rowCount)
+	for i, fraction := range f.cumulativeFractions() {
+		percentStrs[i] = fmt.Sprintf("%.1f%%", fraction*100)
+	}
+	return percentStrs
+}
+
+// cumulativeFractions returns the fractions of values in range at or below
+// the upper bounds of rows of visible buckets. Hidden buckets are included
+// in the rows after them.
+func (f *HistogramFormatter) cumulativeFractions() []float64 {
+	total := 0
+	for _, count := range f.histogram.counts {
+		total += count
+	}
+	var fractions []float64
+	cum := 0
+	for i, count := range f.histogram.counts {
+		cum += count
+		if f.isHidden(i) {
+			continue
+		}
+		fraction := 0.0
+		if total > 0 {
+			fraction = float64(cum) / float64(total)
+		}
+		fractions = append(fractions, fraction)
+	}
+	return fractions
+}
+
 func alignRightStringSlice(ss []string) {
 	w := stringSliceMaxWidth(ss)
 	for i, countStr := range ss {
@@ -365,118 +1090,402 @@ func alignRightStringSlice(ss []string) {
 func stringSliceMaxWidth(ss []string) int {
 	w := 0
 	for _, s := range ss {
-		w = Max(w, len(s))
+		w = MustMax(w, stringWidth(s))
 	}
 	return w
 }
 
-func padStartSpace(targetWidth int, s string) string {
-	return fmt.Sprintf("%*s", targetWidth, s)
-}
-
-func (f *HistogramFormatter) CountAndBarStrings(countAndBarMaxWidth int, barWidthRatio float64, barChar string, padEnd bool) []string {
+func (f *HistogramFormatter) CountAndBarStrings(countAndBarMaxWidth int, barWidthRatio float64, barChar string, padEnd bool) ([]string, error) {
 	counts := f.CountStrings()
-	countWidth := len(counts[0])
-	barMaxWidth := countAndBarMaxWidth - (len(" ") + countWidth + len(" |"))
-	bars := f.BarStrings(barMaxWidth, barWidthRatio, barChar, padEnd)
+	countWidth := stringWidth(counts[0])
+	barMaxWidth := countAndBarMaxWidth - (len(" ") + countWidth + len(" |") + f.cdfBarTotalWidth())
+	bars, err := f.BarStrings(barMaxWidth, barWidthRatio, barChar, padEnd)
+	if err != nil {
+		return nil, err
+	}
 
 	countAndBars := make([]string, len(counts))
 	for i := range countAndBars {
-		countAndBars[i] = fmt.Sprintf("%s |%s", counts[i], bars[i])
+		countAndBars[i] = fmt.Sprintf("%s |%s", colorize(counts[i], f.rowColor(i)), bars[i])
 	}
-	return countAndBars
+	return countAndBars, nil
 }
 
-func (f *HistogramFormatter) BarStrings(barMaxWidth int, barWidthRatio float64, barChar string, padEnd bool) []string {
+// BarStrings returns the bars of rows. It returns an error wrapping
+// errGraphWidthTooSmall if barMaxWidth is barMinWidth or less.
+func (f *HistogramFormatter) BarStrings(barMaxWidth int, barWidthRatio float64, barChar string, padEnd bool) ([]string, error) {
 	if barMaxWidth <= barMinWidth {
-		log.Fatalf("bar max width becomes too small, retry with larger graphWidth, barMaxWidth=%d, graphWidth=%d", barMaxWidth, f.graphWidth)
+		return nil, fmt.Errorf("%w, retry with larger graph width: barMaxWidth=%d, graphWidth=%d", errGraphWidthTooSmall, barMaxWidth, f.graphWidth)
 	}
 
-	bars := make([]string, len(f.histogram.counts)+1)
-	for i, count := range f.histogram.counts {
-		barWidth := int(float64(count) * barWidthRatio)
-		if padEnd {
-			bars[i] = strings.Repeat(f.barChar, barWidth) + strings.Repeat(" ", barMaxWidth-barWidth)
+	counts, barRowCount := f.rowCounts()
+	bars := make([]string, len(counts))
+	for i, count := range counts[:barRowCount] {
+		var barWidth int
+		if f.barStyle == BarStyleBlocks {
+			bars[i], barWidth = blockBar(float64(count) * barWidthRatio * float64(stringWidth(f.barChar)))
 		} else {
-			bars[i] = strings.Repeat(f.barChar, barWidth)
+			n := int(float64(count) * barWidthRatio)
+			bars[i] = strings.Repeat(f.barChar, n)
+			barWidth = n * stringWidth(f.barChar)
+		}
+		if f.color {
+			bars[i] = colorize(bars[i], f.barColor)
+		}
+		if padEnd || f.cdfBar {
+			bars[i] += strings.Repeat(" ", barMaxWidth-barWidth)
+		}
+	}
+	if f.cdfBar {
+		for i, fraction := range f.cumulativeFractions() {
+			cdfWidth := int(fraction * cdfBarWidth)
+			bars[i] += " |" + strings.Repeat(cdfBarChar, cdfWidth)
+			if padEnd {
+				bars[i] += strings.Repeat(" ", cdfBarWidth-cdfWidth)
+			}
 		}
 	}
 	if padEnd {
-		bars[len(f.histogram.counts)] = strings.Repeat(" ", barMaxWidth)
+		for i := barRowCount; i < len(counts); i++ {
+			bars[i] = strings.Repeat(" ", barMaxWidth+f.cdfBarTotalWidth())
+		}
 	}
-	return bars
+	return bars, nil
 }
 
-func (f *HistogramFormatter) LineStrings(graphWidth int, barChar string, padEnd bool) []string {
+func (f *HistogramFormatter) LineStrings(graphWidth int, barChar string, padEnd bool) ([]string, error) {
 	ranges := f.RangeStrings()
 	counts := f.CountStrings()
 
-	rangeWidth := len(ranges[0])
-	countWidth := len(counts[0])
-	barMaxWidth := graphWidth - (rangeWidth + len("  ") + countWidth + len(" |"))
+	rangeWidth := stringWidth(ranges[0])
+	countWidth := stringWidth(counts[0])
+	barMaxWidth := graphWidth - (rangeWidth + len("  ") + countWidth + len(" |") + f.cdfBarTotalWidth())
 
 	maxCount := f.histogram.MaxCount()
 	barWidthRatio := float64(0)
 	if maxCount != 0 {
-		barWidthRatio = float64(barMaxWidth) / (float64(maxCount) * float64(len(barChar)))
+		barWidthRatio = float64(barMaxWidth) / (float64(maxCount) * float64(stringWidth(barChar)))
 	}
 
-	bars := f.BarStrings(barMaxWidth, barWidthRatio, barChar, padEnd)
+	bars, err := f.BarStrings(barMaxWidth, barWidthRatio, barChar, padEnd)
+	if err != nil {
+		return nil, err
+	}
 
 	lines := make([]string, len(ranges))
 	for i := range lines {
-		lines[i] = fmt.Sprintf("%s  %s |%s", ranges[i], counts[i], bars[i])
+		color := f.rowColor(i)
+		lines[i] = fmt.Sprintf("%s  %s |%s", colorize(ranges[i], color), colorize(counts[i], color), bars[i])
 	}
-	return lines
+	return lines, nil
 }
 
+// Format returns the formatted histogram. It returns an error if bars do
+// not fit in the graph width.
+func (f *HistogramFormatter) Format() (string, error) {
+	var sb strings.Builder
+	if _, err := f.WriteTo(&sb); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+// WriteTo writes the formatted histogram to w line by line without
+// building the whole output as a string. Nothing is written if bars do
+// not fit in the graph width.
+func (f *HistogramFormatter) WriteTo(w io.Writer) (int64, error) {
+	lines, err := f.LineStrings(f.graphWidth, f.barChar, false)
+	if err != nil {
+		return 0, err
+	}
+	return writeLines(w, lines)
+}
+
+// writeLines writes lines to w, each followed by a newline, and returns
+// the number of bytes written.
+func writeLines(w io.Writer, lines []string) (int64, error) {
+	var n int64
+	for _, line := range lines {
+		for _, s := range []string{line, "\n"} {
+			m, err := io.WriteString(w, s)
+			n += int64(m)
+			if err != nil {
+				return n, err
+			}
+		}
+	}
+	return n, nil
+}
+
+// String is like Format but returns the error message if it fails.
 func (f *HistogramFormatter) String() string {
-	lines := f.LineStrings(f.graphWidth, f.barChar, false)
-	return strings.Join(lines, "\n") + "\n"
+	s, err := f.Format()
+	if err != nil {
+		return err.Error() + "\n"
+	}
+	return s
 }
 
 type Number interface {
 	constraints.Integer | constraints.Float
 }
 
+// Recorder is the interface implemented by histogram backends.
+// Histogram[float64], ConcurrentHistogram[float64], HDRHistogram and
+// TDigest implement it.
+type Recorder interface {
+	AddValue(v float64)
+	Counts() []int
+	Quantile(q float64) float64
+}
+
 type Histogram[T Number] struct {
 	rangePoints     []T
 	counts          []int
 	outOfRangeCount int
+	// underflowCount and overflowCount are the parts of outOfRangeCount
+	// below and above the buckets. NaN is neither of them.
+	underflowCount  int
+	overflowCount   int
+	boundaryEpsilon float64
+	closure         Closure
+	inclusiveMax    bool
+	// uniformWidth is the width of the buckets if rangePoints are equally
+	// spaced, or 0 otherwise.
+	uniformWidth float64
 }
 
 func NewHistogram[T Number](rangePoints []T) *Histogram[T] {
 	counts := make([]int, len(rangePoints)-1)
-	return &Histogram[T]{rangePoints: rangePoints, counts: counts}
+	return &Histogram[T]{rangePoints: rangePoints, counts: counts, closure: ClosureLeft, uniformWidth: uniformWidth(rangePoints)}
 }
 
+// BuildRangePoints returns count+1 points from min to max which are
+// equally spaced. The first point is exactly min and the last point is
+// exactly max, and the points never decrease even if rounding errors occur.
+// For data of a constant value, use ConstantDataRange for min and max,
+// since buckets between equal min and max cannot hold any value.
 func BuildRangePoints[T Number](count int, min, max T) []T {
 	rangePoints := make([]T, count+1)
+	// max-min overflows to infinity for floats when min and max are
+	// extremely far apart, so use half of the width and double it later.
+	width := max - min
+	scale := T(1)
+	if math.IsInf(float64(width), 0) {
+		width = max/2 - min/2
+		scale = 2
+	}
 	for i := 0; i <= count; i++ {
-		rangePoints[i] = min + (max-min)*T(i)/T(count)
+		// Measure from the nearer end so that rounding errors do not
+		// accumulate toward the ends and both ends are exact.
+		var p T
+		if 2*i <= count {
+			p = min + width*T(i)/T(count)*scale
+		} else {
+			p = max - width*T(count-i)/T(count)*scale
+		}
+		if i > 0 && p < rangePoints[i-1] {
+			p = rangePoints[i-1]
+		}
+		if p > max {
+			p = max
+		}
+		rangePoints[i] = p
+	}
+	return rangePoints
+}
+
+// BuildLogRangePoints returns count+1 points from min to max which are
+// equally spaced on a logarithmic scale. It returns an error if min is not
+// positive or max is not greater than min.
+func BuildLogRangePoints(count int, min, max float64) ([]float64, error) {
+	if err := validateLogRange(min, max); err != nil {
+		return nil, err
+	}
+
+	logMin := math.Log(min)
+	logMax := math.Log(max)
+	rangePoints := make([]float64, count+1)
+	for i := 0; i <= count; i++ {
+		rangePoints[i] = math.Exp(logMin + (logMax-logMin)*float64(i)/float64(count))
+	}
+	rangePoints[0] = min
+	rangePoints[count] = max
+	return rangePoints, nil
+}
+
+// MustBuildLogRangePoints is like BuildLogRangePoints but panics if the
+// range is invalid.
+func MustBuildLogRangePoints(count int, min, max float64) []float64 {
+	rangePoints, err := BuildLogRangePoints(count, min, max)
+	if err != nil {
+		panic(err)
 	}
 	return rangePoints
 }
 
+// validateLogRange returns an error if min and max cannot be the ends of
+// a log scale axis.
+func validateLogRange(min, max float64) error {
+	if !(min > 0) {
+		return fmt.Errorf("min must be positive for log scale, got %g", min)
+	}
+	if !(max > min) {
+		return fmt.Errorf("max must be greater than min for log scale, got %g and %g", min, max)
+	}
+	return nil
+}
+
 func (h *Histogram[T]) AddValues(values []T) {
 	for _, v := range values {
 		h.AddValue(v)
 	}
 }
 
+// AddFromReader parses each line of r with parse and counts the value
+// until the end of r, without keeping values in memory. The line passed to
+// parse is only valid during the call. It returns the number of values
+// added, which are kept even if an error stops reading.
+func (h *Histogram[T]) AddFromReader(r io.Reader, parse func([]byte) (T, error)) (n int, err error) {
+	scanner := bufio.NewScanner(r)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		v, err := parse(scanner.Bytes())
+		if err != nil {
+			return n, fmt.Errorf("line %d: %w", lineNum, err)
+		}
+		h.AddValue(v)
+		n++
+	}
+	return n, scanner.Err()
+}
+
+// AddValue counts v in the bucket i where rangePoints[i] <= v < rangePoints[i+1],
+// or rangePoints[i] < v <= rangePoints[i+1] with ClosureRight. Values
+// outside of the buckets, including the last range point, or the first one
+// with ClosureRight, are counted as out of range.
 func (h *Histogram[T]) AddValue(v T) {
-	if v < h.rangePoints[0] || v > h.rangePoints[len(h.rangePoints)-1] {
-		h.outOfRangeCount++
-		return
+	h.addValueCount(v, 1)
+}
+
+// SetBoundaryEpsilon sets the tolerance relative to the bucket width
+// within which a value is regarded as equal to the nearest range point, so
+// that values off by floating point noise land in the intuitive bucket.
+// The default is 0, which compares values exactly.
+func (h *Histogram[T]) SetBoundaryEpsilon(epsilon float64) {
+	if epsilon < 0 {
+		panic(fmt.Sprintf("boundary epsilon must not be negative, got %g", epsilon))
+	}
+	h.boundaryEpsilon = epsilon
+}
+
+// SetClosure sets which end of a bucket is included in it. It must be
+// called before adding values. The default is ClosureLeft, which is also
+// used for an empty closure.
+func (h *Histogram[T]) SetClosure(closure Closure) {
+	if closure == "" {
+		closure = ClosureLeft
+	}
+	if closure != ClosureLeft && closure != ClosureRight {
+		panic(fmt.Sprintf("invalid closure: %q", closure))
+	}
+	h.closure = closure
+}
+
+// SetInclusiveMax sets whether the last bucket includes the last range
+// point, so that values equal to the axis maximum are counted in it
+// instead of as out of range. It has no effect with ClosureRight, whose
+// buckets already include their upper bounds. The default is false.
+func (h *Histogram[T]) SetInclusiveMax(inclusive bool) {
+	h.inclusiveMax = inclusive
+}
+
+// Closure returns which end of a bucket is included in it.
+func (h *Histogram[T]) Closure() Closure {
+	if h.closure == "" {
+		return ClosureLeft
+	}
+	return h.closure
+}
+
+// snapToRangePoint returns the range point nearest to v if it is within
+// the boundary epsilon, and v otherwise.
+func (h *Histogram[T]) snapToRangePoint(v T) T {
+	points := h.rangePoints
+	j := sort.Search(len(points), func(i int) bool { return points[i] >= v })
+	for _, k := range []int{j - 1, j} {
+		if k < 0 || k >= len(points) {
+			continue
+		}
+		// Use the narrower of the buckets adjacent to the point.
+		width := math.Inf(1)
+		if k > 0 {
+			width = float64(points[k] - points[k-1])
+		}
+		if k < len(points)-1 {
+			width = math.Min(width, float64(points[k+1]-points[k]))
+		}
+		if math.Abs(float64(v)-float64(points[k])) <= h.boundaryEpsilon*width {
+			return points[k]
+		}
+	}
+	return v
+}
+
+// addValueCount counts v n times.
+func (h *Histogram[T]) addValueCount(v T, n int) {
+	switch i := h.locate(v); i {
+	case locateUnderflow:
+		h.outOfRangeCount += n
+		h.underflowCount += n
+	case locateOverflow:
+		h.outOfRangeCount += n
+		h.overflowCount += n
+	case locateNaN:
+		h.outOfRangeCount += n
+	default:
+		h.counts[i] += n
+	}
+}
+
+// Results of locate for values which are not in any bucket.
+const (
+	locateUnderflow = -1
+	locateOverflow  = -2
+	locateNaN       = -3
+)
+
+// locate returns the index of the bucket which v is counted in, or one of
+// locateUnderflow, locateOverflow and locateNaN. It only reads the settings
+// of h, so it is safe to call concurrently.
+func (h *Histogram[T]) locate(v T) int {
+	if h.boundaryEpsilon > 0 {
+		v = h.snapToRangePoint(v)
+	}
+	var i int
+	var ok bool
+	if h.uniformWidth > 0 {
+		i, ok = uniformBucketIndexClosed(h.rangePoints, h.uniformWidth, v, h.closure)
+	} else {
+		i, ok = bucketIndexClosed(h.rangePoints, v, h.closure)
+	}
+	if !ok && h.inclusiveMax && v == h.rangePoints[len(h.rangePoints)-1] {
+		i, ok = len(h.counts)-1, true
+	}
+	if ok {
+		return i
 	}
-	i := sort.Search(len(h.rangePoints), func(i int) bool { return h.rangePoints[i] > v }) - 1
-	if i < len(h.counts) {
-		h.counts[i]++
+	switch first := h.rangePoints[0]; {
+	case v < first || v == first && h.Closure() == ClosureRight:
+		return locateUnderflow
+	case v >= first:
+		return locateOverflow
+	default:
+		return locateNaN
 	}
 }
 
 func (h *Histogram[T]) MaxCount() int {
-	return Max(h.counts...)
+	return MustMax(h.counts...)
 }
 
 func (h *Histogram[T]) RangePoints() []T {
@@ -491,13 +1500,162 @@ func (h *Histogram[T]) Counts() []int {
 	return countsCopy
 }
 
+// OutOfRangeCount returns the number of added values which are not in any
+// bucket.
+func (h *Histogram[T]) OutOfRangeCount() int {
+	return h.outOfRangeCount
+}
+
+// UnderflowCount returns the number of added values below the first
+// bucket.
+func (h *Histogram[T]) UnderflowCount() int {
+	return h.underflowCount
+}
+
+// OverflowCount returns the number of added values above the last bucket.
+func (h *Histogram[T]) OverflowCount() int {
+	return h.overflowCount
+}
+
+// TotalCount returns the number of added values including those out of
+// range.
+func (h *Histogram[T]) TotalCount() int {
+	total := h.outOfRangeCount
+	for _, count := range h.counts {
+		total += count
+	}
+	return total
+}
+
+// Percentages returns the share of each bucket in percent of all added
+// values including those out of range. It returns zeros if no value is
+// added.
+func (h *Histogram[T]) Percentages() []float64 {
+	percentages := make([]float64, len(h.counts))
+	total := h.TotalCount()
+	if total == 0 {
+		return percentages
+	}
+	for i, count := range h.counts {
+		percentages[i] = float64(count) / float64(total) * 100
+	}
+	return percentages
+}
+
+// Quantile estimates the q-quantile of the values counted in buckets,
+// interpolating linearly within the bucket which contains it. Out of range
+// values are not taken into account. It returns NaN if no value is counted
+// in buckets. q must be between 0 and 1.
+func (h *Histogram[T]) Quantile(q float64) float64 {
+	if q < 0 || q > 1 || math.IsNaN(q) {
+		panic("q must be between 0 and 1")
+	}
+
+	total := 0
+	for _, c := range h.counts {
+		total += c
+	}
+	if total == 0 {
+		return math.NaN()
+	}
+
+	target := q * float64(total)
+	cum := 0
+	for i, c := range h.counts {
+		if c == 0 {
+			continue
+		}
+		if target <= float64(cum+c) {
+			lower := float64(h.rangePoints[i])
+			upper := float64(h.rangePoints[i+1])
+			return lower + (upper-lower)*(target-float64(cum))/float64(c)
+		}
+		cum += c
+	}
+	// Not reached except for rounding errors in target.
+	return float64(h.rangePoints[len(h.rangePoints)-1])
+}
+
+// ErrRangePointsMismatch is returned when histograms with different range
+// points are combined.
+var ErrRangePointsMismatch = errors.New("range points mismatch")
+
+// ErrClosureMismatch is returned when histograms with different closures
+// are combined.
+var ErrClosureMismatch = errors.New("closure mismatch")
+
+// ErrInclusiveMaxMismatch is returned when histograms of which only one
+// counts the axis maximum in the last bucket are combined.
+var ErrInclusiveMaxMismatch = errors.New("inclusive max mismatch")
+
+// ErrBoundaryEpsilonMismatch is returned when histograms with different
+// boundary epsilons are combined.
+var ErrBoundaryEpsilonMismatch = errors.New("boundary epsilon mismatch")
+
+// Merge adds counts of other to h. Both histograms must have the same range
+// points and count edge values the same way, otherwise
+// ErrRangePointsMismatch, ErrClosureMismatch, ErrInclusiveMaxMismatch or
+// ErrBoundaryEpsilonMismatch is returned and h is unchanged.
+func (h *Histogram[T]) Merge(other *Histogram[T]) error {
+	if !slices.Equal(h.rangePoints, other.rangePoints) {
+		return ErrRangePointsMismatch
+	}
+	if h.Closure() != other.Closure() {
+		return ErrClosureMismatch
+	}
+	if h.inclusiveMax != other.inclusiveMax {
+		return ErrInclusiveMaxMismatch
+	}
+	if h.boundaryEpsilon != other.boundaryEpsilon {
+		return ErrBoundaryEpsilonMismatch
+	}
+	for i, c := range other.counts {
+		h.counts[i] += c
+	}
+	h.outOfRangeCount += other.outOfRangeCount
+	h.underflowCount += other.underflowCount
+	h.overflowCount += other.overflowCount
+	return nil
+}
+
+// Coarsen returns a new histogram whose buckets each aggregate factor
+// adjacent buckets of h, so that h can be binned finely once and displayed
+// at coarser resolutions without reading values again. If the bucket count
+// of h is not a multiple of factor, the last bucket aggregates the rest.
+func (h *Histogram[T]) Coarsen(factor int) *Histogram[T] {
+	if factor < 1 {
+		panic(fmt.Sprintf("factor must be positive, got %d", factor))
+	}
+	rangePoints := make([]T, 0, len(h.counts)/factor+2)
+	for i := 0; i < len(h.counts); i += factor {
+		rangePoints = append(rangePoints, h.rangePoints[i])
+	}
+	rangePoints = append(rangePoints, h.rangePoints[len(h.rangePoints)-1])
+	coarse := NewHistogram(rangePoints)
+	coarse.closure = h.closure
+	coarse.inclusiveMax = h.inclusiveMax
+	coarse.boundaryEpsilon = h.boundaryEpsilon
+	for i, count := range h.counts {
+		coarse.counts[i/factor] += count
+	}
+	coarse.outOfRangeCount = h.outOfRangeCount
+	coarse.underflowCount = h.underflowCount
+	coarse.overflowCount = h.overflowCount
+	return coarse
+}
+
 func (h *Histogram[T]) Equal(o *Histogram[T]) bool {
 	return slices.Equal(h.rangePoints, o.rangePoints) && slices.Equal(h.counts, o.counts)
 }
 
-func Min[T constraints.Ordered](values ...T) T {
+// errEmptyValues is returned by Min and Max for no value.
+var errEmptyValues = errors.New("values must not be empty")
+
+// Min returns the minimum of values. It returns an error for no value.
+func Min[T constraints.Ordered](values ...T) (T, error) {
 	if len(values) == 0 {
-		panic("values must not be empty")
+		var zero T
+		return zero, errEmptyValues
 	}
 
 	min := values[0]
@@ -506,12 +1664,23 @@ func Min[T constraints.Ordered](values ...T) T {
 			min = values[i]
 		}
 	}
+	return min, nil
+}
+
+// MustMin is like Min but panics for no value.
+func MustMin[T constraints.Ordered](values ...T) T {
+	min, err := Min(values...)
+	if err != nil {
+		panic(err)
+	}
 	return min
 }
 
-func Max[T constraints.Ordered](values ...T) T {
+// Max returns the maximum of values. It returns an error for no value.
+func Max[T constraints.Ordered](values ...T) (T, error) {
 	if len(values) == 0 {
-		panic("values must not be empty")
+		var zero T
+		return zero, errEmptyValues
 	}
 
 	max := values[0]
@@ -520,20 +1689,31 @@ func Max[T constraints.Ordered](values ...T) T {
 			max = values[i]
 		}
 	}
+	return max, nil
+}
+
+// MustMax is like Max but panics for no value.
+func MustMax[T constraints.Ordered](values ...T) T {
+	max, err := Max(values...)
+	if err != nil {
+		panic(err)
+	}
 	return max
 }
 
-func ceilSecondSignificantDigitToMultiplesOfTwoOrFive(v float64) float64 {
+var errNotFinite = errors.New("value must be finite")
+
+func ceilSecondSignificantDigitToMultiplesOfTwoOrFive(v float64) (float64, error) {
 	if v < 0 {
-		return -floorSecondSignificantDigitToMultiplesOfTwoOrFive(-v)
+		f, err := floorSecondSignificantDigitToMultiplesOfTwoOrFive(-v)
+		return -f, err
 	}
 
-	s := fmt.Sprintf("%.1e", v)
-	// s is like 4.6e+01
-	d1 := mustAtoi(s[0:1])
-	d2 := mustAtoi(s[2:3])
-	exp := mustAtoi(s[4:])
-	if v > mustParseFloat(s, float64BitSize) {
+	rounded, d1, d2, exp, err := splitTwoSignificantDigits(v)
+	if err != nil {
+		return 0, err
+	}
+	if v > rounded {
 		if d2 == 9 {
 			d1++
 			d2 = 0
@@ -552,21 +1732,20 @@ func ceilSecondSignificantDigitToMultiplesOfTwoOrFive(v float64) float64 {
 		d1++
 		d2 = 0
 	}
-	s2 := fmt.Sprintf("%d.%de%d", d1, d2, exp)
-	return mustParseFloat(s2, float64BitSize)
+	return joinTwoSignificantDigits(d1, d2, exp)
 }
 
-func floorSecondSignificantDigitToMultiplesOfTwoOrFive(v float64) float64 {
+func floorSecondSignificantDigitToMultiplesOfTwoOrFive(v float64) (float64, error) {
 	if v < 0 {
-		return -ceilSecondSignificantDigitToMultiplesOfTwoOrFive(-v)
+		c, err := ceilSecondSignificantDigitToMultiplesOfTwoOrFive(-v)
+		return -c, err
 	}
 
-	s := fmt.Sprintf("%.1e", v)
-	// s is like 4.6e+01
-	d1 := mustAtoi(s[0:1])
-	d2 := mustAtoi(s[2:3])
-	exp := mustAtoi(s[4:])
-	if v < mustParseFloat(s, float64BitSize) {
+	rounded, d1, d2, exp, err := splitTwoSignificantDigits(v)
+	if err != nil {
+		return 0, err
+	}
+	if v < rounded {
 		if d2 == 0 {
 			d1--
 			d2 = 9
@@ -578,22 +1757,38 @@ func floorSecondSignificantDigitToMultiplesOfTwoOrFive(v float64) float64 {
 	case 1, 3, 7, 9:
 		d2--
 	}
-	s2 := fmt.Sprintf("%d.%de%d", d1, d2, exp)
-	return mustParseFloat(s2, float64BitSize)
+	return joinTwoSignificantDigits(d1, d2, exp)
 }
 
-func mustAtoi(s string) int {
-	i, err := strconv.Atoi(s)
+// splitTwoSignificantDigits rounds v to two significant digits and returns
+// the rounded value, the first digit, the second digit and the exponent.
+func splitTwoSignificantDigits(v float64) (rounded float64, d1, d2, exp int, err error) {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0, 0, 0, 0, errNotFinite
+	}
+
+	s := strconv.FormatFloat(v, 'e', 1, float64BitSize)
+	// s is like 4.6e+01
+	if len(s) < len("0.0e+00") || s[1] != '.' || s[3] != 'e' {
+		return 0, 0, 0, 0, fmt.Errorf("unexpected scientific notation: %q", s)
+	}
+	d1 = int(s[0] - '0')
+	d2 = int(s[2] - '0')
+	exp, err = strconv.Atoi(s[4:])
 	if err != nil {
-		panic("expected integer string")
+		return 0, 0, 0, 0, fmt.Errorf("unexpected exponent in scientific notation: %q", s)
 	}
-	return i
+	// Rounding up near math.MaxFloat64 overflows. ParseFloat returns +Inf
+	// with an error then, which still compares correctly with v.
+	rounded, _ = strconv.ParseFloat(s, float64BitSize)
+	return rounded, d1, d2, exp, nil
 }
 
-func mustParseFloat(s string, bitSize int) float64 {
-	f, err := strconv.ParseFloat(s, bitSize)
+func joinTwoSignificantDigits(d1, d2, exp int) (float64, error) {
+	s := fmt.Sprintf("%d.%de%d", d1, d2, exp)
+	f, err := strconv.ParseFloat(s, float64BitSize)
 	if err != nil {
-		panic("failed to parse float value")
+		return 0, fmt.Errorf("rounded value out of range: %s", s)
 	}
-	return f
+	return f, nil
 }
@@ -1,9 +1,18 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"math"
 	"math/rand"
+	"os"
+	"path/filepath"
 	"sort"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -84,6 +93,396 @@ func TestHistogram_AddValue(t *testing.T) {
 	}
 }
 
+func TestHistogram_AddFromReader(t *testing.T) {
+	parse := func(b []byte) (float64, error) {
+		return strconv.ParseFloat(string(b), 64)
+	}
+	h := NewHistogram(BuildRangePoints[float64](2, 0, 4))
+	n, err := h.AddFromReader(strings.NewReader("0.5\n1\n3\n7\n"), parse)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := n, 4; got != want {
+		t.Errorf("value count mismatch, got=%d, want=%d", got, want)
+	}
+	if got, want := h.Counts(), []int{2, 1}; !slices.Equal(got, want) {
+		t.Errorf("counts mismatch, got=%v, want=%v", got, want)
+	}
+
+	n, err = h.AddFromReader(strings.NewReader("2\nx\n3\n"), parse)
+	if err == nil || !strings.HasPrefix(err.Error(), "line 2: ") {
+		t.Errorf("error mismatch, got=%v", err)
+	}
+	if got, want := n, 1; got != want {
+		t.Errorf("value count mismatch after error, got=%d, want=%d", got, want)
+	}
+	if got, want := h.Counts(), []int{2, 2}; !slices.Equal(got, want) {
+		t.Errorf("counts mismatch after error, got=%v, want=%v", got, want)
+	}
+}
+
+func TestHistogram_SetInclusiveMax(t *testing.T) {
+	testCases := []struct {
+		closure        Closure
+		inclusive      bool
+		want           []int
+		wantOutOfRange int
+	}{
+		{closure: ClosureLeft, inclusive: false, want: []int{1, 0, 0, 0, 1}, wantOutOfRange: 2},
+		{closure: ClosureLeft, inclusive: true, want: []int{1, 0, 0, 0, 2}, wantOutOfRange: 1},
+		{closure: ClosureRight, inclusive: true, want: []int{0, 0, 0, 0, 2}, wantOutOfRange: 2},
+	}
+	for _, tc := range testCases {
+		h := NewHistogram(BuildRangePoints[float64](5, 0, 5))
+		h.SetClosure(tc.closure)
+		h.SetInclusiveMax(tc.inclusive)
+		h.AddValues([]float64{0, 4.5, 5, 5.5})
+		if got := h.Counts(); !slices.Equal(got, tc.want) || h.outOfRangeCount != tc.wantOutOfRange {
+			t.Errorf("counts mismatch, testCase=%+v, got=%v, outOfRange=%d", tc, got, h.outOfRangeCount)
+		}
+	}
+}
+
+func TestHistogram_UnderflowOverflowCount(t *testing.T) {
+	testCases := []struct {
+		closure       Closure
+		wantUnderflow int
+		wantOverflow  int
+	}{
+		{closure: ClosureLeft, wantUnderflow: 1, wantOverflow: 3},
+		{closure: ClosureRight, wantUnderflow: 2, wantOverflow: 2},
+	}
+	for _, tc := range testCases {
+		h := NewHistogram(BuildRangePoints[float64](2, 0, 2))
+		h.SetClosure(tc.closure)
+		h.AddValues([]float64{-1, 0, 1, 2, 3, 4, math.NaN()})
+		if got, want := h.UnderflowCount(), tc.wantUnderflow; got != want {
+			t.Errorf("underflow count mismatch, closure=%s, got=%d, want=%d", tc.closure, got, want)
+		}
+		if got, want := h.OverflowCount(), tc.wantOverflow; got != want {
+			t.Errorf("overflow count mismatch, closure=%s, got=%d, want=%d", tc.closure, got, want)
+		}
+		if got, want := h.OutOfRangeCount(), tc.wantUnderflow+tc.wantOverflow+1; got != want {
+			t.Errorf("out of range count mismatch, closure=%s, got=%d, want=%d", tc.closure, got, want)
+		}
+	}
+}
+
+func TestHistogram_SetBoundaryEpsilon(t *testing.T) {
+	testCases := []struct {
+		epsilon float64
+		input   float64
+		want    []int
+	}{
+		{epsilon: 0, input: 0.3 - 1e-15, want: []int{0, 0, 1, 0}},
+		{epsilon: 1e-9, input: 0.3 - 1e-15, want: []int{0, 0, 0, 1}},
+		{epsilon: 1e-9, input: 0.3 + 1e-15, want: []int{0, 0, 0, 1}},
+		{epsilon: 1e-9, input: -1e-17, want: []int{1, 0, 0, 0}},
+		{epsilon: 1e-9, input: 0.25, want: []int{0, 0, 1, 0}},
+		{epsilon: 0.1, input: 0.295, want: []int{0, 0, 0, 1}},
+		{epsilon: 0.1, input: 0.285, want: []int{0, 0, 1, 0}},
+	}
+	for _, tc := range testCases {
+		h := NewHistogram([]float64{0, 0.1, 0.2, 0.3, 0.4})
+		h.SetBoundaryEpsilon(tc.epsilon)
+		h.AddValue(tc.input)
+		if got := h.Counts(); !slices.Equal(got, tc.want) {
+			t.Errorf("counts mismatch, testCase=%+v, got=%v, want=%v", tc, got, tc.want)
+		}
+	}
+}
+
+func TestBuildLogRangePoints(t *testing.T) {
+	testCases := []struct {
+		count    int
+		min, max float64
+		want     []float64
+	}{
+		{count: 3, min: 1, max: 1000, want: []float64{1, 10, 100, 1000}},
+		{count: 2, min: 0.001, max: 0.1, want: []float64{0.001, 0.01, 0.1}},
+		{count: 4, min: 1, max: 16, want: []float64{1, 2, 4, 8, 16}},
+	}
+	for _, tc := range testCases {
+		got, err := BuildLogRangePoints(tc.count, tc.min, tc.max)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != len(tc.want) {
+			t.Fatalf("length mismatch, count=%d, got=%v, want=%v", tc.count, got, tc.want)
+		}
+		for i := range got {
+			if math.Abs(got[i]-tc.want[i]) > 1e-9*tc.want[i] {
+				t.Errorf("result mismatch, count=%d, min=%g, max=%g, got=%v, want=%v", tc.count, tc.min, tc.max, got, tc.want)
+				break
+			}
+		}
+		if got[0] != tc.min || got[tc.count] != tc.max {
+			t.Errorf("ends mismatch, got=%v, want min=%g, max=%g", got, tc.min, tc.max)
+		}
+	}
+
+	// Like log scale data containing 0 or a single value.
+	for _, r := range [][2]float64{{0, 10}, {-1, 10}, {5, 5}, {10, 1}} {
+		if _, err := BuildLogRangePoints(3, r[0], r[1]); err == nil {
+			t.Errorf("error expected, min=%g, max=%g", r[0], r[1])
+		}
+	}
+}
+
+// TestRunAxisRangeErrors runs the command with axis ranges which cannot be
+// built, which must fail with an error instead of a panic.
+func TestRunAxisRangeErrors(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "values.txt")
+	if err := os.WriteFile(filename, []byte("1\n2\n10\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	testCases := []struct {
+		args []string
+		want string
+	}{
+		{args: []string{"-x", "0.5", filename}, want: "--axis-max 0.5 must be greater than the axis min value 1 decided from values"},
+		{args: []string{"-n", "20", filename}, want: "--axis-min 20 must be less than the axis max value 10 decided from values"},
+		{args: []string{"--backend", "tdigest", "-x", "0.5", filename}, want: "--axis-max 0.5 must be greater than"},
+		{args: []string{"--scale", "log", "-x", "0.5", filename}, want: "--axis-max 0.5 must be greater than"},
+		{args: []string{"--scale", "log", "--nice-edges", "-n", "20", filename}, want: "--axis-min 20 must be less than"},
+		{args: []string{"--scale", "log", "--backend", "hdr", "-x", "0.5", filename}, want: "--axis-max 0.5 must be greater than"},
+	}
+	for _, tc := range testCases {
+		cfg, err := ParseConfig(tc.args)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = run(context.Background(), io.Discard, cfg)
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("error mismatch, args=%q, got=%v, want=%s", tc.args, err, tc.want)
+		}
+	}
+}
+
+func TestHistogram_Quantile(t *testing.T) {
+	h := NewHistogram(BuildRangePoints[float64](4, 0, 40))
+	h.AddValues([]float64{5, 15, 15, 25, 25, 25, 25, 35, 100})
+	testCases := []struct {
+		input float64
+		want  float64
+	}{
+		{input: 0, want: 0},
+		{input: 0.125, want: 10},
+		{input: 0.25, want: 15},
+		{input: 0.5, want: 22.5},
+		{input: 0.75, want: 27.5},
+		{input: 0.875, want: 30},
+		{input: 1, want: 40},
+	}
+	for _, tc := range testCases {
+		got := h.Quantile(tc.input)
+		if got != tc.want {
+			t.Errorf("result mismatch, input=%g, got=%g, want=%g", tc.input, got, tc.want)
+		}
+	}
+
+	empty := NewHistogram(BuildRangePoints[float64](4, 0, 40))
+	if got := empty.Quantile(0.5); !math.IsNaN(got) {
+		t.Errorf("result mismatch for empty histogram, got=%g, want=NaN", got)
+	}
+}
+
+func TestHistogram_Merge(t *testing.T) {
+	h1 := NewHistogram(BuildRangePoints[float64](5, 0, 5))
+	h1.AddValues([]float64{0, 1.5, 4, 7})
+	h2 := NewHistogram(BuildRangePoints[float64](5, 0, 5))
+	h2.AddValues([]float64{1, 4.5, -1})
+	if err := h1.Merge(h2); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := h1.Counts(), []int{1, 2, 0, 0, 2}; !slices.Equal(got, want) {
+		t.Errorf("counts mismatch, got=%v, want=%v", got, want)
+	}
+	if got, want := h1.outOfRangeCount, 2; got != want {
+		t.Errorf("out of range count mismatch, got=%d, want=%d", got, want)
+	}
+
+	h3 := NewHistogram(BuildRangePoints[float64](4, 0, 5))
+	h3.AddValue(1)
+	if err := h1.Merge(h3); !errors.Is(err, ErrRangePointsMismatch) {
+		t.Errorf("error mismatch, got=%v, want=%v", err, ErrRangePointsMismatch)
+	}
+	if got, want := h1.Counts(), []int{1, 2, 0, 0, 2}; !slices.Equal(got, want) {
+		t.Errorf("counts must be unchanged on error, got=%v, want=%v", got, want)
+	}
+
+	h4 := NewHistogram(BuildRangePoints[float64](5, 0, 5))
+	h4.SetInclusiveMax(true)
+	if err := h1.Merge(h4); !errors.Is(err, ErrInclusiveMaxMismatch) {
+		t.Errorf("error mismatch, got=%v, want=%v", err, ErrInclusiveMaxMismatch)
+	}
+	h5 := NewHistogram(BuildRangePoints[float64](5, 0, 5))
+	h5.SetBoundaryEpsilon(1e-9)
+	if err := h1.Merge(h5); !errors.Is(err, ErrBoundaryEpsilonMismatch) {
+		t.Errorf("error mismatch, got=%v, want=%v", err, ErrBoundaryEpsilonMismatch)
+	}
+}
+
+func TestHistogram_Percentages(t *testing.T) {
+	h := NewHistogram(BuildRangePoints[float64](4, 0, 4))
+	if got, want := h.Percentages(), []float64{0, 0, 0, 0}; !slices.Equal(got, want) {
+		t.Errorf("result mismatch for empty histogram, got=%v, want=%v", got, want)
+	}
+
+	h.AddValues([]float64{0, 1, 1, 3, 3, 3, 3, 5})
+	if got, want := h.Percentages(), []float64{12.5, 25, 0, 50}; !slices.Equal(got, want) {
+		t.Errorf("result mismatch, got=%v, want=%v", got, want)
+	}
+}
+
+func TestHistogram_Coarsen(t *testing.T) {
+	fine := NewHistogram(BuildRangePoints[float64](6, 0, 6))
+	fine.AddValues([]float64{0, 1, 1, 2, 3, 4, 5, 5, 5, 7})
+
+	coarse := fine.Coarsen(3)
+	if got, want := coarse.RangePoints(), []float64{0, 3, 6}; !slices.Equal(got, want) {
+		t.Errorf("range points mismatch, got=%v, want=%v", got, want)
+	}
+	if got, want := coarse.Counts(), []int{4, 5}; !slices.Equal(got, want) {
+		t.Errorf("counts mismatch, got=%v, want=%v", got, want)
+	}
+	if got, want := coarse.outOfRangeCount, 1; got != want {
+		t.Errorf("out of range count mismatch, got=%d, want=%d", got, want)
+	}
+	if got, want := fine.Coarsen(1), fine; !got.Equal(want) {
+		t.Errorf("factor 1 must keep buckets, got=%v, want=%v", got.Counts(), want.Counts())
+	}
+
+	ragged := fine.Coarsen(4)
+	if got, want := ragged.RangePoints(), []float64{0, 4, 6}; !slices.Equal(got, want) {
+		t.Errorf("range points mismatch for the rest, got=%v, want=%v", got, want)
+	}
+	if got, want := ragged.Counts(), []int{5, 4}; !slices.Equal(got, want) {
+		t.Errorf("counts mismatch for the rest, got=%v, want=%v", got, want)
+	}
+}
+
+func TestHistogram_AddValueProperty(t *testing.T) {
+	rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
+	const n = 1000
+	for i := 0; i < n; i++ {
+		min := 100 * (rnd.Float64() - 0.5)
+		max := min + 100*rnd.Float64()
+		count := 1 + rnd.Intn(50)
+		h := NewHistogram(BuildRangePoints(count, min, max))
+		values := make([]float64, rnd.Intn(100))
+		for j := range values {
+			// Some values are outside of [min, max) and some are on range points.
+			if rnd.Intn(10) == 0 {
+				values[j] = h.rangePoints[rnd.Intn(len(h.rangePoints))]
+			} else {
+				values[j] = min - 10 + (max-min+20)*rnd.Float64()
+			}
+		}
+
+		for _, v := range values {
+			before := h.Counts()
+			h.AddValue(v)
+			after := h.Counts()
+			for k := range after {
+				if after[k] == before[k] {
+					continue
+				}
+				if !(h.rangePoints[k] <= v && v < h.rangePoints[k+1]) {
+					t.Errorf("value counted in wrong bucket, value=%g, bucket=[%g, %g)", v, h.rangePoints[k], h.rangePoints[k+1])
+				}
+			}
+		}
+
+		total := h.outOfRangeCount
+		for _, c := range h.counts {
+			total += c
+		}
+		if total != len(values) {
+			t.Errorf("total count mismatch, rangePoints=%v, values=%v, got=%d, want=%d", h.rangePoints, values, total, len(values))
+		}
+	}
+}
+
+func TestBuildRangePointsMonotonic(t *testing.T) {
+	testCases := []struct {
+		count    int
+		min, max float64
+	}{
+		{count: 10, min: -math.MaxFloat64, max: math.MaxFloat64},
+		{count: 3, min: -math.MaxFloat64, max: 0},
+		{count: 7, min: 1, max: math.Nextafter(1, 2)},
+		{count: 10, min: 5e-324, max: 1e-323},
+		{count: 100, min: -1e300, max: 1e308},
+	}
+	rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
+	for i := 0; i < 1000; i++ {
+		min := math.Ldexp(rnd.Float64()-0.5, rnd.Intn(2000)-1000)
+		max := min + math.Ldexp(rnd.Float64(), rnd.Intn(2000)-1000)
+		testCases = append(testCases, struct {
+			count    int
+			min, max float64
+		}{count: 1 + rnd.Intn(100), min: min, max: max})
+	}
+	for _, tc := range testCases {
+		got := BuildRangePoints(tc.count, tc.min, tc.max)
+		for j := 1; j < len(got); j++ {
+			if !(got[j-1] <= got[j]) {
+				t.Errorf("range points must not decrease, count=%d, min=%g, max=%g, got=%v", tc.count, tc.min, tc.max, got)
+				break
+			}
+		}
+		if got[0] != tc.min || got[len(got)-1] != tc.max {
+			t.Errorf("range point ends must be exactly min and max, count=%d, min=%g, max=%g, got=%v", tc.count, tc.min, tc.max, got)
+		}
+	}
+}
+
+func TestBuildRangePoints(t *testing.T) {
+	testCases := []struct {
+		count    int
+		min, max float64
+		want     []float64
+	}{
+		{count: 5, min: 0, max: 5, want: []float64{0, 1, 2, 3, 4, 5}},
+		{count: 3, min: 0.1, max: 0.7, want: []float64{0.1, 0.3, 0.5, 0.7}},
+		{count: 4, min: -1, max: 1, want: []float64{-1, -0.5, 0, 0.5, 1}},
+		{count: 2, min: -math.MaxFloat64, max: math.MaxFloat64, want: []float64{-math.MaxFloat64, 0, math.MaxFloat64}},
+	}
+	for _, tc := range testCases {
+		got := BuildRangePoints(tc.count, tc.min, tc.max)
+		if !slices.Equal(got, tc.want) {
+			t.Errorf("result mismatch, count=%d, min=%g, max=%g, got=%v, want=%v", tc.count, tc.min, tc.max, got, tc.want)
+		}
+	}
+}
+
+func TestStreamingBinner(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "values.txt")
+	if err := os.WriteFile(filename, []byte("0.5\n1.5\n1.7\n4.2\n9\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := Config{
+		BucketCount: 5,
+		AxisMin:     axisRangeEnd{Value: 0},
+		AxisMax:     axisRangeEnd{Value: 5},
+		Scale:       scaleLinear,
+	}
+	sources := []Source{&fileSource{filename: filename, format: plainInputFormat{}, parse: parseFiniteFloat}}
+
+	got, err := (&streamingBinner{cfg: cfg}).Bin(sources)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := (&memoryBinner{cfg: cfg}).Bin(sources)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got[0].Equal(want[0]) || got[0].outOfRangeCount != want[0].outOfRangeCount {
+		t.Errorf("histogram mismatch, got=%+v, want=%+v", got[0], want[0])
+	}
+}
+
 func TestHistogramFormatter(t *testing.T) {
 	t.Run("case1", func(t *testing.T) {
 		histogram := NewHistogram(BuildRangePoints[float64](10, 0, 10))
@@ -93,7 +492,7 @@ func TestHistogramFormatter(t *testing.T) {
 			}
 		}
 
-		formatter := NewHistogramFormatter(histogram, defaultBarChar, 40, "%.2f")
+		formatter := MustNewHistogramFormatter(histogram, WithBarChar(defaultBarChar), WithWidth(40), WithPointFormat("%.2f"))
 		got := formatter.String()
 		want := ` 0.00 ~  1.00   0 |
  1.00 ~  2.00   2 |**
@@ -105,6 +504,128 @@ func TestHistogramFormatter(t *testing.T) {
  7.00 ~  8.00  14 |****************
  8.00 ~  9.00  16 |******************
  9.00 ~ 10.00  18 |*********************
+ out of range   0 |
+`
+		if got != want {
+			t.Errorf("result mismatch,\n got=%q,\nwant=%q", got, want)
+			fmt.Printf("\n%s", got)
+		}
+	})
+	t.Run("minCount", func(t *testing.T) {
+		histogram := NewHistogram(BuildRangePoints[float64](5, 0, 5))
+		histogram.AddValues([]float64{0, 1, 1, 1, 2, 3, 3, 3, 3, 4, 8})
+
+		formatter := MustNewHistogramFormatter(histogram, WithBarChar(defaultBarChar), WithWidth(40), WithPointFormat("%.2f"))
+		formatter.SetMinCount(2)
+		got := formatter.String()
+		want := ` 1.00 ~ 2.00  3 |*****************
+ 3.00 ~ 4.00  4 |***********************
+  rare (< 2)  3 |
+out of range  1 |
+`
+		if got != want {
+			t.Errorf("result mismatch,\n got=%q,\nwant=%q", got, want)
+			fmt.Printf("\n%s", got)
+		}
+	})
+	t.Run("duration", func(t *testing.T) {
+		histogram := NewHistogram(BuildRangePoints[float64](3, 0, 3))
+		histogram.AddValues([]float64{0, 1, 1, 1, 1, 2, 2, 5})
+
+		formatter := MustNewHistogramFormatter(histogram, WithBarChar(defaultBarChar), WithWidth(40), WithPointFormat("%.2f"))
+		formatter.SetDuration(2 * time.Second)
+		got := formatter.String()
+		want := ` 0.00 ~ 1.00  1 0.5/s |****
+ 1.00 ~ 2.00  4   2/s |*****************
+ 2.00 ~ 3.00  2   1/s |********
+out of range  1 0.5/s |
+`
+		if got != want {
+			t.Errorf("result mismatch,\n got=%q,\nwant=%q", got, want)
+			fmt.Printf("\n%s", got)
+		}
+	})
+	t.Run("cumulative", func(t *testing.T) {
+		histogram := NewHistogram(BuildRangePoints[float64](4, 0, 4))
+		histogram.AddValues([]float64{0, 1, 1, 1, 1, 2, 3, 3, 5})
+
+		formatter := MustNewHistogramFormatter(histogram, WithBarChar(defaultBarChar), WithWidth(40), WithPointFormat("%.2f"))
+		formatter.SetMinCount(2)
+		formatter.SetCumulative(true)
+		got := formatter.String()
+		want := ` 1.00 ~ 2.00  4  62.5% |****************
+ 3.00 ~ 4.00  2 100.0% |********
+  rare (< 2)  2        |
+out of range  1        |
+`
+		if got != want {
+			t.Errorf("result mismatch,\n got=%q,\nwant=%q", got, want)
+			fmt.Printf("\n%s", got)
+		}
+	})
+	t.Run("percent", func(t *testing.T) {
+		histogram := NewHistogram(BuildRangePoints[float64](4, 0, 4))
+		histogram.AddValues([]float64{0, 1, 1, 1, 1, 2, 3, 3, 5})
+
+		formatter := MustNewHistogramFormatter(histogram, WithBarChar(defaultBarChar), WithWidth(40), WithPointFormat("%.2f"))
+		formatter.SetMinCount(2)
+		formatter.SetPercent(true)
+		got := formatter.String()
+		want := ` 1.00 ~ 2.00  4 44.4% |*****************
+ 3.00 ~ 4.00  2 22.2% |********
+  rare (< 2)  2 22.2% |
+out of range  1 11.1% |
+`
+		if got != want {
+			t.Errorf("result mismatch,\n got=%q,\nwant=%q", got, want)
+			fmt.Printf("\n%s", got)
+		}
+	})
+	t.Run("cdfBar", func(t *testing.T) {
+		histogram := NewHistogram(BuildRangePoints[float64](4, 0, 4))
+		histogram.AddValues([]float64{0, 1, 1, 1, 1, 2, 3, 3, 5})
+
+		formatter := MustNewHistogramFormatter(histogram, WithBarChar(defaultBarChar), WithWidth(60), WithPointFormat("%.2f"))
+		formatter.SetMinCount(2)
+		formatter.SetCDFBar(true)
+		got := formatter.String()
+		want := ` 1.00 ~ 2.00  4 |********************* |============
+ 3.00 ~ 4.00  2 |**********            |====================
+  rare (< 2)  2 |
+out of range  1 |
+`
+		if got != want {
+			t.Errorf("result mismatch,\n got=%q,\nwant=%q", got, want)
+			fmt.Printf("\n%s", got)
+		}
+	})
+	t.Run("widths", func(t *testing.T) {
+		histogram := NewHistogram([]float64{0, 1, 3, 10})
+		histogram.AddValues([]float64{0, 0.5, 1, 2, 5, 12})
+
+		formatter := MustNewHistogramFormatter(histogram, WithBarChar(defaultBarChar), WithWidth(60), WithPointFormat("%.2f"))
+		formatter.SetWidths(true)
+		got := formatter.String()
+		want := ` 0.00 ~  1.00 (width 1.00)  2 |*****************************
+ 1.00 ~  3.00 (width 2.00)  2 |*****************************
+ 3.00 ~ 10.00 (width 7.00)  1 |**************
+              out of range  1 |
+`
+		if got != want {
+			t.Errorf("result mismatch,\n got=%q,\nwant=%q", got, want)
+			fmt.Printf("\n%s", got)
+		}
+	})
+	t.Run("multiByteBarChar", func(t *testing.T) {
+		histogram := NewHistogram(BuildRangePoints[float64](3, 0, 3))
+		histogram.AddValues([]float64{0, 1, 1, 2, 2, 2, 2})
+
+		formatter := MustNewHistogramFormatter(histogram, WithBarChar("█"), WithWidth(40), WithPointFormat("%.2f"))
+		got := formatter.String()
+		want := ` 0.00 ~ 1.00  1 |█████
+ 1.00 ~ 2.00  2 |███████████
+ 2.00 ~ 3.00  4 |███████████████████████
+out of range  0 |
 `
 		if got != want {
 			t.Errorf("result mismatch,\n got=%q,\nwant=%q", got, want)
@@ -114,7 +635,7 @@ func TestHistogramFormatter(t *testing.T) {
 	t.Run("allZero", func(t *testing.T) {
 		histogram := NewHistogram(BuildRangePoints[float64](10, 0, 10))
 
-		formatter := NewHistogramFormatter(histogram, defaultBarChar, 40, "%.2f")
+		formatter := MustNewHistogramFormatter(histogram, WithBarChar(defaultBarChar), WithWidth(40), WithPointFormat("%.2f"))
 		got := formatter.String()
 		want := ` 0.00 ~  1.00  0 |
  1.00 ~  2.00  0 |
@@ -126,6 +647,7 @@ func TestHistogramFormatter(t *testing.T) {
  7.00 ~  8.00  0 |
  8.00 ~  9.00  0 |
  9.00 ~ 10.00  0 |
+ out of range  0 |
 `
 		if got != want {
 			t.Errorf("result mismatch,\n got=%q,\nwant=%q", got, want)
@@ -134,6 +656,65 @@ func TestHistogramFormatter(t *testing.T) {
 	})
 }
 
+func TestDecideAxisRange(t *testing.T) {
+	auto := axisRangeEnd{Auto: true}
+	testCases := []struct {
+		name             string
+		axisMin, axisMax axisRangeEnd
+		dataMin, dataMax float64
+		includeZero      bool
+		wantMin, wantMax float64
+	}{
+		{name: "positive", axisMin: auto, axisMax: auto, dataMin: 1.1, dataMax: 9.3, wantMin: 1, wantMax: 9.4},
+		{name: "negative", axisMin: auto, axisMax: auto, dataMin: -9.3, dataMax: -1.1, wantMin: -9.4, wantMax: -1},
+		{name: "negativeSmall", axisMin: auto, axisMax: auto, dataMin: -0.0123, dataMax: -0.0021, wantMin: -0.014, wantMax: -0.002},
+		{name: "mixed", axisMin: auto, axisMax: auto, dataMin: -3.3, dataMax: 7.7, wantMin: -3.4, wantMax: 7.8},
+		{name: "positiveIncludeZero", axisMin: auto, axisMax: auto, dataMin: 1.1, dataMax: 9.3, includeZero: true, wantMin: 0, wantMax: 9.4},
+		{name: "negativeIncludeZero", axisMin: auto, axisMax: auto, dataMin: -9.3, dataMax: -1.1, includeZero: true, wantMin: -9.4, wantMax: 0},
+		{name: "mixedIncludeZero", axisMin: auto, axisMax: auto, dataMin: -3.3, dataMax: 7.7, includeZero: true, wantMin: -3.6, wantMax: 8.4},
+		{name: "mixedIncludeZeroExplicitMin", axisMin: axisRangeEnd{Value: -3.3}, axisMax: auto, dataMin: -3.3, dataMax: 7.7, includeZero: true, wantMin: -3.3, wantMax: 7.8},
+		{name: "constant", axisMin: auto, axisMax: auto, dataMin: 5, dataMax: 5, wantMin: 4.5, wantMax: 5.5},
+		{name: "constantExplicitMin", axisMin: axisRangeEnd{Value: 5}, axisMax: auto, dataMin: 5, dataMax: 5, wantMin: 5, wantMax: 5.5},
+		{name: "constantZeroIncludeZero", axisMin: auto, axisMax: auto, dataMin: 0, dataMax: 0, includeZero: true, wantMin: -0.5, wantMax: 0.5},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			gotMin, gotMax, err := decideAxisRange(tc.axisMin, tc.axisMax, tc.dataMin, tc.dataMax, 10, tc.includeZero)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if gotMin != tc.wantMin || gotMax != tc.wantMax {
+				t.Errorf("result mismatch, got=[%g, %g], want=[%g, %g]", gotMin, gotMax, tc.wantMin, tc.wantMax)
+			}
+			if tc.includeZero && tc.axisMin.Auto && tc.axisMax.Auto {
+				points := BuildRangePoints(10, gotMin, gotMax)
+				if !slices.Contains(points, 0) {
+					t.Errorf("zero must be a range point, got=%v", points)
+				}
+			}
+		})
+	}
+}
+
+func TestConstantDataRange(t *testing.T) {
+	testCases := []struct {
+		input            float64
+		wantMin, wantMax float64
+	}{
+		{input: 5, wantMin: 4.5, wantMax: 5.5},
+		{input: 200, wantMin: 150, wantMax: 250},
+		{input: -30, wantMin: -35, wantMax: -25},
+		{input: 0.002, wantMin: 0.0015, wantMax: 0.0025},
+		{input: 0, wantMin: -0.5, wantMax: 0.5},
+	}
+	for _, tc := range testCases {
+		gotMin, gotMax := ConstantDataRange(tc.input)
+		if math.Abs(gotMin-tc.wantMin) > 1e-15 || math.Abs(gotMax-tc.wantMax) > 1e-15 {
+			t.Errorf("result mismatch, input=%g, got=[%g, %g], want=[%g, %g]", tc.input, gotMin, gotMax, tc.wantMin, tc.wantMax)
+		}
+	}
+}
+
 func TestCeilSecondSignificantDigitToMultiplesOfTwoOrFive(t *testing.T) {
 	testCases := []struct {
 		input float64
@@ -164,7 +745,10 @@ func TestCeilSecondSignificantDigitToMultiplesOfTwoOrFive(t *testing.T) {
 		{input: -1.3, want: -1.2},
 	}
 	for _, tc := range testCases {
-		got := ceilSecondSignificantDigitToMultiplesOfTwoOrFive(tc.input)
+		got, err := ceilSecondSignificantDigitToMultiplesOfTwoOrFive(tc.input)
+		if err != nil {
+			t.Fatalf("unexpected error, input=%g, err=%v", tc.input, err)
+		}
 		if got != tc.want {
 			t.Errorf("result mismatch, input=%g, got=%g, want=%g", tc.input, got, tc.want)
 		}
@@ -176,7 +760,10 @@ func TestCeilSecondSignificantDigitToMultiplesOfTwoOrFiveProperty(t *testing.T)
 	const n = 100000
 	for i := 0; i < n; i++ {
 		v := 10 * (rnd.Float64() - 0.5)
-		v2 := ceilSecondSignificantDigitToMultiplesOfTwoOrFive(v)
+		v2, err := ceilSecondSignificantDigitToMultiplesOfTwoOrFive(v)
+		if err != nil {
+			t.Fatalf("unexpected error, input=%g, err=%v", v, err)
+		}
 		if v2 < v {
 			t.Errorf("ceilSecondSignificantDigitToMultiplesOfTwoOrFive output must not be smaller than input, input=%g, output=%g", v, v2)
 		}
@@ -187,7 +774,7 @@ func BenchmarkCeilSecondSignificantDigitToMultiplesOfTwoOrFive(b *testing.B) {
 	rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
 	for i := 0; i < b.N; i++ {
 		v := rnd.Float64()
-		_ = ceilSecondSignificantDigitToMultiplesOfTwoOrFive(v)
+		_, _ = ceilSecondSignificantDigitToMultiplesOfTwoOrFive(v)
 	}
 }
 
@@ -225,7 +812,10 @@ func TestFloorSecondSignificantDigitToMultiplesOfTwoOrFive(t *testing.T) {
 		{input: -1.3, want: -1.4},
 	}
 	for _, tc := range testCases {
-		got := floorSecondSignificantDigitToMultiplesOfTwoOrFive(tc.input)
+		got, err := floorSecondSignificantDigitToMultiplesOfTwoOrFive(tc.input)
+		if err != nil {
+			t.Fatalf("unexpected error, input=%g, err=%v", tc.input, err)
+		}
 		if got != tc.want {
 			t.Errorf("result mismatch, input=%g, got=%g, want=%g", tc.input, got, tc.want)
 		}
@@ -237,9 +827,217 @@ func TestFloorSecondSignificantDigitToMultiplesOfTwoOrFiveProperty(t *testing.T)
 	const n = 100000
 	for i := 0; i < n; i++ {
 		v := 10 * (rnd.Float64() - 0.5)
-		v2 := floorSecondSignificantDigitToMultiplesOfTwoOrFive(v)
+		v2, err := floorSecondSignificantDigitToMultiplesOfTwoOrFive(v)
+		if err != nil {
+			t.Fatalf("unexpected error, input=%g, err=%v", v, err)
+		}
 		if v2 > v {
 			t.Errorf("floorSecondSignificantDigitToMultiplesOfTwoOrFive output must not be greater than input, input=%g, output=%g", v, v2)
 		}
 	}
 }
+
+func FuzzCeilSecondSignificantDigitToMultiplesOfTwoOrFive(f *testing.F) {
+	for _, v := range []float64{0, 1.41, -1.3, 0.99, 9.9, 5e-324, math.MaxFloat64, -math.MaxFloat64, math.Inf(1), math.NaN()} {
+		f.Add(v)
+	}
+	f.Fuzz(func(t *testing.T, v float64) {
+		got, err := ceilSecondSignificantDigitToMultiplesOfTwoOrFive(v)
+		if err != nil {
+			return
+		}
+		if got < v {
+			t.Errorf("output must not be smaller than input, input=%g, output=%g", v, got)
+		}
+	})
+}
+
+func FuzzFloorSecondSignificantDigitToMultiplesOfTwoOrFive(f *testing.F) {
+	for _, v := range []float64{0, 1.41, -1.3, 0.99, 9.9, 5e-324, math.MaxFloat64, -math.MaxFloat64, math.Inf(-1), math.NaN()} {
+		f.Add(v)
+	}
+	f.Fuzz(func(t *testing.T, v float64) {
+		got, err := floorSecondSignificantDigitToMultiplesOfTwoOrFive(v)
+		if err != nil {
+			return
+		}
+		if got > v {
+			t.Errorf("output must not be greater than input, input=%g, output=%g", v, got)
+		}
+	})
+}
+
+func FuzzParseAxisRangeEnd(f *testing.F) {
+	for _, s := range []string{"auto", "0", "-1.5", "1e308", "1e309", "NaN", "-Inf", "0x1p-2", "", "p99", "p0", "pNaN"} {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		got, err := parseAxisRangeEnd(s)
+		if err != nil {
+			return
+		}
+		if got.Auto {
+			if got.Percentile != 0 && !(got.Percentile > 0 && got.Percentile < 100) {
+				t.Errorf("percentile must be between 0 and 100, input=%q, got=%g", s, got.Percentile)
+			}
+			return
+		}
+		if math.IsNaN(got.Value) || math.IsInf(got.Value, 0) {
+			t.Errorf("value must be finite, input=%q, got=%g", s, got.Value)
+		}
+	})
+}
+
+func FuzzReadFloat64Values(f *testing.F) {
+	for _, s := range []string{"1\n2\n3\n", "1.5", "", "\n", "NaN\n", "1e999\n", "-0\n+Inf\n"} {
+		f.Add([]byte(s))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		values, err := readFloat64Values(bytes.NewReader(data), plainInputFormat{})
+		if err != nil {
+			return
+		}
+		for _, v := range values {
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				t.Errorf("value must be finite, input=%q, got=%g", data, v)
+			}
+		}
+	})
+}
+
+func TestNewHistogramFormatterErrors(t *testing.T) {
+	h := NewHistogram(BuildRangePoints[float64](2, 0, 2))
+	h2 := NewHistogram(BuildRangePoints[float64](2, 0, 4))
+	if _, err := NewHistogramFormatter(h, WithBarChar("")); err == nil {
+		t.Error("want error for empty barChar")
+	}
+	if _, err := NewHistogramFormatter(h, WithWidth(0)); err == nil {
+		t.Error("want error for zero graphWidth")
+	}
+	if _, err := NewMultipleHistogramFormatter(nil); err == nil {
+		t.Error("want error for no histogram")
+	}
+	if _, err := NewMultipleHistogramFormatter([]*Histogram[float64]{h, h2}); err == nil {
+		t.Error("want error for different range points")
+	}
+	if _, err := NewMultipleHistogramFormatter([]*Histogram[float64]{h, h}, WithLabels([]string{"a"})); err == nil {
+		t.Error("want error for labels of a wrong length")
+	}
+	f, err := NewMultipleHistogramFormatter([]*Histogram[float64]{h, h})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := f.SetLabels([]string{"a", "b", "c"}); err == nil {
+		t.Error("want error for SetLabels of a wrong length")
+	}
+	if err := f.SetLabels([]string{"a", "b"}); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+}
+
+func TestHistogramFormatterOptions(t *testing.T) {
+	h := NewHistogram(BuildRangePoints[float64](2, 0, 2))
+	h.AddValues([]float64{0, 1, 1})
+
+	// Options give the same result as setters.
+	withSetters := MustNewHistogramFormatter(h, WithBarChar("#"), WithWidth(60), WithPointFormat("%.1f"))
+	withSetters.SetPercent(true)
+	withSetters.SetCumulative(true)
+	withSetters.SetBarStyle(BarStyleBlocks)
+	want, err := withSetters.Format()
+	if err != nil {
+		t.Fatal(err)
+	}
+	withOptions := MustNewHistogramFormatter(h, WithBarChar("#"), WithWidth(60), WithPointFormat("%.1f"),
+		WithPercent(true), WithCumulative(true), WithBarStyle(BarStyleBlocks))
+	if got, err := withOptions.Format(); err != nil || got != want {
+		t.Errorf("result mismatch, err=%v,\n got=%s\nwant=%s", err, got, want)
+	}
+
+	f := MustNewHistogramFormatter(h)
+	if f.barChar != defaultBarChar || f.graphWidth != defaultGraphWidth || f.pointFmt != defaultPointFmt {
+		t.Errorf("defaults mismatch, got=%q, %d, %q", f.barChar, f.graphWidth, f.pointFmt)
+	}
+}
+
+func TestHistogramFormatter_WriteTo(t *testing.T) {
+	h := NewHistogram(BuildRangePoints[float64](4, 0, 4))
+	h.AddValues([]float64{0, 1, 1, 3.5, 9})
+
+	formatters := map[string]interface {
+		String() string
+		WriteTo(w io.Writer) (int64, error)
+	}{
+		"single":   MustNewHistogramFormatter(h),
+		"multiple": MustNewMultipleHistogramFormatter([]*Histogram[float64]{h, h}, WithLabels([]string{"a", "b"})),
+		"vertical": NewVerticalHistogramFormatter(h, WithHeight(4)),
+	}
+	for name, f := range formatters {
+		var buf bytes.Buffer
+		n, err := f.WriteTo(&buf)
+		if err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+		if got, want := buf.String(), f.String(); got != want {
+			t.Errorf("result mismatch, formatter=%s,\n got=%s\nwant=%s", name, got, want)
+		}
+		if got, want := n, int64(buf.Len()); got != want {
+			t.Errorf("byte count mismatch, formatter=%s, got=%d, want=%d", name, got, want)
+		}
+	}
+
+	var buf bytes.Buffer
+	if _, err := MustNewHistogramFormatter(h, WithWidth(20)).WriteTo(&buf); !errors.Is(err, errGraphWidthTooSmall) || buf.Len() != 0 {
+		t.Errorf("error mismatch, got=%v, written=%q", err, buf.String())
+	}
+}
+
+func TestHistogramFormatter_FormatGraphWidthTooSmall(t *testing.T) {
+	h := NewHistogram(BuildRangePoints[float64](2, 0, 2))
+	h.AddValues([]float64{0, 1, 1})
+
+	f := MustNewHistogramFormatter(h, WithBarChar("*"), WithWidth(20), WithPointFormat("%.2f"))
+	if _, err := f.Format(); !errors.Is(err, errGraphWidthTooSmall) {
+		t.Errorf("error mismatch, got=%v, want=%v", err, errGraphWidthTooSmall)
+	}
+	mf := MustNewMultipleHistogramFormatter([]*Histogram[float64]{h, h}, WithBarChar("*"), WithWidth(40), WithPointFormat("%.2f"))
+	if _, err := mf.Format(); !errors.Is(err, errGraphWidthTooSmall) {
+		t.Errorf("error mismatch, got=%v, want=%v", err, errGraphWidthTooSmall)
+	}
+}
+
+func TestMinMax(t *testing.T) {
+	if got, err := Min(3, 1, 2); err != nil || got != 1 {
+		t.Errorf("Min result mismatch, got=%d, err=%v, want=1", got, err)
+	}
+	if got, err := Max(3, 1, 2); err != nil || got != 3 {
+		t.Errorf("Max result mismatch, got=%d, err=%v, want=3", got, err)
+	}
+	if _, err := Min[int](); !errors.Is(err, errEmptyValues) {
+		t.Errorf("Min error mismatch, got=%v, want=%v", err, errEmptyValues)
+	}
+	if _, err := Max[float64](); !errors.Is(err, errEmptyValues) {
+		t.Errorf("Max error mismatch, got=%v, want=%v", err, errEmptyValues)
+	}
+}
+
+func TestBlockBar(t *testing.T) {
+	testCases := []struct {
+		width     float64
+		want      string
+		wantCells int
+	}{
+		{width: 0, want: "", wantCells: 0},
+		{width: 0.1, want: "", wantCells: 0},
+		{width: 0.125, want: "▏", wantCells: 1},
+		{width: 1, want: "█", wantCells: 1},
+		{width: 2.5, want: "██▌", wantCells: 3},
+		{width: 3.99, want: "███▉", wantCells: 4},
+	}
+	for _, tc := range testCases {
+		got, gotCells := blockBar(tc.width)
+		if got != tc.want || gotCells != tc.wantCells {
+			t.Errorf("result mismatch, width=%g, got=%q (%d cells), want=%q (%d cells)", tc.width, got, gotCells, tc.want, tc.wantCells)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import "math"
+
+// niceMantissas are the leading digits of nice numbers, 1, 2 or 5 times a
+// power of ten.
+var niceMantissas = []float64{1, 2, 5}
+
+// BuildNiceRangePoints returns points from min or below to max or above
+// spaced equally by a nice step, 1, 2 or 5 times a power of ten, which
+// makes the bucket count closest to count. So the returned bucket count
+// may differ from count slightly. max must be greater than min.
+func BuildNiceRangePoints(count int, min, max float64) []float64 {
+	if max <= min {
+		panic("max must be greater than min")
+	}
+
+	raw := (max - min) / float64(count)
+	exp := int(math.Floor(math.Log10(raw)))
+	frac := raw / math.Pow10(exp)
+	var mantissa float64
+	switch {
+	case frac < 1.5:
+		mantissa = 1
+	case frac < 3.5:
+		mantissa = 2
+	case frac < 7.5:
+		mantissa = 5
+	default:
+		mantissa = 1
+		exp++
+	}
+
+	// Points are k*step. Dividing by a power of ten instead of multiplying
+	// by a negative power keeps points like 0.3 exact.
+	point := func(k float64) float64 {
+		if exp < 0 {
+			return k * mantissa / math.Pow10(-exp)
+		}
+		return k * mantissa * math.Pow10(exp)
+	}
+	step := point(1)
+	kMin := math.Floor(min / step)
+	kMax := math.Ceil(max / step)
+	// Guard against rounding errors of the divisions above.
+	for point(kMin) > min {
+		kMin--
+	}
+	for point(kMax) < max {
+		kMax++
+	}
+
+	rangePoints := make([]float64, 0, int(kMax-kMin)+1)
+	for k := kMin; k <= kMax; k++ {
+		rangePoints = append(rangePoints, point(k))
+	}
+	return rangePoints
+}
+
+// BuildNiceLogRangePoints returns points from min or below to max or above
+// which are nice numbers. Either 1, 2 and 5 times powers of ten or powers
+// of ten only are used, whichever makes the bucket count closer to count.
+// It returns an error if min is not positive or max is not greater than
+// min.
+func BuildNiceLogRangePoints(count int, min, max float64) ([]float64, error) {
+	if err := validateLogRange(min, max); err != nil {
+		return nil, err
+	}
+
+	fine := buildNiceLogPoints(niceMantissas, min, max)
+	decades := buildNiceLogPoints(niceMantissas[:1], min, max)
+	if absInt(len(decades)-1-count) < absInt(len(fine)-1-count) {
+		return decades, nil
+	}
+	return fine, nil
+}
+
+func buildNiceLogPoints(mantissas []float64, min, max float64) []float64 {
+	var candidates []float64
+	for exp := int(math.Floor(math.Log10(min))) - 1; exp <= int(math.Floor(math.Log10(max)))+1; exp++ {
+		for _, mantissa := range mantissas {
+			if exp < 0 {
+				candidates = append(candidates, mantissa/math.Pow10(-exp))
+			} else {
+				candidates = append(candidates, mantissa*math.Pow10(exp))
+			}
+		}
+	}
+
+	first := 0
+	for first+1 < len(candidates) && candidates[first+1] <= min {
+		first++
+	}
+	last := len(candidates) - 1
+	for last-1 >= 0 && candidates[last-1] >= max {
+		last--
+	}
+	return candidates[first : last+1]
+}
+
+func absInt(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
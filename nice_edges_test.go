@@ -0,0 +1,53 @@
+package main
+
+import (
+	"testing"
+
+	"golang.org/x/exp/slices"
+)
+
+func TestBuildNiceRangePoints(t *testing.T) {
+	testCases := []struct {
+		count    int
+		min, max float64
+		want     []float64
+	}{
+		{count: 5, min: 0, max: 100, want: []float64{0, 20, 40, 60, 80, 100}},
+		{count: 7, min: 0, max: 96, want: []float64{0, 10, 20, 30, 40, 50, 60, 70, 80, 90, 100}},
+		{count: 4, min: 3.2, max: 27.9, want: []float64{0, 5, 10, 15, 20, 25, 30}},
+		{count: 3, min: 0.1, max: 0.4, want: []float64{0.1, 0.2, 0.3, 0.4}},
+		{count: 2, min: -1.3, max: 0.7, want: []float64{-2, -1, 0, 1}},
+		{count: 10, min: 1000, max: 3000, want: []float64{1000, 1200, 1400, 1600, 1800, 2000, 2200, 2400, 2600, 2800, 3000}},
+	}
+	for _, tc := range testCases {
+		got := BuildNiceRangePoints(tc.count, tc.min, tc.max)
+		if !slices.Equal(got, tc.want) {
+			t.Errorf("result mismatch, count=%d, min=%g, max=%g, got=%v, want=%v", tc.count, tc.min, tc.max, got, tc.want)
+		}
+	}
+}
+
+func TestBuildNiceLogRangePoints(t *testing.T) {
+	testCases := []struct {
+		count    int
+		min, max float64
+		want     []float64
+	}{
+		{count: 6, min: 1, max: 100, want: []float64{1, 2, 5, 10, 20, 50, 100}},
+		{count: 3, min: 0.3, max: 700, want: []float64{0.1, 1, 10, 100, 1000}},
+		{count: 10, min: 0.3, max: 7, want: []float64{0.2, 0.5, 1, 2, 5, 10}},
+		{count: 2, min: 20, max: 40, want: []float64{20, 50}},
+	}
+	for _, tc := range testCases {
+		got, err := BuildNiceLogRangePoints(tc.count, tc.min, tc.max)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(got, tc.want) {
+			t.Errorf("result mismatch, count=%d, min=%g, max=%g, got=%v, want=%v", tc.count, tc.min, tc.max, got, tc.want)
+		}
+	}
+	if _, err := BuildNiceLogRangePoints(3, 0, 10); err == nil {
+		t.Error("error expected for zero min")
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Methods of outlier detection.
+const (
+	outlierMethodIQR    = "iqr"
+	outlierMethodStddev = "stddev"
+)
+
+// outlierRule tells which values are outliers. With outlierMethodIQR,
+// values further than Factor times the interquartile range below the first
+// quartile or above the third quartile are outliers. With
+// outlierMethodStddev, values further than Factor times the standard
+// deviation from the mean are.
+type outlierRule struct {
+	Method string
+	Factor float64
+}
+
+// defaultOutlierFactors are the factors of methods when not given.
+var defaultOutlierFactors = map[string]float64{
+	outlierMethodIQR:    1.5,
+	outlierMethodStddev: 3,
+}
+
+// parseOutlierRule parses s like "iqr", "iqr:3" or "stddev:2".
+func parseOutlierRule(s string) (outlierRule, error) {
+	method, factorStr, hasFactor := strings.Cut(s, ":")
+	factor, ok := defaultOutlierFactors[method]
+	if !ok {
+		return outlierRule{}, fmt.Errorf("invalid outlier method %q", method)
+	}
+	if hasFactor {
+		v, err := strconv.ParseFloat(factorStr, float64BitSize)
+		if err != nil || !(v > 0) {
+			return outlierRule{}, fmt.Errorf("invalid outlier factor %q", factorStr)
+		}
+		factor = v
+	}
+	return outlierRule{Method: method, Factor: factor}, nil
+}
+
+// bounds returns the smallest and the largest values of sorted which are
+// not outliers.
+func (r outlierRule) bounds(sorted weightedValues) (float64, float64) {
+	if r.Method == outlierMethodStddev {
+		m, s := sorted.mean(), sorted.stddev()
+		return m - r.Factor*s, m + r.Factor*s
+	}
+	q1, q3 := sorted.quantileSorted(0.25), sorted.quantileSorted(0.75)
+	iqr := q3 - q1
+	return q1 - r.Factor*iqr, q3 + r.Factor*iqr
+}
+
+var errAllValuesOutliers = errors.New("all values are outliers")
+
+// dropOutliers removes outliers from each list of lists in place with the
+// bounds decided from values of all lists, so that all histograms drop
+// values by the same criteria. It reports the number of dropped values of
+// each list with cfg.Warner.
+func dropOutliers(cfg Config, lists []weightedValues, labels []string) error {
+	all := concatWeightedValues(lists)
+	all.sort()
+	lo, hi := cfg.DropOutliers.bounds(all)
+
+	kept := 0
+	for i := range lists {
+		total := lists[i].total()
+		dropped := lists[i].filter(func(v float64) bool { return v >= lo && v <= hi })
+		kept += total - dropped
+		if dropped > 0 {
+			cfg.warn(Warning{
+				Kind:    warningOutliersDropped,
+				Message: fmt.Sprintf("dropped %d of %d values outside %g to %g as outliers", dropped, total, lo, hi),
+				Source:  labels[i],
+				Details: map[string]any{"dropped": dropped, "totalCount": total, "lower": lo, "upper": hi},
+			})
+		}
+	}
+	if kept == 0 {
+		return errAllValuesOutliers
+	}
+	return nil
+}
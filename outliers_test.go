@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"testing"
+
+	"golang.org/x/exp/slices"
+)
+
+func TestParseOutlierRule(t *testing.T) {
+	testCases := []struct {
+		s       string
+		want    outlierRule
+		wantErr bool
+	}{
+		{s: "iqr", want: outlierRule{Method: outlierMethodIQR, Factor: 1.5}},
+		{s: "iqr:3", want: outlierRule{Method: outlierMethodIQR, Factor: 3}},
+		{s: "stddev", want: outlierRule{Method: outlierMethodStddev, Factor: 3}},
+		{s: "stddev:2.5", want: outlierRule{Method: outlierMethodStddev, Factor: 2.5}},
+		{s: "mad", wantErr: true},
+		{s: "iqr:0", wantErr: true},
+		{s: "iqr:", wantErr: true},
+		{s: "stddev:NaN", wantErr: true},
+	}
+	for _, tc := range testCases {
+		got, err := parseOutlierRule(tc.s)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("error expected, s=%q", tc.s)
+			}
+			continue
+		}
+		if err != nil || got != tc.want {
+			t.Errorf("result mismatch, s=%q, got=%+v, err=%v, want=%+v", tc.s, got, err, tc.want)
+		}
+	}
+}
+
+func TestDropOutliers(t *testing.T) {
+	var buf bytes.Buffer
+	warner, err := newWarner(warningsText, io.Discard, newLogger(&buf, slog.LevelInfo))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := Config{DropOutliers: &outlierRule{Method: outlierMethodIQR, Factor: 1.5}, Warner: warner}
+	lists := []weightedValues{{values: []float64{1, 2, 3, 4, 100}}, {values: []float64{2, 3, -50}}}
+	if err := dropOutliers(cfg, lists, []string{"a", "b"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := lists[0].values, []float64{1, 2, 3, 4}; !slices.Equal(got, want) {
+		t.Errorf("result mismatch, got=%v, want=%v", got, want)
+	}
+	if got, want := lists[1].values, []float64{2, 3}; !slices.Equal(got, want) {
+		t.Errorf("result mismatch, got=%v, want=%v", got, want)
+	}
+	want := "level=WARN msg=\"dropped 1 of 5 values outside -0.5 to 5.5 as outliers\" source=a\n" +
+		"level=WARN msg=\"dropped 1 of 3 values outside -0.5 to 5.5 as outliers\" source=b\n"
+	if got := buf.String(); got != want {
+		t.Errorf("warning mismatch,\n got=%q,\nwant=%q", got, want)
+	}
+
+	cfg = Config{DropOutliers: &outlierRule{Method: outlierMethodStddev, Factor: 0.5}}
+	if err := dropOutliers(cfg, []weightedValues{{values: []float64{0, 10}}}, []string{"a"}); err != errAllValuesOutliers {
+		t.Errorf("error mismatch, got=%v, want=%v", err, errAllValuesOutliers)
+	}
+}
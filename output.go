@@ -0,0 +1,376 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/exp/slices"
+)
+
+// OutputFormat is a Renderer with a name.
+// Implementations are registered with RegisterOutputFormat and selected by
+// Name with the --output flag.
+type OutputFormat interface {
+	// Name returns the name of the format used in the --output flag.
+	Name() string
+	Renderer
+}
+
+// RenderModel is the input of OutputFormat.Render.
+type RenderModel struct {
+	// Histograms are the histograms to render, which have the same range
+	// points.
+	Histograms []*Histogram[float64]
+	// Labels are names of histograms like input filenames.
+	Labels []string
+	// Stats are statistics of values of histograms including those out of
+	// range. It may be nil when histograms are not built from values.
+	Stats []Stats
+	// ComputedStats are statistics of each histogram by StatComputers
+	// shown in the footer of the text output. It may be nil.
+	ComputedStats [][]ComputedStat
+	// values are values of histograms when the binner kept them in memory,
+	// from which quantile computes exact quantiles. It is nil otherwise.
+	values       []weightedValues
+	valuesSorted bool
+	// Title is the title of the chart. Formats without a place for a title
+	// ignore it.
+	Title string
+	// MetricName is the name of the metric in formats for monitoring
+	// systems.
+	MetricName string
+	// Color is whether to color the output for terminals.
+	Color bool
+	// Palette is the colors of histograms in colored output, and
+	// SeriesColors overrides them by the index of histograms.
+	Palette      Palette
+	SeriesColors map[int]string
+	// Annotations are reference values sorted by value. Formats without a
+	// place for them ignore them.
+	Annotations []Annotation
+
+	BarChar     string
+	BarStyle    BarStyle
+	Orientation Orientation
+	GraphWidth  int
+	GraphHeight int
+	// FitHeight is the maximum number of bucket rows in text output.
+	// Adjacent buckets are aggregated into rows to fit. 0 means no limit.
+	FitHeight  int
+	PointFmt   string
+	TickStyle  TickStyle
+	MinCount   int
+	Duration   time.Duration
+	Percent    bool
+	Cumulative bool
+	CDFBar     bool
+	// Widths is whether to show the width of each bucket, which is useful
+	// when buckets have different widths.
+	Widths bool
+}
+
+// outputList is the --output value to show the registered format names.
+const outputList = "list"
+
+var (
+	outputFormatsMu sync.RWMutex
+	outputFormats   = make(map[string]OutputFormat)
+)
+
+// RegisterOutputFormat makes f available by its name.
+// It panics if a format with the same name is already registered.
+func RegisterOutputFormat(f OutputFormat) {
+	outputFormatsMu.Lock()
+	defer outputFormatsMu.Unlock()
+
+	name := f.Name()
+	if name == outputList {
+		panic(fmt.Sprintf("output format name %q is reserved", name))
+	}
+	if _, dup := outputFormats[name]; dup {
+		panic(fmt.Sprintf("output format %q is already registered", name))
+	}
+	outputFormats[name] = f
+}
+
+// LookupOutputFormat returns the registered format with name.
+func LookupOutputFormat(name string) (OutputFormat, bool) {
+	outputFormatsMu.RLock()
+	defer outputFormatsMu.RUnlock()
+
+	f, ok := outputFormats[name]
+	return f, ok
+}
+
+// OutputFormatNames returns the sorted names of the registered formats.
+func OutputFormatNames() []string {
+	outputFormatsMu.RLock()
+	defer outputFormatsMu.RUnlock()
+
+	names := make([]string, 0, len(outputFormats))
+	for name := range outputFormats {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+func init() {
+	RegisterOutputFormat(textOutputFormat{})
+	RegisterOutputFormat(jsonOutputFormat{})
+	RegisterOutputFormat(percentileTableOutputFormat{})
+	RegisterOutputFormat(svgOutputFormat{})
+	RegisterOutputFormat(htmlOutputFormat{})
+	RegisterOutputFormat(prometheusOutputFormat{})
+	RegisterOutputFormat(lineOutputFormat{})
+	RegisterOutputFormat(csvOutputFormat)
+	RegisterOutputFormat(tsvOutputFormat)
+}
+
+// textOutputFormat renders histograms as bar charts for terminals.
+type textOutputFormat struct{}
+
+func (textOutputFormat) Name() string { return "text" }
+
+func (textOutputFormat) Render(w io.Writer, m *RenderModel) error {
+	if m.Title != "" {
+		if _, err := fmt.Fprintf(w, "%s\n\n", m.Title); err != nil {
+			return err
+		}
+	}
+	if err := renderText(w, m); err != nil {
+		return err
+	}
+	return writeComputedStats(w, m)
+}
+
+func renderText(w io.Writer, m *RenderModel) error {
+	if m.Orientation == OrientationVertical {
+		return renderVertical(w, m)
+	}
+
+	histograms := m.Histograms
+	factor := fitHeightFactor(len(histograms[0].counts), m.FitHeight)
+	if factor > 1 {
+		histograms = make([]*Histogram[float64], len(m.Histograms))
+		for i, h := range m.Histograms {
+			histograms[i] = h.Coarsen(factor)
+		}
+	}
+
+	opts := []FormatterOption{
+		WithBarChar(m.BarChar),
+		WithWidth(m.GraphWidth),
+		WithPointFormat(m.PointFmt),
+		WithTickStyle(m.TickStyle),
+		WithMinCount(m.MinCount),
+		WithDuration(m.Duration),
+		WithPercent(m.Percent),
+		WithCumulative(m.Cumulative),
+		WithCDFBar(m.CDFBar),
+		WithBucketWidths(m.Widths),
+		WithAnnotations(m.Annotations),
+		WithColors(m.Color),
+		WithSeriesColors(m.seriesColors()),
+		WithBarStyle(m.BarStyle),
+	}
+	if len(m.Labels) > 1 {
+		opts = append(opts, WithLabels(m.Labels))
+	}
+	formatter, err := NewMultipleHistogramFormatter(histograms, opts...)
+	if err != nil {
+		return err
+	}
+	if _, err := formatter.WriteTo(w); err != nil {
+		return err
+	}
+	if factor > 1 {
+		_, err := fmt.Fprintf(w, "(each row aggregates %d buckets to fit %d rows)\n", factor, m.FitHeight)
+		return err
+	}
+	return nil
+}
+
+// seriesColor returns the ANSI color of the i-th histogram.
+func (m *RenderModel) seriesColor(i int) string {
+	return seriesColor(m.Palette, m.SeriesColors, i)
+}
+
+// seriesColors returns the ANSI colors of all histograms.
+func (m *RenderModel) seriesColors() []string {
+	colors := make([]string, len(m.Histograms))
+	for i := range colors {
+		colors[i] = m.seriesColor(i)
+	}
+	return colors
+}
+
+// fitHeightFactor returns how many adjacent buckets to aggregate into a row
+// so that bucketCount buckets fit in maxRows rows. It returns 1 if they
+// fit already or maxRows is 0.
+func fitHeightFactor(bucketCount, maxRows int) int {
+	if maxRows <= 0 || bucketCount <= maxRows {
+		return 1
+	}
+	return (bucketCount + maxRows - 1) / maxRows
+}
+
+// renderVertical renders histograms as column charts one after another.
+// Rows other than buckets like rare ones and extra columns are not shown.
+func renderVertical(w io.Writer, m *RenderModel) error {
+	for i, h := range m.Histograms {
+		opts := []FormatterOption{
+			WithBarChar(m.BarChar),
+			WithHeight(m.GraphHeight),
+			WithPointFormat(m.PointFmt),
+			WithTickStyle(m.TickStyle),
+			WithBarStyle(m.BarStyle),
+		}
+		if m.Color {
+			opts = append(opts, WithBarColor(m.seriesColor(i)))
+		}
+		formatter := NewVerticalHistogramFormatter(h, opts...)
+		if i > 0 {
+			if _, err := io.WriteString(w, "\n"); err != nil {
+				return err
+			}
+		}
+		if len(m.Histograms) > 1 {
+			if _, err := fmt.Fprintln(w, m.Labels[i]); err != nil {
+				return err
+			}
+		}
+		if _, err := formatter.WriteTo(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// jsonOutputFormat renders histograms as a JSON array of labeled
+// histograms.
+type jsonOutputFormat struct{}
+
+func (jsonOutputFormat) Name() string { return "json" }
+
+func (jsonOutputFormat) Render(w io.Writer, m *RenderModel) error {
+	type labeledHistogram struct {
+		Label     string              `json:"label"`
+		Histogram *Histogram[float64] `json:"histogram"`
+	}
+	items := make([]labeledHistogram, len(m.Histograms))
+	for i, h := range m.Histograms {
+		items[i] = labeledHistogram{Label: m.Labels[i], Histogram: h}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(items)
+}
+
+// percentileTableColumns are the quantiles shown by percentileTableOutputFormat.
+var percentileTableColumns = []struct {
+	header string
+	q      float64
+}{
+	{header: "p50", q: 0.5},
+	{header: "p90", q: 0.9},
+	{header: "p95", q: 0.95},
+	{header: "p99", q: 0.99},
+	{header: "max", q: 1},
+}
+
+// percentileTableOutputFormat renders a table of percentiles per input.
+// Rows after the first one also show the relative differences from the
+// first input. The maximum comes from Stats, and percentiles are computed
+// from values kept in memory. They are estimated from buckets when values
+// are counted while reading them, like with an explicit axis range, so use
+// more buckets for more precise values then.
+type percentileTableOutputFormat struct{}
+
+func (percentileTableOutputFormat) Name() string { return "percentile-table" }
+
+func (percentileTableOutputFormat) Render(w io.Writer, m *RenderModel) error {
+	rows := make([][]string, 0, len(m.Histograms)+1)
+	header := []string{"input"}
+	for _, col := range percentileTableColumns {
+		header = append(header, col.header)
+	}
+	rows = append(rows, header)
+
+	var base []float64
+	for i := range m.Histograms {
+		row := []string{m.Labels[i]}
+		values := make([]float64, len(percentileTableColumns))
+		for j, col := range percentileTableColumns {
+			values[j] = m.quantile(i, col.q)
+			cell := fmt.Sprintf(m.PointFmt, values[j])
+			if i > 0 {
+				cell += " " + formatRelativeDelta(values[j], base[j])
+			}
+			row = append(row, cell)
+		}
+		if i == 0 {
+			base = values
+		}
+		rows = append(rows, row)
+	}
+
+	widths := make([]int, len(header))
+	for _, row := range rows {
+		for j, cell := range row {
+			widths[j] = MustMax(widths[j], stringWidth(cell))
+		}
+	}
+	for _, row := range rows {
+		cells := make([]string, len(row))
+		for j, cell := range row {
+			if j == 0 {
+				cells[j] = padEndSpace(widths[j], cell)
+			} else {
+				cells[j] = padStartSpace(widths[j], cell)
+			}
+		}
+		if _, err := fmt.Fprintln(w, strings.Join(cells, "  ")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// quantile returns the q-quantile of values of the i-th histogram. The
+// minimum and the maximum come from Stats if it is set. Others are
+// computed from values if the binner kept them, or estimated from buckets
+// otherwise.
+func (m *RenderModel) quantile(i int, q float64) float64 {
+	switch {
+	case m.Stats != nil && q == 0:
+		return m.Stats[i].Min
+	case m.Stats != nil && q == 1:
+		return m.Stats[i].Max
+	case m.values == nil:
+		return m.Histograms[i].Quantile(q)
+	}
+	if !m.valuesSorted {
+		for _, w := range m.values {
+			w.sort()
+		}
+		m.valuesSorted = true
+	}
+	if len(m.values[i].values) == 0 {
+		return math.NaN()
+	}
+	return m.values[i].quantileSorted(q)
+}
+
+// formatRelativeDelta formats the difference of v from base in percent.
+func formatRelativeDelta(v, base float64) string {
+	if base == 0 || math.IsNaN(v) || math.IsNaN(base) {
+		return "(n/a)"
+	}
+	return fmt.Sprintf("(%+.1f%%)", (v-base)/math.Abs(base)*100)
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// FileExtensioner is implemented by output formats to name files written
+// with --output-dir. Formats without it get ".txt".
+type FileExtensioner interface {
+	// FileExtension returns the extension including the leading dot.
+	FileExtension() string
+}
+
+func (jsonOutputFormat) FileExtension() string { return ".json" }
+func (svgOutputFormat) FileExtension() string  { return ".svg" }
+
+// combinedOutputName is the base name of the file with all inputs written
+// with --output-dir.
+const combinedOutputName = "combined"
+
+// compressionExtensions are removed from input names before their own
+// extensions to name output files.
+var compressionExtensions = []string{".gz", ".bz2", ".zst"}
+
+// writeOutputDir renders each histogram of m to its own file in dir named
+// after its label, and all of them together to a combined file when there
+// are multiple inputs. Histograms share the range points, so the files
+// can be compared with each other.
+func writeOutputDir(dir string, r Renderer, m *RenderModel) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	ext := ".txt"
+	if e, ok := r.(FileExtensioner); ok {
+		ext = e.FileExtension()
+	}
+
+	names := outputBaseNames(m.Labels)
+	for i, name := range names {
+		single := *m
+		single.Histograms = m.Histograms[i : i+1]
+		single.Labels = m.Labels[i : i+1]
+		if m.Stats != nil {
+			single.Stats = m.Stats[i : i+1]
+		}
+		if err := writeOutputFile(filepath.Join(dir, name+ext), r, &single); err != nil {
+			return err
+		}
+	}
+	if len(m.Histograms) > 1 {
+		return writeOutputFile(filepath.Join(dir, combinedOutputName+ext), r, m)
+	}
+	return nil
+}
+
+func writeOutputFile(filename string, r Renderer, m *RenderModel) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	if err := r.Render(file, m); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// outputBaseNames returns output file names without extensions for labels
+// like input filenames. Duplicated names and the combined file name get suffixes
+// like "-2" so that no file is overwritten.
+func outputBaseNames(labels []string) []string {
+	used := map[string]bool{combinedOutputName: true}
+	names := make([]string, len(labels))
+	for i, label := range labels {
+		base := filepath.Base(label)
+		for _, ext := range compressionExtensions {
+			base = strings.TrimSuffix(base, ext)
+		}
+		base = strings.TrimSuffix(base, filepath.Ext(base))
+
+		name := base
+		for n := 2; used[name]; n++ {
+			name = fmt.Sprintf("%s-%d", base, n)
+		}
+		used[name] = true
+		names[i] = name
+	}
+	return names
+}
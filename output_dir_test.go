@@ -0,0 +1,53 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/exp/slices"
+)
+
+func TestOutputBaseNames(t *testing.T) {
+	got := outputBaseNames([]string{"logs/a.txt", "other/a.txt.gz", "stdin", "combined.csv", "b"})
+	want := []string{"a", "a-2", "stdin", "combined-2", "b"}
+	if !slices.Equal(got, want) {
+		t.Errorf("result mismatch, got=%v, want=%v", got, want)
+	}
+}
+
+func TestWriteOutputDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "out")
+	rangePoints := BuildRangePoints[float64](2, 0, 2)
+	a := NewHistogram(rangePoints)
+	a.AddValues([]float64{0.5, 1.5})
+	b := NewHistogram(rangePoints)
+	b.AddValues([]float64{1.5})
+	m := &RenderModel{
+		Histograms: []*Histogram[float64]{a, b},
+		Labels:     []string{"in/a.txt", "b.txt"},
+	}
+	if err := writeOutputDir(dir, jsonOutputFormat{}, m); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	if want := []string{"a.json", "b.json", "combined.json"}; !slices.Equal(names, want) {
+		t.Errorf("file names mismatch, got=%v, want=%v", names, want)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "b.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(data); !strings.Contains(got, `"label": "b.txt"`) || strings.Contains(got, "in/a.txt") {
+		t.Errorf("b.json must contain only b.txt, got=%s", got)
+	}
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"golang.org/x/exp/slices"
+)
+
+type testOutputFormat struct{ name string }
+
+func (f testOutputFormat) Name() string { return f.name }
+
+func (testOutputFormat) Render(w io.Writer, m *RenderModel) error {
+	_, err := io.WriteString(w, "test")
+	return err
+}
+
+func TestRegisterOutputFormat(t *testing.T) {
+	RegisterOutputFormat(testOutputFormat{name: "test-format"})
+	defer func() {
+		outputFormatsMu.Lock()
+		delete(outputFormats, "test-format")
+		outputFormatsMu.Unlock()
+	}()
+
+	if !slices.Contains(OutputFormatNames(), "test-format") {
+		t.Errorf("registered format must be in names, got=%v", OutputFormatNames())
+	}
+	f, ok := LookupOutputFormat("test-format")
+	if !ok {
+		t.Fatal("registered format must be found")
+	}
+	var buf bytes.Buffer
+	if err := f.Render(&buf, &RenderModel{}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := buf.String(), "test"; got != want {
+		t.Errorf("result mismatch, got=%q, want=%q", got, want)
+	}
+
+	for _, name := range []string{"test-format", outputList} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("registering %q must panic", name)
+				}
+			}()
+			RegisterOutputFormat(testOutputFormat{name: name})
+		}()
+	}
+}
+
+func TestJSONOutputFormat(t *testing.T) {
+	h := NewHistogram(BuildRangePoints[float64](2, 0, 2))
+	h.AddValues([]float64{0.5, 1.5, 1.7})
+	f, ok := LookupOutputFormat("json")
+	if !ok {
+		t.Fatal("json format must be registered")
+	}
+	var buf bytes.Buffer
+	if err := f.Render(&buf, &RenderModel{Histograms: []*Histogram[float64]{h}, Labels: []string{"a.txt"}}); err != nil {
+		t.Fatal(err)
+	}
+	want := `[
+  {
+    "label": "a.txt",
+    "histogram": {
+      "rangePoints": [
+        0,
+        1,
+        2
+      ],
+      "counts": [
+        1,
+        2
+      ],
+      "outOfRangeCount": 0,
+      "underflowCount": 0,
+      "overflowCount": 0
+    }
+  }
+]
+`
+	if got := buf.String(); got != want {
+		t.Errorf("result mismatch,\n got=%s,\nwant=%s", got, want)
+	}
+}
+
+func TestPercentileTableOutputFormat(t *testing.T) {
+	rangePoints := BuildRangePoints[float64](10, 0, 100)
+	a := NewHistogram(rangePoints)
+	b := NewHistogram(rangePoints)
+	c := NewHistogram(rangePoints)
+	for i := 0; i < 100; i++ {
+		a.AddValue(float64(i) + 0.5)
+		b.AddValue(float64(i)/2 + 0.25)
+	}
+	f, ok := LookupOutputFormat("percentile-table")
+	if !ok {
+		t.Fatal("percentile-table format must be registered")
+	}
+	var buf bytes.Buffer
+	m := &RenderModel{
+		Histograms: []*Histogram[float64]{a, b, c},
+		Labels:     []string{"a.txt", "b.txt", "empty.txt"},
+		PointFmt:   "%.1f",
+	}
+	if err := f.Render(&buf, m); err != nil {
+		t.Fatal(err)
+	}
+	want := `input                p50            p90            p95            p99            max
+a.txt               50.0           90.0           95.0           99.0          100.0
+b.txt      25.0 (-50.0%)  45.0 (-50.0%)  47.5 (-50.0%)  49.5 (-50.0%)  50.0 (-50.0%)
+empty.txt      NaN (n/a)      NaN (n/a)      NaN (n/a)      NaN (n/a)      NaN (n/a)
+`
+	if got := buf.String(); got != want {
+		t.Errorf("result mismatch,\n got=%s,\nwant=%s", got, want)
+	}
+}
+
+// TestPercentileTableOutputFormat_Values checks that the maximum comes
+// from Stats and percentiles from values kept in memory instead of the
+// buckets 0 to 1100.
+func TestPercentileTableOutputFormat_Values(t *testing.T) {
+	h := NewHistogram(BuildRangePoints(11, 0.0, 1100))
+	var w weightedValues
+	for i := 1000; i >= 1; i-- {
+		h.AddValue(float64(i))
+		w.add(float64(i), 1)
+	}
+	stats := []Stats{{Count: 1000, Min: 1, Max: 1000}}
+	testCases := []struct {
+		values []weightedValues
+		want   string
+	}{
+		{values: []weightedValues{w}, want: `input     p50     p90     p95     p99      max
+a.txt  500.50  900.10  950.05  990.01  1000.00
+`},
+		// Without values, percentiles are estimated from buckets.
+		{values: nil, want: `input     p50     p90     p95     p99      max
+a.txt  501.00  901.00  951.00  991.00  1000.00
+`},
+	}
+	for _, tc := range testCases {
+		var buf bytes.Buffer
+		m := &RenderModel{
+			Histograms: []*Histogram[float64]{h},
+			Labels:     []string{"a.txt"},
+			Stats:      stats,
+			PointFmt:   "%.2f",
+			values:     tc.values,
+		}
+		if err := (percentileTableOutputFormat{}).Render(&buf, m); err != nil {
+			t.Fatal(err)
+		}
+		if got := buf.String(); got != tc.want {
+			t.Errorf("result mismatch,\n got=%s,\nwant=%s", got, tc.want)
+		}
+	}
+}
+
+func TestFitHeightFactor(t *testing.T) {
+	testCases := []struct {
+		bucketCount, maxRows, want int
+	}{
+		{bucketCount: 10, maxRows: 0, want: 1},
+		{bucketCount: 10, maxRows: 10, want: 1},
+		{bucketCount: 11, maxRows: 10, want: 2},
+		{bucketCount: 50, maxRows: 12, want: 5},
+		{bucketCount: 1000, maxRows: 30, want: 34},
+	}
+	for _, tc := range testCases {
+		if got := fitHeightFactor(tc.bucketCount, tc.maxRows); got != tc.want {
+			t.Errorf("result mismatch, bucketCount=%d, maxRows=%d, got=%d, want=%d", tc.bucketCount, tc.maxRows, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parsePercentiles parses a comma separated list of percentiles like
+// "50,90,99.9". Percentiles must be greater than 0, less than 100 and
+// increasing.
+func parsePercentiles(s string) ([]float64, error) {
+	fields := strings.Split(s, ",")
+	percentiles := make([]float64, len(fields))
+	for i, field := range fields {
+		p, err := strconv.ParseFloat(strings.TrimSpace(field), float64BitSize)
+		if err != nil || !(p > 0 && p < 100) {
+			return nil, fmt.Errorf("invalid percentile %q", field)
+		}
+		if i > 0 && p <= percentiles[i-1] {
+			return nil, fmt.Errorf("percentiles must be increasing, got %g after %g", p, percentiles[i-1])
+		}
+		percentiles[i] = p
+	}
+	return percentiles, nil
+}
+
+// percentileRangePoints returns range points from axisMin to axisMax with
+// inner edges at percentiles of sorted values. Edges which are out of the
+// axis range or equal to the previous edge because of ties are dropped.
+func percentileRangePoints(sorted weightedValues, percentiles []float64, axisMin, axisMax float64) []float64 {
+	rangePoints := []float64{axisMin}
+	for _, p := range percentiles {
+		edge := sorted.quantileSorted(p / 100)
+		if edge > rangePoints[len(rangePoints)-1] && edge < axisMax {
+			rangePoints = append(rangePoints, edge)
+		}
+	}
+	return append(rangePoints, axisMax)
+}
+
+// percentileEdgesBinner reads all values into memory and puts bucket edges
+// at percentiles of values of all sources, so that buckets have known
+// shares of values instead of the same width. The outer edges are the axis
+// range.
+type percentileEdgesBinner struct {
+	cfg   Config
+	lists []weightedValues
+}
+
+func (b *percentileEdgesBinner) values() []weightedValues {
+	return b.lists
+}
+
+func (b *percentileEdgesBinner) Bin(sources []Source) ([]*Histogram[float64], error) {
+	cfg := b.cfg
+	lists := make([]weightedValues, len(sources))
+	err := forEachSource(len(sources), cfg.Parallelism, func(i int) error {
+		w, err := readSourceWeightedValues(sources[i])
+		lists[i] = w
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if cfg.DropOutliers != nil {
+		if err := dropOutliers(cfg, lists, sourceNames(sources)); err != nil {
+			return nil, err
+		}
+	}
+	all := concatWeightedValues(lists)
+	all.sort()
+	if cfg.Scale == scaleLog && all.values[0] <= 0 {
+		return nil, fmt.Errorf("log scale needs positive values, but got %g", all.values[0])
+	}
+
+	bucketCount := len(cfg.EdgesAtPercentiles) + 1
+	dataMin, dataMax := dataRange(cfg.AxisMin, cfg.AxisMax, []weightedValues{all})
+	axisMin, axisMax, err := decideAxisRange(cfg.AxisMin, cfg.AxisMax, dataMin, dataMax, bucketCount, cfg.IncludeZero)
+	if err != nil {
+		return nil, err
+	}
+	rangePoints := percentileRangePoints(all, cfg.EdgesAtPercentiles, axisMin, axisMax)
+	if len(rangePoints)-1 < bucketCount {
+		cfg.warn(Warning{
+			Kind:    warningBucketCountReduced,
+			Message: fmt.Sprintf("reduced bucket count from %d to %d, since some percentiles are equal or out of the axis range", bucketCount, len(rangePoints)-1),
+			Details: map[string]any{"from": bucketCount, "to": len(rangePoints) - 1, "reason": "percentile_ties"},
+		})
+	}
+
+	histograms := make([]*Histogram[float64], len(sources))
+	for i, w := range lists {
+		histogram := NewHistogram(rangePoints)
+		histogram.SetBoundaryEpsilon(cfg.BoundaryEpsilon)
+		histogram.SetClosure(cfg.Closure)
+		histogram.SetInclusiveMax(cfg.InclusiveMax)
+		w.addTo(histogram)
+		histograms[i] = histogram
+	}
+	b.lists = lists
+	return histograms, nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"testing"
+
+	"golang.org/x/exp/slices"
+)
+
+func TestParsePercentiles(t *testing.T) {
+	testCases := []struct {
+		input   string
+		want    []float64
+		wantErr bool
+	}{
+		{input: "50", want: []float64{50}},
+		{input: "50,90,95,99,99.9", want: []float64{50, 90, 95, 99, 99.9}},
+		{input: "50, 90", want: []float64{50, 90}},
+		{input: "", wantErr: true},
+		{input: "0", wantErr: true},
+		{input: "100", wantErr: true},
+		{input: "p50", wantErr: true},
+		{input: "90,50", wantErr: true},
+		{input: "50,50", wantErr: true},
+	}
+	for _, tc := range testCases {
+		got, err := parsePercentiles(tc.input)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("must return an error, input=%q", tc.input)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("unexpected error, input=%q, err=%v", tc.input, err)
+		} else if !slices.Equal(got, tc.want) {
+			t.Errorf("result mismatch, input=%q, got=%v, want=%v", tc.input, got, tc.want)
+		}
+	}
+}
+
+func TestPercentileRangePoints(t *testing.T) {
+	testCases := []struct {
+		sorted      []float64
+		percentiles []float64
+		min, max    float64
+		want        []float64
+	}{
+		{sorted: []float64{1, 2, 3, 4, 5}, percentiles: []float64{50}, min: 0, max: 10, want: []float64{0, 3, 10}},
+		{sorted: []float64{1, 2, 3, 4, 5}, percentiles: []float64{25, 75}, min: 0, max: 10, want: []float64{0, 2, 4, 10}},
+		// Ties collapse into one edge.
+		{sorted: []float64{1, 1, 1, 1, 5}, percentiles: []float64{25, 50, 75}, min: 0, max: 10, want: []float64{0, 1, 10}},
+		// Edges out of the axis range are dropped.
+		{sorted: []float64{1, 2, 3, 4, 5}, percentiles: []float64{25, 75}, min: 3, max: 10, want: []float64{3, 4, 10}},
+		{sorted: []float64{1, 2, 3, 4, 5}, percentiles: []float64{25, 75}, min: 0, max: 4, want: []float64{0, 2, 4}},
+	}
+	for _, tc := range testCases {
+		got := percentileRangePoints(weightedValues{values: tc.sorted}, tc.percentiles, tc.min, tc.max)
+		if !slices.Equal(got, tc.want) {
+			t.Errorf("result mismatch, sorted=%v, percentiles=%v, min=%g, max=%g, got=%v, want=%v",
+				tc.sorted, tc.percentiles, tc.min, tc.max, got, tc.want)
+		}
+	}
+}
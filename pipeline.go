@@ -0,0 +1,757 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Source produces values of an input.
+type Source interface {
+	// Name returns the label of the input like its filename.
+	Name() string
+	// Scan reads the input to the end and calls fn with each value.
+	Scan(fn func(v float64)) error
+}
+
+// Transform changes or drops values read from a Source before binning.
+type Transform interface {
+	// Apply returns the transformed value and whether to keep it.
+	Apply(v float64) (float64, bool)
+}
+
+// sourceTransform is a Transform with state for each source like a
+// counter. forSource returns a new one for a source, so that sources read
+// in parallel do not share the state.
+type sourceTransform interface {
+	Transform
+	forSource() Transform
+}
+
+// countTransform is a Transform which can apply itself to a value read n
+// times at once. It returns the transformed value and how many of the n
+// values to keep.
+type countTransform interface {
+	Transform
+	applyCount(v float64, n int) (float64, int)
+}
+
+// TransformFunc is an adapter to use a function as a Transform.
+type TransformFunc func(v float64) (float64, bool)
+
+func (f TransformFunc) Apply(v float64) (float64, bool) {
+	return f(v)
+}
+
+func (f TransformFunc) applyCount(v float64, n int) (float64, int) {
+	v, keep := f(v)
+	if !keep {
+		return v, 0
+	}
+	return v, n
+}
+
+// countSource is a Source which can pass a value read n times at once,
+// like a record of the counted input format, instead of repeating it.
+type countSource interface {
+	Source
+	scanCounts(fn func(v float64, n int)) error
+}
+
+// scanSourceCounts calls fn with each value of src and its count, which is
+// 1 unless src is a countSource.
+func scanSourceCounts(src Source, fn func(v float64, n int)) error {
+	if cs, ok := src.(countSource); ok {
+		return cs.scanCounts(fn)
+	}
+	return src.Scan(func(v float64) { fn(v, 1) })
+}
+
+// repeatCounts returns the function calling fn with a value as many times
+// as its count, to implement Scan of a countSource.
+func repeatCounts(fn func(v float64)) func(v float64, n int) {
+	return func(v float64, n int) {
+		for i := 0; i < n; i++ {
+			fn(v)
+		}
+	}
+}
+
+// Binner counts values of sources into histograms sharing range points,
+// one histogram per source in the same order.
+type Binner interface {
+	Bin(sources []Source) ([]*Histogram[float64], error)
+}
+
+// valuesBinner is a Binner which keeps values in memory, which are more
+// precise than buckets for statistics like quantiles.
+type valuesBinner interface {
+	Binner
+	// values returns values of sources in the last Bin, which may be
+	// reordered by the caller.
+	values() []weightedValues
+}
+
+// Renderer writes histograms and their display settings in m to w.
+type Renderer interface {
+	Render(w io.Writer, m *RenderModel) error
+}
+
+// Pipeline reads values from Sources, applies Transforms to them, counts
+// them with Binner and writes the result with Renderer.
+type Pipeline struct {
+	Sources    []Source
+	Transforms []Transform
+	Binner     Binner
+	Renderer   Renderer
+	// Warner reports data quality problems. It may be nil.
+	Warner Warner
+	// Logger gets debug messages about inputs read. It may be nil.
+	Logger *slog.Logger
+	// StatComputers compute statistics put in Model. Values are kept in
+	// memory for them only if there are any.
+	StatComputers []StatComputer
+	// Model holds display settings. Histograms and Labels are filled by Run.
+	Model RenderModel
+}
+
+// Run runs the pipeline and writes the rendered result to w.
+func (p *Pipeline) Run(w io.Writer) error {
+	m, err := p.Bin()
+	if err != nil {
+		return err
+	}
+	return p.Renderer.Render(w, m)
+}
+
+// Bin reads and counts values of sources and returns Model filled with the
+// histograms, their labels and the statistics of their values.
+func (p *Pipeline) Bin() (*RenderModel, error) {
+	return p.BinContext(context.Background())
+}
+
+// BinContext is like Bin but stops reading inputs with the error of ctx
+// once it is done.
+func (p *Pipeline) BinContext(ctx context.Context) (*RenderModel, error) {
+	sources := make([]Source, len(p.Sources))
+	statsSources := make([]*statsSource, len(p.Sources))
+	for i, src := range p.Sources {
+		src = sourceWithContext(ctx, src)
+		if len(p.Transforms) > 0 {
+			transforms := make([]Transform, len(p.Transforms))
+			for j, t := range p.Transforms {
+				if st, ok := t.(sourceTransform); ok {
+					t = st.forSource()
+				}
+				transforms[j] = t
+			}
+			src = &transformedSource{Source: src, transforms: transforms}
+		}
+		statsSources[i] = &statsSource{Source: src, keepValues: len(p.StatComputers) > 0}
+		sources[i] = statsSources[i]
+	}
+
+	start := time.Now()
+	histograms, err := p.Binner.Bin(sources)
+	if err != nil {
+		return nil, err
+	}
+	if p.Logger != nil {
+		p.logBinned(ctx, histograms, statsSources, time.Since(start))
+	}
+
+	m := p.Model
+	m.Histograms = histograms
+	if vb, ok := p.Binner.(valuesBinner); ok {
+		m.values = vb.values()
+	}
+	m.Stats = make([]Stats, len(statsSources))
+	for i, src := range statsSources {
+		m.Stats[i] = src.acc.Stats()
+	}
+	m.Labels = make([]string, len(p.Sources))
+	for i, src := range p.Sources {
+		m.Labels[i] = src.Name()
+	}
+	if len(p.StatComputers) > 0 {
+		raws := make([][]float64, len(statsSources))
+		for i, src := range statsSources {
+			raws[i] = src.values
+		}
+		m.ComputedStats = computeStats(p.StatComputers, histograms, raws)
+	}
+	if p.Warner != nil {
+		warnOutOfRange(p.Warner, histograms, m.Labels)
+	}
+	return &m, nil
+}
+
+// logBinned logs the count of values read from each source at debug level
+// and the counts of buckets at trace level.
+func (p *Pipeline) logBinned(ctx context.Context, histograms []*Histogram[float64], sources []*statsSource, elapsed time.Duration) {
+	p.Logger.Debug("binned inputs", "inputs", len(sources), "elapsed", elapsed)
+	for i, src := range sources {
+		p.Logger.Debug("read input", "name", src.Name(), "values", src.acc.Stats().Count)
+		if p.Logger.Enabled(ctx, levelTrace) && i < len(histograms) {
+			h := histograms[i]
+			p.Logger.Log(ctx, levelTrace, "counted buckets", "name", src.Name(),
+				"range_points", h.RangePoints(), "counts", h.Counts(), "out_of_range", h.outOfRangeCount)
+		}
+	}
+}
+
+type transformedSource struct {
+	Source
+	transforms []Transform
+}
+
+func (s *transformedSource) Scan(fn func(v float64)) error {
+	return s.Source.Scan(func(v float64) {
+		for _, t := range s.transforms {
+			var keep bool
+			v, keep = t.Apply(v)
+			if !keep {
+				return
+			}
+		}
+		fn(v)
+	})
+}
+
+// scanCounts passes counts through if all transforms are countTransforms.
+// Otherwise values are transformed one by one, since the transforms may
+// depend on each value read like a sampler by probability.
+func (s *transformedSource) scanCounts(fn func(v float64, n int)) error {
+	for _, t := range s.transforms {
+		if _, ok := t.(countTransform); !ok {
+			return s.Scan(func(v float64) { fn(v, 1) })
+		}
+	}
+	return scanSourceCounts(s.Source, func(v float64, n int) {
+		for _, t := range s.transforms {
+			v, n = t.(countTransform).applyCount(v, n)
+			if n == 0 {
+				return
+			}
+		}
+		fn(v, n)
+	})
+}
+
+// statsSource computes the statistics of values of Source while they are
+// scanned. With keepValues, it also keeps the values, where a value read
+// with a count is repeated by it.
+type statsSource struct {
+	Source
+	acc        statsAccumulator
+	keepValues bool
+	values     []float64
+}
+
+func (s *statsSource) Scan(fn func(v float64)) error {
+	return s.scanCounts(repeatCounts(fn))
+}
+
+func (s *statsSource) scanCounts(fn func(v float64, n int)) error {
+	return scanSourceCounts(s.Source, func(v float64, n int) {
+		s.acc.addCount(v, n)
+		if s.keepValues {
+			for i := 0; i < n; i++ {
+				s.values = append(s.values, v)
+			}
+		}
+		fn(v, n)
+	})
+}
+
+// fileSource reads values from a file, or stdin for stdinFilename.
+//
+// The first skipLines lines like headers are ignored. With skipInvalid,
+// lines which cannot be read are skipped instead of stopping, and their
+// count is reported to warner at the end. Reading stops if more than
+// maxErrors lines are skipped when it is positive.
+type fileSource struct {
+	filename    string
+	label       string
+	format      InputFormat
+	parse       ValueParser
+	skipLines   int
+	skipInvalid bool
+	maxErrors   int
+	warner      Warner
+	// ctx stops reading once it is done if it is not nil.
+	ctx context.Context
+}
+
+// newFileSource returns the source of filename read as cfg says.
+func newFileSource(cfg Config, filename string) *fileSource {
+	return &fileSource{
+		filename:    filename,
+		format:      cfg.InputFormat,
+		parse:       cfg.ValueParser,
+		skipLines:   cfg.SkipLines,
+		skipInvalid: cfg.SkipInvalid,
+		maxErrors:   cfg.MaxErrors,
+		warner:      cfg.Warner,
+	}
+}
+
+// Name returns the label of the source if given, or the filename.
+func (s *fileSource) Name() string {
+	if s.label != "" {
+		return s.label
+	}
+	return displayFilename(s.filename)
+}
+
+func (s *fileSource) Scan(fn func(v float64)) error {
+	return s.scanCounts(repeatCounts(fn))
+}
+
+func (s *fileSource) scanCounts(fn func(v float64, n int)) error {
+	r, err := newReadCloserFile(s.filename)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	var br *bufio.Reader
+	if s.ctx != nil {
+		br = bufio.NewReader(contextReader{ctx: s.ctx, r: r})
+	} else {
+		br = bufio.NewReader(r)
+	}
+	if err := skipLines(br, s.skipLines); err != nil {
+		return fmt.Errorf("%s: %w", s.Name(), err)
+	}
+	if !s.skipInvalid {
+		dropped, err := scanInput(br, s.format, s.parse, fn)
+		if err != nil {
+			return err
+		}
+		s.warnDropped(dropped)
+		return nil
+	}
+	skipped, dropped, err := scanSkippingInvalidLines(br, s.format, s.parse, s.maxErrors, fn)
+	if err != nil {
+		return fmt.Errorf("%s: %w", s.Name(), err)
+	}
+	s.warnDropped(dropped)
+	if len(skipped) > 0 && s.warner != nil {
+		s.warner.Warn(Warning{
+			Kind:    warningInvalidLine,
+			Message: fmt.Sprintf("skipped %d invalid lines, the first one: %s", len(skipped), skipped[0]),
+			Source:  s.Name(),
+			Details: map[string]any{"count": len(skipped)},
+		})
+	}
+	return nil
+}
+
+// warnDropped reports the number of records dropped by the input format
+// like NaN samples of Prometheus if any.
+func (s *fileSource) warnDropped(dropped int) {
+	if dropped == 0 || s.warner == nil {
+		return
+	}
+	s.warner.Warn(Warning{
+		Kind:    warningSamplesDropped,
+		Message: fmt.Sprintf("dropped %d samples without a value like NaN", dropped),
+		Source:  s.Name(),
+		Details: map[string]any{"count": dropped},
+	})
+}
+
+// columnSources returns a source per column of cfg.ColumnFormats, which
+// reads the only input file again for each column so that values are not
+// kept in memory. Sources are labeled like "column 2" unless labels are
+// given.
+func columnSources(cfg Config) []Source {
+	sources := make([]Source, len(cfg.ColumnFormats))
+	for i, format := range cfg.ColumnFormats {
+		src := newFileSource(cfg, cfg.Filenames[0])
+		src.format = format
+		switch f := format.(type) {
+		case csvInputFormat:
+			src.label = fmt.Sprintf("column %d", f.column)
+		case fieldInputFormat:
+			src.label = fmt.Sprintf("column %d", f.field)
+		}
+		if cfg.Labels != nil {
+			src.label = cfg.Labels[i]
+		}
+		sources[i] = src
+	}
+	return sources
+}
+
+func (s *fileSource) withContext(ctx context.Context) Source {
+	clone := *s
+	clone.ctx = ctx
+	return &clone
+}
+
+// contextSource is a Source which can read its input with a context.
+type contextSource interface {
+	// withContext returns a copy of the source which stops reading with
+	// the error of ctx once it is done.
+	withContext(ctx context.Context) Source
+}
+
+// sourceWithContext returns src reading with ctx if it supports it, or src
+// itself otherwise, like sources of values in memory.
+func sourceWithContext(ctx context.Context, src Source) Source {
+	if cs, ok := src.(contextSource); ok {
+		return cs.withContext(ctx)
+	}
+	return src
+}
+
+// contextReader fails reading with the error of ctx once it is done, so
+// that reading a long input stops when it is cancelled.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
+
+// forEachSource calls fn with the index of each of n sources on up to
+// parallelism goroutines at once, since parsing inputs is CPU-bound. It
+// runs them one by one if parallelism is less than 2. The error of the
+// first source in order is returned if some fail.
+func forEachSource(n, parallelism int, fn func(i int) error) error {
+	if parallelism < 2 || n < 2 {
+		for i := 0; i < n; i++ {
+			if err := fn(i); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	errs := make([]error, n)
+	sem := make(chan struct{}, parallelism)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			errs[i] = fn(i)
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readSourceValues reads all values of src into memory.
+func readSourceValues(src Source) ([]float64, error) {
+	var values []float64
+	err := src.Scan(func(v float64) {
+		values = append(values, v)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(values) == 0 {
+		return nil, fmt.Errorf("no value in %s", src.Name())
+	}
+	return values, nil
+}
+
+// countRecorder is a Recorder which can record a value n times at once.
+type countRecorder interface {
+	Recorder
+	addValueCount(v float64, n int)
+}
+
+// addSourceValues adds values of src to r without keeping them in memory.
+// A value read with a count is added with the count at once.
+func addSourceValues(r countRecorder, src Source) error {
+	total := 0
+	err := scanSourceCounts(src, func(v float64, n int) {
+		r.addValueCount(v, n)
+		total += n
+	})
+	if err != nil {
+		return err
+	}
+	if total == 0 {
+		return fmt.Errorf("no value in %s", src.Name())
+	}
+	return nil
+}
+
+// newPipeline wires the stages for cfg.
+func newPipeline(cfg Config) *Pipeline {
+	sources := make([]Source, len(cfg.Filenames))
+	for i, filename := range cfg.Filenames {
+		src := newFileSource(cfg, filename)
+		if cfg.Labels != nil {
+			src.label = cfg.Labels[i]
+		}
+		sources[i] = src
+	}
+	if cfg.ColumnFormats != nil {
+		sources = columnSources(cfg)
+	}
+	if cfg.SummaryStat != nil {
+		sources = []Source{&summarySource{sources: sources, stat: *cfg.SummaryStat}}
+	}
+
+	var binner Binner
+	switch {
+	case cfg.Backend == backendHDR || cfg.Backend == backendTDigest:
+		binner = &sketchBinner{cfg: cfg, newSketch: sketchMaker(cfg)}
+	case len(cfg.EdgesAtPercentiles) > 0:
+		binner = &percentileEdgesBinner{cfg: cfg}
+	case canStream(cfg):
+		binner = &streamingBinner{cfg: cfg}
+	default:
+		binner = &memoryBinner{cfg: cfg}
+	}
+
+	transforms, _ := samplers(cfg)
+	return &Pipeline{
+		Sources:       sources,
+		Transforms:    transforms,
+		Binner:        binner,
+		Renderer:      cfg.OutputFormat,
+		Warner:        cfg.Warner,
+		Logger:        cfg.Logger,
+		StatComputers: RegisteredStatComputers(),
+		Model: RenderModel{
+			BarChar:      defaultBarChar,
+			GraphWidth:   cfg.GraphWidth,
+			PointFmt:     cfg.PointFmt,
+			TickStyle:    cfg.TickStyle,
+			MinCount:     cfg.MinCount,
+			Duration:     cfg.Duration,
+			Percent:      cfg.Percent,
+			Cumulative:   cfg.Cumulative,
+			CDFBar:       cfg.CDFBar,
+			Title:        cfg.Title,
+			MetricName:   cfg.MetricName,
+			Annotations:  cfg.Annotations,
+			Color:        cfg.Color,
+			Palette:      cfg.Palette,
+			SeriesColors: cfg.SeriesColors,
+			BarStyle:     cfg.BarStyle,
+			Orientation:  cfg.Orientation,
+			GraphHeight:  cfg.GraphHeight,
+			FitHeight:    cfg.FitHeight,
+			Widths:       len(cfg.EdgesAtPercentiles) > 0,
+		},
+	}
+}
+
+// canStream returns whether buckets can be decided without reading values.
+// In that case values are counted while reading them so that memory usage
+// does not grow with the input size.
+func canStream(cfg Config) bool {
+	return !cfg.AxisMin.Auto && !cfg.AxisMax.Auto && cfg.BucketCountMethod == "" && cfg.DropOutliers == nil
+}
+
+// sourceNames returns the names of sources.
+func sourceNames(sources []Source) []string {
+	names := make([]string, len(sources))
+	for i, src := range sources {
+		names[i] = src.Name()
+	}
+	return names
+}
+
+// streamingBinner counts values while reading them into buckets for the
+// explicit axis range.
+type streamingBinner struct {
+	cfg Config
+}
+
+func (b *streamingBinner) Bin(sources []Source) ([]*Histogram[float64], error) {
+	rangePoints, err := buildRangePoints(b.cfg, b.cfg.AxisMin.Value, b.cfg.AxisMax.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	histograms := make([]*Histogram[float64], len(sources))
+	err = forEachSource(len(sources), b.cfg.Parallelism, func(i int) error {
+		histogram := NewHistogram(rangePoints)
+		histogram.SetBoundaryEpsilon(b.cfg.BoundaryEpsilon)
+		histogram.SetClosure(b.cfg.Closure)
+		histogram.SetInclusiveMax(b.cfg.InclusiveMax)
+		histograms[i] = histogram
+		return addSourceValues(histogram, sources[i])
+	})
+	if err != nil {
+		return nil, err
+	}
+	return histograms, nil
+}
+
+// sketch is a Recorder which keeps the minimum and the maximum and can be
+// converted to a Histogram afterwards.
+type sketch interface {
+	countRecorder
+	Min() float64
+	Max() float64
+	Histogram(rangePoints []float64) *Histogram[float64]
+}
+
+// sketchMaker returns the function making an empty sketch of the backend
+// of cfg, which is a TDigest unless the backend is hdr.
+func sketchMaker(cfg Config) func() sketch {
+	if cfg.Backend == backendHDR {
+		return func() sketch { return NewHDRHistogram(cfg.HDRDigits) }
+	}
+	return func() sketch { return NewTDigest(cfg.TDigestCompression) }
+}
+
+// sketchBinner records values into sketches while reading them, then
+// converts them to histograms for the axis range, which can be auto
+// without keeping values in memory.
+type sketchBinner struct {
+	cfg       Config
+	newSketch func() sketch
+}
+
+func (b *sketchBinner) Bin(sources []Source) ([]*Histogram[float64], error) {
+	sketches := make([]sketch, len(sources))
+	err := forEachSource(len(sources), b.cfg.Parallelism, func(i int) error {
+		sk := b.newSketch()
+		if err := addSourceValues(sk, sources[i]); err != nil {
+			return err
+		}
+		sketches[i] = sk
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return binSketches(b.cfg, sketches, sourceNames(sources))
+}
+
+// binSketches converts sketches named names to histograms sharing range
+// points for the axis range of cfg.
+func binSketches(cfg Config, sketches []sketch, names []string) ([]*Histogram[float64], error) {
+	minList := make([]float64, len(sketches))
+	maxList := make([]float64, len(sketches))
+	for i, sk := range sketches {
+		if cfg.Scale == scaleLog && sk.Min() <= 0 {
+			return nil, fmt.Errorf("log scale needs positive values, but %s has a value <= 0", names[i])
+		}
+		minList[i] = sk.Min()
+		maxList[i] = sk.Max()
+	}
+
+	axisMin, axisMax, err := decideAxisRange(cfg.AxisMin, cfg.AxisMax, MustMin(minList...), MustMax(maxList...), cfg.BucketCount, cfg.IncludeZero)
+	if err != nil {
+		return nil, err
+	}
+	rangePoints, err := buildRangePoints(cfg, axisMin, axisMax)
+	if err != nil {
+		return nil, err
+	}
+	histograms := make([]*Histogram[float64], len(sketches))
+	for i, sk := range sketches {
+		histograms[i] = sk.Histogram(rangePoints)
+	}
+	return histograms, nil
+}
+
+// memoryBinner reads all values into memory to decide the axis range and
+// the bucket count from them. A value read with a count is kept once with
+// the count.
+type memoryBinner struct {
+	cfg   Config
+	lists []weightedValues
+}
+
+func (b *memoryBinner) values() []weightedValues {
+	return b.lists
+}
+
+func (b *memoryBinner) Bin(sources []Source) ([]*Histogram[float64], error) {
+	cfg := b.cfg
+	lists := make([]weightedValues, len(sources))
+	err := forEachSource(len(sources), cfg.Parallelism, func(i int) error {
+		w, err := readSourceWeightedValues(sources[i])
+		if err != nil {
+			return err
+		}
+		if cfg.Scale == scaleLog && MustMin(w.values...) <= 0 {
+			return fmt.Errorf("log scale needs positive values, but %s has a value <= 0", sources[i].Name())
+		}
+		lists[i] = w
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if cfg.DropOutliers != nil {
+		if err := dropOutliers(cfg, lists, sourceNames(sources)); err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.BucketCountMethod != "" {
+		cfg.BucketCount = suggestBucketCountForValuesList(lists, cfg.BucketCountMethod, cfg.Scale)
+	}
+
+	dataMin, dataMax := dataRange(cfg.AxisMin, cfg.AxisMax, lists)
+	// Buckets of a bin width are not reduced, since the width is what the
+	// user asked for.
+	reduced := false
+	if distinct := countDistinctValues(lists, cfg.BucketCount); cfg.BinWidth == 0 && distinct < cfg.BucketCount {
+		cfg.warn(Warning{
+			Kind:    warningBucketCountReduced,
+			Message: fmt.Sprintf("reduced bucket count from %d to %d, the number of distinct values", cfg.BucketCount, distinct),
+			Details: map[string]any{"from": cfg.BucketCount, "to": distinct, "reason": "distinct_values"},
+		})
+		cfg.BucketCount = distinct
+		reduced = true
+	}
+
+	var axisMin, axisMax float64
+	if reduced && canCenterBuckets(cfg) {
+		axisMin, axisMax = centeredAxisRange(cfg.Scale, dataMin, dataMax, cfg.BucketCount)
+	} else {
+		axisMin, axisMax, err = decideAxisRange(cfg.AxisMin, cfg.AxisMax, dataMin, dataMax, cfg.BucketCount, cfg.IncludeZero)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	rangePoints, err := buildRangePoints(cfg, axisMin, axisMax)
+	if err != nil {
+		return nil, err
+	}
+	histograms := make([]*Histogram[float64], len(sources))
+	for i, w := range lists {
+		histogram := NewHistogram(rangePoints)
+		histogram.SetBoundaryEpsilon(cfg.BoundaryEpsilon)
+		histogram.SetClosure(cfg.Closure)
+		histogram.SetInclusiveMax(cfg.InclusiveMax)
+		w.addTo(histogram)
+		histograms[i] = histogram
+	}
+	b.lists = lists
+	return histograms, nil
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"golang.org/x/exp/slices"
+)
+
+type testSource struct {
+	name   string
+	values []float64
+}
+
+func (s *testSource) Name() string { return s.name }
+
+func (s *testSource) Scan(fn func(v float64)) error {
+	for _, v := range s.values {
+		fn(v)
+	}
+	return nil
+}
+
+func TestPipeline_Run(t *testing.T) {
+	cfg := Config{
+		BucketCount: 2,
+		AxisMin:     axisRangeEnd{Value: 0},
+		AxisMax:     axisRangeEnd{Value: 4},
+		Scale:       scaleLinear,
+	}
+	p := &Pipeline{
+		Sources: []Source{
+			&testSource{name: "a", values: []float64{0.5, 1, 3, -7}},
+			&testSource{name: "b", values: []float64{2, 2.5, 3.5}},
+		},
+		Transforms: []Transform{
+			TransformFunc(func(v float64) (float64, bool) { return v, v >= 0 }),
+			TransformFunc(func(v float64) (float64, bool) { return v * 2, true }),
+		},
+		Binner:   &streamingBinner{cfg: cfg},
+		Renderer: jsonOutputFormat{},
+	}
+	var buf bytes.Buffer
+	if err := p.Run(&buf); err != nil {
+		t.Fatal(err)
+	}
+	got := strings.Join(strings.Fields(buf.String()), "")
+	want := `[{"label":"a","histogram":{"rangePoints":[0,2,4],"counts":[1,1],"outOfRangeCount":1,"underflowCount":0,"overflowCount":1}},` +
+		`{"label":"b","histogram":{"rangePoints":[0,2,4],"counts":[0,0],"outOfRangeCount":3,"underflowCount":0,"overflowCount":3}}]`
+	if got != want {
+		t.Errorf("result mismatch,\n got=%s,\nwant=%s", got, want)
+	}
+}
+
+func TestMemoryBinner(t *testing.T) {
+	cfg := Config{
+		BucketCount: 4,
+		AxisMin:     axisRangeEnd{Auto: true},
+		AxisMax:     axisRangeEnd{Auto: true},
+		Scale:       scaleLinear,
+	}
+	histograms, err := (&memoryBinner{cfg: cfg}).Bin([]Source{
+		&testSource{name: "a", values: []float64{1, 2, 3}},
+		&testSource{name: "b", values: []float64{4, 5}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := histograms[0].RangePoints(), []float64{1, 2, 3, 4, 5}; !slices.Equal(got, want) {
+		t.Errorf("range points mismatch, got=%v, want=%v", got, want)
+	}
+	if got, want := histograms[1].Counts(), []int{0, 0, 0, 1}; !slices.Equal(got, want) {
+		t.Errorf("counts mismatch, got=%v, want=%v", got, want)
+	}
+
+	histograms, err = (&memoryBinner{cfg: cfg}).Bin([]Source{&testSource{name: "constant", values: []float64{3, 3, 3}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := histograms[0].RangePoints(), []float64{2.5, 3.5}; !slices.Equal(got, want) {
+		t.Errorf("range points mismatch for constant values, got=%v, want=%v", got, want)
+	}
+	if got, want := histograms[0].Counts(), []int{3}; !slices.Equal(got, want) {
+		t.Errorf("counts mismatch for constant values, got=%v, want=%v", got, want)
+	}
+
+	_, err = (&memoryBinner{cfg: cfg}).Bin([]Source{&testSource{name: "empty"}})
+	if err == nil || err.Error() != "no value in empty" {
+		t.Errorf("error mismatch, got=%v", err)
+	}
+}
+
+func TestCanStream(t *testing.T) {
+	explicit := Config{AxisMin: axisRangeEnd{Value: 0}, AxisMax: axisRangeEnd{Value: 10}}
+	testCases := []struct {
+		name   string
+		modify func(cfg *Config)
+		want   bool
+	}{
+		{name: "explicit range", modify: func(cfg *Config) {}, want: true},
+		{name: "bin width", modify: func(cfg *Config) { cfg.BinWidth = 2 }, want: true},
+		{name: "auto min", modify: func(cfg *Config) { cfg.AxisMin = axisRangeEnd{Auto: true} }, want: false},
+		{name: "percentile max", modify: func(cfg *Config) { cfg.AxisMax = axisRangeEnd{Auto: true, Percentile: 99} }, want: false},
+		{name: "bucket count method", modify: func(cfg *Config) { cfg.BucketCountMethod = BucketCountSturges }, want: false},
+		{name: "drop outliers", modify: func(cfg *Config) { cfg.DropOutliers = &outlierRule{} }, want: false},
+	}
+	for _, tc := range testCases {
+		cfg := explicit
+		tc.modify(&cfg)
+		if got := canStream(cfg); got != tc.want {
+			t.Errorf("result mismatch, name=%s, got=%v, want=%v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestForEachSource(t *testing.T) {
+	for _, parallelism := range []int{1, 3} {
+		var mu sync.Mutex
+		seen := make([]bool, 8)
+		err := forEachSource(len(seen), parallelism, func(i int) error {
+			mu.Lock()
+			seen[i] = true
+			mu.Unlock()
+			if i == 5 || i == 6 {
+				return fmt.Errorf("error %d", i)
+			}
+			return nil
+		})
+		if err == nil || err.Error() != "error 5" {
+			t.Errorf("error mismatch, parallelism=%d, got=%v, want=error 5", parallelism, err)
+		}
+		if parallelism > 1 && slices.Contains(seen, false) {
+			t.Errorf("all sources must be visited, parallelism=%d, got=%v", parallelism, seen)
+		}
+	}
+}
+
+func TestPipeline_BinParallel(t *testing.T) {
+	cfg := Config{
+		BucketCount: 4,
+		AxisMin:     axisRangeEnd{Auto: true},
+		AxisMax:     axisRangeEnd{Auto: true},
+		Scale:       scaleLinear,
+		Backend:     backendHistogram,
+		SampleEvery: 2,
+	}
+	var sources []Source
+	for i := 0; i < 8; i++ {
+		values := make([]float64, 100)
+		for j := range values {
+			values[j] = float64(i*100 + j)
+		}
+		sources = append(sources, &testSource{name: fmt.Sprint(i), values: values})
+	}
+	bin := func(parallelism int) *RenderModel {
+		cfg.Parallelism = parallelism
+		p := newPipeline(cfg)
+		p.Sources = sources
+		m, err := p.Bin()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return m
+	}
+	want, got := bin(1), bin(4)
+	for i := range want.Histograms {
+		if !got.Histograms[i].Equal(want.Histograms[i]) {
+			t.Errorf("histogram %d mismatch, got=%v, want=%v", i, got.Histograms[i], want.Histograms[i])
+		}
+	}
+}
+
+func TestPipeline_BinStats(t *testing.T) {
+	cfg := Config{
+		BucketCount: 2,
+		AxisMin:     axisRangeEnd{Value: 0},
+		AxisMax:     axisRangeEnd{Value: 4},
+		Scale:       scaleLinear,
+	}
+	p := &Pipeline{
+		Sources: []Source{&testSource{name: "a", values: []float64{2, 4, 4, 4, 5, 5, 7, 9}}},
+		Binner:  &streamingBinner{cfg: cfg},
+	}
+	m, err := p.Bin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := m.Stats[0]
+	want := Stats{Count: 8, Min: 2, Max: 9, Mean: 5, Stddev: math.Sqrt(32.0 / 7)}
+	if got.Count != want.Count || got.Min != want.Min || got.Max != want.Max || got.Mean != want.Mean ||
+		math.Abs(got.Stddev-want.Stddev) > 1e-12 {
+		t.Errorf("stats mismatch, got=%+v, want=%+v", got, want)
+	}
+}
+
+func TestPipeline_BinContext(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "values.txt")
+	if err := os.WriteFile(filename, []byte("1\n2\n3\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := Config{
+		BucketCount: 2,
+		AxisMin:     axisRangeEnd{Value: 0},
+		AxisMax:     axisRangeEnd{Value: 4},
+		Scale:       scaleLinear,
+		InputFormat: plainInputFormat{},
+		ValueParser: parseFiniteFloat,
+	}
+	stat, err := parseSummaryStat("max")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &Pipeline{
+		Sources: []Source{
+			newFileSource(cfg, filename),
+			&summarySource{sources: []Source{newFileSource(cfg, filename)}, stat: stat},
+		},
+		Binner: &streamingBinner{cfg: cfg},
+	}
+	m, err := p.BinContext(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := m.Histograms[0].Counts(), []int{1, 2}; !slices.Equal(got, want) {
+		t.Errorf("counts mismatch, got=%v, want=%v", got, want)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for i := range p.Sources {
+		q := *p
+		q.Sources = p.Sources[i : i+1]
+		if _, err := q.BinContext(ctx); !errors.Is(err, context.Canceled) {
+			t.Errorf("error mismatch, source=%s, got=%v, want=%v", q.Sources[0].Name(), err, context.Canceled)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"runtime/trace"
+)
+
+// startProfiling starts writing a CPU profile and an execution trace to
+// the files if their names are not empty. The returned stop function
+// stops them and writes a heap profile to memProfile if it is not empty.
+func startProfiling(cpuProfile, memProfile, traceFile string) (stop func() error, err error) {
+	var stops []func() error
+	stopAll := func() error {
+		var firstErr error
+		for i := len(stops) - 1; i >= 0; i-- {
+			if err := stops[i](); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+		return firstErr
+	}
+
+	if cpuProfile != "" {
+		f, err := os.Create(cpuProfile)
+		if err != nil {
+			return nil, err
+		}
+		if err := pprof.StartCPUProfile(f); err != nil {
+			f.Close()
+			return nil, err
+		}
+		stops = append(stops, func() error {
+			pprof.StopCPUProfile()
+			return f.Close()
+		})
+	}
+
+	if traceFile != "" {
+		f, err := os.Create(traceFile)
+		if err != nil {
+			stopAll()
+			return nil, err
+		}
+		if err := trace.Start(f); err != nil {
+			f.Close()
+			stopAll()
+			return nil, err
+		}
+		stops = append(stops, func() error {
+			trace.Stop()
+			return f.Close()
+		})
+	}
+
+	if memProfile != "" {
+		stops = append(stops, func() error {
+			f, err := os.Create(memProfile)
+			if err != nil {
+				return err
+			}
+			// Get up-to-date statistics of live objects.
+			runtime.GC()
+			if err := pprof.WriteHeapProfile(f); err != nil {
+				f.Close()
+				return err
+			}
+			return f.Close()
+		})
+	}
+	return stopAll, nil
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStartProfiling(t *testing.T) {
+	dir := t.TempDir()
+	cpuProfile := filepath.Join(dir, "cpu.pprof")
+	memProfile := filepath.Join(dir, "mem.pprof")
+	traceFile := filepath.Join(dir, "trace.out")
+
+	stop, err := startProfiling(cpuProfile, memProfile, traceFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := NewHistogram(BuildRangePoints[float64](10, 0, 1))
+	for i := 0; i < 1000; i++ {
+		h.AddValue(float64(i) / 1000)
+	}
+	if err := stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, filename := range []string{cpuProfile, memProfile, traceFile} {
+		info, err := os.Stat(filename)
+		if err != nil {
+			t.Error(err)
+		} else if info.Size() == 0 {
+			t.Errorf("%s must not be empty", filename)
+		}
+	}
+}
+
+func TestStartProfiling_None(t *testing.T) {
+	stop, err := startProfiling("", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := stop(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// defaultMetricName is the metric name of prometheusOutputFormat when
+// --metric-name is not given.
+const defaultMetricName = "histogram"
+
+var metricNameRegexp = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+
+// validMetricName returns whether name can be used as a Prometheus metric
+// name.
+func validMetricName(name string) bool {
+	return metricNameRegexp.MatchString(name)
+}
+
+// prometheusOutputFormat renders histograms in the Prometheus text
+// exposition format for the textfile collector of node_exporter. Each
+// input is a series with an "input" label.
+//
+// Values below the first range point are counted in all buckets, since
+// they are less than every upper bound. A value equal to an upper bound is
+// counted in the next bucket unlike "le" says, since buckets include their
+// lower bounds unless --closure right is given.
+type prometheusOutputFormat struct{}
+
+func (prometheusOutputFormat) Name() string { return "prometheus" }
+
+func (prometheusOutputFormat) FileExtension() string { return ".prom" }
+
+func (prometheusOutputFormat) Render(w io.Writer, m *RenderModel) error {
+	name := m.MetricName
+	if name == "" {
+		name = defaultMetricName
+	}
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "# HELP %s Distribution of values read by histogram.\n", name)
+	fmt.Fprintf(bw, "# TYPE %s histogram\n", name)
+	for i, h := range m.Histograms {
+		input := fmt.Sprintf(`input="%s"`, escapeLabelValue(m.Labels[i]))
+		cumulative := h.underflowCount
+		for j, count := range h.counts {
+			cumulative += count
+			le := strconv.FormatFloat(h.rangePoints[j+1], 'g', -1, 64)
+			fmt.Fprintf(bw, "%s_bucket{%s,le=\"%s\"} %d\n", name, input, le, cumulative)
+		}
+		fmt.Fprintf(bw, "%s_bucket{%s,le=\"+Inf\"} %d\n", name, input, h.TotalCount())
+		if m.Stats != nil {
+			s := m.Stats[i]
+			sum := s.Mean * float64(s.Count)
+			fmt.Fprintf(bw, "%s_sum{%s} %s\n", name, input, strconv.FormatFloat(sum, 'g', -1, 64))
+		}
+		fmt.Fprintf(bw, "%s_count{%s} %d\n", name, input, h.TotalCount())
+	}
+	return bw.Flush()
+}
+
+var labelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// escapeLabelValue escapes s for a label value in the text exposition
+// format.
+func escapeLabelValue(s string) string {
+	return labelValueReplacer.Replace(s)
+}
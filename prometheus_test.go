@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestPrometheusOutputFormat(t *testing.T) {
+	rangePoints := BuildRangePoints[float64](3, 0, 1.5)
+	h := NewHistogram(rangePoints)
+	h.AddValues([]float64{-1, 0.25, 0.75, 0.75, 1.25, 2})
+	m := &RenderModel{
+		Histograms: []*Histogram[float64]{h},
+		Labels:     []string{`a"b.txt`},
+		Stats:      []Stats{{Count: 6, Mean: 1}},
+		MetricName: "latency_seconds",
+	}
+	var buf bytes.Buffer
+	if err := (prometheusOutputFormat{}).Render(&buf, m); err != nil {
+		t.Fatal(err)
+	}
+	got := buf.String()
+	want := `# HELP latency_seconds Distribution of values read by histogram.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{input="a\"b.txt",le="0.5"} 2
+latency_seconds_bucket{input="a\"b.txt",le="1"} 4
+latency_seconds_bucket{input="a\"b.txt",le="1.5"} 5
+latency_seconds_bucket{input="a\"b.txt",le="+Inf"} 6
+latency_seconds_sum{input="a\"b.txt"} 6
+latency_seconds_count{input="a\"b.txt"} 6
+`
+	if got != want {
+		t.Errorf("result mismatch,\n got=%q,\nwant=%q", got, want)
+	}
+}
+
+func TestValidMetricName(t *testing.T) {
+	testCases := []struct {
+		name string
+		want bool
+	}{
+		{name: "histogram", want: true},
+		{name: "http:request_duration_seconds", want: true},
+		{name: "_x1", want: true},
+		{name: "1x", want: false},
+		{name: "a-b", want: false},
+		{name: "", want: false},
+	}
+	for _, tc := range testCases {
+		if got := validMetricName(tc.name); got != tc.want {
+			t.Errorf("result mismatch, name=%q, got=%v, want=%v", tc.name, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// recipeExcludedFlags are flags which do not change the analysis, so they
+// are neither saved to nor loaded from recipes.
+var recipeExcludedFlags = map[string]bool{
+	"config":      true,
+	"recipe":      true,
+	"save-recipe": true,
+	"save":        true,
+	"parallelism": true,
+	"cpuprofile":  true,
+	"memprofile":  true,
+	"trace":       true,
+	"help":        true,
+	"version":     true,
+}
+
+// Recipe is the flags of an analysis by their names, which can be saved
+// to a YAML file like
+//
+//	bucket-count: "20"
+//	scale: log
+//	value-type: duration
+//
+// and applied to another run on new data.
+type Recipe map[string]string
+
+// recipeFromContext returns the flags set on the command line or by an
+// applied recipe. Flags left to their defaults are not included, since
+// some defaults depend on other flags. Values of slice flags are joined
+// with commas, which Set splits again.
+func recipeFromContext(cCtx *cli.Context) Recipe {
+	recipe := make(Recipe)
+	for _, flag := range cCtx.App.Flags {
+		name := flag.Names()[0]
+		if recipeExcludedFlags[name] || !cCtx.IsSet(name) {
+			continue
+		}
+		switch flag.(type) {
+		case *cli.StringSliceFlag:
+			recipe[name] = strings.Join(cCtx.StringSlice(name), ",")
+		case *cli.IntSliceFlag:
+			ints := cCtx.IntSlice(name)
+			values := make([]string, len(ints))
+			for i, n := range ints {
+				values[i] = strconv.Itoa(n)
+			}
+			recipe[name] = strings.Join(values, ",")
+		default:
+			recipe[name] = fmt.Sprint(cCtx.Value(name))
+		}
+	}
+	return recipe
+}
+
+// applyRecipe sets flags in recipe which are not set on the command line,
+// so that the command line overrides the recipe.
+func applyRecipe(cCtx *cli.Context, recipe Recipe) error {
+	known := make(map[string]bool)
+	for _, flag := range cCtx.App.Flags {
+		known[flag.Names()[0]] = true
+	}
+	for name, value := range recipe {
+		if !known[name] || recipeExcludedFlags[name] {
+			return fmt.Errorf("unknown flag %q", name)
+		}
+		if cCtx.IsSet(name) {
+			continue
+		}
+		if err := cCtx.Set(name, value); err != nil {
+			return fmt.Errorf("invalid value %q for %q: %s", value, name, err)
+		}
+	}
+	return nil
+}
+
+func readRecipeFile(filename string) (Recipe, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var recipe Recipe
+	if err := yaml.Unmarshal(data, &recipe); err != nil {
+		return nil, fmt.Errorf("invalid recipe file %s: %s", filename, err)
+	}
+	return recipe, nil
+}
+
+func writeRecipeFile(filename string, recipe Recipe) error {
+	data, err := yaml.Marshal(recipe)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, data, 0o644)
+}
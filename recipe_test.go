@@ -0,0 +1,86 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/exp/slices"
+)
+
+func TestRecipe(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "recipe.yaml")
+	cfg, err := ParseConfig([]string{"-c", "4", "--scale", "log", "-d", "5m", "--cpuprofile", "cpu.out", "--save-recipe", filename, "a.txt"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := writeRecipeFile(cfg.SaveRecipe, cfg.Recipe); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `bucket-count: "4"
+duration: 5m0s
+scale: log
+`
+	if got := string(data); got != want {
+		t.Errorf("recipe mismatch,\n got=%q,\nwant=%q", got, want)
+	}
+
+	cfg2, err := ParseConfig([]string{"--recipe", filename, "-c", "3", "b.txt"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := cfg2.Scale, scaleLog; got != want {
+		t.Errorf("scale mismatch, got=%s, want=%s", got, want)
+	}
+	if got, want := cfg2.PointFmt, logScalePointFmt; got != want {
+		t.Errorf("point format mismatch, got=%s, want=%s", got, want)
+	}
+	if got, want := cfg2.Duration, cfg.Duration; got != want {
+		t.Errorf("duration mismatch, got=%s, want=%s", got, want)
+	}
+	if got, want := cfg2.BucketCount, 3; got != want {
+		t.Errorf("command line must override recipe, got=%d, want=%d", got, want)
+	}
+
+	// Slice flags are saved as comma separated values.
+	cfg, err = ParseConfig([]string{"--columns", "1,2", "--label", "a", "--label", "b", "--save-recipe", filename, "a.txt"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := writeRecipeFile(cfg.SaveRecipe, cfg.Recipe); err != nil {
+		t.Fatal(err)
+	}
+	data, err = os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = `columns: 1,2
+label: a,b
+`
+	if got := string(data); got != want {
+		t.Errorf("recipe mismatch,\n got=%q,\nwant=%q", got, want)
+	}
+	cfg2, err = ParseConfig([]string{"--recipe", filename, "b.txt"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := cfg2.Labels, []string{"a", "b"}; !slices.Equal(got, want) {
+		t.Errorf("labels mismatch, got=%v, want=%v", got, want)
+	}
+	if got, want := len(cfg2.ColumnFormats), 2; got != want {
+		t.Errorf("columns mismatch, got=%d, want=%d", got, want)
+	}
+
+	if err := os.WriteFile(filename, []byte("bucket-size: 3\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	_, err = ParseConfig([]string{"--recipe", filename, "b.txt"})
+	if err == nil || !strings.Contains(err.Error(), `unknown flag "bucket-size"`) {
+		t.Errorf("error mismatch, got=%v", err)
+	}
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/exp/slices"
+	"gopkg.in/yaml.v3"
+)
+
+// Formats of reports.
+const (
+	reportFormatText = "text"
+	reportFormatHTML = "html"
+)
+
+// Types of report panels.
+const (
+	panelHistogram       = "histogram"
+	panelECDF            = "ecdf"
+	panelPercentileTable = "percentile-table"
+)
+
+// Report is a combined report of several panels over named inputs, read
+// from a YAML file like
+//
+//	title: API latency
+//	format: html
+//	inputs:
+//	  - name: before
+//	    files: [before-1.log, before-2.log]
+//	  - name: after
+//	    files: [after.log]
+//	panels:
+//	  - type: histogram
+//	  - type: ecdf
+//	    inputs: [after]
+//	  - type: percentile-table
+//
+// Values of all files of an input are counted together. A panel shows all
+// inputs unless its inputs are given. Relative file paths are resolved
+// against the directory of the report file.
+type Report struct {
+	Title  string        `yaml:"title"`
+	Format string        `yaml:"format"`
+	Inputs []ReportInput `yaml:"inputs"`
+	Panels []ReportPanel `yaml:"panels"`
+}
+
+// ReportInput is a named group of files.
+type ReportInput struct {
+	Name  string   `yaml:"name"`
+	Files []string `yaml:"files"`
+}
+
+// ReportPanel is a section of a report. Type is "histogram", "ecdf" or
+// "percentile-table".
+type ReportPanel struct {
+	Type   string   `yaml:"type"`
+	Title  string   `yaml:"title"`
+	Inputs []string `yaml:"inputs"`
+}
+
+func readReportFile(filename string) (*Report, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var report Report
+	if err := yaml.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("invalid report file %s: %s", filename, err)
+	}
+	if err := report.validate(); err != nil {
+		return nil, fmt.Errorf("invalid report file %s: %s", filename, err)
+	}
+	dir := filepath.Dir(filename)
+	for i := range report.Inputs {
+		for j, file := range report.Inputs[i].Files {
+			if file != stdinFilename && !filepath.IsAbs(file) {
+				report.Inputs[i].Files[j] = filepath.Join(dir, file)
+			}
+		}
+	}
+	return &report, nil
+}
+
+func (r *Report) validate() error {
+	switch r.Format {
+	case "":
+		r.Format = reportFormatText
+	case reportFormatText, reportFormatHTML:
+	default:
+		return fmt.Errorf(`format must be "%s" or "%s", got %q`, reportFormatText, reportFormatHTML, r.Format)
+	}
+	if len(r.Inputs) == 0 {
+		return fmt.Errorf("no input")
+	}
+	names := make(map[string]bool)
+	for i, input := range r.Inputs {
+		switch {
+		case input.Name == "":
+			return fmt.Errorf("input #%d has no name", i+1)
+		case names[input.Name]:
+			return fmt.Errorf("input %q is defined twice", input.Name)
+		case len(input.Files) == 0:
+			return fmt.Errorf("input %q has no file", input.Name)
+		}
+		names[input.Name] = true
+	}
+	if len(r.Panels) == 0 {
+		return fmt.Errorf("no panel")
+	}
+	for i, panel := range r.Panels {
+		switch panel.Type {
+		case panelHistogram, panelECDF, panelPercentileTable:
+		default:
+			return fmt.Errorf(`panel #%d type must be "%s", "%s" or "%s", got %q`, i+1, panelHistogram, panelECDF, panelPercentileTable, panel.Type)
+		}
+		for _, name := range panel.Inputs {
+			if !names[name] {
+				return fmt.Errorf("panel #%d has unknown input %q", i+1, name)
+			}
+		}
+	}
+	return nil
+}
+
+// reportInputSource reads values of all files of a report input.
+type reportInputSource struct {
+	name    string
+	sources []Source
+}
+
+func (s *reportInputSource) Name() string { return s.name }
+
+func (s *reportInputSource) Scan(fn func(v float64)) error {
+	for _, src := range s.sources {
+		if err := src.Scan(fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// panelSources returns the sources of the inputs shown in panel.
+func (r *Report) panelSources(panel ReportPanel, cfg Config) []Source {
+	var sources []Source
+	for _, input := range r.Inputs {
+		if len(panel.Inputs) > 0 && !slices.Contains(panel.Inputs, input.Name) {
+			continue
+		}
+		src := &reportInputSource{name: input.Name}
+		for _, file := range input.Files {
+			src.sources = append(src.sources, newFileSource(cfg, file))
+		}
+		sources = append(sources, src)
+	}
+	return sources
+}
+
+// panelTitle returns the title of the i-th panel, which is its type if
+// not given.
+func (r *Report) panelTitle(i int) string {
+	if title := r.Panels[i].Title; title != "" {
+		return title
+	}
+	return r.Panels[i].Type
+}
+
+// renderPanel writes the i-th panel of r to w. Histograms are drawn as SVG
+// charts in HTML reports and as text otherwise.
+func (r *Report) renderPanel(ctx context.Context, w io.Writer, cfg Config, i int) error {
+	panel := r.Panels[i]
+	p := newPipeline(cfg)
+	p.Sources = r.panelSources(panel, cfg)
+	m, err := p.BinContext(ctx)
+	if err != nil {
+		return fmt.Errorf("panel %q: %w", r.panelTitle(i), err)
+	}
+
+	var renderer Renderer = textOutputFormat{}
+	switch panel.Type {
+	case panelHistogram:
+		if r.Format == reportFormatHTML {
+			renderer = svgOutputFormat{}
+			m.Title = r.panelTitle(i)
+		}
+	case panelECDF:
+		m.Cumulative = true
+		m.CDFBar = true
+	case panelPercentileTable:
+		renderer = percentileTableOutputFormat{}
+	}
+	return renderer.Render(w, m)
+}
+
+var htmlCombinedReportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{if .Title}}{{.Title}}{{else}}Histogram report{{end}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+h1 { font-size: 1.4em; }
+h2 { font-size: 1.1em; margin-top: 2em; }
+svg { max-width: 100%; height: auto; border: 1px solid #ddd; }
+</style>
+</head>
+<body>
+{{if .Title}}<h1>{{.Title}}</h1>
+{{end}}{{range .Panels}}<h2>{{.Title}}</h2>
+{{if .Chart}}{{.Chart}}{{else}}<pre>{{.Text}}</pre>
+{{end}}{{end}}</body>
+</html>
+`))
+
+// runReport renders the report in cfg.ReportFile to w with the settings of
+// cfg like the bucket count.
+func runReport(ctx context.Context, w io.Writer, cfg Config) error {
+	r, err := readReportFile(cfg.ReportFile)
+	if err != nil {
+		return err
+	}
+	// Color codes are for terminals and break HTML.
+	cfg.Color = cfg.Color && r.Format == reportFormatText
+
+	if r.Format == reportFormatText {
+		if r.Title != "" {
+			if _, err := fmt.Fprintf(w, "# %s\n\n", r.Title); err != nil {
+				return err
+			}
+		}
+		for i := range r.Panels {
+			if i > 0 {
+				if _, err := io.WriteString(w, "\n"); err != nil {
+					return err
+				}
+			}
+			if _, err := fmt.Fprintf(w, "== %s\n", r.panelTitle(i)); err != nil {
+				return err
+			}
+			if err := r.renderPanel(ctx, w, cfg, i); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	type panel struct {
+		Title string
+		Chart template.HTML
+		Text  string
+	}
+	data := struct {
+		Title  string
+		Panels []panel
+	}{Title: r.Title}
+	for i := range r.Panels {
+		var buf bytes.Buffer
+		if err := r.renderPanel(ctx, &buf, cfg, i); err != nil {
+			return err
+		}
+		p := panel{Title: r.panelTitle(i)}
+		if r.Panels[i].Type == panelHistogram {
+			p.Chart = template.HTML(buf.String())
+		} else {
+			p.Text = buf.String()
+		}
+		data.Panels = append(data.Panels, p)
+	}
+	return htmlCombinedReportTemplate.Execute(w, data)
+}
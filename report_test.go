@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunReport(t *testing.T) {
+	cfg, err := ParseConfig([]string{"-c", "5", "--color", "never", "report", "testdata/report.yaml"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := cfg.ReportFile, "testdata/report.yaml"; got != want {
+		t.Errorf("report file mismatch, got=%s, want=%s", got, want)
+	}
+	if got, want := cfg.BucketCount, 5; got != want {
+		t.Errorf("bucket count mismatch, got=%d, want=%d", got, want)
+	}
+
+	var buf bytes.Buffer
+	if err := runReport(context.Background(), &buf, cfg); err != nil {
+		t.Fatal(err)
+	}
+	got := buf.String()
+	want := `# Latency
+
+== histogram
+               a                               both
+ 7.00 ~ 22.00  99 |**************              183 |***************************
+22.00 ~ 37.00  70 |**********                  157 |***********************
+37.00 ~ 52.00  22 |***                          48 |*******
+52.00 ~ 67.00   8 |*                            10 |*
+67.00 ~ 82.00   1 |                              2 |
+ out of range   0 |                              0 |
+
+== ECDF of a
+ 7.00 ~ 22.00  99  49.5% |******************************** |=========
+22.00 ~ 37.00  70  84.5% |**********************           |================
+37.00 ~ 52.00  22  95.5% |*******                          |===================
+52.00 ~ 67.00   8  99.5% |**                               |===================
+67.00 ~ 82.00   1 100.0% |                                 |====================
+ out of range   0        |
+
+== percentile-table
+input            p50            p90            p95            p99            max
+a              22.17          42.11          46.86          60.94          81.78
+both   22.77 (+2.7%)  41.09 (-2.4%)  45.97 (-1.9%)  60.57 (-0.6%)  81.78 (+0.0%)
+`
+	if got != want {
+		t.Errorf("result mismatch,\n got=%q,\nwant=%q", got, want)
+		fmt.Printf("\n%s", got)
+	}
+
+	data, err := os.ReadFile("testdata/report.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	htmlReport := filepath.Join(t.TempDir(), "report.yaml")
+	html := strings.Replace(string(data), "title: Latency\n", "title: Latency\nformat: html\n", 1)
+	html = strings.ReplaceAll(html, "latency_", filepath.Join(mustAbs("testdata"), "latency_"))
+	if err := os.WriteFile(htmlReport, []byte(html), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg.ReportFile = htmlReport
+	buf.Reset()
+	if err := runReport(context.Background(), &buf, cfg); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"<h1>Latency</h1>", "<h2>histogram</h2>\n<svg", "<h2>ECDF of a</h2>\n<pre>"} {
+		if !strings.Contains(buf.String(), s) {
+			t.Errorf("HTML report must contain %q, got=%s", s, buf.String())
+		}
+	}
+}
+
+func mustAbs(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		panic(err)
+	}
+	return abs
+}
+
+func TestReportValidate(t *testing.T) {
+	input := ReportInput{Name: "a", Files: []string{"a.txt"}}
+	testCases := []struct {
+		report Report
+		want   string
+	}{
+		{report: Report{Format: "pdf"}, want: `format must be "text" or "html", got "pdf"`},
+		{report: Report{}, want: "no input"},
+		{report: Report{Inputs: []ReportInput{{Files: []string{"a.txt"}}}}, want: "input #1 has no name"},
+		{report: Report{Inputs: []ReportInput{input, input}}, want: `input "a" is defined twice`},
+		{report: Report{Inputs: []ReportInput{{Name: "a"}}}, want: `input "a" has no file`},
+		{report: Report{Inputs: []ReportInput{input}}, want: "no panel"},
+		{report: Report{Inputs: []ReportInput{input}, Panels: []ReportPanel{{Type: "pie"}}}, want: `panel #1 type must be "histogram", "ecdf" or "percentile-table", got "pie"`},
+		{report: Report{Inputs: []ReportInput{input}, Panels: []ReportPanel{{Type: "ecdf", Inputs: []string{"b"}}}}, want: `panel #1 has unknown input "b"`},
+	}
+	for _, tc := range testCases {
+		err := tc.report.validate()
+		if err == nil || err.Error() != tc.want {
+			t.Errorf("error mismatch, report=%+v, got=%v, want=%s", tc.report, err, tc.want)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+)
+
+// everyNthSampler is a Transform keeping every nth value read of each
+// input.
+type everyNthSampler struct {
+	n    int
+	seen int
+}
+
+func (s *everyNthSampler) Apply(v float64) (float64, bool) {
+	s.seen++
+	return v, s.seen%s.n == 0
+}
+
+// applyCount keeps the values which would be kept if v were read n times
+// one by one.
+func (s *everyNthSampler) applyCount(v float64, n int) (float64, int) {
+	kept := (s.seen+n)/s.n - s.seen/s.n
+	s.seen += n
+	return v, kept
+}
+
+// forSource returns a sampler counting values of another input.
+func (s *everyNthSampler) forSource() Transform {
+	return &everyNthSampler{n: s.n}
+}
+
+// probabilitySampler is a Transform keeping each value with probability p
+// independently. random returns a number in [0, 1).
+type probabilitySampler struct {
+	p      float64
+	random func() float64
+}
+
+func (s *probabilitySampler) Apply(v float64) (float64, bool) {
+	return v, s.random() < s.p
+}
+
+// samplers returns the Transforms sampling values as cfg says, and the
+// factor to scale counts of sampled values up to estimates of all values.
+func samplers(cfg Config) ([]Transform, float64) {
+	var transforms []Transform
+	factor := 1.0
+	if cfg.SampleEvery > 1 {
+		transforms = append(transforms, &everyNthSampler{n: cfg.SampleEvery})
+		factor *= float64(cfg.SampleEvery)
+	}
+	if cfg.SampleProb > 0 && cfg.SampleProb < 1 {
+		transforms = append(transforms, &probabilitySampler{p: cfg.SampleProb, random: rand.Float64})
+		factor /= cfg.SampleProb
+	}
+	return transforms, factor
+}
+
+// scaleCounts multiplies counts of h by factor, rounding them to integers,
+// so that counts of sampled values estimate those of all values.
+func scaleCounts(h *Histogram[float64], factor float64) {
+	scale := func(count int) int {
+		return int(math.Round(float64(count) * factor))
+	}
+	for i, count := range h.counts {
+		h.counts[i] = scale(count)
+	}
+	// NaN values are neither below nor above the range.
+	nanCount := h.outOfRangeCount - h.underflowCount - h.overflowCount
+	h.underflowCount = scale(h.underflowCount)
+	h.overflowCount = scale(h.overflowCount)
+	h.outOfRangeCount = h.underflowCount + h.overflowCount + scale(nanCount)
+}
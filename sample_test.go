@@ -0,0 +1,73 @@
+package main
+
+import (
+	"testing"
+
+	"golang.org/x/exp/slices"
+)
+
+func TestSamplers(t *testing.T) {
+	values := []float64{1, 2, 3, 4, 5, 6, 7}
+	apply := func(transforms []Transform) []float64 {
+		var kept []float64
+	values:
+		for _, v := range values {
+			for _, tr := range transforms {
+				var keep bool
+				if v, keep = tr.Apply(v); !keep {
+					continue values
+				}
+			}
+			kept = append(kept, v)
+		}
+		return kept
+	}
+
+	transforms, factor := samplers(Config{SampleEvery: 3})
+	if got, want := apply(transforms), []float64{3, 6}; !slices.Equal(got, want) {
+		t.Errorf("every nth result mismatch, got=%v, want=%v", got, want)
+	}
+	if got, want := factor, 3.0; got != want {
+		t.Errorf("factor mismatch, got=%g, want=%g", got, want)
+	}
+	// Values read with counts keep the same share as read one by one.
+	every := &everyNthSampler{n: 3}
+	var kept []int
+	for _, n := range []int{2, 1, 7, 0, 2000000000} {
+		_, k := every.applyCount(1, n)
+		kept = append(kept, k)
+	}
+	if want := []int{0, 1, 2, 0, 666666667}; !slices.Equal(kept, want) {
+		t.Errorf("every nth count result mismatch, got=%v, want=%v", kept, want)
+	}
+
+	randoms := []float64{0.1, 0.6, 0.3, 0.9, 0.2, 0.5, 0.0}
+	i := 0
+	sampler := &probabilitySampler{p: 0.4, random: func() float64 {
+		r := randoms[i]
+		i++
+		return r
+	}}
+	if got, want := apply([]Transform{sampler}), []float64{1, 3, 5, 7}; !slices.Equal(got, want) {
+		t.Errorf("probability result mismatch, got=%v, want=%v", got, want)
+	}
+
+	if _, factor := samplers(Config{SampleEvery: 2, SampleProb: 0.25}); factor != 8 {
+		t.Errorf("combined factor mismatch, got=%g, want=8", factor)
+	}
+	if transforms, factor := samplers(Config{SampleEvery: 1, SampleProb: 1}); transforms != nil || factor != 1 {
+		t.Errorf("no sampling expected, got=%v, factor=%g", transforms, factor)
+	}
+}
+
+func TestScaleCounts(t *testing.T) {
+	h := NewHistogram([]float64{0, 1, 2})
+	h.AddValues([]float64{-1, 0.5, 1.5, 1.5, 3, 3})
+	scaleCounts(h, 2.5)
+	if got, want := h.counts, []int{3, 5}; !slices.Equal(got, want) {
+		t.Errorf("counts mismatch, got=%v, want=%v", got, want)
+	}
+	if h.underflowCount != 3 || h.overflowCount != 5 || h.outOfRangeCount != 8 {
+		t.Errorf("out of range counts mismatch, got=%d below, %d above, %d in total", h.underflowCount, h.overflowCount, h.outOfRangeCount)
+	}
+}
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/exp/slices"
+)
+
+// defaultSeriesName is the series of values posted without the series
+// query parameter.
+const defaultSeriesName = "default"
+
+// valueCount is a value which occurred count times.
+type valueCount struct {
+	value float64
+	count int
+}
+
+// series is the sketch of values of a series and their statistics. The
+// values themselves are not kept, so the memory of a series is bounded by
+// its sketch however many values arrive.
+type series struct {
+	sketch sketch
+	acc    statsAccumulator
+}
+
+// seriesStore keeps values posted to the server per series name, recorded
+// into sketches of the backend of the config, which is a TDigest unless it
+// is hdr.
+type seriesStore struct {
+	mu        sync.Mutex
+	newSketch func() sketch
+	series    map[string]*series
+}
+
+func newSeriesStore(cfg Config) *seriesStore {
+	return &seriesStore{newSketch: sketchMaker(cfg), series: make(map[string]*series)}
+}
+
+func (s *seriesStore) add(name string, values []valueCount) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ser, ok := s.series[name]
+	if !ok {
+		ser = &series{sketch: s.newSketch()}
+		s.series[name] = ser
+	}
+	for _, v := range values {
+		ser.sketch.addValueCount(v.value, v.count)
+		ser.acc.addCount(v.value, v.count)
+	}
+}
+
+// names returns the sorted names of the series.
+func (s *seriesStore) names() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	names := make([]string, 0, len(s.series))
+	for name := range s.series {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// bin returns the histogram of the series for the axis range and the
+// bucket count of cfg and the statistics of its values. ok is false if the
+// series does not exist.
+func (s *seriesStore) bin(cfg Config, name string) (h *Histogram[float64], stats Stats, ok bool, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ser, ok := s.series[name]
+	if !ok {
+		return nil, Stats{}, false, nil
+	}
+	histograms, err := binSketches(cfg, []sketch{ser.sketch}, []string{name})
+	if err != nil {
+		return nil, Stats{}, true, err
+	}
+	return histograms[0], ser.acc.Stats(), true, nil
+}
+
+// outputContentTypes are the Content-Type headers of output formats served
+// by the server. Other formats are served as plain text.
+var outputContentTypes = map[string]string{
+	"json": "application/json",
+	"svg":  "image/svg+xml",
+	"html": "text/html; charset=utf-8",
+}
+
+// newServeMux returns the handler of the server mode. Values are posted to
+//
+//	POST /values?series=name
+//
+// as lines in the input format of cfg, or as a JSON array of numbers and
+// strings with the Content-Type "application/json". A body larger than
+// maxPostedBodySize is rejected with 413. The histogram of all values of a
+// series is rendered in the output format of cfg or the one given with the
+// output query parameter at
+//
+//	GET /histogram/{name}?output=json
+//
+// Histograms are converted from the sketch of the series on every request,
+// so the axis range follows the values as they arrive.
+func newServeMux(cfg Config, store *seriesStore) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /values", func(w http.ResponseWriter, r *http.Request) {
+		name := r.URL.Query().Get("series")
+		if name == "" {
+			name = defaultSeriesName
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, maxPostedBodySize)
+		values, err := readPostedValues(r, cfg)
+		if err != nil {
+			status := http.StatusBadRequest
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				status = http.StatusRequestEntityTooLarge
+			}
+			http.Error(w, err.Error(), status)
+			return
+		}
+		if len(values) > 0 {
+			store.add(name, values)
+		}
+		cfg.logger().Debug("received values", "series", name, "count", len(values))
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("GET /histogram/{name}", func(w http.ResponseWriter, r *http.Request) {
+		name := r.PathValue("name")
+		h, stats, ok, err := store.bin(cfg, name)
+		if !ok {
+			http.Error(w, fmt.Sprintf("no series %q", name), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		format := cfg.OutputFormat
+		if output := r.URL.Query().Get("output"); output != "" {
+			if format, ok = LookupOutputFormat(output); !ok {
+				http.Error(w, fmt.Sprintf("unknown output format %q", output), http.StatusBadRequest)
+				return
+			}
+		}
+
+		buf := getBuffer()
+		defer putBuffer(buf)
+		if err := renderSeries(buf, cfg, format, name, h, stats); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		contentType, ok := outputContentTypes[format.Name()]
+		if !ok {
+			contentType = "text/plain; charset=utf-8"
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.Write(buf.Bytes())
+	})
+	return mux
+}
+
+// bufferPool keeps buffers of rendered histograms, which are rendered on
+// every request in the server mode and on every interval in the statsd
+// mode, so that redrawing does not allocate a buffer each time.
+var bufferPool = sync.Pool{
+	New: func() any { return new(bytes.Buffer) },
+}
+
+// maxPooledBufferSize is the capacity above which a buffer is dropped
+// instead of being returned to bufferPool, so that one huge chart does not
+// pin its memory.
+const maxPooledBufferSize = 1 << 20
+
+func getBuffer() *bytes.Buffer {
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	return buf
+}
+
+func putBuffer(buf *bytes.Buffer) {
+	if buf.Cap() <= maxPooledBufferSize {
+		bufferPool.Put(buf)
+	}
+}
+
+// renderSeries writes the histogram h of the series name with the
+// statistics of its values in format to w.
+func renderSeries(w io.Writer, cfg Config, format OutputFormat, name string, h *Histogram[float64], stats Stats) error {
+	m := newPipeline(cfg).Model
+	m.Histograms = []*Histogram[float64]{h}
+	m.Labels = []string{name}
+	m.Stats = []Stats{stats}
+	return format.Render(w, &m)
+}
+
+// maxPostedBodySize is the maximum size of the body of a request posting
+// values.
+const maxPostedBodySize = 10 << 20
+
+// readPostedValues reads all values in the body of r. Runs of the same
+// value, like those of the counted input format, are returned as one
+// valueCount. No value is returned if any of them is invalid.
+func readPostedValues(r *http.Request, cfg Config) ([]valueCount, error) {
+	var values []valueCount
+	add := func(v float64) {
+		if n := len(values); n > 0 && values[n-1].value == v {
+			values[n-1].count++
+			return
+		}
+		values = append(values, valueCount{value: v, count: 1})
+	}
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "application/json" {
+		err := cfg.InputFormat.Scan(r.Body, cfg.ValueParser, add)
+		return values, err
+	}
+
+	var items []json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&items); err != nil {
+		return nil, fmt.Errorf("invalid JSON array: %w", err)
+	}
+	for _, item := range items {
+		var value float64
+		if len(item) > 0 && item[0] == '"' {
+			var s string
+			if err := json.Unmarshal(item, &s); err != nil {
+				return nil, fmt.Errorf("invalid JSON string %s: %s", item, err)
+			}
+			v, err := cfg.ValueParser(s)
+			if err != nil {
+				return nil, err
+			}
+			value = v
+		} else if err := json.Unmarshal(item, &value); err != nil {
+			return nil, fmt.Errorf("invalid JSON number %s: %s", item, err)
+		}
+		add(value)
+	}
+	return values, nil
+}
+
+// serveShutdownTimeout is how long serve waits for requests in progress
+// to finish when it is stopped.
+const serveShutdownTimeout = 5 * time.Second
+
+// serve runs the server mode on addr with series in store until it fails,
+// or until ctx is done, in which case it shuts down and returns nil.
+func serve(ctx context.Context, addr string, cfg Config, store *seriesStore) error {
+	srv := &http.Server{Addr: addr, Handler: newServeMux(cfg, store)}
+	shutdownErr := make(chan error, 1)
+	stop := context.AfterFunc(ctx, func() {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), serveShutdownTimeout)
+		defer cancel()
+		shutdownErr <- srv.Shutdown(shutdownCtx)
+	})
+	defer stop()
+
+	cfg.logger().Info("listening", "addr", addr)
+	if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return <-shutdownErr
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestServeMux(t *testing.T) {
+	cfg := Config{
+		BucketCount:  2,
+		AxisMin:      axisRangeEnd{Value: 0},
+		AxisMax:      axisRangeEnd{Value: 4},
+		Scale:        scaleLinear,
+		GraphWidth:   40,
+		PointFmt:     "%g",
+		TickStyle:    TickStyleFixed,
+		Backend:      backendHistogram,
+		InputFormat:  plainInputFormat{},
+		ValueParser:  parseFiniteFloat,
+		OutputFormat: textOutputFormat{},
+
+		TDigestCompression: 100,
+	}
+	srv := httptest.NewServer(newServeMux(cfg, newSeriesStore(cfg)))
+	defer srv.Close()
+
+	post := func(query, contentType, body string) int {
+		t.Helper()
+		res, err := http.Post(srv.URL+"/values"+query, contentType, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		return res.StatusCode
+	}
+	get := func(path string) (int, string) {
+		t.Helper()
+		res, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		body, err := io.ReadAll(res.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res.StatusCode, string(body)
+	}
+
+	if got, want := post("?series=a", "text/plain", "0.5\n1\n3\n"), http.StatusNoContent; got != want {
+		t.Errorf("status mismatch for lines, got=%d, want=%d", got, want)
+	}
+	if got, want := post("?series=a", "application/json", `[1.5, "2.5", 9]`), http.StatusNoContent; got != want {
+		t.Errorf("status mismatch for JSON, got=%d, want=%d", got, want)
+	}
+	if got, want := post("?series=a", "text/plain", "1\nx\n"), http.StatusBadRequest; got != want {
+		t.Errorf("status mismatch for invalid value, got=%d, want=%d", got, want)
+	}
+	if got, want := post("?series=a", "text/plain", strings.Repeat("1\n", maxPostedBodySize/2+1)), http.StatusRequestEntityTooLarge; got != want {
+		t.Errorf("status mismatch for too large body, got=%d, want=%d", got, want)
+	}
+
+	status, body := get("/histogram/a")
+	if status != http.StatusOK {
+		t.Fatalf("status mismatch, got=%d, want=%d", status, http.StatusOK)
+	}
+	want := `       0 ~ 2  3 |***********************
+       2 ~ 4  2 |***************
+out of range  1 |
+`
+	if body != want {
+		t.Errorf("result mismatch,\n got=%q,\nwant=%q", body, want)
+	}
+
+	if status, body := get("/histogram/a?output=json"); status != http.StatusOK || !strings.Contains(body, `"label": "a"`) {
+		t.Errorf("result mismatch for json, status=%d, body=%s", status, body)
+	}
+	if status, _ := get("/histogram/a?output=pdf"); status != http.StatusBadRequest {
+		t.Errorf("status mismatch for unknown format, got=%d, want=%d", status, http.StatusBadRequest)
+	}
+	if status, _ := get("/histogram/b"); status != http.StatusNotFound {
+		t.Errorf("status mismatch for unknown series, got=%d, want=%d", status, http.StatusNotFound)
+	}
+}
+
+func TestServe_Cancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() {
+		errc <- serve(ctx, "127.0.0.1:0", Config{}, newSeriesStore(Config{TDigestCompression: 100}))
+	}()
+	cancel()
+	select {
+	case err := <-errc:
+		if err != nil {
+			t.Errorf("serve must stop cleanly when cancelled, got=%v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("serve did not stop when cancelled")
+	}
+}
+
+func TestSeriesStore_Bounded(t *testing.T) {
+	cfg := Config{
+		BucketCount:        4,
+		AxisMin:            axisRangeEnd{Auto: true},
+		AxisMax:            axisRangeEnd{Auto: true},
+		Scale:              scaleLinear,
+		TDigestCompression: 100,
+	}
+	store := newSeriesStore(cfg)
+	for i := 0; i < 20000; i++ {
+		store.add("a", []valueCount{{value: float64(i % 1000), count: 1}})
+	}
+	// A run of a value is recorded at once however long it is.
+	store.add("a", []valueCount{{value: 500, count: 1 << 40}})
+
+	digest := store.series["a"].sketch.(*TDigest)
+	if got := len(digest.Counts()); got > 2*int(cfg.TDigestCompression) {
+		t.Errorf("centroids must be bounded by the compression, got %d", got)
+	}
+	h, stats, ok, err := store.bin(cfg, "a")
+	if err != nil || !ok {
+		t.Fatalf("bin failed, ok=%v, err=%v", ok, err)
+	}
+	if got, want := stats.Count, 20000+1<<40; got != want {
+		t.Errorf("count mismatch, got=%d, want=%d", got, want)
+	}
+	total := 0
+	for _, c := range h.Counts() {
+		total += c
+	}
+	if total != stats.Count {
+		t.Errorf("histogram total mismatch, got=%d, want=%d", total, stats.Count)
+	}
+}
+
+func TestBufferPool(t *testing.T) {
+	buf := getBuffer()
+	buf.WriteString("stale")
+	putBuffer(buf)
+	if got := getBuffer(); got.Len() != 0 {
+		t.Errorf("buffer from the pool must be empty, got %q", got.String())
+	}
+
+	large := getBuffer()
+	large.Grow(maxPooledBufferSize + 1)
+	putBuffer(large)
+	for i := 0; i < 10; i++ {
+		if getBuffer() == large {
+			t.Fatal("a buffer larger than maxPooledBufferSize must not be pooled")
+		}
+	}
+}
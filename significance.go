@@ -0,0 +1,259 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"golang.org/x/exp/slices"
+)
+
+// StatTest is a statistical test of whether two inputs come from the same
+// distribution.
+type StatTest string
+
+const (
+	// StatTestKS is the two-sample Kolmogorov-Smirnov test.
+	StatTestKS StatTest = "ks"
+	// StatTestChiSquare is the chi-square test of homogeneity of counts in
+	// buckets.
+	StatTestChiSquare StatTest = "chi2"
+)
+
+func parseStatTest(s string) (StatTest, error) {
+	switch test := StatTest(s); test {
+	case StatTestKS, StatTestChiSquare:
+		return test, nil
+	default:
+		return "", fmt.Errorf("invalid statistical test: %q", s)
+	}
+}
+
+// TestResult is the result of a statistical test. A small PValue like
+// below 0.05 means the difference between the inputs is significant.
+type TestResult struct {
+	Statistic float64 `json:"statistic"`
+	PValue    float64 `json:"pValue"`
+	// DF is the degrees of freedom of the chi-square test, which is 0 for
+	// other tests.
+	DF int `json:"df,omitempty"`
+}
+
+var errEmptyHistogram = errors.New("histogram has no value")
+
+// KolmogorovSmirnovTest runs the two-sample Kolmogorov-Smirnov test on the
+// counts of a and b, which must have the same range points. The statistic
+// is the largest difference of the empirical distribution functions at the
+// range points, so it is at most the one of the values when buckets are
+// coarse. Values below and above the range are counted at the ends.
+func KolmogorovSmirnovTest[T Number](a, b *Histogram[T]) (TestResult, error) {
+	if !slices.Equal(a.rangePoints, b.rangePoints) {
+		return TestResult{}, ErrRangePointsMismatch
+	}
+	countsA, countsB := testCells(a), testCells(b)
+	na, nb := sumInts(countsA), sumInts(countsB)
+	if na == 0 || nb == 0 {
+		return TestResult{}, errEmptyHistogram
+	}
+	d := 0.0
+	cumA, cumB := 0, 0
+	for i := range countsA {
+		cumA += countsA[i]
+		cumB += countsB[i]
+		d = math.Max(d, math.Abs(float64(cumA)/float64(na)-float64(cumB)/float64(nb)))
+	}
+	return TestResult{Statistic: d, PValue: ksPValue(d, na, nb)}, nil
+}
+
+// KolmogorovSmirnovTestValues runs the two-sample Kolmogorov-Smirnov test
+// on values of two inputs.
+func KolmogorovSmirnovTestValues(a, b []float64) (TestResult, error) {
+	if len(a) == 0 || len(b) == 0 {
+		return TestResult{}, errEmptyValues
+	}
+	sortedA, sortedB := slices.Clone(a), slices.Clone(b)
+	slices.Sort(sortedA)
+	slices.Sort(sortedB)
+	d := 0.0
+	i, j := 0, 0
+	for i < len(sortedA) && j < len(sortedB) {
+		// Steps at the same value are taken together.
+		v := math.Min(sortedA[i], sortedB[j])
+		for i < len(sortedA) && sortedA[i] == v {
+			i++
+		}
+		for j < len(sortedB) && sortedB[j] == v {
+			j++
+		}
+		d = math.Max(d, math.Abs(float64(i)/float64(len(a))-float64(j)/float64(len(b))))
+	}
+	return TestResult{Statistic: d, PValue: ksPValue(d, len(a), len(b))}, nil
+}
+
+// ksPValue returns the asymptotic p-value of the Kolmogorov-Smirnov
+// statistic d for samples of sizes na and nb, with the small sample
+// correction by Stephens.
+func ksPValue(d float64, na, nb int) float64 {
+	ne := float64(na) * float64(nb) / float64(na+nb)
+	sqrtNe := math.Sqrt(ne)
+	lambda := (sqrtNe + 0.12 + 0.11/sqrtNe) * d
+	if lambda < 0.2 {
+		// The series converges too slowly and the sum is 1 anyway.
+		return 1
+	}
+	sum := 0.0
+	sign := 1.0
+	for j := 1; j <= 100; j++ {
+		term := sign * 2 * math.Exp(-2*float64(j*j)*lambda*lambda)
+		sum += term
+		if math.Abs(term) < 1e-12 {
+			break
+		}
+		sign = -sign
+	}
+	return math.Max(0, math.Min(1, sum))
+}
+
+// ChiSquareTest runs the chi-square test of homogeneity on the counts of a
+// and b, which must have the same range points. Values below and above
+// the range are counted as extra buckets, and buckets empty in both
+// histograms are ignored.
+func ChiSquareTest[T Number](a, b *Histogram[T]) (TestResult, error) {
+	if !slices.Equal(a.rangePoints, b.rangePoints) {
+		return TestResult{}, ErrRangePointsMismatch
+	}
+	countsA, countsB := testCells(a), testCells(b)
+	na, nb := sumInts(countsA), sumInts(countsB)
+	if na == 0 || nb == 0 {
+		return TestResult{}, errEmptyHistogram
+	}
+	n := float64(na + nb)
+	stat := 0.0
+	cells := 0
+	for i := range countsA {
+		total := float64(countsA[i] + countsB[i])
+		if total == 0 {
+			continue
+		}
+		cells++
+		ea, eb := total*float64(na)/n, total*float64(nb)/n
+		da, db := float64(countsA[i])-ea, float64(countsB[i])-eb
+		stat += da*da/ea + db*db/eb
+	}
+	if cells < 2 {
+		return TestResult{}, errors.New("chi-square test needs at least 2 non-empty buckets")
+	}
+	df := cells - 1
+	return TestResult{Statistic: stat, PValue: upperIncompleteGamma(float64(df)/2, stat/2), DF: df}, nil
+}
+
+// testCells returns the underflow count, the counts of buckets and the
+// overflow count of h. NaN values are not included.
+func testCells[T Number](h *Histogram[T]) []int {
+	cells := make([]int, 0, len(h.counts)+2)
+	cells = append(cells, h.underflowCount)
+	cells = append(cells, h.counts...)
+	return append(cells, h.overflowCount)
+}
+
+func sumInts(values []int) int {
+	sum := 0
+	for _, v := range values {
+		sum += v
+	}
+	return sum
+}
+
+// upperIncompleteGamma returns the regularized upper incomplete gamma
+// function Q(a, x), which is the p-value of the chi-square statistic 2x
+// with 2a degrees of freedom. It uses the series of P(a, x) for small x
+// and the continued fraction of Q(a, x) otherwise, as in Numerical
+// Recipes.
+func upperIncompleteGamma(a, x float64) float64 {
+	const (
+		maxIterations = 1000
+		eps           = 1e-15
+		tiny          = 1e-300
+	)
+	if x <= 0 {
+		return 1
+	}
+	lgammaA, _ := math.Lgamma(a)
+	prefix := math.Exp(-x + a*math.Log(x) - lgammaA)
+	if x < a+1 {
+		sum := 1 / a
+		term := sum
+		for n := 1; n < maxIterations; n++ {
+			term *= x / (a + float64(n))
+			sum += term
+			if math.Abs(term) < math.Abs(sum)*eps {
+				break
+			}
+		}
+		return math.Max(0, 1-sum*prefix)
+	}
+	// Modified Lentz's method.
+	b := x + 1 - a
+	c := 1 / tiny
+	d := 1 / b
+	h := d
+	for i := 1; i < maxIterations; i++ {
+		an := -float64(i) * (float64(i) - a)
+		b += 2
+		d = an*d + b
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = b + an/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		delta := d * c
+		h *= delta
+		if math.Abs(delta-1) < eps {
+			break
+		}
+	}
+	return prefix * h
+}
+
+// runStatTest runs test between a and b.
+func runStatTest(test StatTest, a, b *Histogram[float64]) (TestResult, error) {
+	switch test {
+	case StatTestKS:
+		r, err := KolmogorovSmirnovTest(a, b)
+		if err != nil {
+			return TestResult{}, fmt.Errorf("Kolmogorov-Smirnov test: %w", err)
+		}
+		return r, nil
+	case StatTestChiSquare:
+		r, err := ChiSquareTest(a, b)
+		if err != nil {
+			return TestResult{}, fmt.Errorf("chi-square test: %w", err)
+		}
+		return r, nil
+	default:
+		return TestResult{}, fmt.Errorf("unknown statistical test: %q", test)
+	}
+}
+
+// formatTestResults formats the results of tests between a and b, one
+// line per test.
+func formatTestResults(tests []StatTest, a, b *Histogram[float64]) (string, error) {
+	var sb strings.Builder
+	for _, test := range tests {
+		r, err := runStatTest(test, a, b)
+		if err != nil {
+			return "", err
+		}
+		switch test {
+		case StatTestKS:
+			fmt.Fprintf(&sb, "Kolmogorov-Smirnov: D=%.4g p=%.4g\n", r.Statistic, r.PValue)
+		case StatTestChiSquare:
+			fmt.Fprintf(&sb, "chi-square: X2=%.4g df=%d p=%.4g\n", r.Statistic, r.DF, r.PValue)
+		}
+	}
+	return sb.String(), nil
+}
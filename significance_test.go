@@ -0,0 +1,106 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestUpperIncompleteGamma(t *testing.T) {
+	testCases := []struct {
+		df   int
+		x    float64
+		want float64
+	}{
+		{df: 1, x: 3.841459, want: 0.05},
+		{df: 5, x: 11.0705, want: 0.05},
+		{df: 2, x: 2, want: math.Exp(-1)},
+		{df: 2, x: 0.5, want: math.Exp(-0.25)},
+		{df: 10, x: 0, want: 1},
+	}
+	for _, c := range testCases {
+		if got := upperIncompleteGamma(float64(c.df)/2, c.x/2); math.Abs(got-c.want) > 1e-5 {
+			t.Errorf("result mismatch, df=%d, x=%g, got=%g, want=%g", c.df, c.x, got, c.want)
+		}
+	}
+}
+
+func TestKSPValue(t *testing.T) {
+	// The critical value of the Kolmogorov distribution at 5% is 1.358.
+	n := 1000000
+	d := 1.3581 / math.Sqrt(float64(n)/2)
+	if got, want := ksPValue(d, n, n), 0.05; math.Abs(got-want) > 1e-3 {
+		t.Errorf("result mismatch, got=%g, want=%g", got, want)
+	}
+	if got, want := ksPValue(0, 10, 10), 1.0; got != want {
+		t.Errorf("result mismatch for no difference, got=%g, want=%g", got, want)
+	}
+}
+
+func TestKolmogorovSmirnovTest(t *testing.T) {
+	a := []float64{1, 2, 3, 4}
+	b := []float64{3, 4, 5, 6}
+	r, err := KolmogorovSmirnovTestValues(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := r.Statistic, 0.5; got != want {
+		t.Errorf("statistic of values mismatch, got=%g, want=%g", got, want)
+	}
+
+	ha, hb := NewHistogram([]float64{0, 2, 4, 6}), NewHistogram([]float64{0, 2, 4, 6})
+	ha.AddValues(a)
+	hb.AddValues(b)
+	r, err = KolmogorovSmirnovTest(ha, hb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := r.Statistic, 0.5; got != want {
+		t.Errorf("statistic of histograms mismatch, got=%g, want=%g", got, want)
+	}
+
+	r, err = KolmogorovSmirnovTest(ha, ha)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Statistic != 0 || r.PValue != 1 {
+		t.Errorf("result mismatch for the same histograms, got=%+v", r)
+	}
+
+	if _, err := KolmogorovSmirnovTest(ha, NewHistogram([]float64{0, 3, 6})); !errors.Is(err, ErrRangePointsMismatch) {
+		t.Errorf("error mismatch, got=%v, want=%v", err, ErrRangePointsMismatch)
+	}
+	if _, err := KolmogorovSmirnovTestValues(a, nil); !errors.Is(err, errEmptyValues) {
+		t.Errorf("error mismatch, got=%v, want=%v", err, errEmptyValues)
+	}
+}
+
+func TestChiSquareTest(t *testing.T) {
+	ha, hb := NewHistogram([]float64{0, 1, 2, 3}), NewHistogram([]float64{0, 1, 2, 3})
+	for i, n := range []int{10, 20, 0} {
+		ha.addValueCount(float64(i)+0.5, n)
+	}
+	for i, n := range []int{20, 10, 0} {
+		hb.addValueCount(float64(i)+0.5, n)
+	}
+	// Both rows of the 2x2 table have expected counts of 15, so the
+	// statistic is 4 * 5^2 / 15. The empty bucket is ignored.
+	r, err := ChiSquareTest(ha, hb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := r.Statistic, 20.0/3; math.Abs(got-want) > 1e-12 {
+		t.Errorf("statistic mismatch, got=%g, want=%g", got, want)
+	}
+	if got, want := r.DF, 1; got != want {
+		t.Errorf("degrees of freedom mismatch, got=%d, want=%d", got, want)
+	}
+	if got, want := r.PValue, 0.009823; math.Abs(got-want) > 1e-5 {
+		t.Errorf("p-value mismatch, got=%g, want=%g", got, want)
+	}
+
+	empty := NewHistogram([]float64{0, 1, 2, 3})
+	if _, err := ChiSquareTest(ha, empty); !errors.Is(err, errEmptyHistogram) {
+		t.Errorf("error mismatch, got=%v, want=%v", err, errEmptyHistogram)
+	}
+}
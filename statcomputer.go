@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// StatComputer computes a statistic of an input shown in the footer of the
+// text output, like an Apdex score. Implementations are registered with
+// RegisterStatComputer from an init function of a file added to this
+// command, since package main cannot be imported by other programs.
+type StatComputer interface {
+	// Name returns the name shown with the statistic.
+	Name() string
+	// Compute returns the formatted statistic of h and raw, which are all
+	// values of the input including those out of range in input order.
+	Compute(h *Histogram[float64], raw []float64) string
+}
+
+// ComputedStat is a statistic formatted by a StatComputer.
+type ComputedStat struct {
+	Name  string
+	Value string
+}
+
+var (
+	statComputersMu sync.RWMutex
+	statComputers   []StatComputer
+)
+
+// RegisterStatComputer adds c to the statistics computed for every input.
+// Statistics are shown in the order of registration. It panics if a
+// computer with the same name is already registered.
+func RegisterStatComputer(c StatComputer) {
+	statComputersMu.Lock()
+	defer statComputersMu.Unlock()
+
+	for _, c2 := range statComputers {
+		if c2.Name() == c.Name() {
+			panic(fmt.Sprintf("stat computer %q is already registered", c.Name()))
+		}
+	}
+	statComputers = append(statComputers, c)
+}
+
+// RegisteredStatComputers returns the registered computers in the order of
+// registration.
+func RegisteredStatComputers() []StatComputer {
+	statComputersMu.RLock()
+	defer statComputersMu.RUnlock()
+
+	return append([]StatComputer(nil), statComputers...)
+}
+
+// computeStats returns the statistics of each histogram and its raw
+// values by computers.
+func computeStats(computers []StatComputer, histograms []*Histogram[float64], raws [][]float64) [][]ComputedStat {
+	result := make([][]ComputedStat, len(histograms))
+	for i, h := range histograms {
+		for _, c := range computers {
+			result[i] = append(result[i], ComputedStat{Name: c.Name(), Value: c.Compute(h, raws[i])})
+		}
+	}
+	return result
+}
+
+// writeComputedStats writes a footer line of statistics per input, or
+// nothing if no statistic is computed.
+func writeComputedStats(w io.Writer, m *RenderModel) error {
+	for i, stats := range m.ComputedStats {
+		if len(stats) == 0 {
+			continue
+		}
+		fields := make([]string, len(stats))
+		for j, s := range stats {
+			fields[j] = s.Name + "=" + s.Value
+		}
+		if _, err := fmt.Fprintf(w, "%s: %s\n", m.Labels[i], strings.Join(fields, " ")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
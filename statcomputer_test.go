@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+// apdexComputer computes the Apdex score of response times with the
+// target time t.
+type apdexComputer struct {
+	t float64
+}
+
+func (apdexComputer) Name() string { return "apdex" }
+
+func (c apdexComputer) Compute(h *Histogram[float64], raw []float64) string {
+	satisfied, tolerating := 0, 0
+	for _, v := range raw {
+		switch {
+		case v <= c.t:
+			satisfied++
+		case v <= 4*c.t:
+			tolerating++
+		}
+	}
+	return fmt.Sprintf("%.2f", (float64(satisfied)+float64(tolerating)/2)/float64(len(raw)))
+}
+
+// outOfRangeComputer shows the out of range count of the histogram.
+type outOfRangeComputer struct{}
+
+func (outOfRangeComputer) Name() string { return "outside" }
+
+func (outOfRangeComputer) Compute(h *Histogram[float64], raw []float64) string {
+	return fmt.Sprint(h.outOfRangeCount)
+}
+
+func TestPipeline_StatComputers(t *testing.T) {
+	cfg := Config{
+		BucketCount: 2,
+		AxisMin:     axisRangeEnd{Value: 0},
+		AxisMax:     axisRangeEnd{Value: 4},
+		Scale:       scaleLinear,
+	}
+	p := &Pipeline{
+		Sources: []Source{
+			&testSource{name: "a", values: []float64{0.5, 1, 3, 7}},
+			&testSource{name: "b", values: []float64{2, 5, 9}},
+		},
+		Binner:        &streamingBinner{cfg: cfg},
+		Renderer:      textOutputFormat{},
+		StatComputers: []StatComputer{apdexComputer{t: 1}, outOfRangeComputer{}},
+		Model:         RenderModel{BarChar: defaultBarChar, GraphWidth: 50, PointFmt: "%.1f"},
+	}
+	var buf bytes.Buffer
+	if err := p.Run(&buf); err != nil {
+		t.Fatal(err)
+	}
+	got := buf.String()
+	want := `              a                 b
+   0.0 ~ 2.0  2 |************** 0 |
+   2.0 ~ 4.0  1 |*******        1 |*******
+out of range  1 |               2 |
+a: apdex=0.62 outside=1
+b: apdex=0.17 outside=2
+`
+	if got != want {
+		t.Errorf("result mismatch,\n got=%q,\nwant=%q", got, want)
+		fmt.Printf("\n%s", got)
+	}
+}
+
+func TestRegisterStatComputer(t *testing.T) {
+	saved := statComputers
+	t.Cleanup(func() { statComputers = saved })
+	statComputers = nil
+
+	RegisterStatComputer(apdexComputer{t: 1})
+	RegisterStatComputer(outOfRangeComputer{})
+	got := RegisteredStatComputers()
+	if len(got) != 2 || got[0].Name() != "apdex" || got[1].Name() != "outside" {
+		t.Errorf("registered computers mismatch, got=%v", got)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("registering a duplicate name must panic")
+		}
+	}()
+	RegisterStatComputer(apdexComputer{t: 2})
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+
+	"golang.org/x/exp/slices"
+)
+
+// stateFormat marks state files, so that they are told apart from raw
+// inputs by their first bytes.
+const stateFormat = "histogram-state"
+
+// stateSniffSize is the number of bytes read to tell state files apart
+// from raw inputs.
+const stateSniffSize = 64
+
+// stateFile is the JSON representation of histograms saved with --save,
+// which can be given as inputs instead of raw values, like
+//
+//	{
+//	  "format": "histogram-state",
+//	  "inputs": [
+//	    {"label": "a.txt", "histogram": {...}, "stats": {...}}
+//	  ]
+//	}
+//
+// The format field must come first.
+type stateFile struct {
+	Format string       `json:"format"`
+	Inputs []stateInput `json:"inputs"`
+}
+
+type stateInput struct {
+	Label     string              `json:"label"`
+	Histogram *Histogram[float64] `json:"histogram"`
+	Stats     Stats               `json:"stats"`
+}
+
+// writeStateFile saves histograms of m and their statistics to filename.
+func writeStateFile(filename string, m *RenderModel) error {
+	state := stateFile{Format: stateFormat, Inputs: make([]stateInput, len(m.Histograms))}
+	for i, h := range m.Histograms {
+		state.Inputs[i] = stateInput{Label: m.Labels[i], Histogram: h}
+		if m.Stats != nil {
+			state.Inputs[i].Stats = m.Stats[i]
+		}
+	}
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, append(data, '\n'), 0o644)
+}
+
+// isStateFile returns whether filename is a state file saved with --save,
+// whose first bytes are the format field. Stdin is never a state file,
+// since it cannot be read again after sniffing.
+func isStateFile(filename string) (bool, error) {
+	if filename == stdinFilename {
+		return false, nil
+	}
+	r, err := newReadCloserFile(filename)
+	if err != nil {
+		return false, err
+	}
+	defer r.Close()
+
+	buf := make([]byte, stateSniffSize)
+	n, err := io.ReadFull(r, buf)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return false, err
+	}
+	var compact []byte
+	for _, b := range buf[:n] {
+		if b != ' ' && b != '\t' && b != '\r' && b != '\n' {
+			compact = append(compact, b)
+		}
+	}
+	return bytes.HasPrefix(compact, []byte(`{"format":"`+stateFormat+`"`)), nil
+}
+
+func readStateFile(filename string) (*stateFile, error) {
+	r, err := newReadCloserFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	var state stateFile
+	if err := json.NewDecoder(r).Decode(&state); err != nil {
+		return nil, fmt.Errorf("invalid state file %s: %s", filename, err)
+	}
+	switch {
+	case state.Format != stateFormat:
+		return nil, fmt.Errorf("invalid state file %s: format must be %q, got %q", filename, stateFormat, state.Format)
+	case len(state.Inputs) == 0:
+		return nil, fmt.Errorf("invalid state file %s: no input", filename)
+	}
+	for i, input := range state.Inputs {
+		if input.Histogram == nil {
+			return nil, fmt.Errorf("invalid state file %s: input #%d has no histogram", filename, i+1)
+		}
+	}
+	return &state, nil
+}
+
+// stateInputFilenames returns whether filenames are all state files, or
+// an error if state files and raw inputs are mixed.
+func stateInputFilenames(filenames []string) (bool, error) {
+	states := 0
+	for _, filename := range filenames {
+		ok, err := isStateFile(filename)
+		if err != nil {
+			return false, err
+		}
+		if ok {
+			states++
+		}
+	}
+	if states > 0 && states < len(filenames) {
+		return false, errors.New("state files and raw inputs cannot be given together")
+	}
+	return states > 0, nil
+}
+
+// binStates fills the histograms of the state files with their labels and
+// statistics into the display settings of p instead of reading values.
+// Inputs with the same label in several files, like shards of a dataset
+// binned on different machines, are merged. All histograms must have the
+// same range points to be shown together.
+func binStates(p *Pipeline, filenames []string) (*RenderModel, error) {
+	m := p.Model
+	index := make(map[string]int)
+	for _, filename := range filenames {
+		state, err := readStateFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		for _, input := range state.Inputs {
+			if len(m.Histograms) > 0 && !slices.Equal(input.Histogram.RangePoints(), m.Histograms[0].RangePoints()) {
+				return nil, fmt.Errorf("%s: %q: %w", displayFilename(filename), input.Label, ErrRangePointsMismatch)
+			}
+			i, ok := index[input.Label]
+			if !ok {
+				index[input.Label] = len(m.Histograms)
+				m.Histograms = append(m.Histograms, input.Histogram)
+				m.Labels = append(m.Labels, input.Label)
+				m.Stats = append(m.Stats, input.Stats)
+				continue
+			}
+			if err := m.Histograms[i].Merge(input.Histogram); err != nil {
+				return nil, fmt.Errorf("%s: %q: %w", displayFilename(filename), input.Label, err)
+			}
+			m.Stats[i] = mergeStats(m.Stats[i], input.Stats)
+		}
+	}
+	if p.Warner != nil {
+		warnOutOfRange(p.Warner, m.Histograms, m.Labels)
+	}
+	return &m, nil
+}
+
+// mergeStats returns the statistics of values of a and b together.
+func mergeStats(a, b Stats) Stats {
+	switch {
+	case a.Count == 0:
+		return b
+	case b.Count == 0:
+		return a
+	}
+	n := a.Count + b.Count
+	na, nb := float64(a.Count), float64(b.Count)
+	mean := (na*a.Mean + nb*b.Mean) / float64(n)
+	delta := b.Mean - a.Mean
+	// Sums of squared differences from the means, combined with Chan's
+	// parallel algorithm.
+	m2 := a.Stddev*a.Stddev*(na-1) + b.Stddev*b.Stddev*(nb-1) + delta*delta*na*nb/float64(n)
+	return Stats{
+		Count:  n,
+		Min:    math.Min(a.Min, b.Min),
+		Max:    math.Max(a.Max, b.Max),
+		Mean:   mean,
+		Stddev: math.Sqrt(m2 / float64(n-1)),
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"path/filepath"
+	"testing"
+)
+
+func TestStateFile(t *testing.T) {
+	dir := t.TempDir()
+	cfg := Config{
+		BucketCount:  5,
+		AxisMin:      axisRangeEnd{Value: 0},
+		AxisMax:      axisRangeEnd{Value: 500},
+		Scale:        scaleLinear,
+		GraphWidth:   40,
+		PointFmt:     "%g",
+		TickStyle:    TickStyleFixed,
+		Backend:      backendHistogram,
+		InputFormat:  plainInputFormat{},
+		ValueParser:  parseFiniteFloat,
+		OutputFormat: textOutputFormat{},
+	}
+	runSaving := func(filename, save string) (string, *Histogram[float64]) {
+		t.Helper()
+		c := cfg
+		c.Filenames = []string{filename}
+		c.Labels = []string{"latency"}
+		c.Save = save
+		var buf bytes.Buffer
+		if err := run(context.Background(), &buf, c); err != nil {
+			t.Fatal(err)
+		}
+		state, err := readStateFile(save)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return buf.String(), state.Inputs[0].Histogram
+	}
+	stateA, stateB := filepath.Join(dir, "a.json"), filepath.Join(dir, "b.json")
+	rawOutput, histA := runSaving("testdata/latency_a.txt", stateA)
+	_, histB := runSaving("testdata/latency_b.txt", stateB)
+
+	for _, filename := range []string{"testdata/latency_a.txt", stateA} {
+		got, err := isStateFile(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := filename == stateA; got != want {
+			t.Errorf("isStateFile mismatch, filename=%s, got=%v, want=%v", filename, got, want)
+		}
+	}
+
+	c := cfg
+	c.Filenames = []string{stateA}
+	var buf bytes.Buffer
+	if err := run(context.Background(), &buf, c); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); got != rawOutput {
+		t.Errorf("output mismatch with the raw input,\n got:\n%s\nwant:\n%s", got, rawOutput)
+	}
+
+	// Inputs with the same label are merged.
+	c.Filenames = []string{stateA, stateB}
+	c.Save = filepath.Join(dir, "merged.json")
+	if err := run(context.Background(), &bytes.Buffer{}, c); err != nil {
+		t.Fatal(err)
+	}
+	merged, err := readStateFile(c.Save)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(merged.Inputs), 1; got != want {
+		t.Fatalf("input count mismatch, got=%d, want=%d", got, want)
+	}
+	want := NewHistogram(histA.RangePoints())
+	for _, h := range []*Histogram[float64]{histA, histB} {
+		if err := want.Merge(h); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := merged.Inputs[0].Histogram; !got.Equal(want) {
+		t.Errorf("merged histogram mismatch, got=%v, want=%v", got, want)
+	}
+
+	c.Filenames = []string{stateA, "testdata/latency_b.txt"}
+	c.Save = ""
+	if err := run(context.Background(), &bytes.Buffer{}, c); err == nil {
+		t.Error("mixing state files and raw inputs must fail")
+	}
+}
+
+func TestMergeStats(t *testing.T) {
+	a := []float64{1, 2, 3, 10}
+	b := []float64{4, 8, -1}
+	var accA, accB, accAll statsAccumulator
+	for _, v := range a {
+		accA.add(v)
+		accAll.add(v)
+	}
+	for _, v := range b {
+		accB.add(v)
+		accAll.add(v)
+	}
+	got, want := mergeStats(accA.Stats(), accB.Stats()), accAll.Stats()
+	if got.Count != want.Count || got.Min != want.Min || got.Max != want.Max ||
+		math.Abs(got.Mean-want.Mean) > 1e-12 || math.Abs(got.Stddev-want.Stddev) > 1e-12 {
+		t.Errorf("result mismatch, got=%+v, want=%+v", got, want)
+	}
+	if got := mergeStats(Stats{}, accB.Stats()); got != accB.Stats() {
+		t.Errorf("result mismatch with empty stats, got=%+v, want=%+v", got, accB.Stats())
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import "math"
+
+func mean(values []float64) float64 {
+	var sum kahanSum
+	for _, v := range values {
+		sum.add(v)
+	}
+	return sum.value() / float64(len(values))
+}
+
+// kahanSum is a sum of float64 values with Neumaier's variant of Kahan
+// summation, which keeps the rounding error of each addition to
+// compensate it. The result stays accurate for many values and for values
+// of very different magnitudes, where a naive sum loses small ones.
+type kahanSum struct {
+	sum          float64
+	compensation float64
+}
+
+func (s *kahanSum) add(v float64) {
+	t := s.sum + v
+	if math.Abs(s.sum) >= math.Abs(v) {
+		s.compensation += (s.sum - t) + v
+	} else {
+		s.compensation += (v - t) + s.sum
+	}
+	s.sum = t
+}
+
+func (s *kahanSum) value() float64 {
+	return s.sum + s.compensation
+}
+
+func stddev(values []float64) float64 {
+	if len(values) < 2 {
+		return 0
+	}
+	m := mean(values)
+	sum := 0.0
+	for _, v := range values {
+		sum += (v - m) * (v - m)
+	}
+	return math.Sqrt(sum / float64(len(values)-1))
+}
+
+// quantileSorted returns the q-quantile of sorted values with linear
+// interpolation between the closest ranks.
+func quantileSorted(sorted []float64, q float64) float64 {
+	if len(sorted) == 0 {
+		panic("values must not be empty")
+	}
+	pos := q * float64(len(sorted)-1)
+	i := int(math.Floor(pos))
+	if i >= len(sorted)-1 {
+		return sorted[len(sorted)-1]
+	}
+	frac := pos - float64(i)
+	return sorted[i] + (sorted[i+1]-sorted[i])*frac
+}
+
+// Stats is summary statistics of values of an input.
+type Stats struct {
+	Count  int     `json:"count"`
+	Min    float64 `json:"min"`
+	Max    float64 `json:"max"`
+	Mean   float64 `json:"mean"`
+	Stddev float64 `json:"stddev"`
+}
+
+// statsAccumulator computes Stats of values added one by one without
+// keeping them. The mean comes from a compensated sum, and the variance
+// is updated with Welford's online algorithm, which does not suffer from
+// the catastrophic cancellation of subtracting the squared sum from the
+// sum of squares when values are large compared to their spread.
+type statsAccumulator struct {
+	count int
+	min   float64
+	max   float64
+	sum   kahanSum
+	// runningMean and m2 are the mean and the sum of squared differences
+	// from it in Welford's algorithm.
+	runningMean float64
+	m2          float64
+}
+
+func (a *statsAccumulator) add(v float64) {
+	a.addCount(v, 1)
+}
+
+// addCount adds v n times at once with the weighted form of Welford's
+// algorithm.
+func (a *statsAccumulator) addCount(v float64, n int) {
+	if n <= 0 {
+		return
+	}
+	if a.count == 0 || v < a.min {
+		a.min = v
+	}
+	if a.count == 0 || v > a.max {
+		a.max = v
+	}
+	a.count += n
+	a.sum.add(v * float64(n))
+	delta := v - a.runningMean
+	a.runningMean += delta * float64(n) / float64(a.count)
+	a.m2 += delta * (v - a.runningMean) * float64(n)
+}
+
+// Stats returns the statistics of added values. Stddev is the sample
+// standard deviation, which is 0 for less than two values.
+func (a *statsAccumulator) Stats() Stats {
+	s := Stats{Count: a.count, Min: a.min, Max: a.max}
+	if a.count == 0 {
+		return s
+	}
+	s.Mean = a.sum.value() / float64(a.count)
+	if a.count > 1 {
+		s.Stddev = math.Sqrt(a.m2 / float64(a.count-1))
+	}
+	return s
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/exp/slices"
+)
+
+func TestStatsAccumulator(t *testing.T) {
+	testCases := []struct {
+		values []float64
+		want   Stats
+	}{
+		{values: nil, want: Stats{}},
+		{values: []float64{3}, want: Stats{Count: 1, Min: 3, Max: 3, Mean: 3}},
+		{values: []float64{2, 4, 4, 4, 5, 5, 7, 9}, want: Stats{Count: 8, Min: 2, Max: 9, Mean: 5, Stddev: math.Sqrt(32.0 / 7)}},
+		// The sum of squares of these values loses the spread entirely in
+		// float64.
+		{values: []float64{1e9 + 1, 1e9 + 2, 1e9 + 3}, want: Stats{Count: 3, Min: 1e9 + 1, Max: 1e9 + 3, Mean: 1e9 + 2, Stddev: 1}},
+	}
+	for _, tc := range testCases {
+		var acc statsAccumulator
+		for _, v := range tc.values {
+			acc.add(v)
+		}
+		if got := acc.Stats(); got != tc.want {
+			t.Errorf("result mismatch, values=%v, got=%+v, want=%+v", tc.values, got, tc.want)
+		}
+	}
+}
+
+func TestStatsAccumulator_AddCount(t *testing.T) {
+	var acc statsAccumulator
+	acc.addCount(1, 3)
+	acc.addCount(5, 0)
+	acc.addCount(10, 1)
+	// Same as 1, 1, 1 and 10.
+	want := Stats{Count: 4, Min: 1, Max: 10, Mean: 3.25, Stddev: 4.5}
+	if got := acc.Stats(); got != want {
+		t.Errorf("result mismatch, got=%+v, want=%+v", got, want)
+	}
+}
+
+// TestStats_CountedInput checks that statistics weight each value of the
+// counted input format by its count.
+func TestStats_CountedInput(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "counted.txt")
+	if err := os.WriteFile(filename, []byte("1 3\n10 1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := Config{
+		BucketCount: 2,
+		AxisMin:     axisRangeEnd{Value: 0},
+		AxisMax:     axisRangeEnd{Value: 20},
+		Scale:       scaleLinear,
+		InputFormat: countedInputFormat{},
+		ValueParser: parseFiniteFloat,
+		Filenames:   []string{filename},
+	}
+	m, err := newPipeline(cfg).Bin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Stats{Count: 4, Min: 1, Max: 10, Mean: 3.25, Stddev: 4.5}
+	if got := m.Stats[0]; got != want {
+		t.Errorf("stats mismatch, got=%+v, want=%+v", got, want)
+	}
+	// 3 of 4 values are in the first bucket, so the median is in it,
+	// while it would be on the edge of the buckets for 1 and 10.
+	if got := m.Histograms[0].Quantile(0.5); got >= 10 {
+		t.Errorf("median must be in the first bucket, got=%g", got)
+	}
+}
+
+// TestStats_CountedInputLargeCount checks that a large count of the
+// counted input format is added at once instead of value by value, which
+// would take minutes and, with values kept in memory, gigabytes.
+func TestStats_CountedInputLargeCount(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "counted.txt")
+	if err := os.WriteFile(filename, []byte("0.5 2000000000\n1.5 3\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	testCases := []struct {
+		name       string
+		cfg        Config
+		wantCounts []int
+	}{
+		// The 3 values of 1.5 are outliers, which leave a single bucket.
+		{name: "memory", cfg: Config{AxisMin: axisRangeEnd{Auto: true}, AxisMax: axisRangeEnd{Auto: true}, DropOutliers: &outlierRule{Method: outlierMethodIQR, Factor: 1.5}}, wantCounts: []int{2000000000}},
+		{name: "streaming", cfg: Config{AxisMin: axisRangeEnd{Value: 0}, AxisMax: axisRangeEnd{Value: 2}}, wantCounts: []int{2000000000, 3}},
+		{name: "sketch", cfg: Config{AxisMin: axisRangeEnd{Value: 0}, AxisMax: axisRangeEnd{Value: 2}, Backend: backendTDigest, TDigestCompression: 100}, wantCounts: []int{2000000000, 3}},
+		// The median 0.5 is the inner edge.
+		{name: "percentile edges", cfg: Config{AxisMin: axisRangeEnd{Value: 0}, AxisMax: axisRangeEnd{Value: 2}, EdgesAtPercentiles: []float64{50}}, wantCounts: []int{0, 2000000003}},
+	}
+	for _, tc := range testCases {
+		cfg := tc.cfg
+		cfg.BucketCount = 2
+		cfg.Scale = scaleLinear
+		cfg.InputFormat = countedInputFormat{}
+		cfg.ValueParser = parseFiniteFloat
+		cfg.Filenames = []string{filename}
+		m, err := newPipeline(cfg).Bin()
+		if err != nil {
+			t.Errorf("unexpected error, binner=%s, err=%v", tc.name, err)
+			continue
+		}
+		if got, want := m.Stats[0].Count, 2000000003; got != want {
+			t.Errorf("count mismatch, binner=%s, got=%d, want=%d", tc.name, got, want)
+		}
+		if got := m.Histograms[0].Counts(); !slices.Equal(got, tc.wantCounts) {
+			t.Errorf("bucket counts mismatch, binner=%s, got=%v, want=%v", tc.name, got, tc.wantCounts)
+		}
+	}
+}
+
+func TestMean(t *testing.T) {
+	// A naive sum loses the small values next to the large ones.
+	values := []float64{1e16, 1, 1, 1, 1, -1e16}
+	want := 4.0 / 6
+	if got := mean(values); got != want {
+		t.Errorf("mean mismatch, got=%v, want=%v", got, want)
+	}
+	var acc statsAccumulator
+	for _, v := range values {
+		acc.add(v)
+	}
+	if got := acc.Stats().Mean; got != want {
+		t.Errorf("accumulated mean mismatch, got=%v, want=%v", got, want)
+	}
+}
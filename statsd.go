@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// statsdTimingTypes are the statsd metric types whose values are
+// aggregated into histograms. Other types like counters and gauges are
+// ignored.
+var statsdTimingTypes = map[string]bool{
+	"ms": true,
+	"h":  true,
+	"d":  true,
+}
+
+// statsdMetric is a metric in a statsd line like "api.latency:123|ms|@0.5".
+type statsdMetric struct {
+	name       string
+	value      float64
+	typ        string
+	sampleRate float64
+}
+
+// minStatsdSampleRate is the smallest sample rate accepted. A value with a
+// smaller rate would stand for so many values that a single packet could
+// dominate a histogram.
+const minStatsdSampleRate = 1e-3
+
+// count returns how many values the metric stands for, which is the
+// inverse of the sample rate rounded. It is recorded as the weight of the
+// value, so it is at most 1/minStatsdSampleRate.
+func (m statsdMetric) count() int {
+	return MustMax(1, int(math.Round(1/m.sampleRate)))
+}
+
+func parseStatsdLine(line string) (statsdMetric, error) {
+	name, rest, ok := strings.Cut(line, ":")
+	fields := strings.Split(rest, "|")
+	if !ok || name == "" || len(fields) < 2 {
+		return statsdMetric{}, fmt.Errorf("invalid statsd line %q, want name:value|type", line)
+	}
+	value, err := parseFiniteFloat(fields[0])
+	if err != nil {
+		return statsdMetric{}, fmt.Errorf("invalid statsd line %q: %s", line, err)
+	}
+	m := statsdMetric{name: name, value: value, typ: fields[1], sampleRate: 1}
+	for _, field := range fields[2:] {
+		if s, ok := strings.CutPrefix(field, "@"); ok {
+			rate, err := strconv.ParseFloat(s, 64)
+			if err != nil || !(rate > 0 && rate <= 1) {
+				return statsdMetric{}, fmt.Errorf("invalid statsd line %q: invalid sample rate %q", line, s)
+			}
+			if rate < minStatsdSampleRate {
+				return statsdMetric{}, fmt.Errorf("invalid statsd line %q: sample rate must be at least %g, got %q", line, minStatsdSampleRate, s)
+			}
+			m.sampleRate = rate
+		}
+		// Other fields like tags are ignored.
+	}
+	return m, nil
+}
+
+// listenStatsd reads statsd packets from conn and adds values of timing
+// metrics to store per metric name until reading fails. Invalid lines are
+// reported with cfg.Warner and skipped.
+func listenStatsd(conn net.PacketConn, cfg Config, store *seriesStore) error {
+	buf := make([]byte, 65535)
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			return err
+		}
+		for _, line := range strings.Split(string(buf[:n]), "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" {
+				continue
+			}
+			m, err := parseStatsdLine(line)
+			if err != nil {
+				cfg.warn(Warning{Kind: warningInvalidLine, Message: err.Error(), Source: "statsd"})
+				continue
+			}
+			if !statsdTimingTypes[m.typ] {
+				cfg.logger().Log(context.Background(), levelTrace, "ignored metric", "name", m.name, "type", m.typ)
+				continue
+			}
+			store.add(m.name, []valueCount{{value: m.value, count: m.count()}})
+		}
+	}
+}
+
+// writeAllSeries writes the histogram of each series in store to w with a
+// header line of its name. A series which cannot be rendered is reported
+// in place of its histogram.
+func writeAllSeries(w io.Writer, cfg Config, store *seriesStore) error {
+	for _, name := range store.names() {
+		h, stats, _, err := store.bin(cfg, name)
+		if _, err := fmt.Fprintf(w, "== %s (%d values)\n", name, stats.Count); err != nil {
+			return err
+		}
+		if err == nil {
+			err = renderSeries(w, cfg, cfg.OutputFormat, name, h, stats)
+		}
+		if err != nil {
+			if _, err := fmt.Fprintf(w, "error: %s\n", err); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// runStatsd runs the statsd mode, which listens on cfg.Statsd and prints
+// the histograms of all metrics every cfg.StatsdInterval until ctx is
+// done. With cfg.Serve, the histograms are also served over HTTP.
+func runStatsd(ctx context.Context, cfg Config) error {
+	conn, err := net.ListenPacket("udp", cfg.Statsd)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	cfg.logger().Info("listening for statsd", "addr", conn.LocalAddr().String())
+
+	store := newSeriesStore(cfg)
+	errc := make(chan error, 2)
+	go func() {
+		errc <- listenStatsd(conn, cfg, store)
+	}()
+	if cfg.Serve != "" {
+		go func() {
+			errc <- serve(ctx, cfg.Serve, cfg, store)
+		}()
+	}
+
+	ticker := time.NewTicker(cfg.StatsdInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-errc:
+			return err
+		case <-ticker.C:
+			// All series are written at once from a pooled buffer instead
+			// of many small writes to stdout every interval.
+			buf := getBuffer()
+			err := writeAllSeries(buf, cfg, store)
+			if err == nil {
+				_, err = buf.WriteTo(os.Stdout)
+			}
+			putBuffer(buf)
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
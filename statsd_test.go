@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"log/slog"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/exp/slices"
+)
+
+func TestParseStatsdLine(t *testing.T) {
+	testCases := []struct {
+		line    string
+		want    statsdMetric
+		wantErr bool
+	}{
+		{line: "api.latency:123|ms", want: statsdMetric{name: "api.latency", value: 123, typ: "ms", sampleRate: 1}},
+		{line: "api.latency:1.5|h|@0.25|#env:prod", want: statsdMetric{name: "api.latency", value: 1.5, typ: "h", sampleRate: 0.25}},
+		{line: "hits:1|c", want: statsdMetric{name: "hits", value: 1, typ: "c", sampleRate: 1}},
+		{line: "api.latency", wantErr: true},
+		{line: ":1|ms", wantErr: true},
+		{line: "api.latency:1", wantErr: true},
+		{line: "api.latency:x|ms", wantErr: true},
+		{line: "api.latency:1|ms|@2", wantErr: true},
+		{line: "api.latency:1|ms|@0.001", want: statsdMetric{name: "api.latency", value: 1, typ: "ms", sampleRate: 0.001}},
+		// A tiny rate would stand for billions of values.
+		{line: "api.latency:1|ms|@1e-9", wantErr: true},
+	}
+	for _, tc := range testCases {
+		got, err := parseStatsdLine(tc.line)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("want error, line=%q, got=%+v", tc.line, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tc.want {
+			t.Errorf("result mismatch, line=%q, got=%+v, want=%+v", tc.line, got, tc.want)
+		}
+	}
+}
+
+func TestListenStatsd(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var warnings bytes.Buffer
+	cfg := Config{
+		BucketCount:        2,
+		AxisMin:            axisRangeEnd{Value: 0},
+		AxisMax:            axisRangeEnd{Value: 40},
+		Scale:              scaleLinear,
+		TDigestCompression: 100,
+		Warner:             &textWarner{logger: newLogger(&warnings, slog.LevelInfo)},
+	}
+	store := newSeriesStore(cfg)
+	reads := make(chan struct{})
+	done := make(chan error, 1)
+	go func() {
+		done <- listenStatsd(&readNotifyingConn{PacketConn: conn, reads: reads}, cfg, store)
+	}()
+	waitRead := func() {
+		t.Helper()
+		select {
+		case <-reads:
+		case <-time.After(5 * time.Second):
+			t.Fatal("listenStatsd did not read a packet")
+		}
+	}
+	// The first read means the listener is ready.
+	waitRead()
+
+	client, err := net.Dial("udp", conn.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	if _, err := client.Write([]byte("api:10|ms\napi:20|ms|@0.5\napi:30|ms|@0.001\nhits:1|c\nbad\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	// The next read means the packet has been processed.
+	waitRead()
+	conn.Close()
+	<-done
+
+	if got, want := store.names(), []string{"api"}; !slices.Equal(got, want) {
+		t.Errorf("names mismatch, got=%v, want=%v", got, want)
+	}
+	h, stats, _, err := store.bin(cfg, "api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Values sampled at 0.5 and 0.001 stand for 2 and 1000 values, which
+	// are recorded as weights.
+	if got, want := h.Counts(), []int{1, 1002}; !slices.Equal(got, want) {
+		t.Errorf("counts mismatch, got=%v, want=%v", got, want)
+	}
+	if got, want := stats.Count, 1003; got != want {
+		t.Errorf("count mismatch, got=%d, want=%d", got, want)
+	}
+	if !strings.Contains(warnings.String(), `invalid statsd line \"bad\"`) {
+		t.Errorf("warning mismatch, got=%q", warnings.String())
+	}
+}
+
+// readNotifyingConn sends to reads before each read, so that a test knows
+// that packets read before have been processed.
+type readNotifyingConn struct {
+	net.PacketConn
+	reads chan struct{}
+}
+
+func (c *readNotifyingConn) ReadFrom(p []byte) (int, net.Addr, error) {
+	c.reads <- struct{}{}
+	return c.PacketConn.ReadFrom(p)
+}
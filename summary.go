@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/exp/slices"
+)
+
+// SummaryStat is a statistic computed from all values of an input.
+type SummaryStat struct {
+	// Name is the name given to --summary-across-files like "p99".
+	Name string
+	// compute returns the statistic of sorted values.
+	compute func(sorted []float64) float64
+}
+
+// parseSummaryStat parses "min", "max", "mean", "median" or a percentile
+// like "p99" and "p99.9".
+func parseSummaryStat(s string) (SummaryStat, error) {
+	switch s {
+	case "min":
+		return SummaryStat{Name: s, compute: func(sorted []float64) float64 { return sorted[0] }}, nil
+	case "max":
+		return SummaryStat{Name: s, compute: func(sorted []float64) float64 { return sorted[len(sorted)-1] }}, nil
+	case "mean":
+		return SummaryStat{Name: s, compute: mean}, nil
+	case "median":
+		return SummaryStat{Name: s, compute: func(sorted []float64) float64 { return quantileSorted(sorted, 0.5) }}, nil
+	}
+	if strings.HasPrefix(s, "p") {
+		p, err := strconv.ParseFloat(s[1:], float64BitSize)
+		if err == nil && p >= 0 && p <= 100 {
+			q := p / 100
+			return SummaryStat{Name: s, compute: func(sorted []float64) float64 { return quantileSorted(sorted, q) }}, nil
+		}
+	}
+	return SummaryStat{}, fmt.Errorf("invalid summary statistic: %q", s)
+}
+
+// summarySource yields the statistic of each of sources, so that the
+// distribution of the statistic across inputs is shown. Values of one
+// source are kept in memory at a time.
+type summarySource struct {
+	sources []Source
+	stat    SummaryStat
+}
+
+func (s *summarySource) Name() string {
+	return fmt.Sprintf("%s across %d files", s.stat.Name, len(s.sources))
+}
+
+func (s *summarySource) withContext(ctx context.Context) Source {
+	sources := make([]Source, len(s.sources))
+	for i, src := range s.sources {
+		sources[i] = sourceWithContext(ctx, src)
+	}
+	return &summarySource{sources: sources, stat: s.stat}
+}
+
+func (s *summarySource) Scan(fn func(v float64)) error {
+	for _, src := range s.sources {
+		values, err := readSourceValues(src)
+		if err != nil {
+			return err
+		}
+		slices.Sort(values)
+		fn(s.stat.compute(values))
+	}
+	return nil
+}
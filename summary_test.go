@@ -0,0 +1,49 @@
+package main
+
+import (
+	"testing"
+
+	"golang.org/x/exp/slices"
+)
+
+func TestSummarySource(t *testing.T) {
+	sources := []Source{
+		&testSource{name: "a", values: []float64{3, 1, 2}},
+		&testSource{name: "b", values: []float64{10, 20, 30, 40, 50}},
+	}
+	testCases := []struct {
+		stat string
+		want []float64
+	}{
+		{stat: "min", want: []float64{1, 10}},
+		{stat: "max", want: []float64{3, 50}},
+		{stat: "mean", want: []float64{2, 30}},
+		{stat: "median", want: []float64{2, 30}},
+		{stat: "p75", want: []float64{2.5, 40}},
+		{stat: "p100", want: []float64{3, 50}},
+	}
+	for _, tc := range testCases {
+		stat, err := parseSummaryStat(tc.stat)
+		if err != nil {
+			t.Fatal(err)
+		}
+		src := &summarySource{sources: sources, stat: stat}
+		got, err := readSourceValues(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(got, tc.want) {
+			t.Errorf("result mismatch, stat=%s, got=%v, want=%v", tc.stat, got, tc.want)
+		}
+	}
+
+	for _, s := range []string{"", "p", "p101", "p-1", "avg"} {
+		if _, err := parseSummaryStat(s); err == nil {
+			t.Errorf("error expected, input=%q", s)
+		}
+	}
+
+	if _, err := readSourceValues(&summarySource{sources: []Source{&testSource{name: "empty"}}}); err == nil {
+		t.Error("error expected for an empty source")
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"io"
+)
+
+// svgSeriesColors are fill colors of bars for each input in order.
+var svgSeriesColors = []string{"#4e79a7", "#f28e2b", "#59a14f", "#e15759", "#76b7b2", "#edc948", "#b07aa1", "#ff9da7"}
+
+// Layout of the SVG chart in pixels.
+const (
+	svgWidth        = 640
+	svgHeight       = 400
+	svgMarginLeft   = 60
+	svgMarginRight  = 20
+	svgMarginTop    = 40
+	svgMarginBottom = 60
+	svgYTickCount   = 5
+)
+
+// svgOutputFormat renders histograms as an SVG bar chart. Bars of inputs
+// are grouped side by side in each bucket.
+type svgOutputFormat struct{}
+
+func (svgOutputFormat) Name() string { return "svg" }
+
+func (svgOutputFormat) Render(w io.Writer, m *RenderModel) error {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="sans-serif" font-size="12">`+"\n",
+		svgWidth, svgHeight, svgWidth, svgHeight)
+	fmt.Fprintf(&buf, `<rect width="%d" height="%d" fill="white"/>`+"\n", svgWidth, svgHeight)
+	if m.Title != "" {
+		fmt.Fprintf(&buf, `<text x="%d" y="%d" text-anchor="middle" font-size="16">%s</text>`+"\n",
+			svgWidth/2, svgMarginTop/2+6, html.EscapeString(m.Title))
+	}
+
+	plotWidth := float64(svgWidth - svgMarginLeft - svgMarginRight)
+	plotHeight := float64(svgHeight - svgMarginTop - svgMarginBottom)
+	left := float64(svgMarginLeft)
+	bottom := float64(svgHeight - svgMarginBottom)
+
+	maxCount := 0
+	for _, h := range m.Histograms {
+		maxCount = MustMax(maxCount, h.MaxCount())
+	}
+	yTicks := []float64{0, 1}
+	if maxCount > 0 {
+		// Ticks never step by less than one count.
+		yTicks = BuildNiceRangePoints(MustMin(svgYTickCount, maxCount), 0, float64(maxCount))
+	}
+	yMax := yTicks[len(yTicks)-1]
+	y := func(count float64) float64 {
+		return bottom - count/yMax*plotHeight
+	}
+
+	// Y axis with grid lines.
+	for _, tick := range yTicks {
+		fmt.Fprintf(&buf, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="#ddd"/>`+"\n",
+			left, y(tick), left+plotWidth, y(tick))
+		fmt.Fprintf(&buf, `<text x="%.1f" y="%.1f" text-anchor="end">%g</text>`+"\n",
+			left-6, y(tick)+4, tick)
+	}
+
+	// Bars.
+	rangePoints := m.Histograms[0].RangePoints()
+	bucketCount := len(rangePoints) - 1
+	bucketWidth := plotWidth / float64(bucketCount)
+	barWidth := bucketWidth * 0.9 / float64(len(m.Histograms))
+	for i, h := range m.Histograms {
+		color := svgSeriesColors[i%len(svgSeriesColors)]
+		for j, count := range h.Counts() {
+			x := left + bucketWidth*float64(j) + bucketWidth*0.05 + barWidth*float64(i)
+			fmt.Fprintf(&buf, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" fill="%s"><title>%s: %d</title></rect>`+"\n",
+				x, y(float64(count)), barWidth, bottom-y(float64(count)), color, html.EscapeString(m.Labels[i]), count)
+		}
+	}
+
+	// X axis with tick labels at bucket edges.
+	fmt.Fprintf(&buf, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="black"/>`+"\n",
+		left, bottom, left+plotWidth, bottom)
+	for j, tick := range FormatTicks(rangePoints, m.TickStyle, m.PointFmt) {
+		x := left + bucketWidth*float64(j)
+		fmt.Fprintf(&buf, `<text x="%.1f" y="%.1f" text-anchor="end" transform="rotate(-45 %.1f %.1f)">%s</text>`+"\n",
+			x, bottom+14, x, bottom+14, html.EscapeString(tick))
+	}
+
+	// Annotations as dashed vertical lines at their values.
+	for i, a := range m.Annotations {
+		bucket, ok := findBucket(rangePoints, a.Value)
+		if !ok {
+			continue
+		}
+		lower, upper := rangePoints[bucket], rangePoints[bucket+1]
+		x := left + bucketWidth*(float64(bucket)+(a.Value-lower)/(upper-lower))
+		// Stagger labels so that those of close values do not overlap.
+		ly := float64(svgMarginTop) - 4 + float64(i%2)*14
+		fmt.Fprintf(&buf, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="#c00" stroke-dasharray="4 3"/>`+"\n",
+			x, float64(svgMarginTop), x, bottom)
+		fmt.Fprintf(&buf, `<text x="%.1f" y="%.1f" fill="#c00">%s</text>`+"\n",
+			x+3, ly+10, html.EscapeString(a.Label))
+	}
+
+	// Legend for multiple inputs.
+	if len(m.Histograms) > 1 {
+		for i, label := range m.Labels {
+			ly := float64(svgMarginTop + 16*i)
+			fmt.Fprintf(&buf, `<rect x="%.1f" y="%.1f" width="10" height="10" fill="%s"/>`+"\n",
+				left+plotWidth-150, ly, svgSeriesColors[i%len(svgSeriesColors)])
+			fmt.Fprintf(&buf, `<text x="%.1f" y="%.1f">%s</text>`+"\n",
+				left+plotWidth-135, ly+9, html.EscapeString(label))
+		}
+	}
+
+	buf.WriteString("</svg>\n")
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// findBucket returns the index of the bucket of rangePoints containing v.
+// The last edge belongs to the last bucket here, so that an annotation at
+// the axis max is drawn.
+func findBucket(rangePoints []float64, v float64) (int, bool) {
+	last := len(rangePoints) - 1
+	if !(v >= rangePoints[0] && v <= rangePoints[last]) {
+		return 0, false
+	}
+	for i := 1; i < last; i++ {
+		if v < rangePoints[i] {
+			return i - 1, true
+		}
+	}
+	return last - 1, true
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"testing"
+)
+
+func TestSVGOutputFormat(t *testing.T) {
+	rangePoints := BuildRangePoints[float64](3, 0, 3)
+	a := NewHistogram(rangePoints)
+	a.AddValues([]float64{0.5, 1.5, 1.7})
+	b := NewHistogram(rangePoints)
+	b.AddValues([]float64{2.5})
+	m := &RenderModel{
+		Histograms: []*Histogram[float64]{a, b},
+		Labels:     []string{"a&b.txt", "c.txt"},
+		PointFmt:   "%.1f",
+		Title:      "<title>",
+	}
+	var buf bytes.Buffer
+	if err := (svgOutputFormat{}).Render(&buf, m); err != nil {
+		t.Fatal(err)
+	}
+
+	dec := xml.NewDecoder(&buf)
+	rects := 0
+	var texts []string
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("invalid SVG: %v", err)
+		}
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			if tok.Name.Local == "rect" {
+				rects++
+			}
+		case xml.CharData:
+			texts = append(texts, string(tok))
+		}
+	}
+	// A background, 3 buckets for 2 inputs and 2 legend marks.
+	if got, want := rects, 1+3*2+2; got != want {
+		t.Errorf("rect count mismatch, got=%d, want=%d", got, want)
+	}
+	for _, want := range []string{"<title>", "a&b.txt", "1.0", "3.0"} {
+		found := false
+		for _, text := range texts {
+			if text == want {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("text %q not found in %q", want, texts)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"math"
+	"sort"
+)
+
+// TDigest is a t-digest sketch, which estimates quantiles from a small
+// number of centroids. Centroids are kept small near both tails, so
+// extreme quantiles like p99 and p999 are much more accurate than those of
+// the middle, and memory usage depends on the compression, not on the
+// number of recorded values.
+//
+// This is the merging variant with the k2 scale function described in
+// "Computing Extremely Accurate Quantiles Using t-Digests" by Dunning and
+// Ertl, which bounds the error relative to the distance from the nearer
+// tail.
+type TDigest struct {
+	compression float64
+	centroids   []tdigestCentroid
+	buffer      []tdigestCentroid
+	totalCount  int
+	min         float64
+	max         float64
+}
+
+type tdigestCentroid struct {
+	mean  float64
+	count int
+}
+
+// NewTDigest returns an empty TDigest. The number of centroids is bounded
+// by about compression. compression must be at least 10.
+func NewTDigest(compression float64) *TDigest {
+	if !(compression >= 10) {
+		panic("compression must be at least 10")
+	}
+	return &TDigest{
+		compression: compression,
+		min:         math.Inf(1),
+		max:         math.Inf(-1),
+	}
+}
+
+var _ Recorder = (*TDigest)(nil)
+
+// AddValue records v. NaN and infinities are ignored.
+func (d *TDigest) AddValue(v float64) {
+	d.addValueCount(v, 1)
+}
+
+// addValueCount records v n times as a single buffered centroid.
+func (d *TDigest) addValueCount(v float64, n int) {
+	if math.IsNaN(v) || math.IsInf(v, 0) || n <= 0 {
+		return
+	}
+	d.buffer = append(d.buffer, tdigestCentroid{mean: v, count: n})
+	d.totalCount += n
+	d.min = math.Min(d.min, v)
+	d.max = math.Max(d.max, v)
+	if len(d.buffer) >= d.bufferSize() {
+		d.compress()
+	}
+}
+
+func (d *TDigest) AddValues(values []float64) {
+	for _, v := range values {
+		d.AddValue(v)
+	}
+}
+
+func (d *TDigest) bufferSize() int {
+	return int(5 * d.compression)
+}
+
+// scale is the k2 scale function, which maps a quantile to an index so
+// that a centroid spans at most 1 of it. It is -Inf and +Inf at 0 and 1,
+// so the minimum and the maximum are kept in singletons.
+func (d *TDigest) scale(q float64) float64 {
+	n := math.Max(float64(d.totalCount), d.compression)
+	z := 4*math.Log(n/d.compression) + 24
+	return d.compression / z * math.Log(q/(1-q))
+}
+
+// compress merges buffered values into centroids.
+func (d *TDigest) compress() {
+	if len(d.buffer) == 0 {
+		return
+	}
+	all := make([]tdigestCentroid, 0, len(d.centroids)+len(d.buffer))
+	all = append(all, d.centroids...)
+	all = append(all, d.buffer...)
+	d.buffer = d.buffer[:0]
+	sort.Slice(all, func(i, j int) bool { return all[i].mean < all[j].mean })
+
+	total := float64(d.totalCount)
+	merged := all[:1]
+	cum := 0.0
+	kLower := d.scale(0)
+	for _, c := range all[1:] {
+		last := &merged[len(merged)-1]
+		if d.scale((cum+float64(last.count+c.count))/total)-kLower <= 1 {
+			// Update the mean incrementally to keep it within the merged values.
+			last.count += c.count
+			last.mean += (c.mean - last.mean) * float64(c.count) / float64(last.count)
+			continue
+		}
+		cum += float64(last.count)
+		kLower = d.scale(cum / total)
+		merged = append(merged, c)
+	}
+	d.centroids = merged
+}
+
+// Counts returns counts of centroids in ascending order of their means.
+func (d *TDigest) Counts() []int {
+	d.compress()
+	counts := make([]int, len(d.centroids))
+	for i, c := range d.centroids {
+		counts[i] = c.count
+	}
+	return counts
+}
+
+// TotalCount returns the number of recorded values.
+func (d *TDigest) TotalCount() int {
+	return d.totalCount
+}
+
+// Min returns the minimum recorded value. It returns +Inf if no value is
+// recorded.
+func (d *TDigest) Min() float64 {
+	return d.min
+}
+
+// Max returns the maximum recorded value. It returns -Inf if no value is
+// recorded.
+func (d *TDigest) Max() float64 {
+	return d.max
+}
+
+// Quantile estimates the q-quantile of recorded values. The mean of each
+// centroid is placed at the middle of its rank range and the quantile is
+// interpolated linearly between them, or between the minimum or the maximum
+// and the first or the last centroid. It returns NaN if no value is
+// recorded. q must be between 0 and 1.
+func (d *TDigest) Quantile(q float64) float64 {
+	if q < 0 || q > 1 || math.IsNaN(q) {
+		panic("q must be between 0 and 1")
+	}
+	switch {
+	case d.totalCount == 0:
+		return math.NaN()
+	case q == 0:
+		return d.min
+	case q == 1:
+		return d.max
+	}
+	d.compress()
+
+	target := q * float64(d.totalCount)
+	prevRank, prevValue := 0.0, d.min
+	cum := 0.0
+	for _, c := range d.centroids {
+		if c.count == 1 {
+			// A singleton is an exact value with its rank range.
+			if target < cum {
+				return interpolate(prevRank, prevValue, cum, c.mean, target)
+			}
+			if target <= cum+1 {
+				return c.mean
+			}
+			prevRank, prevValue = cum+1, c.mean
+		} else {
+			rank := cum + float64(c.count)/2
+			if target <= rank {
+				return interpolate(prevRank, prevValue, rank, c.mean, target)
+			}
+			prevRank, prevValue = rank, c.mean
+		}
+		cum += float64(c.count)
+	}
+	return interpolate(prevRank, prevValue, cum, d.max, target)
+}
+
+func interpolate(x0, y0, x1, y1, x float64) float64 {
+	if x1 <= x0 {
+		return y1
+	}
+	return y0 + (y1-y0)*(x-x0)/(x1-x0)
+}
+
+// Histogram converts d to a Histogram with rangePoints. The count of each
+// centroid is added to the Histogram bucket which contains its mean.
+func (d *TDigest) Histogram(rangePoints []float64) *Histogram[float64] {
+	d.compress()
+	histogram := NewHistogram(rangePoints)
+	for _, c := range d.centroids {
+		histogram.addValueCount(c.mean, c.count)
+	}
+	return histogram
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"golang.org/x/exp/slices"
+)
+
+func TestTDigest_Quantile(t *testing.T) {
+	rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
+	d := NewTDigest(100)
+	values := make([]float64, 100000)
+	for i := range values {
+		values[i] = rnd.ExpFloat64()
+	}
+	d.AddValues(values)
+	slices.Sort(values)
+
+	if got, want := d.TotalCount(), len(values); got != want {
+		t.Errorf("total count mismatch, got=%d, want=%d", got, want)
+	}
+	if got := len(d.Counts()); got > 200 {
+		t.Errorf("too many centroids, got=%d", got)
+	}
+	testCases := []struct {
+		q float64
+		// maxRankError is the allowed error in the rank of the estimate
+		// relative to the number of values.
+		maxRankError float64
+	}{
+		{q: 0.5, maxRankError: 0.01},
+		{q: 0.9, maxRankError: 0.005},
+		{q: 0.99, maxRankError: 0.001},
+		{q: 0.999, maxRankError: 0.0002},
+	}
+	for _, tc := range testCases {
+		got := d.Quantile(tc.q)
+		rank := float64(searchFloat64s(values, got)) / float64(len(values))
+		if math.Abs(rank-tc.q) > tc.maxRankError {
+			t.Errorf("quantile rank error too large, q=%g, got=%g, rank=%g", tc.q, got, rank)
+		}
+	}
+	if got, want := d.Quantile(0), values[0]; got != want {
+		t.Errorf("quantile 0 mismatch, got=%g, want=%g", got, want)
+	}
+	if got, want := d.Quantile(1), values[len(values)-1]; got != want {
+		t.Errorf("quantile 1 mismatch, got=%g, want=%g", got, want)
+	}
+}
+
+func searchFloat64s(values []float64, v float64) int {
+	i, _ := slices.BinarySearch(values, v)
+	return i
+}
+
+func TestTDigest_SmallInput(t *testing.T) {
+	d := NewTDigest(100)
+	if got := d.Quantile(0.5); !math.IsNaN(got) {
+		t.Errorf("quantile of empty digest mismatch, got=%g, want=NaN", got)
+	}
+	d.AddValues([]float64{3, 1, 2, math.NaN(), math.Inf(1)})
+	if got, want := d.Counts(), []int{1, 1, 1}; !slices.Equal(got, want) {
+		t.Errorf("counts mismatch, got=%v, want=%v", got, want)
+	}
+	for _, tc := range []struct{ q, want float64 }{{0, 1}, {0.5, 2}, {1, 3}} {
+		if got := d.Quantile(tc.q); got != tc.want {
+			t.Errorf("quantile mismatch, q=%g, got=%g, want=%g", tc.q, got, tc.want)
+		}
+	}
+
+	histogram := d.Histogram(BuildRangePoints[float64](2, 0, 3))
+	if got, want := histogram.Counts(), []int{1, 1}; !slices.Equal(got, want) {
+		t.Errorf("histogram counts mismatch, got=%v, want=%v", got, want)
+	}
+	if got, want := histogram.outOfRangeCount, 1; got != want {
+		t.Errorf("histogram out of range count mismatch, got=%d, want=%d", got, want)
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// templateOutputFormat renders histograms with a text/template given with
+// --template, so that any text format can be produced without code
+// changes. The template is executed with templateData, like
+//
+//	{{range .Histograms}}{{.Label}}: {{.Stats.Count}} values
+//	{{range .Buckets}}{{printf "%g,%g,%d" .Lower .Upper .Count}}
+//	{{end}}{{end}}
+//
+// Besides the functions of text/template, "repeat" repeats a string like
+// strings.Repeat and "scale" rounds a fraction times a width to an int, so
+// that bars are drawn like
+//
+//	{{repeat "*" (scale .Ratio 40)}}
+//
+// It is not registered since it needs the template.
+type templateOutputFormat struct {
+	tmpl *template.Template
+}
+
+func (templateOutputFormat) Name() string { return "template" }
+
+// templateData is the data given to the template of templateOutputFormat.
+type templateData struct {
+	Title      string
+	Histograms []templateHistogram
+}
+
+type templateHistogram struct {
+	Label string
+	// Stats are the statistics of all values including those out of range.
+	Stats Stats
+	// Total is the number of all values including those out of range.
+	Total int
+	// OutOfRange is the number of values not in any bucket, of which
+	// Underflow are below the buckets and Overflow are above them.
+	OutOfRange int
+	Underflow  int
+	Overflow   int
+	Buckets    []templateBucket
+}
+
+type templateBucket struct {
+	Lower float64
+	Upper float64
+	Count int
+	// Percent is the share of Count in all values including those out of
+	// range.
+	Percent float64
+	// CumulativePercent is the share of values in range at or below Upper
+	// in all values in range.
+	CumulativePercent float64
+	// Ratio is Count divided by the largest count of the buckets of the
+	// histogram, which is 0 if all are empty.
+	Ratio float64
+}
+
+var templateFuncs = template.FuncMap{
+	"repeat": func(s string, n int) string {
+		if n < 0 {
+			n = 0
+		}
+		return strings.Repeat(s, n)
+	},
+	"scale": func(fraction float64, width int) int {
+		return int(math.Round(fraction * float64(width)))
+	},
+}
+
+// readTemplateOutputFormat parses the template in filename.
+func readTemplateOutputFormat(filename string) (templateOutputFormat, error) {
+	text, err := os.ReadFile(filename)
+	if err != nil {
+		return templateOutputFormat{}, err
+	}
+	tmpl, err := template.New(filepath.Base(filename)).Funcs(templateFuncs).Parse(string(text))
+	if err != nil {
+		return templateOutputFormat{}, err
+	}
+	return templateOutputFormat{tmpl: tmpl}, nil
+}
+
+func (f templateOutputFormat) Render(w io.Writer, m *RenderModel) error {
+	return f.tmpl.Execute(w, newTemplateData(m))
+}
+
+func newTemplateData(m *RenderModel) templateData {
+	data := templateData{Title: m.Title, Histograms: make([]templateHistogram, len(m.Histograms))}
+	for i, h := range m.Histograms {
+		th := templateHistogram{
+			Label:      m.Labels[i],
+			Total:      h.TotalCount(),
+			OutOfRange: h.outOfRangeCount,
+			Underflow:  h.underflowCount,
+			Overflow:   h.overflowCount,
+			Buckets:    make([]templateBucket, len(h.counts)),
+		}
+		if m.Stats != nil {
+			th.Stats = m.Stats[i]
+		}
+		percentages := h.Percentages()
+		maxCount := h.MaxCount()
+		inRange := th.Total - th.OutOfRange
+		cumulative := 0
+		for j, count := range h.counts {
+			cumulative += count
+			b := templateBucket{
+				Lower:   h.rangePoints[j],
+				Upper:   h.rangePoints[j+1],
+				Count:   count,
+				Percent: percentages[j],
+			}
+			if inRange > 0 {
+				b.CumulativePercent = float64(cumulative) / float64(inRange) * 100
+			}
+			if maxCount > 0 {
+				b.Ratio = float64(count) / float64(maxCount)
+			}
+			th.Buckets[j] = b
+		}
+		data.Histograms[i] = th
+	}
+	return data
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTemplateOutputFormat(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "out.tmpl")
+	text := `{{.Title}}
+{{range .Histograms}}{{.Label}} n={{.Stats.Count}} total={{.Total}} out={{.OutOfRange}}/{{.Underflow}}/{{.Overflow}}
+{{range .Buckets}}{{printf "%g-%g %d %.1f%% %.1f%%" .Lower .Upper .Count .Percent .CumulativePercent}} {{repeat "#" (scale .Ratio 4)}}
+{{end}}{{end}}`
+	if err := os.WriteFile(filename, []byte(text), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := readTemplateOutputFormat(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := NewHistogram(BuildRangePoints[float64](2, 0, 4))
+	h.AddValues([]float64{1, 2, 3, 3.5, -1})
+	m := &RenderModel{
+		Title:      "latency",
+		Histograms: []*Histogram[float64]{h},
+		Labels:     []string{"a.txt"},
+		Stats:      []Stats{{Count: 5}},
+	}
+	var buf bytes.Buffer
+	if err := f.Render(&buf, m); err != nil {
+		t.Fatal(err)
+	}
+	want := `latency
+a.txt n=5 total=5 out=1/1/0
+0-2 1 20.0% 25.0% #
+2-4 3 60.0% 100.0% ####
+`
+	if got := buf.String(); got != want {
+		t.Errorf("result mismatch,\n got=%s\nwant=%s", got, want)
+	}
+}
+
+func TestReadTemplateOutputFormat_Error(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "bad.tmpl")
+	if err := os.WriteFile(filename, []byte("{{range .Histograms}}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	_, err := readTemplateOutputFormat(filename)
+	if err == nil || !strings.Contains(err.Error(), "bad.tmpl") {
+		t.Errorf("error mismatch, got=%v", err)
+	}
+}
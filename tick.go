@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// TickStyle selects how axis point values are formatted.
+type TickStyle string
+
+const (
+	// TickStyleFixed formats points with the user supplied point format.
+	TickStyleFixed TickStyle = "fixed"
+	// TickStyleSci formats points in scientific notation like 1.5e-05.
+	TickStyleSci TickStyle = "sci"
+	// TickStyleEng formats points in engineering notation like 15e-06,
+	// where the exponent is always a multiple of three.
+	TickStyleEng TickStyle = "eng"
+	// TickStyleAuto uses TickStyleFixed unless fixed notation loses
+	// resolution or the magnitudes of points are too far apart, in which
+	// case it uses TickStyleSci.
+	TickStyleAuto TickStyle = "auto"
+)
+
+var tickStyles = []TickStyle{TickStyleFixed, TickStyleSci, TickStyleEng, TickStyleAuto}
+
+func parseTickStyle(s string) (TickStyle, error) {
+	for _, style := range tickStyles {
+		if s == string(style) {
+			return style, nil
+		}
+	}
+	return "", fmt.Errorf("invalid tick style: %q", s)
+}
+
+// autoTickMaxFixedMagnitude and autoTickMaxSpread are thresholds for
+// TickStyleAuto to switch to scientific notation.
+const autoTickMaxFixedMagnitude = 1e6
+const autoTickMaxSpread = 1e4
+
+// maxTickPrecision is the maximum number of digits after the decimal point
+// used for scientific and engineering notation.
+const maxTickPrecision = 15
+
+// FormatTicks formats points with style. pointFmt is used for
+// TickStyleFixed and for TickStyleAuto when fixed notation is chosen.
+func FormatTicks(points []float64, style TickStyle, pointFmt string) []string {
+	switch style {
+	case TickStyleSci:
+		return formatTicksWithMinPrecision(points, formatSci)
+	case TickStyleEng:
+		return formatTicksWithMinPrecision(points, formatEng)
+	case TickStyleAuto:
+		fixed := formatTicksFixed(points, pointFmt)
+		if !hasAdjacentDuplicate(fixed) && !isTickSpreadTooWide(points) {
+			return fixed
+		}
+		return formatTicksWithMinPrecision(points, formatSci)
+	default:
+		return formatTicksFixed(points, pointFmt)
+	}
+}
+
+func formatTicksFixed(points []float64, pointFmt string) []string {
+	ticks := make([]string, len(points))
+	for i, p := range points {
+		ticks[i] = fmt.Sprintf(pointFmt, p)
+	}
+	return ticks
+}
+
+// formatTicksWithMinPrecision returns the ticks formatted with the smallest
+// precision with which adjacent ticks are distinguishable.
+func formatTicksWithMinPrecision(points []float64, format func(v float64, prec int) string) []string {
+	ticks := make([]string, len(points))
+	for prec := 1; ; prec++ {
+		for i, p := range points {
+			ticks[i] = format(p, prec)
+		}
+		if !hasAdjacentDuplicate(ticks) || prec == maxTickPrecision {
+			return ticks
+		}
+	}
+}
+
+func hasAdjacentDuplicate(ticks []string) bool {
+	for i := 1; i < len(ticks); i++ {
+		if ticks[i] == ticks[i-1] {
+			return true
+		}
+	}
+	return false
+}
+
+func isTickSpreadTooWide(points []float64) bool {
+	maxAbs := 0.0
+	minAbs := math.Inf(1)
+	for _, p := range points {
+		a := math.Abs(p)
+		maxAbs = math.Max(maxAbs, a)
+		if a != 0 {
+			minAbs = math.Min(minAbs, a)
+		}
+	}
+	if maxAbs == 0 {
+		return false
+	}
+	return maxAbs >= autoTickMaxFixedMagnitude || maxAbs/minAbs >= autoTickMaxSpread
+}
+
+func formatSci(v float64, prec int) string {
+	return strconv.FormatFloat(v, 'e', prec, float64BitSize)
+}
+
+func formatEng(v float64, prec int) string {
+	if v == 0 || math.IsInf(v, 0) || math.IsNaN(v) {
+		return strconv.FormatFloat(v, 'f', prec, float64BitSize)
+	}
+
+	exp := int(math.Floor(math.Log10(math.Abs(v))))
+	exp -= ((exp % 3) + 3) % 3
+	mantissa := v / math.Pow10(exp)
+	s := strconv.FormatFloat(mantissa, 'f', prec, float64BitSize)
+	// Rounding may carry the mantissa up to 1000.
+	if m, err := strconv.ParseFloat(s, float64BitSize); err == nil && math.Abs(m) >= 1000 {
+		exp += 3
+		s = strconv.FormatFloat(mantissa/1000, 'f', prec, float64BitSize)
+	}
+	return fmt.Sprintf("%se%+03d", s, exp)
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"testing"
+
+	"golang.org/x/exp/slices"
+)
+
+func TestFormatTicks(t *testing.T) {
+	testCases := []struct {
+		points []float64
+		style  TickStyle
+		want   []string
+	}{
+		{points: []float64{0, 0.5, 1}, style: TickStyleFixed, want: []string{"0.00", "0.50", "1.00"}},
+		{points: []float64{0.00001, 0.00005, 0.0001}, style: TickStyleSci, want: []string{"1.0e-05", "5.0e-05", "1.0e-04"}},
+		{points: []float64{0.00001, 0.00002, 0.00003}, style: TickStyleSci, want: []string{"1.0e-05", "2.0e-05", "3.0e-05"}},
+		{points: []float64{0.00001, 0.000015, 0.00002}, style: TickStyleSci, want: []string{"1.0e-05", "1.5e-05", "2.0e-05"}},
+		{points: []float64{0.00001, 0.00005, 0.0001}, style: TickStyleEng, want: []string{"10.0e-06", "50.0e-06", "100.0e-06"}},
+		{points: []float64{0, 1500, 999999}, style: TickStyleEng, want: []string{"0.0", "1.5e+03", "1.0e+06"}},
+		{points: []float64{0, 0.5, 1}, style: TickStyleAuto, want: []string{"0.00", "0.50", "1.00"}},
+		{points: []float64{0.00001, 0.00005, 0.0001}, style: TickStyleAuto, want: []string{"1.0e-05", "5.0e-05", "1.0e-04"}},
+		{points: []float64{1, 100, 1e5}, style: TickStyleAuto, want: []string{"1.0e+00", "1.0e+02", "1.0e+05"}},
+	}
+	for _, tc := range testCases {
+		got := FormatTicks(tc.points, tc.style, "%.2f")
+		if !slices.Equal(got, tc.want) {
+			t.Errorf("result mismatch, points=%v, style=%s, got=%q, want=%q", tc.points, tc.style, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import "math"
+
+// uniformWidthTolerance is the relative difference of bucket widths
+// within which range points are regarded as equally spaced. Rounding
+// errors of BuildRangePoints are far smaller.
+const uniformWidthTolerance = 1e-9
+
+// uniformWidth returns the width of the buckets between rangePoints if
+// they are equally spaced, or 0 otherwise.
+func uniformWidth[T Number](rangePoints []T) float64 {
+	n := len(rangePoints) - 1
+	if n < 1 {
+		return 0
+	}
+	width := (float64(rangePoints[n]) - float64(rangePoints[0])) / float64(n)
+	if !(width > 0) || math.IsInf(width, 0) {
+		return 0
+	}
+	for i := 0; i < n; i++ {
+		w := float64(rangePoints[i+1]) - float64(rangePoints[i])
+		if math.Abs(w-width) > width*uniformWidthTolerance {
+			return 0
+		}
+	}
+	return width
+}
+
+// uniformBucketIndexClosed is like bucketIndexClosed for rangePoints
+// equally spaced by width, which computes the index from v in constant
+// time instead of searching it. The index is corrected against the range
+// points, so that values at rounded points land in the same buckets as
+// with bucketIndexClosed.
+func uniformBucketIndexClosed[T Number](rangePoints []T, width float64, v T, closure Closure) (int, bool) {
+	last := len(rangePoints) - 1
+	// Written in this form so that NaN is also out of range.
+	if closure == ClosureRight {
+		if !(v > rangePoints[0] && v <= rangePoints[last]) {
+			return 0, false
+		}
+	} else if !(v >= rangePoints[0] && v < rangePoints[last]) {
+		return 0, false
+	}
+
+	i := int((float64(v) - float64(rangePoints[0])) / width)
+	if i < 0 {
+		i = 0
+	} else if i > last-1 {
+		i = last - 1
+	}
+	if closure == ClosureRight {
+		for i > 0 && v <= rangePoints[i] {
+			i--
+		}
+		for i < last-1 && v > rangePoints[i+1] {
+			i++
+		}
+		return i, true
+	}
+	for i > 0 && v < rangePoints[i] {
+		i--
+	}
+	for i < last-1 && v >= rangePoints[i+1] {
+		i++
+	}
+	return i, true
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestUniformWidth(t *testing.T) {
+	testCases := []struct {
+		rangePoints []float64
+		want        float64
+	}{
+		{rangePoints: []float64{0, 1, 2, 3}, want: 1},
+		{rangePoints: BuildRangePoints[float64](10, 0.1, 0.7), want: 0.06},
+		{rangePoints: []float64{0, 1, 3}, want: 0},
+		{rangePoints: []float64{1, 1, 1}, want: 0},
+		{rangePoints: []float64{0}, want: 0},
+		{rangePoints: []float64{-math.MaxFloat64, math.MaxFloat64}, want: 0},
+		{rangePoints: MustBuildLogRangePoints(3, 1, 1000), want: 0},
+	}
+	for _, tc := range testCases {
+		got := uniformWidth(tc.rangePoints)
+		if math.Abs(got-tc.want) > 1e-12 {
+			t.Errorf("result mismatch, rangePoints=%v, got=%g, want=%g", tc.rangePoints, got, tc.want)
+		}
+	}
+
+	if got := uniformWidth(BuildRangePoints[int](3, 0, 10)); got != 0 {
+		t.Errorf("result mismatch for unequal int buckets, got=%g, want=0", got)
+	}
+}
+
+func TestUniformBucketIndexClosed(t *testing.T) {
+	rangePoints := BuildRangePoints[float64](7, 0.1, 0.8)
+	width := uniformWidth(rangePoints)
+	if width == 0 {
+		t.Fatalf("range points must be uniform: %v", rangePoints)
+	}
+	values := []float64{math.NaN(), math.Inf(-1), math.Inf(1), -1, 0.1, 0.8, 0.3, 0.7}
+	values = append(values, rangePoints...)
+	for _, p := range rangePoints {
+		values = append(values, math.Nextafter(p, math.Inf(-1)), math.Nextafter(p, math.Inf(1)))
+	}
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 1000; i++ {
+		values = append(values, r.Float64())
+	}
+	for _, closure := range []Closure{ClosureLeft, ClosureRight} {
+		for _, v := range values {
+			gotI, gotOK := uniformBucketIndexClosed(rangePoints, width, v, closure)
+			wantI, wantOK := bucketIndexClosed(rangePoints, v, closure)
+			if gotI != wantI || gotOK != wantOK {
+				t.Errorf("result mismatch, closure=%s, v=%v, got=(%d, %v), want=(%d, %v)", closure, v, gotI, gotOK, wantI, wantOK)
+			}
+		}
+	}
+
+	intPoints := []int{0, 5, 10, 15}
+	for v := -2; v <= 17; v++ {
+		gotI, gotOK := uniformBucketIndexClosed(intPoints, uniformWidth(intPoints), v, ClosureLeft)
+		wantI, wantOK := bucketIndexClosed(intPoints, v, ClosureLeft)
+		if gotI != wantI || gotOK != wantOK {
+			t.Errorf("result mismatch, v=%d, got=(%d, %v), want=(%d, %v)", v, gotI, gotOK, wantI, wantOK)
+		}
+	}
+}
+
+func BenchmarkHistogram_AddValue(b *testing.B) {
+	r := rand.New(rand.NewSource(1))
+	values := make([]float64, 1024)
+	for i := range values {
+		values[i] = r.Float64() * 100
+	}
+	b.Run("uniform", func(b *testing.B) {
+		h := NewHistogram(BuildRangePoints[float64](1000, 0, 100))
+		for i := 0; i < b.N; i++ {
+			h.AddValue(values[i%len(values)])
+		}
+	})
+	b.Run("search", func(b *testing.B) {
+		h := NewHistogram(BuildRangePoints[float64](1000, 0, 100))
+		h.uniformWidth = 0
+		for i := 0; i < b.N; i++ {
+			h.AddValue(values[i%len(values)])
+		}
+	})
+}
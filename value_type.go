@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// ValueParser converts a value in text to a float64 value. s may share
+// memory with a read buffer, so it must not be kept after returning, though
+// it may be copied to errors.
+type ValueParser func(s string) (float64, error)
+
+// Value types selected with the --value-type flag.
+const (
+	valueTypeFloat    = "float"
+	valueTypeDuration = "duration"
+	valueTypeBytes    = "bytes"
+)
+
+// newValueParser returns the ValueParser for valueType. durationUnit is
+// the unit of values converted from durations.
+func newValueParser(valueType string, durationUnit time.Duration) (ValueParser, error) {
+	switch valueType {
+	case valueTypeFloat:
+		return parseFiniteFloat, nil
+	case valueTypeDuration:
+		return func(s string) (float64, error) {
+			return parseDurationValue(s, durationUnit)
+		}, nil
+	case valueTypeBytes:
+		return parseBytesValue, nil
+	default:
+		return nil, fmt.Errorf("invalid value type: %q", valueType)
+	}
+}
+
+// parseDurationUnit parses a unit accepted by time.ParseDuration like "ms".
+func parseDurationUnit(s string) (time.Duration, error) {
+	unit, err := time.ParseDuration("1" + s)
+	if err != nil || s == "" || strings.ContainsAny(s, "0123456789.") {
+		return 0, fmt.Errorf("invalid duration unit: %q", s)
+	}
+	return unit, nil
+}
+
+// parseDurationValue parses s like "120ms" or "2m3s" with
+// time.ParseDuration and returns it in unit.
+func parseDurationValue(s string, unit time.Duration) (float64, error) {
+	d, err := time.ParseDuration(strings.TrimSpace(s))
+	if err != nil {
+		return 0, err
+	}
+	return float64(d) / float64(unit), nil
+}
+
+// byteUnitPrefixes are the prefixes of byte size units in ascending order.
+const byteUnitPrefixes = "KMGTPE"
+
+// parseBytesValue parses a byte size like "512", "4K", "1.5MiB" or "2GB"
+// and returns it in bytes. Units are case insensitive. "KB" and the like
+// are powers of 1000 and "KiB" and the like are powers of 1024. A prefix
+// alone like "4K" is a power of 1024 as printed by du and ls -h.
+func parseBytesValue(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	i := strings.IndexFunc(s, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.' && r != '-' && r != '+' && r != 'e' && r != 'E'
+	})
+	// Keep an exponent only if digits follow it, so that "2E" is 2 exbibytes.
+	numEnd := len(s)
+	if i != -1 {
+		numEnd = i
+	}
+	for numEnd > 0 && (s[numEnd-1] == 'e' || s[numEnd-1] == 'E') {
+		numEnd--
+	}
+	numPart, unit := s[:numEnd], strings.TrimSpace(s[numEnd:])
+	value, err := parseFiniteFloat(numPart)
+	if err != nil {
+		return 0, fmt.Errorf("invalid byte size: %q", s)
+	}
+	multiplier, ok := byteUnitMultiplier(unit)
+	if !ok {
+		return 0, fmt.Errorf("invalid byte size unit: %q", s)
+	}
+	return value * multiplier, nil
+}
+
+func byteUnitMultiplier(unit string) (float64, bool) {
+	unit = strings.ToUpper(unit)
+	if unit == "" || unit == "B" {
+		return 1, true
+	}
+	exp := strings.IndexByte(byteUnitPrefixes, unit[0]) + 1
+	if exp == 0 {
+		return 0, false
+	}
+	switch unit[1:] {
+	case "":
+		return math.Pow(1024, float64(exp)), true
+	case "B":
+		return math.Pow(1000, float64(exp)), true
+	case "IB":
+		return math.Pow(1024, float64(exp)), true
+	default:
+		return 0, false
+	}
+}
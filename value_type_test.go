@@ -0,0 +1,122 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/exp/slices"
+)
+
+func TestParseDurationValue(t *testing.T) {
+	testCases := []struct {
+		input   string
+		unit    time.Duration
+		want    float64
+		wantErr bool
+	}{
+		{input: "120ms", unit: time.Millisecond, want: 120},
+		{input: "1.5s", unit: time.Millisecond, want: 1500},
+		{input: "2m3s", unit: time.Second, want: 123},
+		{input: "250us", unit: time.Millisecond, want: 0.25},
+		{input: " 3µs ", unit: time.Nanosecond, want: 3000},
+		{input: "0", unit: time.Second, want: 0},
+		{input: "-1h", unit: time.Minute, want: -60},
+		{input: "12", unit: time.Second, wantErr: true},
+		{input: "", unit: time.Second, wantErr: true},
+	}
+	for _, tc := range testCases {
+		got, err := parseDurationValue(tc.input, tc.unit)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("error expected, input=%q", tc.input)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("unexpected error, input=%q, err=%v", tc.input, err)
+		} else if got != tc.want {
+			t.Errorf("result mismatch, input=%q, unit=%s, got=%g, want=%g", tc.input, tc.unit, got, tc.want)
+		}
+	}
+}
+
+func TestParseDurationUnit(t *testing.T) {
+	for _, s := range []string{"ns", "us", "µs", "ms", "s", "m", "h"} {
+		if _, err := parseDurationUnit(s); err != nil {
+			t.Errorf("unexpected error, input=%q, err=%v", s, err)
+		}
+	}
+	for _, s := range []string{"", "sec", "5ms", "m30s"} {
+		if _, err := parseDurationUnit(s); err == nil {
+			t.Errorf("error expected, input=%q", s)
+		}
+	}
+}
+
+func TestDurationValueType(t *testing.T) {
+	parse, err := newValueParser(valueTypeDuration, time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testCases := []struct {
+		format string
+		input  string
+		want   []float64
+	}{
+		{format: "plain", input: "120ms\n1.5s\n", want: []float64{120, 1500}},
+		{format: "csv", input: "2m3s,GET\n", want: []float64{123000}},
+		{format: "jsonl", input: "\"10ms\"\n2.5\n", want: []float64{10, 2.5}},
+	}
+	for _, tc := range testCases {
+		format, _ := LookupInputFormat(tc.format)
+		var got []float64
+		err := format.Scan(strings.NewReader(tc.input), parse, func(v float64) {
+			got = append(got, v)
+		})
+		if err != nil {
+			t.Errorf("unexpected error, format=%s, err=%v", tc.format, err)
+		} else if !slices.Equal(got, tc.want) {
+			t.Errorf("result mismatch, format=%s, got=%v, want=%v", tc.format, got, tc.want)
+		}
+	}
+}
+
+func TestParseBytesValue(t *testing.T) {
+	testCases := []struct {
+		input   string
+		want    float64
+		wantErr bool
+	}{
+		{input: "512", want: 512},
+		{input: "512B", want: 512},
+		{input: "4K", want: 4096},
+		{input: "4.0K", want: 4096},
+		{input: "1.5MiB", want: 1.5 * 1024 * 1024},
+		{input: "2GB", want: 2e9},
+		{input: "2 gb", want: 2e9},
+		{input: "10kB", want: 10000},
+		{input: "1e3", want: 1000},
+		{input: "2E", want: 2 * 1024 * 1024 * 1024 * 1024 * 1024 * 1024},
+		{input: "3T", want: 3 * 1024 * 1024 * 1024 * 1024},
+		{input: "1.5Mb", want: 1.5e6},
+		{input: "K", wantErr: true},
+		{input: "4X", wantErr: true},
+		{input: "4KiBB", wantErr: true},
+		{input: "", wantErr: true},
+	}
+	for _, tc := range testCases {
+		got, err := parseBytesValue(tc.input)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("error expected, input=%q, got=%g", tc.input, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("unexpected error, input=%q, err=%v", tc.input, err)
+		} else if got != tc.want {
+			t.Errorf("result mismatch, input=%q, got=%g, want=%g", tc.input, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Orientation selects the direction in which bars grow.
+type Orientation string
+
+const (
+	// OrientationHorizontal draws a row per bucket with bars growing to the
+	// right.
+	OrientationHorizontal Orientation = "horizontal"
+	// OrientationVertical draws a column per bucket with bars growing
+	// upward and the bucket edges laid out under the columns.
+	OrientationVertical Orientation = "vertical"
+)
+
+func parseOrientation(s string) (Orientation, error) {
+	switch orientation := Orientation(s); orientation {
+	case OrientationHorizontal, OrientationVertical:
+		return orientation, nil
+	default:
+		return "", fmt.Errorf("invalid orientation: %q", s)
+	}
+}
+
+const defaultGraphHeight = 20
+const graphMinHeight = 2
+
+// eighthHeightBlocks are block elements from empty to full height in
+// eighths.
+var eighthHeightBlocks = []string{" ", "▁", "▂", "▃", "▄", "▅", "▆", "▇", "█"}
+
+// VerticalHistogramFormatter formats a histogram as a column chart.
+type VerticalHistogramFormatter struct {
+	formatterOptions
+	histogram *Histogram[float64]
+}
+
+// NewVerticalHistogramFormatter returns a formatter of histogram
+// configured with opts. It panics if the height is less than 2.
+func NewVerticalHistogramFormatter(histogram *Histogram[float64], opts ...FormatterOption) *VerticalHistogramFormatter {
+	o := newFormatterOptions(opts)
+	if o.graphHeight < graphMinHeight {
+		panic(fmt.Sprintf("graph height must be %d or larger, got %d", graphMinHeight, o.graphHeight))
+	}
+	return &VerticalHistogramFormatter{
+		formatterOptions: o,
+		histogram:        histogram,
+	}
+}
+
+// SetTickStyle sets the style of axis point labels.
+// The default is TickStyleFixed.
+func (f *VerticalHistogramFormatter) SetTickStyle(style TickStyle) {
+	WithTickStyle(style)(&f.formatterOptions)
+}
+
+// SetBarStyle sets how bars are drawn. The default is BarStyleChar.
+func (f *VerticalHistogramFormatter) SetBarStyle(style BarStyle) {
+	WithBarStyle(style)(&f.formatterOptions)
+}
+
+// SetBarColor sets the ANSI color of bars. The default is empty, which
+// means no color.
+func (f *VerticalHistogramFormatter) SetBarColor(color string) {
+	WithBarColor(color)(&f.formatterOptions)
+}
+
+func (f *VerticalHistogramFormatter) LineStrings() []string {
+	ticks := FormatTicks(f.histogram.rangePoints, f.tickStyle, f.pointFmt)
+	columnWidth := stringSliceMaxWidth(ticks) + len(" ")
+	counts := f.histogram.counts
+	maxCount := f.histogram.MaxCount()
+
+	maxCountStr := strconv.Itoa(maxCount)
+	axisWidth := len(maxCountStr)
+
+	// Bar heights in eighths of a row.
+	heights := make([]int, len(counts))
+	if maxCount != 0 {
+		for i, count := range counts {
+			height := float64(count) / float64(maxCount) * float64(f.graphHeight)
+			if f.barStyle == BarStyleBlocks {
+				heights[i] = int(height * 8)
+			} else {
+				heights[i] = int(height) * 8
+			}
+		}
+	}
+
+	var lines []string
+	for row := f.graphHeight - 1; row >= 0; row-- {
+		label := ""
+		if row == f.graphHeight-1 {
+			label = maxCountStr
+		}
+		var b strings.Builder
+		for _, height := range heights {
+			var cell string
+			switch eighths := height - row*8; {
+			case eighths >= 8:
+				if f.barStyle == BarStyleBlocks {
+					cell = strings.Repeat(eighthHeightBlocks[8], columnWidth-1)
+				} else {
+					cell = padEndSpace(columnWidth-1, strings.Repeat(f.barChar, (columnWidth-1)/stringWidth(f.barChar)))
+				}
+			case eighths > 0:
+				cell = strings.Repeat(eighthHeightBlocks[eighths], columnWidth-1)
+			default:
+				b.WriteString(strings.Repeat(" ", columnWidth))
+				continue
+			}
+			b.WriteString(colorize(cell, f.barColor))
+			b.WriteString(" ")
+		}
+		lines = append(lines, strings.TrimRight(fmt.Sprintf("%*s |%s", axisWidth, label, b.String()), " "))
+	}
+	lines = append(lines, fmt.Sprintf("%*s +%s", axisWidth, "0", strings.Repeat("-", columnWidth*len(counts))))
+
+	var b strings.Builder
+	for i, tick := range ticks {
+		if i < len(ticks)-1 {
+			b.WriteString(padEndSpace(columnWidth, tick))
+		} else {
+			b.WriteString(tick)
+		}
+	}
+	lines = append(lines, fmt.Sprintf("%*s  %s", axisWidth, "", b.String()))
+	lines = append(lines, fmt.Sprintf("out of range: %d", f.histogram.outOfRangeCount))
+	return lines
+}
+
+func (f *VerticalHistogramFormatter) String() string {
+	var sb strings.Builder
+	f.WriteTo(&sb)
+	return sb.String()
+}
+
+// WriteTo writes the formatted histogram to w line by line without
+// building the whole output as a string.
+func (f *VerticalHistogramFormatter) WriteTo(w io.Writer) (int64, error) {
+	return writeLines(w, f.LineStrings())
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestVerticalHistogramFormatter(t *testing.T) {
+	t.Run("char", func(t *testing.T) {
+		histogram := NewHistogram(BuildRangePoints[float64](4, 0, 4))
+		histogram.AddValues([]float64{0, 1, 1, 1, 1, 2, 3, 3, 5})
+
+		formatter := NewVerticalHistogramFormatter(histogram, WithBarChar(defaultBarChar), WithHeight(4), WithPointFormat("%.1f"))
+		got := formatter.String()
+		want := `4 |    ***
+  |    ***
+  |    ***     ***
+  |*** *** *** ***
+0 +----------------
+   0.0 1.0 2.0 3.0 4.0
+out of range: 1
+`
+		if got != want {
+			t.Errorf("result mismatch,\n got=%q,\nwant=%q", got, want)
+			fmt.Printf("\n%s", got)
+		}
+	})
+	t.Run("blocks", func(t *testing.T) {
+		histogram := NewHistogram(BuildRangePoints[float64](3, 0, 3))
+		histogram.AddValues([]float64{0, 1, 1, 1, 1, 2, 2, 2})
+
+		formatter := NewVerticalHistogramFormatter(histogram, WithBarChar(defaultBarChar), WithHeight(2), WithPointFormat("%g"))
+		formatter.SetBarStyle(BarStyleBlocks)
+		got := formatter.String()
+		want := `4 |  █ ▄
+  |▄ █ █
+0 +------
+   0 1 2 3
+out of range: 0
+`
+		if got != want {
+			t.Errorf("result mismatch,\n got=%q,\nwant=%q", got, want)
+			fmt.Printf("\n%s", got)
+		}
+	})
+}
+
+func TestParseOrientation(t *testing.T) {
+	for _, s := range []string{"horizontal", "vertical"} {
+		got, err := parseOrientation(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != s {
+			t.Errorf("result mismatch, got=%s, want=%s", got, s)
+		}
+	}
+	if _, err := parseOrientation("diagonal"); err == nil {
+		t.Error("want error for an invalid orientation")
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+)
+
+// Warning kinds.
+const (
+	warningBucketCountReduced = "bucket_count_reduced"
+	warningOutOfRange         = "out_of_range"
+	warningInvalidLine        = "invalid_line"
+	warningOutliersDropped    = "outliers_dropped"
+	warningSamplesDropped     = "samples_dropped"
+)
+
+// Warning is a data quality problem found while building histograms, which
+// does not stop the output.
+type Warning struct {
+	// Kind identifies the problem for automation, like "out_of_range".
+	Kind string `json:"kind"`
+	// Message is the human readable description.
+	Message string `json:"message"`
+	// Source is the label of the input the warning is about, if any.
+	Source string `json:"source,omitempty"`
+	// Details holds values specific to Kind.
+	Details map[string]any `json:"details,omitempty"`
+}
+
+// Warner reports warnings.
+type Warner interface {
+	Warn(w Warning)
+}
+
+// Values of the --warnings flag.
+const (
+	warningsText = "text"
+	warningsJSON = "json"
+)
+
+// newWarner returns the Warner for the --warnings flag value. Text
+// warnings are logged with logger, and JSON ones are written to w.
+func newWarner(format string, w io.Writer, logger *slog.Logger) (Warner, error) {
+	switch format {
+	case warningsText:
+		return &textWarner{logger: logger}, nil
+	case warningsJSON:
+		return &jsonWarner{enc: json.NewEncoder(w)}, nil
+	default:
+		return nil, fmt.Errorf("invalid warnings format: %q", format)
+	}
+}
+
+// textWarner logs the message of each warning at the warning level, so
+// warnings are hidden by -q like other messages.
+type textWarner struct {
+	logger *slog.Logger
+}
+
+func (t *textWarner) Warn(w Warning) {
+	if w.Source != "" {
+		t.logger.Warn(w.Message, "source", w.Source)
+	} else {
+		t.logger.Warn(w.Message)
+	}
+}
+
+// jsonWarner writes each warning as a JSON object in a line.
+type jsonWarner struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+func (j *jsonWarner) Warn(w Warning) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	// There is nowhere left to report a failure to write a warning.
+	_ = j.enc.Encode(w)
+}
+
+// warn reports w to cfg.Warner. Warnings are discarded if it is nil.
+func (cfg Config) warn(w Warning) {
+	if cfg.Warner != nil {
+		cfg.Warner.Warn(w)
+	}
+}
+
+// warnOutOfRange reports histograms having values out of the axis range.
+func warnOutOfRange(warner Warner, histograms []*Histogram[float64], labels []string) {
+	for i, h := range histograms {
+		if h.outOfRangeCount == 0 {
+			continue
+		}
+		total := h.outOfRangeCount
+		for _, c := range h.counts {
+			total += c
+		}
+		fraction := float64(h.outOfRangeCount) / float64(total)
+		warner.Warn(Warning{
+			Kind:    warningOutOfRange,
+			Message: fmt.Sprintf("%d of %d values (%.3g%%) are out of range, %d below and %d above", h.outOfRangeCount, total, fraction*100, h.underflowCount, h.overflowCount),
+			Source:  labels[i],
+			Details: map[string]any{
+				"outOfRangeCount": h.outOfRangeCount,
+				"underflowCount":  h.underflowCount,
+				"overflowCount":   h.overflowCount,
+				"totalCount":      total,
+				"fraction":        fraction,
+			},
+		})
+	}
+}
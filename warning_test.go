@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"testing"
+
+	"golang.org/x/exp/slices"
+)
+
+func TestWarnings(t *testing.T) {
+	cfg := Config{
+		BucketCount: 2,
+		AxisMin:     axisRangeEnd{Value: 0},
+		AxisMax:     axisRangeEnd{Value: 4},
+		Scale:       scaleLinear,
+	}
+	sources := []Source{&testSource{name: "a", values: []float64{1, 3, 5, 7}}}
+
+	testCases := []struct {
+		format string
+		level  slog.Level
+		want   string
+	}{
+		{
+			format: warningsText,
+			level:  slog.LevelInfo,
+			want:   "level=WARN msg=\"2 of 4 values (50%) are out of range, 0 below and 2 above\" source=a\n",
+		},
+		// -q hides text warnings like other messages.
+		{
+			format: warningsText,
+			level:  logLevel(true, 0),
+			want:   "",
+		},
+		{
+			format: warningsJSON,
+			level:  slog.LevelInfo,
+			want:   `{"kind":"out_of_range","message":"2 of 4 values (50%) are out of range, 0 below and 2 above","source":"a","details":{"fraction":0.5,"outOfRangeCount":2,"overflowCount":2,"totalCount":4,"underflowCount":0}}` + "\n",
+		},
+	}
+	for _, tc := range testCases {
+		var buf bytes.Buffer
+		warner, err := newWarner(tc.format, &buf, newLogger(&buf, tc.level))
+		if err != nil {
+			t.Fatal(err)
+		}
+		p := &Pipeline{
+			Sources:  sources,
+			Binner:   &streamingBinner{cfg: cfg},
+			Renderer: jsonOutputFormat{},
+			Warner:   warner,
+		}
+		if err := p.Run(io.Discard); err != nil {
+			t.Fatal(err)
+		}
+		if got := buf.String(); got != tc.want {
+			t.Errorf("result mismatch, format=%s,\n got=%s\nwant=%s", tc.format, got, tc.want)
+		}
+	}
+}
+
+func TestWarnings_BucketCountReduced(t *testing.T) {
+	var buf bytes.Buffer
+	warner, err := newWarner(warningsJSON, &buf, discardLogger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := Config{
+		BucketCount: 10,
+		AxisMin:     axisRangeEnd{Auto: true},
+		AxisMax:     axisRangeEnd{Auto: true},
+		Scale:       scaleLinear,
+		Warner:      warner,
+	}
+	// A fixed bucket count is reduced with a warning, and buckets are
+	// centered on the values so that 5 is not on the upper end.
+	histograms, err := (&memoryBinner{cfg: cfg}).Bin([]Source{&testSource{name: "a", values: []float64{1, 2, 3, 4, 5}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"kind":"bucket_count_reduced","message":"reduced bucket count from 10 to 5, the number of distinct values","details":{"from":10,"reason":"distinct_values","to":5}}` + "\n"
+	if got := buf.String(); got != want {
+		t.Errorf("result mismatch for fixed bucket count,\n got=%s\nwant=%s", got, want)
+	}
+	if got, want := histograms[0].RangePoints(), []float64{0.5, 1.5, 2.5, 3.5, 4.5, 5.5}; !slices.Equal(got, want) {
+		t.Errorf("range points mismatch, got=%v, want=%v", got, want)
+	}
+	if got, want := histograms[0].Counts(), []int{1, 1, 1, 1, 1}; !slices.Equal(got, want) || histograms[0].outOfRangeCount != 0 {
+		t.Errorf("counts mismatch, got=%v, outOfRange=%d, want=%v", got, histograms[0].outOfRangeCount, want)
+	}
+	buf.Reset()
+
+	// 300 values give 10 buckets with Sturges' formula.
+	var values []float64
+	for i := 0; i < 100; i++ {
+		values = append(values, 1, 2, 3)
+	}
+	cfg.BucketCountMethod = BucketCountSturges
+	_, err = (&memoryBinner{cfg: cfg}).Bin([]Source{&testSource{name: "a", values: values}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = `{"kind":"bucket_count_reduced","message":"reduced bucket count from 10 to 3, the number of distinct values","details":{"from":10,"reason":"distinct_values","to":3}}` + "\n"
+	if got := buf.String(); got != want {
+		t.Errorf("result mismatch,\n got=%s\nwant=%s", got, want)
+	}
+}
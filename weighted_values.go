@@ -0,0 +1,197 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"golang.org/x/exp/slices"
+)
+
+// weightedValues are values kept in memory with their counts, like
+// records of the counted input format, so that a value read with a large
+// count takes no more memory than one read once. Statistics of them are
+// those of each value repeated by its count.
+type weightedValues struct {
+	values []float64
+	// counts are those of values in the same order, or nil if all of them
+	// are 1, so that plain inputs take no more memory than values.
+	counts []int
+}
+
+// add appends v counted n times. Nothing is added if n is not positive.
+func (w *weightedValues) add(v float64, n int) {
+	if n <= 0 {
+		return
+	}
+	if n != 1 && w.counts == nil {
+		w.counts = make([]int, len(w.values), cap(w.values))
+		for i := range w.counts {
+			w.counts[i] = 1
+		}
+	}
+	w.values = append(w.values, v)
+	if w.counts != nil {
+		w.counts = append(w.counts, n)
+	}
+}
+
+// count returns the count of the i-th value.
+func (w weightedValues) count(i int) int {
+	if w.counts == nil {
+		return 1
+	}
+	return w.counts[i]
+}
+
+// total returns the sum of counts.
+func (w weightedValues) total() int {
+	if w.counts == nil {
+		return len(w.values)
+	}
+	total := 0
+	for _, n := range w.counts {
+		total += n
+	}
+	return total
+}
+
+// concatWeightedValues returns values of all lists together.
+func concatWeightedValues(lists []weightedValues) weightedValues {
+	var all weightedValues
+	for _, w := range lists {
+		if w.counts == nil && all.counts == nil {
+			all.values = append(all.values, w.values...)
+			continue
+		}
+		for i, v := range w.values {
+			all.add(v, w.count(i))
+		}
+	}
+	return all
+}
+
+// sort sorts values in place in increasing order with their counts.
+func (w weightedValues) sort() {
+	if w.counts == nil {
+		slices.Sort(w.values)
+		return
+	}
+	sort.Sort(byWeightedValue(w))
+}
+
+type byWeightedValue weightedValues
+
+func (w byWeightedValue) Len() int           { return len(w.values) }
+func (w byWeightedValue) Less(i, j int) bool { return w.values[i] < w.values[j] }
+func (w byWeightedValue) Swap(i, j int) {
+	w.values[i], w.values[j] = w.values[j], w.values[i]
+	w.counts[i], w.counts[j] = w.counts[j], w.counts[i]
+}
+
+// quantileSorted is like quantileSorted of values each repeated by its
+// count, where w is sorted.
+func (w weightedValues) quantileSorted(q float64) float64 {
+	if w.counts == nil {
+		return quantileSorted(w.values, q)
+	}
+	total := w.total()
+	if total == 0 {
+		panic("values must not be empty")
+	}
+	pos := q * float64(total-1)
+	rank := int(math.Floor(pos))
+	if rank >= total-1 {
+		return w.values[len(w.values)-1]
+	}
+	// Find the values at rank and rank+1 in the repeated values.
+	i, below := 0, 0
+	for below+w.counts[i] <= rank {
+		below += w.counts[i]
+		i++
+	}
+	lower, upper := w.values[i], w.values[i]
+	if below+w.counts[i] == rank+1 {
+		upper = w.values[i+1]
+	}
+	frac := pos - float64(rank)
+	return lower + (upper-lower)*frac
+}
+
+// mean is like mean of values each repeated by its count.
+func (w weightedValues) mean() float64 {
+	if w.counts == nil {
+		return mean(w.values)
+	}
+	return w.stats().Mean
+}
+
+// stddev is like stddev of values each repeated by its count.
+func (w weightedValues) stddev() float64 {
+	if w.counts == nil {
+		return stddev(w.values)
+	}
+	return w.stats().Stddev
+}
+
+func (w weightedValues) stats() Stats {
+	var acc statsAccumulator
+	for i, v := range w.values {
+		acc.addCount(v, w.counts[i])
+	}
+	return acc.Stats()
+}
+
+// clone returns a copy of w which does not share memory with it.
+func (w weightedValues) clone() weightedValues {
+	return weightedValues{values: slices.Clone(w.values), counts: slices.Clone(w.counts)}
+}
+
+// filter removes values for which keep returns false in place and returns
+// the sum of their counts.
+func (w *weightedValues) filter(keep func(v float64) bool) (dropped int) {
+	kept := 0
+	for i, v := range w.values {
+		if !keep(v) {
+			dropped += w.count(i)
+			continue
+		}
+		w.values[kept] = v
+		if w.counts != nil {
+			w.counts[kept] = w.counts[i]
+		}
+		kept++
+	}
+	w.values = w.values[:kept]
+	if w.counts != nil {
+		w.counts = w.counts[:kept]
+	}
+	return dropped
+}
+
+// addTo counts values in h by their counts.
+func (w weightedValues) addTo(h *Histogram[float64]) {
+	if w.counts == nil {
+		h.AddValues(w.values)
+		return
+	}
+	for i, v := range w.values {
+		h.addValueCount(v, w.counts[i])
+	}
+}
+
+// readSourceWeightedValues is like readSourceValues but keeps a value read
+// with a count once with the count.
+func readSourceWeightedValues(src Source) (weightedValues, error) {
+	var w weightedValues
+	err := scanSourceCounts(src, func(v float64, n int) {
+		w.add(v, n)
+	})
+	if err != nil {
+		return weightedValues{}, err
+	}
+	if len(w.values) == 0 {
+		return weightedValues{}, fmt.Errorf("no value in %s", src.Name())
+	}
+	return w, nil
+}
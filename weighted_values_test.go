@@ -0,0 +1,48 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	"golang.org/x/exp/slices"
+)
+
+// TestWeightedValues checks that statistics of weighted values are those
+// of values repeated by their counts.
+func TestWeightedValues(t *testing.T) {
+	var w weightedValues
+	w.add(2.5, 4)
+	w.add(1, 1)
+	w.add(9, 0)
+	w.add(5, 2)
+	w.add(1, 2)
+	repeated := []float64{2.5, 2.5, 2.5, 2.5, 1, 5, 5, 1, 1}
+
+	if got, want := w.total(), len(repeated); got != want {
+		t.Errorf("total mismatch, got=%d, want=%d", got, want)
+	}
+	sorted := w.clone()
+	sorted.sort()
+	slices.Sort(repeated)
+	for _, q := range []float64{0, 0.1, 0.25, 0.3, 0.5, 0.75, 0.9, 1} {
+		if got, want := sorted.quantileSorted(q), quantileSorted(repeated, q); got != want {
+			t.Errorf("quantile mismatch, q=%g, got=%g, want=%g", q, got, want)
+		}
+	}
+	if got, want := w.mean(), mean(repeated); got != want {
+		t.Errorf("mean mismatch, got=%g, want=%g", got, want)
+	}
+	if got, want := w.stddev(), stddev(repeated); math.Abs(got-want) > 1e-12 {
+		t.Errorf("stddev mismatch, got=%g, want=%g", got, want)
+	}
+
+	if got, want := w.filter(func(v float64) bool { return v < 5 }), 2; got != want {
+		t.Errorf("dropped count mismatch, got=%d, want=%d", got, want)
+	}
+	if got, want := w.values, []float64{2.5, 1, 1}; !slices.Equal(got, want) {
+		t.Errorf("filtered values mismatch, got=%v, want=%v", got, want)
+	}
+	if got, want := w.counts, []int{4, 1, 2}; !slices.Equal(got, want) {
+		t.Errorf("filtered counts mismatch, got=%v, want=%v", got, want)
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+)
+
+// wideRuneRanges are the ranges of East Asian Wide and Fullwidth runes,
+// which take two cells in terminals.
+var wideRuneRanges = &unicode.RangeTable{
+	R16: []unicode.Range16{
+		{Lo: 0x1100, Hi: 0x115f, Stride: 1},
+		{Lo: 0x231a, Hi: 0x231b, Stride: 1},
+		{Lo: 0x2329, Hi: 0x232a, Stride: 1},
+		{Lo: 0x23e9, Hi: 0x23ec, Stride: 1},
+		{Lo: 0x25fd, Hi: 0x25fe, Stride: 1},
+		{Lo: 0x2614, Hi: 0x2615, Stride: 1},
+		{Lo: 0x2e80, Hi: 0x303e, Stride: 1},
+		{Lo: 0x3041, Hi: 0x33ff, Stride: 1},
+		{Lo: 0x3400, Hi: 0x4dbf, Stride: 1},
+		{Lo: 0x4e00, Hi: 0x9fff, Stride: 1},
+		{Lo: 0xa000, Hi: 0xa4cf, Stride: 1},
+		{Lo: 0xa960, Hi: 0xa97f, Stride: 1},
+		{Lo: 0xac00, Hi: 0xd7a3, Stride: 1},
+		{Lo: 0xf900, Hi: 0xfaff, Stride: 1},
+		{Lo: 0xfe10, Hi: 0xfe19, Stride: 1},
+		{Lo: 0xfe30, Hi: 0xfe6f, Stride: 1},
+		{Lo: 0xff00, Hi: 0xff60, Stride: 1},
+		{Lo: 0xffe0, Hi: 0xffe6, Stride: 1},
+	},
+	R32: []unicode.Range32{
+		{Lo: 0x16fe0, Hi: 0x16fe4, Stride: 1},
+		{Lo: 0x17000, Hi: 0x18cff, Stride: 1},
+		{Lo: 0x1b000, Hi: 0x1b2ff, Stride: 1},
+		{Lo: 0x1f300, Hi: 0x1f64f, Stride: 1},
+		{Lo: 0x1f680, Hi: 0x1f6ff, Stride: 1},
+		{Lo: 0x1f900, Hi: 0x1f9ff, Stride: 1},
+		{Lo: 0x1fa70, Hi: 0x1faff, Stride: 1},
+		{Lo: 0x20000, Hi: 0x2fffd, Stride: 1},
+		{Lo: 0x30000, Hi: 0x3fffd, Stride: 1},
+	},
+}
+
+// runeWidth returns the number of terminal cells r takes, which is 2 for
+// East Asian wide runes, 0 for combining marks and format characters like
+// zero width spaces, and 1 for others.
+func runeWidth(r rune) int {
+	switch {
+	case r < 0x20 || r == 0x7f:
+		return 0
+	case r < 0x7f:
+		return 1
+	case unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf):
+		return 0
+	case unicode.Is(wideRuneRanges, r):
+		return 2
+	default:
+		return 1
+	}
+}
+
+// stringWidth returns the number of terminal cells s takes. Unlike len(s),
+// it counts a multi-byte rune like "█" as one cell and a wide rune like
+// "漢" as two.
+func stringWidth(s string) int {
+	w := 0
+	for _, r := range s {
+		w += runeWidth(r)
+	}
+	return w
+}
+
+// truncateToWidth returns s cut to at most width cells, ending with "…" if
+// it is cut.
+func truncateToWidth(s string, width int) string {
+	if stringWidth(s) <= width {
+		return s
+	}
+	w := 0
+	for i, r := range s {
+		if w+runeWidth(r) > width-1 {
+			return s[:i] + "…"
+		}
+		w += runeWidth(r)
+	}
+	return s
+}
+
+// truncateStartToWidth is like truncateToWidth but cuts s at the start,
+// which keeps the distinctive end of labels like filenames.
+func truncateStartToWidth(s string, width int) string {
+	if stringWidth(s) <= width {
+		return s
+	}
+	runes := []rune(s)
+	w := 0
+	for i := len(runes) - 1; i >= 0; i-- {
+		if w+runeWidth(runes[i]) > width-1 {
+			return "…" + string(runes[i+1:])
+		}
+		w += runeWidth(runes[i])
+	}
+	return s
+}
+
+// padStartSpace pads s with spaces at the start up to targetWidth cells.
+func padStartSpace(targetWidth int, s string) string {
+	return strings.Repeat(" ", MustMax(0, targetWidth-stringWidth(s))) + s
+}
+
+// padEndSpace pads s with spaces at the end up to targetWidth cells.
+func padEndSpace(targetWidth int, s string) string {
+	return s + strings.Repeat(" ", MustMax(0, targetWidth-stringWidth(s)))
+}
@@ -0,0 +1,73 @@
+package main
+
+import "testing"
+
+func TestStringWidth(t *testing.T) {
+	testCases := []struct {
+		s    string
+		want int
+	}{
+		{s: "", want: 0},
+		{s: "abc", want: 3},
+		{s: "█▌", want: 2},
+		{s: "1.5µs", want: 5},
+		{s: "漢字", want: 4},
+		{s: "ｱｲ", want: 2},
+		{s: "ＡＢ", want: 4},
+		{s: "é", want: 1},
+		{s: "a​b", want: 2},
+	}
+	for _, c := range testCases {
+		if got := stringWidth(c.s); got != c.want {
+			t.Errorf("result mismatch, s=%q, got=%d, want=%d", c.s, got, c.want)
+		}
+	}
+}
+
+func TestTruncateToWidth(t *testing.T) {
+	testCases := []struct {
+		s     string
+		width int
+		want  string
+	}{
+		{s: "abc", width: 3, want: "abc"},
+		{s: "abcd", width: 3, want: "ab…"},
+		{s: "漢字かな", width: 5, want: "漢字…"},
+		{s: "漢字かな", width: 4, want: "漢…"},
+	}
+	for _, c := range testCases {
+		if got := truncateToWidth(c.s, c.width); got != c.want {
+			t.Errorf("result mismatch, s=%q, width=%d, got=%q, want=%q", c.s, c.width, got, c.want)
+		}
+	}
+}
+
+func TestTruncateStartToWidth(t *testing.T) {
+	testCases := []struct {
+		s     string
+		width int
+		want  string
+	}{
+		{s: "abc", width: 3, want: "abc"},
+		{s: "abcd", width: 3, want: "…cd"},
+		{s: "漢字かな", width: 5, want: "…かな"},
+		{s: "漢字かな", width: 4, want: "…な"},
+	}
+	for _, c := range testCases {
+		if got := truncateStartToWidth(c.s, c.width); got != c.want {
+			t.Errorf("result mismatch, s=%q, width=%d, got=%q, want=%q", c.s, c.width, got, c.want)
+		}
+	}
+}
+
+func TestPadSpace(t *testing.T) {
+	if got, want := padStartSpace(6, "漢字"), "  漢字"; got != want {
+		t.Errorf("padStartSpace result mismatch, got=%q, want=%q", got, want)
+	}
+	if got, want := padEndSpace(6, "µs"), "µs    "; got != want {
+		t.Errorf("padEndSpace result mismatch, got=%q, want=%q", got, want)
+	}
+	if got, want := padStartSpace(1, "abc"), "abc"; got != want {
+		t.Errorf("padStartSpace result mismatch, got=%q, want=%q", got, want)
+	}
+}